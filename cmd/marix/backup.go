@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/quocson95/marix/pkg/s3"
+	"github.com/quocson95/marix/pkg/storage"
+)
+
+// backupResult is printed by `marix backup -json` and `marix restore -json`
+type backupResult struct {
+	Action string `json:"action"`
+	Host   string `json:"host"`
+	Bucket string `json:"bucket"`
+}
+
+// runBackup implements `marix backup`, uploading an encrypted backup to S3
+func runBackup(dataDir string, args []string) error {
+	return runS3(dataDir, "backup", "uploads an encrypted backup of the data directory", args,
+		func(client *s3.Client, password string) error {
+			return client.Backup(dataDir, password)
+		})
+}
+
+// runRestore implements `marix restore`, replacing the data directory with
+// the latest backup from S3
+func runRestore(dataDir string, args []string) error {
+	return runS3(dataDir, "restore", "replaces the data directory with the latest backup", args,
+		func(client *s3.Client, password string) error {
+			return client.Restore(dataDir, password)
+		})
+}
+
+// runS3 parses the shared flags of backup and restore and runs action with
+// the S3 settings saved in the Backup & Restore screen
+func runS3(dataDir, name, summary string, args []string, action func(*s3.Client, string) error) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the result as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: marix %s [-json]\n", name)
+		fmt.Fprintf(fs.Output(), "\nUses the S3 settings from the Backup & Restore screen and %s.\n", summary)
+		fmt.Fprintln(fs.Output(), "The backup is encrypted with the password from MARIX_BACKUP_PASSWORD.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return withCode(exitUsage, err)
+	}
+
+	password := os.Getenv("MARIX_BACKUP_PASSWORD")
+	if password == "" {
+		return withCode(exitUsage, fmt.Errorf("MARIX_BACKUP_PASSWORD is not set"))
+	}
+
+	settingsStore, err := storage.NewSettingsStore(dataDir)
+	if err != nil {
+		return fmt.Errorf("failed to open settings: %w", err)
+	}
+	settings := settingsStore.Get()
+	if settings.S3Host == "" || settings.S3AccessKey == "" || settings.S3SecretKey == "" {
+		return withCode(exitUsage, fmt.Errorf("S3 is not configured, set it up in Backup & Restore"))
+	}
+
+	client, err := s3.NewClient(settings.S3Host, settings.S3AccessKey, settings.S3SecretKey)
+	if err != nil {
+		return withCode(exitConnect, fmt.Errorf("S3 connection failed: %w", err))
+	}
+	if err := action(client, password); err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(backupResult{Action: name, Host: settings.S3Host, Bucket: s3.BucketName})
+	}
+	fmt.Fprintf(os.Stderr, "%s complete (%s, bucket %s)\n", name, settings.S3Host, s3.BucketName)
+	return nil
+}
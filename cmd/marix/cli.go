@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/quocson95/marix/pkg/ssh"
+	"github.com/quocson95/marix/pkg/storage"
+)
+
+// Exit codes of the non-interactive subcommands, so scripts can tell
+// failures apart. `marix ssh <server> command` exits with the remote status.
+const (
+	exitFailure  = 1 // Anything not covered below
+	exitUsage    = 2 // Bad flags or arguments
+	exitNotFound = 3 // No such server, tag or file
+	exitConnect  = 4 // Connection or authentication failed
+	exitRemote   = 5 // The remote command failed
+)
+
+// cliError is an error that ends the process with a specific exit code.
+// A nil err exits silently, e.g. to pass on a remote exit status.
+type cliError struct {
+	code int
+	err  error
+}
+
+func (e *cliError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *cliError) Unwrap() error {
+	return e.err
+}
+
+// withCode attaches an exit code to err
+func withCode(code int, err error) error {
+	return &cliError{code: code, err: err}
+}
+
+// exitCode returns the exit code for err and whether it should be printed
+func exitCode(err error) (int, bool) {
+	var cliErr *cliError
+	if errors.As(err, &cliErr) {
+		return cliErr.code, cliErr.err != nil
+	}
+	return exitFailure, true
+}
+
+// masterPassword returns the master password from MARIX_MASTER_PASSWORD,
+// checked against the settings. It is empty when the variable is not set.
+func masterPassword(dataDir string) (string, error) {
+	password := os.Getenv("MARIX_MASTER_PASSWORD")
+	if password == "" {
+		return "", nil
+	}
+	settingsStore, err := storage.NewSettingsStore(dataDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to open settings: %w", err)
+	}
+	if !settingsStore.VerifyMasterPassword(password) {
+		return "", withCode(exitConnect, fmt.Errorf("incorrect master password"))
+	}
+	return password, nil
+}
+
+// findServer looks up a saved server by ID or name
+func findServer(store *storage.Store, nameOrID string) (*storage.Server, error) {
+	server, err := store.Find(nameOrID)
+	if err != nil {
+		return nil, withCode(exitNotFound, err)
+	}
+	return server, nil
+}
+
+// serverConfig builds the SSH config for a saved server, including its jump hosts
+func serverConfig(store *storage.Store, server *storage.Server, password string) (*ssh.SSHConfig, error) {
+	if ssh.NeedsMasterPassword(store, server) && password == "" {
+		return nil, withCode(exitConnect,
+			fmt.Errorf("%s: set MARIX_MASTER_PASSWORD to decrypt the private key", server.Name))
+	}
+	config, err := ssh.ServerSSHConfig(store, server, password)
+	if err == nil {
+		err = config.Validate()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", server.Name, err)
+	}
+	return config, nil
+}
+
+// connectServer looks up a server and connects to it through manager. The
+// client must be given back with manager.Release.
+func connectServer(dataDir string, manager *ssh.Manager, nameOrID string) (*ssh.Client, error) {
+	store, err := storage.NewStore(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open server store: %w", err)
+	}
+	server, err := findServer(store, nameOrID)
+	if err != nil {
+		return nil, err
+	}
+	password, err := masterPassword(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	config, err := serverConfig(store, server, password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a verifier, clients check ~/.ssh/known_hosts and reject unknown hosts
+	client, err := manager.Acquire(config)
+	if err != nil {
+		return nil, withCode(exitConnect, fmt.Errorf("%s: %w", server.Name, err))
+	}
+	store.MarkConnected(server.ID)
+	return client, nil
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// formatBytes formats a byte count with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
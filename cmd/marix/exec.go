@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/quocson95/marix/pkg/ssh"
+	"github.com/quocson95/marix/pkg/storage"
+)
+
+const defaultExecParallel = 5
+
+// execResult is a host's outcome as printed by `marix exec -json`
+type execResult struct {
+	Host       string     `json:"host"`
+	ExitCode   int        `json:"exitCode"` // -1 when the command did not finish
+	Error      string     `json:"error,omitempty"`
+	DurationMs int64      `json:"durationMs"`
+	Output     []execLine `json:"output"`
+}
+
+type execLine struct {
+	Stream string `json:"stream"`
+	Text   string `json:"text"`
+}
+
+// runExec implements `marix exec`, running a command on every server with a
+// tag or in a group
+func runExec(dataDir string, args []string) error {
+	fs := flag.NewFlagSet("exec", flag.ContinueOnError)
+	parallel := fs.Int("parallel", defaultExecParallel, "number of hosts to run on at once")
+	asJSON := fs.Bool("json", false, "print the results as JSON when all hosts are done")
+	report := fs.String("report", "", "also write a summary report to this file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: marix exec [-parallel n] [-json] [-report file] <tag|group:path> -- command...")
+		fmt.Fprintln(fs.Output(), "\nRuns on every server with the tag, or in the group and its subgroups.")
+		fmt.Fprintln(fs.Output(), "Output lines are prefixed with the server name. Exits 4 when a host could")
+		fmt.Fprintln(fs.Output(), "not be reached and 5 when the command failed on a host.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return withCode(exitUsage, err)
+	}
+	command := fs.Args()
+	if len(command) > 1 && command[1] == "--" {
+		command = append(command[:1], command[2:]...)
+	}
+	if len(command) < 2 || *parallel < 1 {
+		fs.Usage()
+		return withCode(exitUsage, fmt.Errorf("a tag or group and a command are required"))
+	}
+	tag := command[0]
+	remote := strings.Join(command[1:], " ")
+
+	store, err := storage.NewStore(dataDir)
+	if err != nil {
+		return fmt.Errorf("failed to open server store: %w", err)
+	}
+	password, err := masterPassword(dataDir)
+	if err != nil {
+		return err
+	}
+
+	var targets []ssh.ExecTarget
+	var failed []ssh.ExecResult // Servers whose config could not be built
+	for _, server := range store.List() {
+		if !server.Matches(tag) {
+			continue
+		}
+		config, err := serverConfig(store, server, password)
+		if err != nil {
+			failed = append(failed, ssh.ExecResult{Target: server.Name, ExitCode: -1, Err: err})
+			continue
+		}
+		targets = append(targets, ssh.ExecTarget{Name: server.Name, Config: config})
+	}
+	if len(targets) == 0 && len(failed) == 0 {
+		return withCode(exitNotFound, fmt.Errorf("no server matches %q", tag))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Hosts write concurrently, keep their lines whole
+	var mu sync.Mutex
+	exec := &ssh.ParallelExec{Concurrency: *parallel}
+	if !*asJSON {
+		for _, result := range failed {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", result.Target, result.Err)
+		}
+		exec.OnLine = func(line ssh.ExecLine) {
+			mu.Lock()
+			defer mu.Unlock()
+			out := os.Stdout
+			if line.Stream == ssh.StreamStderr {
+				out = os.Stderr
+			}
+			fmt.Fprintf(out, "%s | %s\n", line.Target, line.Text)
+		}
+		exec.OnResult = func(result ssh.ExecResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case result.Err != nil:
+				fmt.Fprintf(os.Stderr, "%s: %v\n", result.Target, result.Err)
+			case result.ExitCode != 0:
+				fmt.Fprintf(os.Stderr, "%s: exit status %d\n", result.Target, result.ExitCode)
+			}
+		}
+	}
+	results := append(failed, exec.Run(ctx, targets, remote)...)
+
+	if *report != "" {
+		file, err := os.OpenFile(*report, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		err = ssh.WriteExecReport(file, remote, results)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+
+	unreachable, errored := 0, 0
+	for _, result := range results {
+		switch {
+		case result.Err != nil && result.ExitCode < 0:
+			unreachable++
+		case !result.OK():
+			errored++
+		}
+	}
+
+	if *asJSON {
+		if err := printJSON(execResults(results)); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(os.Stderr, "%d host(s), %d succeeded, %d failed\n",
+			len(results), len(results)-unreachable-errored, unreachable+errored)
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		return withCode(exitFailure, fmt.Errorf("interrupted"))
+	case unreachable > 0:
+		return withCode(exitConnect, nil)
+	case errored > 0:
+		return withCode(exitRemote, nil)
+	}
+	return nil
+}
+
+// execResults converts results for JSON output
+func execResults(results []ssh.ExecResult) []execResult {
+	out := make([]execResult, 0, len(results))
+	for _, r := range results {
+		result := execResult{Host: r.Target, ExitCode: r.ExitCode, Output: []execLine{}}
+		if r.Err != nil {
+			result.Error = r.Err.Error()
+		}
+		if !r.Started.IsZero() {
+			result.DurationMs = r.Finished.Sub(r.Started).Milliseconds()
+		}
+		for _, line := range r.Output {
+			result.Output = append(result.Output, execLine{Stream: line.Stream, Text: line.Text})
+		}
+		out = append(out, result)
+	}
+	return out
+}
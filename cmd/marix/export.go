@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/quocson95/marix/pkg/storage"
+)
+
+// runExport implements `marix export`, writing the saved servers to stdout or a file
+func runExport(dataDir string, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", string(storage.ExportSSHConfig), "output format: ssh-config, json or csv")
+	output := fs.String("o", "", "write to file instead of stdout")
+	includeSecrets := fs.Bool("include-secrets", false, "include passwords and private keys (json and csv only)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: marix export [-format ssh-config|json|csv] [-o file] [-include-secrets]")
+		fmt.Fprintln(fs.Output(), "\nEncrypted keys are decrypted with the master password from MARIX_MASTER_PASSWORD.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return withCode(exitUsage, err)
+	}
+
+	store, err := storage.NewStore(dataDir)
+	if err != nil {
+		return fmt.Errorf("failed to open server store: %w", err)
+	}
+
+	opts := storage.ExportOptions{
+		Format:         storage.ExportFormat(*format),
+		IncludeSecrets: *includeSecrets,
+	}
+
+	if opts.IncludeSecrets {
+		if opts.MasterPassword, err = masterPassword(dataDir); err != nil {
+			return err
+		}
+	}
+
+	if *output == "" {
+		return store.Export(os.Stdout, opts)
+	}
+
+	// Exports may hold secrets, so keep them private like servers.json
+	f, err := os.OpenFile(*output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if err := store.Export(f, opts); err != nil {
+		f.Close()
+		return err
+	}
+	// A failed close can mean the export never fully reached the disk
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	return nil
+}
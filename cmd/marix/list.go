@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/quocson95/marix/pkg/storage"
+)
+
+// listEntry is a server as printed by `marix list -json`, without its secrets
+type listEntry struct {
+	ID              string   `json:"id"`
+	Name            string   `json:"name"`
+	Host            string   `json:"host"`
+	Port            int      `json:"port"`
+	Username        string   `json:"username"`
+	Protocol        string   `json:"protocol,omitempty"`
+	Tags            []string `json:"tags"`
+	Group           string   `json:"group,omitempty"`
+	JumpHosts       []string `json:"jumpHosts,omitempty"` // Names of the jump hosts, first hop first
+	Description     string   `json:"description,omitempty"`
+	Favorite        bool     `json:"favorite,omitempty"`
+	LastConnectedAt int64    `json:"lastConnectedAt,omitempty"` // Unix time
+	ConnectCount    int      `json:"connectCount"`
+}
+
+// runList implements `marix list`, printing the saved servers
+func runList(dataDir string, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	tag := fs.String("tag", "", "only list servers with this tag")
+	group := fs.String("group", "", "only list servers in this group or its subgroups")
+	search := fs.String("search", "", "only list servers fuzzy-matching this in name, host, user, tags or description")
+	order := fs.String("sort", "name", "sort by name, recent (last used) or frequent (most used)")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: marix list [-tag tag] [-group group] [-search text] [-sort order] [-json]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return withCode(exitUsage, err)
+	}
+
+	serverOrder := storage.ServerOrder(*order)
+	if *order == "name" {
+		serverOrder = storage.OrderByName
+	}
+	if !slices.Contains(storage.ServerOrders, serverOrder) {
+		return withCode(exitUsage, fmt.Errorf("unknown sort order %q", *order))
+	}
+
+	store, err := storage.NewStore(dataDir)
+	if err != nil {
+		return fmt.Errorf("failed to open server store: %w", err)
+	}
+
+	var servers []*storage.Server
+	scores := make(map[*storage.Server]int)
+	for _, server := range store.List() {
+		if (*tag != "" && !server.HasTag(*tag)) || !server.InGroup(*group) {
+			continue
+		}
+		score, ok := server.SearchScore(*search)
+		if !ok {
+			continue
+		}
+		scores[server] = score
+		servers = append(servers, server)
+	}
+	storage.SortServers(servers, serverOrder)
+	// Best matches first when searching
+	sort.SliceStable(servers, func(i, j int) bool {
+		return scores[servers[i]] > scores[servers[j]]
+	})
+
+	if *asJSON {
+		entries := make([]listEntry, 0, len(servers))
+		for _, server := range servers {
+			entry := listEntry{
+				ID:              server.ID,
+				Name:            server.Name,
+				Host:            server.Host,
+				Port:            server.Port,
+				Username:        server.Username,
+				Protocol:        server.Protocol,
+				Tags:            server.Tags,
+				Group:           server.Group,
+				Description:     server.Description,
+				Favorite:        server.Favorite,
+				LastConnectedAt: server.LastConnectedAt,
+				ConnectCount:    server.ConnectCount,
+			}
+			if entry.Tags == nil {
+				entry.Tags = []string{}
+			}
+			hops, _ := store.JumpChain(server)
+			for _, hop := range hops {
+				entry.JumpHosts = append(entry.JumpHosts, hop.Name)
+			}
+			entries = append(entries, entry)
+		}
+		return printJSON(entries)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tHOST\tPORT\tUSER\tGROUP\tTAGS")
+	for _, server := range servers {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\n", server.Name, server.Host, server.Port,
+			server.Username, server.Group, strings.Join(server.Tags, ","))
+	}
+	return tw.Flush()
+}
@@ -11,6 +11,38 @@ import (
 	"github.com/quocson95/marix/pkg/tui"
 )
 
+// subcommands run without the TUI, for scripts
+var subcommands = map[string]func(dataDir string, args []string) error{
+	"list":    runList,
+	"ssh":     runSSH,
+	"exec":    runExec,
+	"get":     runGet,
+	"put":     runPut,
+	"backup":  runBackup,
+	"restore": runRestore,
+	"export":  runExport,
+	"tunnel":  runTunnel,
+}
+
+func printUsage() {
+	fmt.Println(`Usage: marix [command]
+
+Without a command, marix starts the terminal UI.
+
+Commands:
+  list      list saved servers
+  ssh       open a shell or run a command on a server
+  exec      run a command on every server with a tag
+  get       download a file or directory
+  put       upload a file or directory
+  backup    upload an encrypted backup to S3
+  restore   restore the latest backup from S3
+  export    export saved servers
+  tunnel    run tunnel profiles in the foreground
+
+Run 'marix <command> -h' for the flags of a command.`)
+}
+
 func main() {
 	// Set up logging to file
 	homeDir, err := os.UserHomeDir()
@@ -46,6 +78,25 @@ func main() {
 	handler := slog.NewTextHandler(logFile, nil)
 	slog.SetDefault(slog.New(handler))
 
+	// Non-interactive subcommands
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			if err := run(dataDir, os.Args[2:]); err != nil {
+				code, show := exitCode(err)
+				if show {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				}
+				logFile.Close()
+				os.Exit(code)
+			}
+			return
+		}
+		if os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+			printUsage()
+			return
+		}
+	}
+
 	// Create the application model
 	appModel, err := tui.NewAppModel()
 	if err != nil {
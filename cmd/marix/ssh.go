@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/quocson95/marix/pkg/ssh"
+	"github.com/quocson95/marix/pkg/storage"
+)
+
+// resizePoll is how often an interactive shell checks the terminal size.
+// Polling works on every platform, unlike SIGWINCH.
+const resizePoll = 250 * time.Millisecond
+
+// runSSH implements `marix ssh`, opening a shell on a saved server or running
+// a command there
+func runSSH(dataDir string, args []string) error {
+	fs := flag.NewFlagSet("ssh", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: marix ssh <server> [--] [command...]")
+		fmt.Fprintln(fs.Output(), "\nWithout a command, opens an interactive shell. With one, runs it and")
+		fmt.Fprintln(fs.Output(), "exits with its status. Hosts must already be in ~/.ssh/known_hosts.")
+		fmt.Fprintln(fs.Output(), "Encrypted keys are decrypted with the master password from MARIX_MASTER_PASSWORD.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return withCode(exitUsage, err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return withCode(exitUsage, fmt.Errorf("no server given"))
+	}
+	command := fs.Args()[1:]
+	if len(command) > 0 && command[0] == "--" {
+		command = command[1:]
+	}
+
+	manager := ssh.NewManager()
+	client, err := connectServer(dataDir, manager, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer manager.Release(client)
+
+	if len(command) > 0 {
+		code, err := client.Run(strings.Join(command, " "), os.Stdout, os.Stderr)
+		switch {
+		case err != nil && code < 0:
+			return withCode(exitConnect, err)
+		case err != nil:
+			return withCode(exitRemote, err)
+		case code != 0:
+			return withCode(code, nil)
+		}
+		return nil
+	}
+
+	settingsStore, err := storage.NewSettingsStore(dataDir)
+	if err != nil {
+		return fmt.Errorf("failed to open settings: %w", err)
+	}
+	return interactiveShell(client, settingsStore.Get())
+}
+
+// interactiveShell connects the local terminal to a shell on client until
+// the shell exits
+func interactiveShell(client *ssh.Client, settings storage.Settings) error {
+	stdin, stdout := os.Stdin.Fd(), os.Stdout.Fd()
+	if !term.IsTerminal(stdin) {
+		return withCode(exitUsage, fmt.Errorf("stdin is not a terminal, give a command to run"))
+	}
+
+	cols, rows, err := term.GetSize(stdout)
+	if err != nil {
+		cols, rows = 80, 24
+	}
+
+	done := make(chan struct{})
+	var once sync.Once
+	client.OnData(func(data []byte) {
+		os.Stdout.Write(data)
+	})
+	client.OnClose(func() {
+		once.Do(func() { close(done) })
+	})
+	client.SetKeepAlive(time.Duration(settings.KeepAliveInterval)*time.Second, settings.KeepAliveCountMax)
+
+	if err := client.CreateShell(cols, rows); err != nil {
+		return withCode(exitConnect, err)
+	}
+
+	state, err := term.MakeRaw(stdin)
+	if err != nil {
+		return fmt.Errorf("failed to set the terminal to raw mode: %w", err)
+	}
+	defer term.Restore(stdin, state)
+
+	// The reader stays blocked on stdin once the shell ends; the process
+	// exits soon after
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if n > 0 {
+				client.Write(buf[:n])
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(resizePoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			if err := client.Err(); err != nil {
+				return withCode(exitConnect, err)
+			}
+			return nil
+		case <-ticker.C:
+			if c, r, err := term.GetSize(stdout); err == nil && (c != cols || r != rows) {
+				cols, rows = c, r
+				client.Resize(cols, rows)
+			}
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/quocson95/marix/pkg/sftp"
+	"github.com/quocson95/marix/pkg/ssh"
+	"github.com/quocson95/marix/pkg/storage"
+)
+
+// transferResult is printed by `marix get -json` and `marix put -json`
+type transferResult struct {
+	Source string `json:"source"`
+	Dest   string `json:"dest"`
+	Files  int    `json:"files"`
+	Bytes  int64  `json:"bytes"`
+}
+
+// transferFlags are the options shared by get and put
+type transferFlags struct {
+	asJSON      *bool
+	verify      *bool
+	include     *string
+	exclude     *string
+	ignoreFiles *bool
+	preset      *string
+}
+
+// addTransferFlags defines the options shared by get and put on fs
+func addTransferFlags(fs *flag.FlagSet) *transferFlags {
+	return &transferFlags{
+		asJSON:      fs.Bool("json", false, "print the result as JSON"),
+		verify:      fs.Bool("verify", false, "compare SHA-256 checksums after each file (always on with verifyTransfers in Settings)"),
+		include:     fs.String("include", "", "comma-separated globs of the only files to copy from a directory"),
+		exclude:     fs.String("exclude", "", "comma-separated globs to leave out of a directory, such as node_modules/,*.log"),
+		ignoreFiles: fs.Bool("ignore-files", false, "leave out what .gitignore and .marixignore files list"),
+		preset:      fs.String("preset", "", "apply the server's saved filter preset `name`"),
+	}
+}
+
+// filter combines the filter flags with the saved preset they name
+func (f *transferFlags) filter(server *storage.Server) (sftp.Filter, error) {
+	filter := sftp.Filter{
+		Include:     sftp.ParsePatterns(*f.include),
+		Exclude:     sftp.ParsePatterns(*f.exclude),
+		IgnoreFiles: *f.ignoreFiles,
+	}
+	if *f.preset == "" {
+		return filter, nil
+	}
+	preset, ok := server.FilterPreset(*f.preset)
+	if !ok {
+		return filter, withCode(exitNotFound, fmt.Errorf("%s has no filter preset named %q", server.Name, *f.preset))
+	}
+	filter.Include = append(preset.Include, filter.Include...)
+	filter.Exclude = append(preset.Exclude, filter.Exclude...)
+	filter.IgnoreFiles = filter.IgnoreFiles || preset.IgnoreFiles
+	return filter, nil
+}
+
+// runGet implements `marix get`, downloading a file or directory
+func runGet(dataDir string, args []string) error {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	flags := addTransferFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: marix get [-json] [-verify] [filter flags] <server>:<remote-path> [local-path]")
+		fmt.Fprintln(fs.Output(), "\nDirectories are copied into local-path, which must then exist.")
+		fmt.Fprintln(fs.Output(), "Filter flags and presets only apply to directories.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return withCode(exitUsage, err)
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return withCode(exitUsage, fmt.Errorf("expected a source and an optional destination"))
+	}
+	server, remotePath, ok := splitRemote(fs.Arg(0))
+	if !ok {
+		return withCode(exitUsage, fmt.Errorf("source must be <server>:<path>, got %q", fs.Arg(0)))
+	}
+	if remotePath == "" {
+		return withCode(exitUsage, fmt.Errorf("no remote path in %q", fs.Arg(0)))
+	}
+	// The scanner names copied directories after the source's last element
+	remotePath = path.Clean(remotePath)
+	localPath := "."
+	if fs.NArg() == 2 {
+		localPath = fs.Arg(1)
+	}
+
+	return transfer(dataDir, server, flags, func(client *sftp.Client) (sftp.TaskType, string, string, error) {
+		info, err := client.Stat(remotePath)
+		if err != nil {
+			return 0, "", "", withCode(exitNotFound, fmt.Errorf("%s: %w", remotePath, err))
+		}
+		dest, err := destPath(localPath, path.Base(remotePath), info.IsDir, func(p string) (bool, bool) {
+			local, err := os.Stat(p)
+			return err == nil, err == nil && local.IsDir()
+		}, filepath.Join)
+		if err != nil {
+			return 0, "", "", err
+		}
+		if info.IsDir {
+			return sftp.TaskDownloadDirectory, remotePath, dest, nil
+		}
+		return sftp.TaskDownloadFile, remotePath, dest, nil
+	})
+}
+
+// runPut implements `marix put`, uploading a file or directory
+func runPut(dataDir string, args []string) error {
+	fs := flag.NewFlagSet("put", flag.ContinueOnError)
+	flags := addTransferFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: marix put [-json] [-verify] [filter flags] <local-path> <server>:<remote-path>")
+		fmt.Fprintln(fs.Output(), "\nDirectories are copied into remote-path, which must then exist.")
+		fmt.Fprintln(fs.Output(), "Filter flags and presets only apply to directories.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return withCode(exitUsage, err)
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return withCode(exitUsage, fmt.Errorf("expected a source and a destination"))
+	}
+	localPath := fs.Arg(0)
+	server, remotePath, ok := splitRemote(fs.Arg(1))
+	if !ok {
+		return withCode(exitUsage, fmt.Errorf("destination must be <server>:<path>, got %q", fs.Arg(1)))
+	}
+
+	return transfer(dataDir, server, flags, func(client *sftp.Client) (sftp.TaskType, string, string, error) {
+		info, err := os.Stat(localPath)
+		if err != nil {
+			return 0, "", "", withCode(exitNotFound, err)
+		}
+		source, err := filepath.Abs(localPath)
+		if err != nil {
+			return 0, "", "", err
+		}
+		if remotePath == "" {
+			if remotePath, err = client.GetWorkingDirectory(); err != nil {
+				return 0, "", "", err
+			}
+		}
+		dest, err := destPath(remotePath, filepath.Base(source), info.IsDir(), func(p string) (bool, bool) {
+			remote, err := client.Stat(p)
+			return err == nil, err == nil && remote.IsDir
+		}, path.Join)
+		if err != nil {
+			return 0, "", "", err
+		}
+		if info.IsDir() {
+			return sftp.TaskUploadDirectory, source, dest, nil
+		}
+		return sftp.TaskUploadFile, source, dest, nil
+	})
+}
+
+// splitRemote splits a <server>:<path> argument
+func splitRemote(arg string) (server, remotePath string, ok bool) {
+	server, remotePath, ok = strings.Cut(arg, ":")
+	return server, remotePath, ok && server != ""
+}
+
+// destPath resolves where a transfer lands, like cp: into target when it is
+// an existing directory, else at target itself. Directories can only be
+// copied into an existing directory.
+func destPath(target, base string, isDir bool, stat func(string) (exists, dir bool), join func(...string) string) (string, error) {
+	if target == "" {
+		target = "."
+	}
+	exists, dir := stat(target)
+	switch {
+	case dir:
+		return join(target, base), nil
+	case isDir && exists:
+		return "", withCode(exitUsage, fmt.Errorf("%s is not a directory", target))
+	case isDir:
+		return "", withCode(exitNotFound, fmt.Errorf("directory %s does not exist", target))
+	}
+	return target, nil
+}
+
+// transfer connects to server and runs the transfer chosen by plan on a
+// task queue, reporting progress on stderr. -verify turns on checksums on
+// top of the settings.
+func transfer(dataDir, server string, flags *transferFlags, plan func(*sftp.Client) (sftp.TaskType, string, string, error)) error {
+	store, err := storage.NewStore(dataDir)
+	if err != nil {
+		return fmt.Errorf("failed to open server store: %w", err)
+	}
+	saved, err := findServer(store, server)
+	if err != nil {
+		return err
+	}
+	filter, err := flags.filter(saved)
+	if err != nil {
+		return err
+	}
+
+	manager := ssh.NewManager()
+	client, err := connectServer(dataDir, manager, server)
+	if err != nil {
+		return err
+	}
+	defer manager.Release(client)
+
+	sftpClient, err := sftp.NewClient(client.GetRawClient())
+	if err != nil {
+		return withCode(exitConnect, err)
+	}
+	defer sftpClient.Close()
+	sftpClient.SetJumpDialer(client.JumpDialer())
+
+	taskType, source, dest, err := plan(sftpClient)
+	if err != nil {
+		return err
+	}
+
+	settingsStore, err := storage.NewSettingsStore(dataDir)
+	if err != nil {
+		return fmt.Errorf("failed to open settings: %w", err)
+	}
+	settings := settingsStore.Get()
+	settings.VerifyTransfers = settings.VerifyTransfers || *flags.verify
+
+	// The queue blocks on some updates, so they are read until the task ends
+	updates := make(chan sftp.TaskProgress, 64)
+	queue := sftp.NewTaskQueue(sftpClient, client.GetConfig(), &settings, 1, updates)
+	task, err := queue.QueueFilteredTask(taskType, source, dest, filepath.Base(source), filter)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	showProgress := term.IsTerminal(os.Stderr.Fd())
+	interrupted := ctx.Done()
+	var last sftp.TaskProgress
+	for {
+		select {
+		case <-interrupted:
+			// Keep reading until the queue reports the task cancelled
+			queue.CancelAllTasks()
+			interrupted = nil
+		case progress := <-updates:
+			if progress.TaskID != task.ID {
+				continue
+			}
+			if progress.LastLog != "" && !showProgress {
+				fmt.Fprintln(os.Stderr, progress.LastLog)
+			}
+			if progress.TotalSize > 0 || progress.TotalFiles > 0 {
+				last = progress
+			}
+			if showProgress {
+				fmt.Fprintf(os.Stderr, "\r\x1b[K%s %3d%% %d/%d files %s/%s",
+					filepath.Base(source), last.Percentage, last.CompletedFiles, last.TotalFiles,
+					formatBytes(last.BytesTransferred), formatBytes(last.TotalSize))
+			}
+
+			switch progress.State {
+			case sftp.TaskCompleted:
+				if showProgress {
+					fmt.Fprintln(os.Stderr)
+				}
+				if *flags.asJSON {
+					return printJSON(transferResult{Source: source, Dest: dest,
+						Files: progress.TotalFiles, Bytes: progress.TotalSize})
+				}
+				return nil
+			case sftp.TaskFailed:
+				if showProgress {
+					fmt.Fprintln(os.Stderr)
+				}
+				for _, failure := range progress.Failures {
+					fmt.Fprintf(os.Stderr, "%s: %v\n", failure.Job.Path, failure.Err)
+				}
+				return fmt.Errorf("transfer failed: %s", progress.Error)
+			case sftp.TaskCancelled:
+				if showProgress {
+					fmt.Fprintln(os.Stderr)
+				}
+				return fmt.Errorf("transfer cancelled")
+			}
+		}
+	}
+}
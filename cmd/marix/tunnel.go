@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/quocson95/marix/pkg/ssh"
+	"github.com/quocson95/marix/pkg/storage"
+)
+
+// runTunnel implements `marix tunnel`, keeping tunnel profiles up without the TUI
+func runTunnel(dataDir string, args []string) error {
+	fs := flag.NewFlagSet("tunnel", flag.ContinueOnError)
+	all := fs.Bool("all", false, "start every saved tunnel profile")
+	list := fs.Bool("list", false, "list tunnel profiles and exit")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: marix tunnel [-all | -list | profile...]")
+		fmt.Fprintln(fs.Output(), "\nRuns tunnel profiles in the foreground, reconnecting until interrupted.")
+		fmt.Fprintln(fs.Output(), "Hosts must already be in ~/.ssh/known_hosts. Encrypted keys are")
+		fmt.Fprintln(fs.Output(), "decrypted with the master password from MARIX_MASTER_PASSWORD.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return withCode(exitUsage, err)
+	}
+
+	store, err := storage.NewStore(dataDir)
+	if err != nil {
+		return fmt.Errorf("failed to open server store: %w", err)
+	}
+	tunnelStore, err := storage.NewTunnelStore(dataDir)
+	if err != nil {
+		return fmt.Errorf("failed to open tunnel profiles: %w", err)
+	}
+
+	if *list {
+		for _, profile := range tunnelStore.List() {
+			server := profile.ServerID
+			if srv, err := store.Get(profile.ServerID); err == nil {
+				server = srv.Name
+			}
+			fmt.Printf("%s\tvia %s\t%d forward(s)\n", profile.Name, server, len(profile.Forwards))
+		}
+		return nil
+	}
+
+	var profiles []*storage.TunnelProfile
+	switch {
+	case *all:
+		profiles = tunnelStore.List()
+	case fs.NArg() > 0:
+		for _, name := range fs.Args() {
+			profile, err := tunnelStore.Find(name)
+			if err != nil {
+				return withCode(exitNotFound, err)
+			}
+			profiles = append(profiles, profile)
+		}
+	default:
+		fs.Usage()
+		return withCode(exitUsage, fmt.Errorf("no tunnel profile given"))
+	}
+	if len(profiles) == 0 {
+		return fmt.Errorf("no tunnel profiles saved")
+	}
+
+	password, err := masterPassword(dataDir)
+	if err != nil {
+		return err
+	}
+
+	// Without a verifier, clients check ~/.ssh/known_hosts and reject unknown hosts
+	manager := ssh.NewManager()
+	tunnels := make([]*ssh.PersistentTunnel, 0, len(profiles))
+	for _, profile := range profiles {
+		tunnel, err := ssh.NewProfileTunnel(manager, store, profile, password)
+		if err != nil {
+			return err
+		}
+		name := profile.Name
+		tunnel.OnChange(func(h ssh.TunnelHealth) {
+			logTunnelHealth(name, h)
+		})
+		tunnels = append(tunnels, tunnel)
+	}
+
+	for _, tunnel := range tunnels {
+		tunnel.Start()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+
+	for _, tunnel := range tunnels {
+		tunnel.Stop()
+	}
+	for _, tunnel := range tunnels {
+		<-tunnel.Done()
+	}
+	return nil
+}
+
+func logTunnelHealth(name string, h ssh.TunnelHealth) {
+	timestamp := time.Now().Format(time.RFC3339)
+	switch h.State {
+	case ssh.TunnelUp:
+		fmt.Printf("%s %s: up\n", timestamp, name)
+		for _, stats := range h.Tunnels {
+			fmt.Printf("%s %s:   %s on %s\n", timestamp, name, stats.Forward, stats.Addr)
+		}
+	case ssh.TunnelReconnecting:
+		fmt.Printf("%s %s: reconnecting in %s (attempt %d): %v\n", timestamp, name,
+			time.Until(h.NextRetry).Round(time.Second), h.Attempts, h.LastError)
+	default:
+		fmt.Printf("%s %s: down\n", timestamp, name)
+	}
+}
@@ -0,0 +1,286 @@
+// Package asciicast reads and writes terminal recordings in the asciicast v2
+// format: a JSON header line followed by one JSON array per event.
+package asciicast
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Event types
+const (
+	EventOutput = "o" // Data written to the terminal
+	EventInput  = "i" // Keys sent by the user
+	EventResize = "r" // Terminal resized, data is "COLSxROWS"
+)
+
+// Header is the first line of a recording
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Event is a timed entry of a recording
+type Event struct {
+	Time float64 // Seconds since the start of the recording
+	Type string
+	Data string
+}
+
+// MarshalJSON encodes the event as [time, type, data]
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{json.Number(strconv.FormatFloat(e.Time, 'f', 6, 64)), e.Type, e.Data})
+}
+
+// UnmarshalJSON decodes an event from [time, type, data]
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var fields []json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if len(fields) != 3 {
+		return fmt.Errorf("expected 3 fields, got %d", len(fields))
+	}
+	if err := json.Unmarshal(fields[0], &e.Time); err != nil {
+		return fmt.Errorf("invalid time: %w", err)
+	}
+	if err := json.Unmarshal(fields[1], &e.Type); err != nil {
+		return fmt.Errorf("invalid type: %w", err)
+	}
+	if err := json.Unmarshal(fields[2], &e.Data); err != nil {
+		return fmt.Errorf("invalid data: %w", err)
+	}
+	return nil
+}
+
+// Size parses the data of a resize event
+func (e Event) Size() (cols, rows int, ok bool) {
+	if _, err := fmt.Sscanf(e.Data, "%dx%d", &cols, &rows); err != nil || cols <= 0 || rows <= 0 {
+		return 0, 0, false
+	}
+	return cols, rows, true
+}
+
+// Recorder writes a recording as events happen. It is safe for concurrent
+// use, so output can be recorded from the SSH reader while input and resizes
+// come from the UI.
+type Recorder struct {
+	mu      sync.Mutex
+	w       *bufio.Writer
+	closer  io.Closer
+	start   time.Time
+	pending []byte // Incomplete UTF-8 sequence at the end of the last output
+	err     error
+}
+
+// NewRecorder writes header to w and returns a recorder for the events
+func NewRecorder(w io.Writer, header Header) (*Recorder, error) {
+	header.Version = 2
+	if header.Timestamp == 0 {
+		header.Timestamp = time.Now().Unix()
+	}
+
+	data, err := json.Marshal(header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal header: %w", err)
+	}
+
+	r := &Recorder{w: bufio.NewWriter(w), start: time.Now()}
+	if closer, ok := w.(io.Closer); ok {
+		r.closer = closer
+	}
+	if err := r.writeLine(data); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Create creates the recording file at path, readable only by the owner
+func Create(path string, header Header) (*Recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create recordings directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording: %w", err)
+	}
+
+	r, err := NewRecorder(file, header)
+	if err != nil {
+		file.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	return r, nil
+}
+
+// writeLine writes one line and flushes it, so an interrupted session still
+// leaves a readable file
+func (r *Recorder) writeLine(data []byte) error {
+	if _, err := r.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	if err := r.w.Flush(); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	return nil
+}
+
+func (r *Recorder) event(eventType, data string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+
+	line, err := json.Marshal(Event{Time: time.Since(r.start).Seconds(), Type: eventType, Data: data})
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	// A failed write is sticky; later events would leave gaps in the timeline
+	r.err = r.writeLine(line)
+	return r.err
+}
+
+// Output records data written to the terminal. A UTF-8 sequence split
+// across reads is held back until it is complete, since JSON strings cannot
+// carry half a character.
+func (r *Recorder) Output(data []byte) error {
+	r.mu.Lock()
+	data = append(r.pending, data...)
+	cut := incompleteSuffix(data)
+	r.pending = append([]byte(nil), data[len(data)-cut:]...)
+	data = data[:len(data)-cut]
+	r.mu.Unlock()
+
+	if len(data) == 0 {
+		return nil
+	}
+	return r.event(EventOutput, string(data))
+}
+
+// Input records keys sent to the remote side
+func (r *Recorder) Input(data []byte) error {
+	return r.event(EventInput, string(data))
+}
+
+// Resize records a change of the terminal size
+func (r *Recorder) Resize(cols, rows int) error {
+	return r.event(EventResize, fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// Close flushes the recording and closes the underlying file
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.pending) > 0 && r.err == nil {
+		if line, err := json.Marshal(Event{Time: time.Since(r.start).Seconds(), Type: EventOutput, Data: string(r.pending)}); err == nil {
+			r.err = r.writeLine(line)
+		}
+		r.pending = nil
+	}
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
+
+// incompleteSuffix returns the length of a UTF-8 sequence cut off at the end
+// of data, or 0
+func incompleteSuffix(data []byte) int {
+	for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+		b := data[len(data)-i]
+		if !utf8.RuneStart(b) {
+			continue
+		}
+		if !utf8.FullRune(data[len(data)-i:]) {
+			return i
+		}
+		return 0
+	}
+	return 0
+}
+
+// Recording is a recording read back from disk
+type Recording struct {
+	Header Header
+	Events []Event
+}
+
+// Duration returns the time of the last event
+func (r *Recording) Duration() float64 {
+	if len(r.Events) == 0 {
+		return 0
+	}
+	return r.Events[len(r.Events)-1].Time
+}
+
+// Read parses a recording. A truncated last line, as left by a crash, is
+// ignored.
+func Read(rd io.Reader) (*Recording, error) {
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read recording: %w", err)
+		}
+		return nil, fmt.Errorf("empty recording")
+	}
+
+	var rec Recording
+	if err := json.Unmarshal(scanner.Bytes(), &rec.Header); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	if rec.Header.Version != 2 {
+		return nil, fmt.Errorf("unsupported asciicast version %d", rec.Header.Version)
+	}
+
+	var bad error
+	for line := 2; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if bad != nil {
+			// Only the last line may be damaged
+			return nil, bad
+		}
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			bad = fmt.Errorf("invalid event on line %d: %w", line, err)
+			continue
+		}
+		rec.Events = append(rec.Events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+
+	return &rec, nil
+}
+
+// Open reads the recording at path
+func Open(path string) (*Recording, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer file.Close()
+
+	return Read(file)
+}
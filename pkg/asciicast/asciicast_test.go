@@ -0,0 +1,134 @@
+package asciicast
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recordings", "session.cast")
+
+	r, err := Create(path, Header{Width: 80, Height: 24, Title: "root@web1:22"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "é" split across two reads
+	if err := r.Output([]byte("caf\xc3")); err != nil {
+		t.Fatalf("Output failed: %v", err)
+	}
+	if err := r.Output([]byte("\xa9\r\n")); err != nil {
+		t.Fatalf("Output failed: %v", err)
+	}
+	if err := r.Input([]byte("ls\r")); err != nil {
+		t.Fatalf("Input failed: %v", err)
+	}
+	if err := r.Resize(100, 30); err != nil {
+		t.Fatalf("Resize failed: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("Expected 0600 permissions, got %o", perm)
+	}
+
+	rec, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if rec.Header.Version != 2 || rec.Header.Width != 80 || rec.Header.Height != 24 || rec.Header.Title != "root@web1:22" {
+		t.Errorf("Unexpected header %+v", rec.Header)
+	}
+	if rec.Header.Timestamp == 0 {
+		t.Error("Expected a timestamp in the header")
+	}
+
+	want := []Event{
+		{Type: EventOutput, Data: "caf"},
+		{Type: EventOutput, Data: "é\r\n"},
+		{Type: EventInput, Data: "ls\r"},
+		{Type: EventResize, Data: "100x30"},
+	}
+	if len(rec.Events) != len(want) {
+		t.Fatalf("Expected %d events, got %+v", len(want), rec.Events)
+	}
+	for i, event := range rec.Events {
+		if event.Type != want[i].Type || event.Data != want[i].Data {
+			t.Errorf("Event %d = %+v, want %+v", i, event, want[i])
+		}
+		if i > 0 && event.Time < rec.Events[i-1].Time {
+			t.Errorf("Event %d goes back in time", i)
+		}
+	}
+	if cols, rows, ok := rec.Events[3].Size(); !ok || cols != 100 || rows != 30 {
+		t.Errorf("Size() = %d, %d, %v", cols, rows, ok)
+	}
+
+	if _, err := Create(path, Header{Width: 80, Height: 24}); err == nil {
+		t.Error("Expected an error overwriting an existing recording")
+	}
+}
+
+func TestRead(t *testing.T) {
+	t.Run("Standard file", func(t *testing.T) {
+		rec, err := Read(strings.NewReader(`{"version": 2, "width": 10, "height": 5}
+[0.5, "o", "hello\u001b[1m"]
+
+[1.25, "o", "world"]
+`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rec.Events) != 2 || rec.Events[0].Data != "hello\x1b[1m" || rec.Duration() != 1.25 {
+			t.Errorf("Unexpected recording %+v", rec)
+		}
+	})
+
+	t.Run("Truncated last line", func(t *testing.T) {
+		rec, err := Read(strings.NewReader("{\"version\": 2, \"width\": 10, \"height\": 5}\n[0.5, \"o\", \"a\"]\n[1.0, \"o\", \"b"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rec.Events) != 1 {
+			t.Errorf("Expected the truncated event to be dropped, got %+v", rec.Events)
+		}
+	})
+
+	for name, input := range map[string]string{
+		"Empty":         "",
+		"Wrong version": `{"version": 1, "width": 10, "height": 5}`,
+		"Bad event":     "{\"version\": 2, \"width\": 10, \"height\": 5}\n[\"x\"]\n[1.0, \"o\", \"b\"]\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Read(strings.NewReader(input)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestEventJSON(t *testing.T) {
+	var buf bytes.Buffer
+	r, err := NewRecorder(&buf, Header{Width: 2, Height: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Output([]byte("\x00\xff"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], `{"version":2,"width":2,"height":1`) {
+		t.Fatalf("Unexpected output %q", buf.String())
+	}
+	if !strings.HasSuffix(lines[1], `,"o","\u0000�"]`) {
+		t.Errorf("Unexpected event line %q", lines[1])
+	}
+}
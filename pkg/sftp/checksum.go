@@ -0,0 +1,236 @@
+package sftp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrChecksumMismatch means a transferred file differs from its source
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// checkFileExtension is the SFTP extension that hashes files on the server
+const checkFileExtension = "check-file"
+
+// SFTP packet types used for check-file requests
+const (
+	fxpInit          = 1
+	fxpVersion       = 2
+	fxpStatus        = 101
+	fxpExtended      = 200
+	fxpExtendedReply = 201
+)
+
+// Verify checks that a local file and a remote file have the same SHA-256,
+// returning ErrChecksumMismatch when they differ
+func (c *Client) Verify(localPath, remotePath string) error {
+	localFile, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open local file: %w", err)
+	}
+	defer localFile.Close()
+	localInfo, err := localFile.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat local file: %w", err)
+	}
+
+	remoteFile, err := c.sftpClient.Open(remotePath)
+	if err != nil {
+		return fmt.Errorf("failed to open remote file: %w", err)
+	}
+	defer remoteFile.Close()
+	remoteInfo, err := remoteFile.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat remote file: %w", err)
+	}
+
+	if localInfo.Size() != remoteInfo.Size() {
+		return fmt.Errorf("%w: %s is %d bytes, %s is %d bytes", ErrChecksumMismatch,
+			localPath, localInfo.Size(), remotePath, remoteInfo.Size())
+	}
+
+	localSum, err := hashPrefix(localFile, localInfo.Size())
+	if err != nil {
+		return fmt.Errorf("failed to hash local file: %w", err)
+	}
+	remoteSum, err := c.remoteHash(remotePath, remoteFile, remoteInfo.Size())
+	if err != nil {
+		return fmt.Errorf("failed to hash remote file: %w", err)
+	}
+	if !bytes.Equal(localSum, remoteSum) {
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, remotePath)
+	}
+	return nil
+}
+
+// hashPrefix returns the SHA-256 of the first n bytes of r
+func hashPrefix(r io.ReaderAt, n int64) ([]byte, error) {
+	h := sha256.New()
+	written, err := io.Copy(h, io.NewSectionReader(r, 0, n))
+	if err != nil {
+		return nil, err
+	}
+	if written != n {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return h.Sum(nil), nil
+}
+
+// remoteHash returns the SHA-256 of the first n bytes of a remote file. It
+// is computed on the server with the check-file extension or sha256sum when
+// it has them, else by reading file back.
+func (c *Client) remoteHash(path string, file io.ReaderAt, n int64) ([]byte, error) {
+	if c.sshClient != nil {
+		if _, ok := c.sftpClient.HasExtension(checkFileExtension); ok {
+			if sum, err := c.checkFile(path, n); err == nil {
+				return sum, nil
+			}
+		}
+		if session, err := c.sshClient.NewSession(); err == nil {
+			defer session.Close()
+			out, err := session.Output(hashCommand(path, n))
+			if fields := strings.Fields(string(out)); err == nil && len(fields) > 0 {
+				if sum, err := hex.DecodeString(fields[0]); err == nil && len(sum) == sha256.Size {
+					return sum, nil
+				}
+			}
+		}
+	}
+	// Fall back to reading the file over SFTP
+	return hashPrefix(file, n)
+}
+
+// hashCommand returns the shell command printing the SHA-256 of the first n
+// bytes of path. The path is quoted, as file names may hold any character.
+func hashCommand(path string, n int64) string {
+	return fmt.Sprintf("head -c %d -- %s | sha256sum", n, shellQuote(path))
+}
+
+// checkFile asks the server for the SHA-256 of the first n bytes of a file
+// with the check-file extension. The SFTP library cannot send extended
+// requests, so this runs on an SFTP channel of its own.
+func (c *Client) checkFile(path string, n int64) ([]byte, error) {
+	session, err := c.sshClient.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	w, err := session.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	r, err := session.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		return nil, err
+	}
+	return checkFileExchange(r, w, path, n)
+}
+
+// checkFileExchange runs a check-file-name request for the SHA-256 of the
+// first n bytes of path on a fresh SFTP stream
+func checkFileExchange(r io.Reader, w io.Writer, path string, n int64) ([]byte, error) {
+	if err := writePacket(w, fxpInit, binary.BigEndian.AppendUint32(nil, 3)); err != nil {
+		return nil, err
+	}
+	typ, _, err := readPacket(r)
+	if err != nil {
+		return nil, err
+	}
+	if typ != fxpVersion {
+		return nil, fmt.Errorf("unexpected SFTP packet %d, want version", typ)
+	}
+
+	const id = 1
+	req := binary.BigEndian.AppendUint32(nil, id)
+	req = appendString(req, "check-file-name")
+	req = appendString(req, path)
+	req = appendString(req, "sha256")
+	req = binary.BigEndian.AppendUint64(req, 0)         // Start offset
+	req = binary.BigEndian.AppendUint64(req, uint64(n)) // Length, 0 is the whole file
+	req = binary.BigEndian.AppendUint32(req, 0)         // Block size, 0 hashes it as one block
+	if err := writePacket(w, fxpExtended, req); err != nil {
+		return nil, err
+	}
+
+	typ, data, err := readPacket(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 4 || binary.BigEndian.Uint32(data) != id {
+		return nil, fmt.Errorf("unexpected SFTP reply")
+	}
+	data = data[4:]
+	switch typ {
+	case fxpExtendedReply:
+	case fxpStatus:
+		msg := ""
+		if len(data) >= 4 {
+			_, msg, _ = readString(data[4:])
+		}
+		return nil, fmt.Errorf("check-file failed: %s", msg)
+	default:
+		return nil, fmt.Errorf("unexpected SFTP packet %d, want extended reply", typ)
+	}
+
+	data, algorithm, err := readString(data)
+	if err != nil {
+		return nil, err
+	}
+	if algorithm != "sha256" || len(data) != sha256.Size {
+		return nil, fmt.Errorf("check-file replied with %s, want a single sha256", algorithm)
+	}
+	return data, nil
+}
+
+func writePacket(w io.Writer, typ byte, payload []byte) error {
+	packet := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+1))
+	packet = append(packet, typ)
+	_, err := w.Write(append(packet, payload...))
+	return err
+}
+
+// maxPacketSize bounds the replies read, check-file ones are small
+const maxPacketSize = 256 * 1024
+
+func readPacket(r io.Reader) (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > maxPacketSize {
+		return 0, nil, fmt.Errorf("bad SFTP packet length %d", length)
+	}
+	data := make([]byte, length-1)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	return header[4], data, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// readString reads a length-prefixed string, returning the rest of b
+func readString(b []byte) ([]byte, string, error) {
+	if len(b) < 4 {
+		return nil, "", io.ErrUnexpectedEOF
+	}
+	n := binary.BigEndian.Uint32(b)
+	if uint32(len(b)-4) < n {
+		return nil, "", io.ErrUnexpectedEOF
+	}
+	return b[4+n:], string(b[4 : 4+n]), nil
+}
@@ -0,0 +1,162 @@
+package sftp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeCheckFileServer answers one check-file-name request on an SFTP stream
+// with reply, built from the request's id, and records the request
+func fakeCheckFileServer(t *testing.T, r io.Reader, w io.Writer, reply func(id uint32) (byte, []byte)) <-chan []byte {
+	requests := make(chan []byte, 1)
+	go func() {
+		defer close(requests)
+		if typ, _, err := readPacket(r); err != nil || typ != fxpInit {
+			t.Errorf("Expected init, got %d (%v)", typ, err)
+			return
+		}
+		version := binary.BigEndian.AppendUint32(nil, 3)
+		version = appendString(version, checkFileExtension)
+		version = appendString(version, "1")
+		writePacket(w, fxpVersion, version)
+
+		typ, data, err := readPacket(r)
+		if err != nil || typ != fxpExtended {
+			t.Errorf("Expected extended request, got %d (%v)", typ, err)
+			return
+		}
+		requests <- data
+		typ, payload := reply(binary.BigEndian.Uint32(data))
+		writePacket(w, typ, payload)
+	}()
+	return requests
+}
+
+func TestCheckFileExchange(t *testing.T) {
+	want := sha256.Sum256([]byte("hello"))
+
+	t.Run("Core Functionality: Hash computed by the server", func(t *testing.T) {
+		clientR, serverW := io.Pipe()
+		serverR, clientW := io.Pipe()
+		requests := fakeCheckFileServer(t, serverR, serverW, func(id uint32) (byte, []byte) {
+			reply := binary.BigEndian.AppendUint32(nil, id)
+			reply = appendString(reply, "sha256")
+			return fxpExtendedReply, append(reply, want[:]...)
+		})
+
+		sum, err := checkFileExchange(clientR, clientW, "/data/hello.txt", 5)
+		if err != nil {
+			t.Fatalf("checkFileExchange failed: %v", err)
+		}
+		if !bytes.Equal(sum, want[:]) {
+			t.Errorf("Expected %x, got %x", want, sum)
+		}
+
+		req := <-requests
+		rest, name, _ := readString(req[4:])
+		rest, path, _ := readString(rest)
+		rest, algorithm, _ := readString(rest)
+		if name != "check-file-name" || path != "/data/hello.txt" || algorithm != "sha256" {
+			t.Errorf("Unexpected request %q %q %q", name, path, algorithm)
+		}
+		if length := binary.BigEndian.Uint64(rest[8:]); length != 5 {
+			t.Errorf("Expected length 5, got %d", length)
+		}
+	})
+
+	t.Run("Error Handling: Server refuses", func(t *testing.T) {
+		clientR, serverW := io.Pipe()
+		serverR, clientW := io.Pipe()
+		fakeCheckFileServer(t, serverR, serverW, func(id uint32) (byte, []byte) {
+			status := binary.BigEndian.AppendUint32(nil, id)
+			status = binary.BigEndian.AppendUint32(status, 8) // Unsupported
+			status = appendString(status, "unsupported hash")
+			return fxpStatus, appendString(status, "")
+		})
+
+		_, err := checkFileExchange(clientR, clientW, "/data/hello.txt", 5)
+		if err == nil || !strings.Contains(err.Error(), "unsupported hash") {
+			t.Errorf("Expected the server's error, got %v", err)
+		}
+	})
+
+	t.Run("Error Handling: Other algorithm", func(t *testing.T) {
+		clientR, serverW := io.Pipe()
+		serverR, clientW := io.Pipe()
+		fakeCheckFileServer(t, serverR, serverW, func(id uint32) (byte, []byte) {
+			reply := binary.BigEndian.AppendUint32(nil, id)
+			reply = appendString(reply, "md5")
+			return fxpExtendedReply, append(reply, make([]byte, 16)...)
+		})
+
+		if _, err := checkFileExchange(clientR, clientW, "/data/hello.txt", 5); err == nil {
+			t.Error("Expected an error for a reply that is not sha256")
+		}
+	})
+}
+
+func TestReadPacket_BadLength(t *testing.T) {
+	packet := binary.BigEndian.AppendUint32(nil, maxPacketSize+1)
+	if _, _, err := readPacket(bytes.NewReader(append(packet, 0))); err == nil {
+		t.Error("Expected an error for an oversized packet")
+	}
+}
+
+func TestReadString_Truncated(t *testing.T) {
+	b := appendString(nil, "sha256")
+	if _, _, err := readString(b[:6]); err == nil {
+		t.Error("Expected an error for a truncated string")
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"":                  "''",
+		"/srv/app/data.bin": "'/srv/app/data.bin'",
+		"my file.txt":       "'my file.txt'",
+		"it's":              `'it'\''s'`,
+		"$(reboot)`id`\"$x": "'$(reboot)`id`\"$x'",
+	}
+	for in, want := range tests {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestHashCommand_HostileName(t *testing.T) {
+	if _, err := exec.LookPath("sha256sum"); err != nil {
+		t.Skip("sha256sum not installed")
+	}
+	dir := t.TempDir()
+
+	// Run by a shell, any of these would create the marker file; the
+	// leading dash would be read as an option of head
+	name := "-x $(touch marker) `touch marker` 'q' \"$HOME\".bin"
+	data := []byte("hello, checksum")
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("sh", "-c", hashCommand(name, 5))
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "marker")); err == nil {
+		t.Fatal("The file name was run as a command")
+	}
+	want := sha256.Sum256(data[:5])
+	if fields := strings.Fields(string(out)); len(fields) == 0 || fields[0] != hex.EncodeToString(want[:]) {
+		t.Errorf("Expected the hash of the first 5 bytes, got %q", out)
+	}
+}
@@ -3,10 +3,12 @@ package sftp
 import (
 	"fmt"
 	"io"
+	"net"
 	"os"
 	pus "path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
@@ -16,6 +18,10 @@ import (
 type Client struct {
 	sshClient  *ssh.Client
 	sftpClient *sftp.Client
+
+	// jumpDial reaches the server through its jump hosts, for engines that
+	// open their own connections (nil for direct connections)
+	jumpDial func(network, addr string) (net.Conn, error)
 }
 
 // FileInfo represents a file/directory info
@@ -40,6 +46,11 @@ func NewClient(sshClient *ssh.Client) (*Client, error) {
 	}, nil
 }
 
+// SetJumpDialer sets the dialer used to reach the server through its jump hosts
+func (c *Client) SetJumpDialer(dial func(network, addr string) (net.Conn, error)) {
+	c.jumpDial = dial
+}
+
 // List lists files in a directory
 func (c *Client) List(path string) ([]FileInfo, error) {
 	entries, err := c.sftpClient.ReadDir(path)
@@ -153,9 +164,7 @@ func (c *Client) RemoveDirectory(path string) error {
 	session, err := c.sshClient.NewSession()
 	if err == nil {
 		defer session.Close()
-		// Use quotes to handle spaces in path. -r for recursive, -f for force (ignore non-existent)
-		cmd := fmt.Sprintf("rm -rf %q", path)
-		if err := session.Run(cmd); err == nil {
+		if err := session.Run(removeCommand(path)); err == nil {
 			return nil
 		}
 		// If SSH command failed, fall back to SFTP recursive delete
@@ -190,6 +199,19 @@ func (c *Client) RemoveDirectory(path string) error {
 	return nil
 }
 
+// removeCommand returns the shell command deleting path recursively. -r for
+// recursive, -f for force (ignore non-existent); the path is quoted, as sync
+// deletes pass names found on the server.
+func removeCommand(path string) string {
+	return "rm -rf -- " + shellQuote(path)
+}
+
+// shellQuote quotes s as a single word for a POSIX shell. Inside single
+// quotes nothing is special, so only the quote itself needs escaping.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
 func isNotExist(err error) bool {
 	if os.IsNotExist(err) {
 		return true
@@ -251,6 +273,11 @@ func (c *Client) Chmod(path string, mode os.FileMode) error {
 	return c.sftpClient.Chmod(path, mode)
 }
 
+// Chtimes sets the modification time of a remote file
+func (c *Client) Chtimes(path string, mtime time.Time) error {
+	return c.sftpClient.Chtimes(path, mtime, mtime)
+}
+
 // Rename renames a file
 func (c *Client) Rename(oldPath, newPath string) error {
 	return c.sftpClient.Rename(oldPath, newPath)
@@ -3,6 +3,7 @@ package sftp
 import (
 	"context"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -13,13 +14,19 @@ type FileJob struct {
 	AbsPath     string // absolute source path
 	DestPath    string // absolute destination path
 	Size        int64
+	ModTime     int64 // Unix seconds
 	IsDir       bool
 	IsRecursive bool // if true, scanner will explode this
+	Attempt     int  // runs so far; retries resume the partial file
 }
 
 // DirectoryScanner handles scanning directories to create transfer jobs
 type DirectoryScanner struct {
 	client *Client
+
+	// Filter leaves out the entries it excludes, and everything inside
+	// excluded directories
+	Filter Filter
 }
 
 // NewDirectoryScanner creates a new scanner
@@ -41,6 +48,7 @@ func (s *DirectoryScanner) ScanLocal(ctx context.Context, root string, remoteRoo
 	}
 
 	baseDir := filepath.Dir(root)
+	filter := newFilterMatcher(s.Filter, filepath.Base(root), os.ReadFile)
 	if info.IsDir() && !strings.HasSuffix(root, string(filepath.Separator)) {
 		// If uploading "folder", we want "folder" to be created in remote.
 		// So base is the parent of root.
@@ -57,6 +65,19 @@ func (s *DirectoryScanner) ScanLocal(ctx context.Context, root string, remoteRoo
 		relPath, _ := filepath.Rel(baseDir, path)
 		destPath := filepath.Join(remoteRoot, relPath)
 
+		if filter != nil {
+			rel := filepath.ToSlash(relPath)
+			if filter.excluded(rel, info.IsDir()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir() {
+				filter.enter(rel, path, filepath.Join)
+			}
+		}
+
 		// Check for symlinks
 		if info.Mode()&os.ModeSymlink != 0 {
 			// It's a symlink. Check what it points to.
@@ -89,6 +110,7 @@ func (s *DirectoryScanner) ScanLocal(ctx context.Context, root string, remoteRoo
 			AbsPath:  path,
 			DestPath: destPath,
 			Size:     info.Size(),
+			ModTime:  info.ModTime().Unix(),
 			IsDir:    info.IsDir(),
 		})
 
@@ -112,6 +134,7 @@ func (s *DirectoryScanner) ScanRemote(ctx context.Context, root string, localRoo
 	var totalSize int64
 
 	baseDir := filepath.Dir(root)
+	filter := newFilterMatcher(s.Filter, path.Base(root), s.client.ReadFile)
 
 	walker := s.client.sftpClient.Walk(root)
 	for walker.Step() {
@@ -122,17 +145,31 @@ func (s *DirectoryScanner) ScanRemote(ctx context.Context, root string, localRoo
 			return nil, 0, ctx.Err()
 		}
 
-		path := walker.Path()
+		remotePath := walker.Path()
 		stat := walker.Stat()
 
-		relPath, _ := filepath.Rel(baseDir, path)
+		relPath, _ := filepath.Rel(baseDir, remotePath)
 		destPath := filepath.Join(localRoot, relPath)
 
+		if filter != nil {
+			rel := filepath.ToSlash(relPath)
+			if filter.excluded(rel, stat.IsDir()) {
+				if stat.IsDir() {
+					walker.SkipDir()
+				}
+				continue
+			}
+			if stat.IsDir() {
+				filter.enter(rel, remotePath, path.Join)
+			}
+		}
+
 		jobs = append(jobs, FileJob{
 			Path:     relPath,
-			AbsPath:  path,
+			AbsPath:  remotePath,
 			DestPath: destPath,
 			Size:     stat.Size(),
+			ModTime:  stat.ModTime().Unix(),
 			IsDir:    stat.IsDir(),
 		})
 
@@ -17,6 +17,12 @@ type TransferEngine interface {
 	// DownloadFile downloads a single file
 	DownloadFile(ctx context.Context, remotePath, localPath string, progress func(int64, string) error) error
 
+	// ResumeUploadFile continues an interrupted upload of a single file
+	ResumeUploadFile(ctx context.Context, localPath, remotePath string, progress func(int64, string) error) error
+
+	// ResumeDownloadFile continues an interrupted download of a single file
+	ResumeDownloadFile(ctx context.Context, remotePath, localPath string, progress func(int64, string) error) error
+
 	// ScanRemoteDirectory recursively scans a remote directory
 	ScanRemoteDirectory(ctx context.Context, path string) ([]FileJob, error)
 
@@ -40,6 +46,9 @@ func NewTransferEngine(client *Client, sshConfig *ssh.SSHConfig, settings *stora
 		log.Printf("[INFO] Rsync disabled in settings. Using Internal Engine.")
 	}
 
-	// Fallback to internal SFTP engine
-	return NewInternalEngine(client)
+	// Fallback to internal SFTP engine. rsync checks every file it copies
+	// on its own, the internal engine only when asked to.
+	engine := NewInternalEngine(client)
+	engine.Verify = settings.VerifyTransfers
+	return engine
 }
@@ -9,6 +9,10 @@ import (
 // InternalEngine implements TransferEngine using the internal SFTP client
 type InternalEngine struct {
 	client *Client
+
+	// Verify compares the SHA-256 of each file with its source once it is
+	// transferred, failing the transfer with ErrChecksumMismatch
+	Verify bool
 }
 
 // NewInternalEngine creates a new internal transfer engine
@@ -21,22 +25,57 @@ func NewInternalEngine(client *Client) *InternalEngine {
 // UploadFile uploads a single file using SFTP
 func (e *InternalEngine) UploadFile(ctx context.Context, localPath, remotePath string, progress func(int64, string) error) error {
 	// Adapter for client.ProgressFunc (func(int64) error)
-	return e.client.Upload(localPath, remotePath, func(bytes int64) error {
+	err := e.client.Upload(localPath, remotePath, func(bytes int64) error {
 		if progress != nil {
 			return progress(bytes, "")
 		}
 		return nil
 	})
+	return e.verify(err, localPath, remotePath)
 }
 
 // DownloadFile downloads a single file using SFTP
 func (e *InternalEngine) DownloadFile(ctx context.Context, remotePath, localPath string, progress func(int64, string) error) error {
-	return e.client.Download(remotePath, localPath, func(bytes int64) error {
+	err := e.client.Download(remotePath, localPath, func(bytes int64) error {
+		if progress != nil {
+			return progress(bytes, "")
+		}
+		return nil
+	})
+	return e.verify(err, localPath, remotePath)
+}
+
+// ResumeUploadFile continues an upload from the partial remote file, once
+// its content is verified against the local one
+func (e *InternalEngine) ResumeUploadFile(ctx context.Context, localPath, remotePath string, progress func(int64, string) error) error {
+	err := e.client.ResumeUpload(localPath, remotePath, VerifyHash, func(bytes int64) error {
+		if progress != nil {
+			return progress(bytes, "")
+		}
+		return nil
+	})
+	return e.verify(err, localPath, remotePath)
+}
+
+// ResumeDownloadFile continues a download from the partial local file, once
+// its content is verified against the remote one
+func (e *InternalEngine) ResumeDownloadFile(ctx context.Context, remotePath, localPath string, progress func(int64, string) error) error {
+	err := e.client.ResumeDownload(remotePath, localPath, VerifyHash, func(bytes int64) error {
 		if progress != nil {
 			return progress(bytes, "")
 		}
 		return nil
 	})
+	return e.verify(err, localPath, remotePath)
+}
+
+// verify checks a finished transfer when Verify is set, err is the
+// transfer's own error
+func (e *InternalEngine) verify(err error, localPath, remotePath string) error {
+	if err != nil || !e.Verify {
+		return err
+	}
+	return e.client.Verify(localPath, remotePath)
 }
 
 // ScanRemoteDirectory scans a remote directory for files
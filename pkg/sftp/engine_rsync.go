@@ -3,10 +3,13 @@ package sftp
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
+	"strconv"
 
 	"github.com/quocson95/marix/pkg/ssh"
 )
@@ -15,6 +18,9 @@ import (
 type RsyncEngine struct {
 	client    *Client
 	sshConfig *ssh.SSHConfig
+
+	// Filter is passed to rsync as include and exclude rules
+	Filter Filter
 }
 
 // NewRsyncEngine creates a new rsync transfer engine
@@ -31,12 +37,24 @@ func (e *RsyncEngine) UploadFile(ctx context.Context, localPath, remotePath stri
 	return e.runRsync(ctx, localPath, remotePath, true, progress)
 }
 
+// ResumeUploadFile continues an upload with rsync, appending to the partial
+// remote file after checking it against the local one
+func (e *RsyncEngine) ResumeUploadFile(ctx context.Context, localPath, remotePath string, progress func(int64, string) error) error {
+	return e.runRsync(ctx, localPath, remotePath, true, progress, "--partial", "--append-verify")
+}
+
 // DownloadFile downloads a single file using rsync
 func (e *RsyncEngine) DownloadFile(ctx context.Context, remotePath, localPath string, progress func(int64, string) error) error {
 	return e.runRsync(ctx, remotePath, localPath, false, progress)
 }
 
-func (e *RsyncEngine) runRsync(ctx context.Context, src, dest string, upload bool, progress func(int64, string) error) error {
+// ResumeDownloadFile continues a download with rsync, appending to the
+// partial local file after checking it against the remote one
+func (e *RsyncEngine) ResumeDownloadFile(ctx context.Context, remotePath, localPath string, progress func(int64, string) error) error {
+	return e.runRsync(ctx, remotePath, localPath, false, progress, "--partial", "--append-verify")
+}
+
+func (e *RsyncEngine) runRsync(ctx context.Context, src, dest string, upload bool, progress func(int64, string) error, extraArgs ...string) error {
 	keyPath := e.sshConfig.PrivateKey
 
 	// Handle key content by writing to temp file
@@ -59,28 +77,57 @@ func (e *RsyncEngine) runRsync(ctx context.Context, src, dest string, upload boo
 		keyPath = tmpFile.Name()
 	}
 
-	sshOpts := fmt.Sprintf("ssh -p %d -i '%s' -o StrictHostKeyChecking=no", e.sshConfig.Port, keyPath)
+	host, port := e.sshConfig.Host, e.sshConfig.Port
+	extraOpts := ""
+
+	// rsync runs its own ssh, so tunnel it through the same jump host chain
+	if e.client.jumpDial != nil {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return fmt.Errorf("failed to open jump host tunnel: %w", err)
+		}
+		defer listener.Close()
+
+		target := net.JoinHostPort(host, strconv.Itoa(port))
+		go ssh.ServeTunnel(listener, e.client.jumpDial, target)
+
+		host = "127.0.0.1"
+		port = listener.Addr().(*net.TCPAddr).Port
+		extraOpts = fmt.Sprintf(" -o HostKeyAlias=%s", e.sshConfig.Host)
+	}
+
+	sshOpts := fmt.Sprintf("ssh -p %d -i '%s' -o StrictHostKeyChecking=no%s", port, keyPath, extraOpts)
 	if runtime.GOOS == "windows" {
 		keyPath = filepath.ToSlash(keyPath)
-		sshOpts = fmt.Sprintf("ssh -p %d -i \"%s\" -o StrictHostKeyChecking=no", e.sshConfig.Port, keyPath)
+		sshOpts = fmt.Sprintf("ssh -p %d -i \"%s\" -o StrictHostKeyChecking=no%s", port, keyPath, extraOpts)
+	}
+
+	if !e.Filter.IsZero() {
+		name := filepath.Base(src)
+		if !upload {
+			name = path.Base(src)
+		}
+		extraArgs = append(extraArgs, e.Filter.RsyncArgs(name)...)
 	}
 
 	var source, destination string
 	if upload {
 		source = src
-		destination = fmt.Sprintf("%s@%s:%s", e.sshConfig.Username, e.sshConfig.Host, dest)
+		destination = fmt.Sprintf("%s@%s:%s", e.sshConfig.Username, host, dest)
 		if runtime.GOOS == "windows" {
 			source = filepath.ToSlash(source)
 		}
 	} else {
-		source = fmt.Sprintf("%s@%s:%s", e.sshConfig.Username, e.sshConfig.Host, src)
+		source = fmt.Sprintf("%s@%s:%s", e.sshConfig.Username, host, src)
 		destination = dest
 		if runtime.GOOS == "windows" {
 			destination = filepath.ToSlash(destination)
 		}
 	}
 
-	cmd := exec.CommandContext(ctx, "rsync", "-avz", "--info=progress2", "-e", sshOpts, source, destination)
+	args := append([]string{"-avz", "--info=progress2"}, extraArgs...)
+	args = append(args, "-e", sshOpts, source, destination)
+	cmd := exec.CommandContext(ctx, "rsync", args...)
 
 	// Stream output
 	stdout, err := cmd.StdoutPipe()
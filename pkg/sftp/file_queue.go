@@ -2,14 +2,32 @@ package sftp
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
+// DefaultRetryDelay is the wait before a failed job's first retry, doubled
+// on each further retry
+const DefaultRetryDelay = 2 * time.Second
+
 // FileQueue manages concurrent file transfers
 type FileQueue struct {
 	concurrency int
 	sem         chan struct{}
+
+	// Retries is how many more times a failed job is run before it counts
+	// as failed, waiting RetryDelay, then twice as long, and so on
+	Retries    int
+	RetryDelay time.Duration
+
+	// ContinueOnError keeps running the other jobs when one fails, instead
+	// of stopping at the first failure
+	ContinueOnError bool
+
+	// OnFailure is called for each job that failed for good
+	OnFailure func(JobFailure)
 }
 
 // NewFileQueue creates a new file queue
@@ -17,24 +35,58 @@ func NewFileQueue(concurrency int) *FileQueue {
 	return &FileQueue{
 		concurrency: concurrency,
 		sem:         make(chan struct{}, concurrency),
+		RetryDelay:  DefaultRetryDelay,
 	}
 }
 
 // JobExecutor is the function that performs the actual transfer
 type JobExecutor func(ctx context.Context, job FileJob) (int64, error)
 
+// JobFailure is a job that failed after all its retries
+type JobFailure struct {
+	Job FileJob
+	Err error
+}
+
+// JobFailuresError is returned by ProcessJobs when jobs failed. Without
+// ContinueOnError it holds the job that stopped the run, and any that failed
+// alongside it.
+type JobFailuresError struct {
+	Failures []JobFailure
+	Total    int
+}
+
+func (e *JobFailuresError) Error() string {
+	if len(e.Failures) == 1 {
+		return fmt.Sprintf("1 of %d files failed: %s: %v", e.Total, e.Failures[0].Job.Path, e.Failures[0].Err)
+	}
+	return fmt.Sprintf("%d of %d files failed", len(e.Failures), e.Total)
+}
+
+// Unwrap returns the errors of the failed jobs
+func (e *JobFailuresError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, failure := range e.Failures {
+		errs[i] = failure.Err
+	}
+	return errs
+}
+
 // ProcessJobs executes a list of jobs concurrently
 func (q *FileQueue) ProcessJobs(ctx context.Context, jobs []FileJob, executor JobExecutor, updateFn func(int, int64)) error {
 	var wg sync.WaitGroup
-	var errOnce sync.Once
-	var firstErr error
+	var mu sync.Mutex
+	var failures []JobFailure
 
 	var filesDone int64 // atomic
 	var bytesDone int64 // atomic
 
 	for _, job := range jobs {
 		// Fast fail on error
-		if firstErr != nil {
+		mu.Lock()
+		failed := !q.ContinueOnError && len(failures) > 0
+		mu.Unlock()
+		if failed {
 			break
 		}
 		if ctx.Err() != nil {
@@ -53,13 +105,21 @@ func (q *FileQueue) ProcessJobs(ctx context.Context, jobs []FileJob, executor Jo
 			}
 
 			// Execute
-			written, err := executor(ctx, j)
+			written, err := q.run(ctx, j, executor)
 			if err != nil {
-				errOnce.Do(func() {
-					firstErr = err
-				})
-				return // Don't update progress on failure? Or count as failed?
-				// For now, simpler to stop.
+				if ctx.Err() != nil {
+					return
+				}
+				// Failures are recorded in both modes, so they can be
+				// listed and retried
+				mu.Lock()
+				defer mu.Unlock()
+				failure := JobFailure{Job: j, Err: err}
+				failures = append(failures, failure)
+				if q.OnFailure != nil {
+					q.OnFailure(failure)
+				}
+				return
 			}
 
 			// Success
@@ -73,5 +133,27 @@ func (q *FileQueue) ProcessJobs(ctx context.Context, jobs []FileJob, executor Jo
 	}
 
 	wg.Wait()
-	return firstErr
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if len(failures) > 0 {
+		return &JobFailuresError{Failures: failures, Total: len(jobs)}
+	}
+	return nil
+}
+
+// run executes job, retrying it with exponential backoff while it fails.
+// Retries have job.Attempt set so executors can resume the transfer.
+func (q *FileQueue) run(ctx context.Context, job FileJob, executor JobExecutor) (int64, error) {
+	written, err := executor(ctx, job)
+	for retry := 0; err != nil && retry < q.Retries; retry++ {
+		select {
+		case <-ctx.Done():
+			return written, err
+		case <-time.After(q.RetryDelay << retry):
+		}
+		job.Attempt++
+		written, err = executor(ctx, job)
+	}
+	return written, err
 }
@@ -60,9 +60,19 @@ func TestFileQueue_ProcessJobs(t *testing.T) {
 
 		jobs[2].Path = "fail"
 
+		var failures []JobFailure
+		queue.OnFailure = func(failure JobFailure) { failures = append(failures, failure) }
+
 		err := queue.ProcessJobs(context.Background(), jobs, executor, nil)
-		if err != expectedErr {
-			t.Errorf("Expected error %v, got %v", expectedErr, err)
+		var failed *JobFailuresError
+		if !errors.As(err, &failed) || !errors.Is(err, expectedErr) {
+			t.Fatalf("Expected a failures error wrapping %v, got %v", expectedErr, err)
+		}
+		if len(failed.Failures) != 1 || failed.Failures[0].Job.Path != "fail" || failed.Total != 5 {
+			t.Errorf("Expected the failed job out of 5, got %+v", failed)
+		}
+		if len(failures) != 1 {
+			t.Errorf("Expected OnFailure to be called once, got %d", len(failures))
 		}
 	})
 
@@ -87,3 +97,111 @@ func TestFileQueue_ProcessJobs(t *testing.T) {
 		}
 	})
 }
+
+func TestFileQueue_Retries(t *testing.T) {
+	t.Run("Core Functionality: Failed job is retried with its attempt", func(t *testing.T) {
+		queue := NewFileQueue(1)
+		queue.Retries = 3
+		queue.RetryDelay = time.Millisecond
+
+		var attempts []int
+		executor := func(ctx context.Context, job FileJob) (int64, error) {
+			attempts = append(attempts, job.Attempt)
+			if len(attempts) < 3 {
+				return 0, errors.New("connection lost")
+			}
+			return job.Size, nil
+		}
+
+		err := queue.ProcessJobs(context.Background(), []FileJob{{Path: "big.iso", Size: 100}}, executor, nil)
+		if err != nil {
+			t.Fatalf("Expected the job to succeed after retrying, got %v", err)
+		}
+		if len(attempts) != 3 || attempts[0] != 0 || attempts[1] != 1 || attempts[2] != 2 {
+			t.Errorf("Expected attempts 0, 1, 2, got %v", attempts)
+		}
+	})
+
+	t.Run("Error Handling: Gives up after the retry limit", func(t *testing.T) {
+		queue := NewFileQueue(1)
+		queue.Retries = 2
+		queue.RetryDelay = time.Millisecond
+		expectedErr := errors.New("permission denied")
+
+		var calls int32
+		executor := func(ctx context.Context, job FileJob) (int64, error) {
+			atomic.AddInt32(&calls, 1)
+			return 0, expectedErr
+		}
+
+		err := queue.ProcessJobs(context.Background(), []FileJob{{Path: "a"}}, executor, nil)
+		if !errors.Is(err, expectedErr) {
+			t.Errorf("Expected error %v, got %v", expectedErr, err)
+		}
+		if calls != 3 {
+			t.Errorf("Expected 3 runs, got %d", calls)
+		}
+	})
+
+	t.Run("Side Effects: Cancelled job is not retried", func(t *testing.T) {
+		queue := NewFileQueue(1)
+		queue.Retries = 3
+		queue.RetryDelay = time.Hour
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var calls int32
+		executor := func(ctx context.Context, job FileJob) (int64, error) {
+			atomic.AddInt32(&calls, 1)
+			cancel()
+			return 0, ctx.Err()
+		}
+
+		err := queue.ProcessJobs(ctx, []FileJob{{Path: "a"}}, executor, nil)
+		if err != context.Canceled {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+		if calls != 1 {
+			t.Errorf("Expected 1 run, got %d", calls)
+		}
+	})
+}
+
+func TestFileQueue_ContinueOnError(t *testing.T) {
+	queue := NewFileQueue(2)
+	queue.ContinueOnError = true
+
+	var reported int32
+	queue.OnFailure = func(failure JobFailure) {
+		atomic.AddInt32(&reported, 1)
+	}
+
+	jobs := []FileJob{{Path: "a", Size: 10}, {Path: "bad1"}, {Path: "b", Size: 10}, {Path: "bad2"}, {Path: "c", Size: 10}}
+	executor := func(ctx context.Context, job FileJob) (int64, error) {
+		if job.Path == "bad1" || job.Path == "bad2" {
+			return 0, errors.New("no space left on device")
+		}
+		return job.Size, nil
+	}
+
+	var done int32
+	err := queue.ProcessJobs(context.Background(), jobs, executor, func(count int, bytes int64) {
+		atomic.AddInt32(&done, 1)
+	})
+
+	var failures *JobFailuresError
+	if !errors.As(err, &failures) {
+		t.Fatalf("Expected JobFailuresError, got %v", err)
+	}
+	if len(failures.Failures) != 2 || failures.Total != 5 {
+		t.Errorf("Expected 2 of 5 jobs failed, got %d of %d", len(failures.Failures), failures.Total)
+	}
+	if done != 3 {
+		t.Errorf("Expected the 3 other jobs to finish, got %d", done)
+	}
+	if reported != 2 {
+		t.Errorf("Expected 2 failures reported, got %d", reported)
+	}
+	if err.Error() != "2 of 5 files failed" {
+		t.Errorf("Unexpected message: %q", err.Error())
+	}
+}
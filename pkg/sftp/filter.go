@@ -0,0 +1,226 @@
+package sftp
+
+import (
+	"path"
+	"strings"
+)
+
+// IgnoreFiles are read in every scanned directory when Filter.IgnoreFiles is
+// set, their patterns applying to that directory and below
+var IgnoreFiles = []string{".gitignore", ".marixignore"}
+
+// Filter selects what a directory transfer copies. Patterns are globs
+// matched against paths inside the transferred directory:
+//   - a pattern without a slash matches a name at any depth ("*.log")
+//   - a pattern with a slash matches from the top ("/build", "src/*.go")
+//   - a trailing slash only matches directories ("node_modules/")
+//   - "**" matches any number of directories ("assets/**/*.png")
+//
+// An excluded directory is skipped with everything in it. When Include is
+// set, only files matching one of its patterns are copied; directories are
+// still walked. Exclusions win over inclusions.
+type Filter struct {
+	Include     []string
+	Exclude     []string
+	IgnoreFiles bool // Also exclude what .gitignore and .marixignore files list
+}
+
+// IsZero reports whether the filter keeps everything
+func (f Filter) IsZero() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0 && !f.IgnoreFiles
+}
+
+// ParsePatterns splits a comma-separated list of patterns, dropping empty ones
+func ParsePatterns(value string) []string {
+	var patterns []string
+	for _, p := range strings.Split(value, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// RsyncArgs translates the filter into rsync options for transferring the
+// directory called name, so rsync skips what the scanners skip. Ignore files
+// become per-directory merge rules, which rsync reads on the sending side.
+func (f Filter) RsyncArgs(name string) []string {
+	var args []string
+	for _, p := range f.Exclude {
+		if rule, ok := parseFilterRule("", p); ok {
+			args = append(args, "--exclude="+rule.rsync(name))
+		}
+	}
+	if f.IgnoreFiles {
+		for _, file := range IgnoreFiles {
+			args = append(args, "--filter=:- "+file)
+		}
+	}
+	if len(f.Include) > 0 {
+		// Walk every directory, keep the matching files and drop the rest
+		args = append(args, "--include=*/")
+		for _, p := range f.Include {
+			if rule, ok := parseFilterRule("", p); ok {
+				args = append(args, "--include="+rule.rsync(name))
+			}
+		}
+		args = append(args, "--exclude=*")
+	}
+	return args
+}
+
+// filterRule is a parsed pattern
+type filterRule struct {
+	base     string // Directory the rule applies under, as a slash path from the scan base
+	pattern  string
+	anchored bool // Matched against the path from base instead of the name
+	dirOnly  bool
+}
+
+// parseFilterRule parses pattern for entries under base. Blank lines,
+// comments and negations ("!keep.log", which rsync cannot express) give no rule.
+func parseFilterRule(base, pattern string) (filterRule, bool) {
+	p := strings.TrimSpace(pattern)
+	if p == "" || strings.HasPrefix(p, "#") || strings.HasPrefix(p, "!") {
+		return filterRule{}, false
+	}
+	rule := filterRule{base: base}
+	if strings.HasSuffix(p, "/") {
+		rule.dirOnly = true
+		p = strings.TrimRight(p, "/")
+	}
+	if strings.HasPrefix(p, "/") {
+		rule.anchored = true
+		p = strings.TrimLeft(p, "/")
+	}
+	if p == "" {
+		return filterRule{}, false
+	}
+	rule.anchored = rule.anchored || strings.Contains(p, "/")
+	rule.pattern = p
+	return rule, true
+}
+
+// match reports whether the entry at rel, a slash path from the scan base,
+// matches the rule
+func (r filterRule) match(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(rel, r.base+"/") {
+			return false
+		}
+		rel = rel[len(r.base)+1:]
+	}
+	if !r.anchored {
+		rel = path.Base(rel)
+	}
+	return matchGlob(strings.Split(r.pattern, "/"), strings.Split(rel, "/"))
+}
+
+// rsync formats the rule as an rsync pattern. rsync anchors at the parent
+// of the transferred directory name, as sources have no trailing slash.
+func (r filterRule) rsync(name string) string {
+	p := r.pattern
+	if r.anchored {
+		p = "/" + name + "/" + p
+	}
+	if r.dirOnly {
+		p += "/"
+	}
+	return p
+}
+
+// matchGlob matches path elements against pattern elements, "**" standing
+// for any number of elements
+func matchGlob(pattern, elems []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(elems); i++ {
+				if matchGlob(pattern[1:], elems[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elems) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], elems[0]); !ok {
+			return false
+		}
+		pattern, elems = pattern[1:], elems[1:]
+	}
+	return len(elems) == 0
+}
+
+// filterMatcher applies a Filter during one scan
+type filterMatcher struct {
+	root     string       // The transferred directory, as a slash path from the scan base
+	excludes []filterRule // From the filter and the ignore files read so far
+	includes []filterRule
+	ignore   bool
+	read     func(path string) ([]byte, error) // Reads an ignore file
+}
+
+// newFilterMatcher prepares filter for scanning the directory root, nil
+// when the filter keeps everything
+func newFilterMatcher(filter Filter, root string, read func(string) ([]byte, error)) *filterMatcher {
+	if filter.IsZero() {
+		return nil
+	}
+	m := &filterMatcher{root: root, ignore: filter.IgnoreFiles, read: read}
+	for _, p := range filter.Exclude {
+		if rule, ok := parseFilterRule(root, p); ok {
+			m.excludes = append(m.excludes, rule)
+		}
+	}
+	for _, p := range filter.Include {
+		if rule, ok := parseFilterRule(root, p); ok {
+			m.includes = append(m.includes, rule)
+		}
+	}
+	return m
+}
+
+// excluded reports whether the entry at rel, a slash path from the scan
+// base, is left out
+func (m *filterMatcher) excluded(rel string, isDir bool) bool {
+	if rel == m.root {
+		return false
+	}
+	for _, rule := range m.excludes {
+		if rule.match(rel, isDir) {
+			return true
+		}
+	}
+	if isDir || len(m.includes) == 0 {
+		return false
+	}
+	for _, rule := range m.includes {
+		if rule.match(rel, isDir) {
+			return false
+		}
+	}
+	return true
+}
+
+// enter reads the ignore files of the directory at rel, found at dir, whose
+// patterns apply to what is walked next
+func (m *filterMatcher) enter(rel, dir string, join func(...string) string) {
+	if !m.ignore {
+		return
+	}
+	for _, name := range IgnoreFiles {
+		data, err := m.read(join(dir, name))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if rule, ok := parseFilterRule(rel, line); ok {
+				m.excludes = append(m.excludes, rule)
+			}
+		}
+	}
+}
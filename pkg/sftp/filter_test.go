@@ -0,0 +1,164 @@
+package sftp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestFilterRule_Match(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{"*.log", "site/debug.log", false, true},
+		{"*.log", "site/logs/app.log", false, true},
+		{"node_modules/", "site/web/node_modules", true, true},
+		{"node_modules/", "site/node_modules", false, false},
+		{"/build", "site/build", true, true},
+		{"/build", "site/web/build", true, false},
+		{"src/*.go", "site/src/main.go", false, true},
+		{"src/*.go", "site/lib/src/main.go", false, false},
+		{"assets/**/*.png", "site/assets/logo.png", false, true},
+		{"assets/**/*.png", "site/assets/img/icons/a.png", false, true},
+		{"**/cache", "site/a/b/cache", true, true},
+	}
+	for _, tt := range tests {
+		rule, ok := parseFilterRule("site", tt.pattern)
+		if !ok {
+			t.Fatalf("%q: expected a rule", tt.pattern)
+		}
+		if got := rule.match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("%q on %s: expected %v, got %v", tt.pattern, tt.path, tt.want, got)
+		}
+	}
+
+	for _, line := range []string{"", "  ", "# comment", "!keep.log", "/"} {
+		if _, ok := parseFilterRule("", line); ok {
+			t.Errorf("%q: expected no rule", line)
+		}
+	}
+}
+
+func TestParsePatterns(t *testing.T) {
+	got := ParsePatterns(" node_modules/, *.log,,.git/ ")
+	want := []string{"node_modules/", "*.log", ".git/"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestDirectoryScanner_Filter(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "site")
+	files := map[string]string{
+		"index.html":                "<html>",
+		"app.log":                   "log",
+		"src/main.go":               "package main",
+		"src/main_test.go":          "package main",
+		"node_modules/lib/index.js": "js",
+		"web/node_modules/dep.js":   "js",
+		"build/out.bin":             "bin",
+		"docs/build/guide.md":       "guide",
+		".gitignore":                "*.tmp\n# comment\n/dist/\n",
+		"scratch.tmp":               "tmp",
+		"dist/bundle.js":            "js",
+		"web/dist/keep.js":          "js",
+		"web/.marixignore":          "secret.txt\n",
+		"web/secret.txt":            "secret",
+		"secret.txt":                "not ignored here",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scan := func(t *testing.T, filter Filter) []string {
+		scanner := NewDirectoryScanner(nil)
+		scanner.Filter = filter
+		jobs, _, err := scanner.ScanLocal(context.Background(), root, "/remote", nil)
+		if err != nil {
+			t.Fatalf("ScanLocal failed: %v", err)
+		}
+		var files []string
+		for _, job := range jobs {
+			if !job.IsDir {
+				files = append(files, filepath.ToSlash(job.Path))
+			}
+		}
+		sort.Strings(files)
+		return files
+	}
+
+	t.Run("Core Functionality: Excluded directories are skipped", func(t *testing.T) {
+		got := scan(t, Filter{Exclude: []string{"node_modules/", "*.log", "/build"}})
+		for _, path := range got {
+			switch path {
+			case "site/app.log", "site/node_modules/lib/index.js", "site/web/node_modules/dep.js", "site/build/out.bin":
+				t.Errorf("Expected %s to be excluded", path)
+			}
+		}
+		if len(got) != len(files)-4 {
+			t.Errorf("Expected %d files, got %v", len(files)-4, got)
+		}
+	})
+
+	t.Run("Core Functionality: Ignore files apply below their directory", func(t *testing.T) {
+		got := scan(t, Filter{IgnoreFiles: true})
+		excluded := map[string]bool{"site/scratch.tmp": true, "site/dist/bundle.js": true, "site/web/secret.txt": true}
+		for _, path := range got {
+			if excluded[path] {
+				t.Errorf("Expected %s to be ignored", path)
+			}
+		}
+		if len(got) != len(files)-len(excluded) {
+			t.Errorf("Expected %d files, got %v", len(files)-len(excluded), got)
+		}
+	})
+
+	t.Run("Core Functionality: Only included files are kept", func(t *testing.T) {
+		got := scan(t, Filter{Include: []string{"*.go"}, Exclude: []string{"*_test.go"}})
+		want := []string{"site/src/main.go"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Edge Case: Zero filter keeps everything", func(t *testing.T) {
+		if got := scan(t, Filter{}); len(got) != len(files) {
+			t.Errorf("Expected %d files, got %d", len(files), len(got))
+		}
+	})
+}
+
+func TestFilter_RsyncArgs(t *testing.T) {
+	filter := Filter{
+		Include:     []string{"*.go"},
+		Exclude:     []string{"node_modules/", "/build", " "},
+		IgnoreFiles: true,
+	}
+	want := []string{
+		"--exclude=node_modules/",
+		"--exclude=/site/build",
+		"--filter=:- .gitignore",
+		"--filter=:- .marixignore",
+		"--include=*/",
+		"--include=*.go",
+		"--exclude=*",
+	}
+	if got := filter.RsyncArgs("site"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if args := (Filter{}).RsyncArgs("site"); len(args) != 0 {
+		t.Errorf("Expected no arguments for an empty filter, got %v", args)
+	}
+}
@@ -0,0 +1,143 @@
+package sftp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ResumeVerify selects how the partial destination of a resumed transfer is
+// checked against the source before it is continued
+type ResumeVerify int
+
+const (
+	// VerifySize continues whenever the partial file is smaller than the source
+	VerifySize ResumeVerify = iota
+	// VerifyHash also compares the SHA-256 of the partial file with the same
+	// prefix of the source, restarting from zero when they differ
+	VerifyHash
+)
+
+// prefixHashFunc returns the SHA-256 of the first n bytes of a file
+type prefixHashFunc func(n int64) ([]byte, error)
+
+// resumeOffset returns where a transfer of a srcSize byte source can continue
+// into a dstSize byte partial destination, 0 when it must restart
+func resumeOffset(srcSize, dstSize int64, verify ResumeVerify, srcHash, dstHash prefixHashFunc) (int64, error) {
+	if dstSize <= 0 || dstSize > srcSize {
+		return 0, nil
+	}
+	if verify == VerifySize {
+		return dstSize, nil
+	}
+
+	want, err := srcHash(dstSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to hash source: %w", err)
+	}
+	got, err := dstHash(dstSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to hash partial file: %w", err)
+	}
+	if !bytes.Equal(want, got) {
+		return 0, nil
+	}
+	return dstSize, nil
+}
+
+// resumeCopy continues copying src into dst from offset, truncating dst
+// there first. onProgress is told about the bytes already in place.
+func resumeCopy(dst io.WriteSeeker, src io.ReadSeeker, offset int64, truncate func(int64) error, onProgress ProgressFunc) error {
+	if err := truncate(offset); err != nil {
+		return fmt.Errorf("failed to truncate partial file: %w", err)
+	}
+	if _, err := dst.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek destination: %w", err)
+	}
+	if _, err := src.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek source: %w", err)
+	}
+
+	var err error
+	if onProgress != nil {
+		if offset > 0 {
+			if err := onProgress(offset); err != nil {
+				return err
+			}
+		}
+		_, err = io.Copy(dst, &progressReader{r: src, onProgress: onProgress})
+	} else {
+		_, err = io.Copy(dst, src)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy data: %w", err)
+	}
+	return nil
+}
+
+// ResumeDownload downloads a file, continuing from a partial local copy
+// left by an interrupted download when it matches the remote file
+func (c *Client) ResumeDownload(remotePath, localPath string, verify ResumeVerify, onProgress ProgressFunc) error {
+	remoteFile, err := c.sftpClient.Open(remotePath)
+	if err != nil {
+		return fmt.Errorf("failed to open remote file: %w", err)
+	}
+	defer remoteFile.Close()
+	remoteInfo, err := remoteFile.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat remote file: %w", err)
+	}
+
+	localFile, err := os.OpenFile(localPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open local file: %w", err)
+	}
+	defer localFile.Close()
+	localInfo, err := localFile.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat local file: %w", err)
+	}
+
+	offset, err := resumeOffset(remoteInfo.Size(), localInfo.Size(), verify,
+		func(n int64) ([]byte, error) { return c.remoteHash(remotePath, remoteFile, n) },
+		func(n int64) ([]byte, error) { return hashPrefix(localFile, n) })
+	if err != nil {
+		return err
+	}
+
+	return resumeCopy(localFile, remoteFile, offset, localFile.Truncate, onProgress)
+}
+
+// ResumeUpload uploads a file, continuing from a partial remote copy left
+// by an interrupted upload when it matches the local file
+func (c *Client) ResumeUpload(localPath, remotePath string, verify ResumeVerify, onProgress ProgressFunc) error {
+	localFile, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open local file: %w", err)
+	}
+	defer localFile.Close()
+	localInfo, err := localFile.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat local file: %w", err)
+	}
+
+	remoteFile, err := c.sftpClient.OpenFile(remotePath, os.O_RDWR|os.O_CREATE)
+	if err != nil {
+		return fmt.Errorf("failed to open remote file: %w", err)
+	}
+	defer remoteFile.Close()
+	remoteInfo, err := remoteFile.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat remote file: %w", err)
+	}
+
+	offset, err := resumeOffset(localInfo.Size(), remoteInfo.Size(), verify,
+		func(n int64) ([]byte, error) { return hashPrefix(localFile, n) },
+		func(n int64) ([]byte, error) { return c.remoteHash(remotePath, remoteFile, n) })
+	if err != nil {
+		return err
+	}
+
+	return resumeCopy(remoteFile, localFile, offset, remoteFile.Truncate, onProgress)
+}
@@ -0,0 +1,130 @@
+package sftp
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func hashOf(data []byte) prefixHashFunc {
+	return func(n int64) ([]byte, error) {
+		return hashPrefix(bytes.NewReader(data), n)
+	}
+}
+
+func TestResumeOffset(t *testing.T) {
+	source := []byte("0123456789abcdef")
+
+	t.Run("Core Functionality: Continue after a matching prefix", func(t *testing.T) {
+		partial := source[:10]
+		offset, err := resumeOffset(int64(len(source)), int64(len(partial)), VerifyHash, hashOf(source), hashOf(partial))
+		if err != nil {
+			t.Fatalf("resumeOffset failed: %v", err)
+		}
+		if offset != 10 {
+			t.Errorf("Expected offset 10, got %d", offset)
+		}
+	})
+
+	t.Run("Core Functionality: Restart when the prefix differs", func(t *testing.T) {
+		partial := []byte("0123X56789")
+		offset, err := resumeOffset(int64(len(source)), int64(len(partial)), VerifyHash, hashOf(source), hashOf(partial))
+		if err != nil {
+			t.Fatalf("resumeOffset failed: %v", err)
+		}
+		if offset != 0 {
+			t.Errorf("Expected offset 0, got %d", offset)
+		}
+	})
+
+	t.Run("Core Functionality: Size only skips hashing", func(t *testing.T) {
+		fail := func(int64) ([]byte, error) { return nil, errors.New("should not hash") }
+		offset, err := resumeOffset(16, 10, VerifySize, fail, fail)
+		if err != nil {
+			t.Fatalf("resumeOffset failed: %v", err)
+		}
+		if offset != 10 {
+			t.Errorf("Expected offset 10, got %d", offset)
+		}
+	})
+
+	t.Run("Edge Case: Destination missing or larger than source", func(t *testing.T) {
+		for _, size := range []int64{0, 17} {
+			offset, err := resumeOffset(16, size, VerifySize, nil, nil)
+			if err != nil || offset != 0 {
+				t.Errorf("Size %d: expected offset 0, got %d (%v)", size, offset, err)
+			}
+		}
+	})
+
+	t.Run("Edge Case: Complete destination needs nothing more", func(t *testing.T) {
+		offset, err := resumeOffset(16, 16, VerifyHash, hashOf(source), hashOf(source))
+		if err != nil || offset != 16 {
+			t.Errorf("Expected offset 16, got %d (%v)", offset, err)
+		}
+	})
+
+	t.Run("Error Handling: Hash failure", func(t *testing.T) {
+		fail := func(int64) ([]byte, error) { return nil, errors.New("read failed") }
+		if _, err := resumeOffset(16, 10, VerifyHash, hashOf(source), fail); err == nil {
+			t.Error("Expected an error when the partial file cannot be hashed")
+		}
+	})
+}
+
+func TestHashPrefix_ShortFile(t *testing.T) {
+	if _, err := hashPrefix(bytes.NewReader([]byte("abc")), 10); err == nil {
+		t.Error("Expected an error when the file is shorter than the prefix")
+	}
+}
+
+func TestResumeCopy(t *testing.T) {
+	source := bytes.Repeat([]byte("marix resume "), 1000)
+	dir := t.TempDir()
+
+	open := func(t *testing.T, content []byte) *os.File {
+		path := filepath.Join(dir, t.Name()[len("TestResumeCopy/"):])
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		file, err := os.OpenFile(path, os.O_RDWR, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { file.Close() })
+		return file
+	}
+
+	t.Run("continue", func(t *testing.T) {
+		dst := open(t, source[:5000])
+		var reported int64
+		err := resumeCopy(dst, bytes.NewReader(source), 5000, dst.Truncate, func(n int64) error {
+			reported += n
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("resumeCopy failed: %v", err)
+		}
+		got, _ := os.ReadFile(dst.Name())
+		if !bytes.Equal(got, source) {
+			t.Errorf("Expected the full source after resuming, got %d bytes", len(got))
+		}
+		if reported != int64(len(source)) {
+			t.Errorf("Expected progress to cover %d bytes, got %d", len(source), reported)
+		}
+	})
+
+	t.Run("restart", func(t *testing.T) {
+		// A stale destination longer than the source is replaced
+		dst := open(t, bytes.Repeat([]byte("x"), len(source)+100))
+		if err := resumeCopy(dst, bytes.NewReader(source), 0, dst.Truncate, nil); err != nil {
+			t.Fatalf("resumeCopy failed: %v", err)
+		}
+		got, _ := os.ReadFile(dst.Name())
+		if !bytes.Equal(got, source) {
+			t.Errorf("Expected the destination to match the source, got %d bytes", len(got))
+		}
+	})
+}
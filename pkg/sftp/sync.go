@@ -0,0 +1,273 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SyncOptions selects how a directory sync compares and cleans up
+type SyncOptions struct {
+	Checksum bool // Compare files of the same size by SHA-256 instead of modification time
+	Delete   bool // Delete destination entries missing from the source (mirror)
+
+	// Filter leaves entries out on both sides, so excluded destination
+	// entries are never deleted
+	Filter Filter
+}
+
+// SyncAction is what a sync does with an entry
+type SyncAction int
+
+const (
+	SyncCopy   SyncAction = iota // Missing from the destination
+	SyncUpdate                   // Differs from the destination
+	SyncDelete                   // Only on the destination, or in the way of a source entry
+)
+
+// SyncEntry is a change a sync makes
+type SyncEntry struct {
+	Action SyncAction
+	Job    FileJob // The source entry for copies and updates, the destination one for deletes
+}
+
+// SyncPlan is what a directory sync would change, computed without
+// touching either side so it can be previewed
+type SyncPlan struct {
+	Type      TaskType // TaskUploadDirectory or TaskDownloadDirectory
+	Source    string
+	Dest      string
+	Name      string
+	Options   SyncOptions
+	Entries   []SyncEntry
+	Unchanged int // Files already up to date
+}
+
+// Count returns how many entries the plan has for action
+func (p *SyncPlan) Count(action SyncAction) int {
+	n := 0
+	for _, entry := range p.Entries {
+		if entry.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Bytes returns how much the plan transfers
+func (p *SyncPlan) Bytes() int64 {
+	var n int64
+	for _, entry := range p.Entries {
+		if entry.Action != SyncDelete && !entry.Job.IsDir {
+			n += entry.Job.Size
+		}
+	}
+	return n
+}
+
+// mtimeWindow is how far apart modification times can be and still match,
+// as SFTP keeps them to the second
+const mtimeWindow = 1
+
+// diffTrees compares the scans of a sync's source and destination, matching
+// entries by relative path. same reports whether two files of the same size
+// have the same content, it is only called with opts.Checksum.
+func diffTrees(source, dest []FileJob, opts SyncOptions, same func(src, dst FileJob) (bool, error)) ([]SyncEntry, int, error) {
+	inDest := make(map[string]FileJob, len(dest))
+	for _, job := range dest {
+		inDest[job.Path] = job
+	}
+	inSource := make(map[string]bool, len(source))
+	for _, job := range source {
+		inSource[job.Path] = true
+	}
+
+	var deletes, transfers []SyncEntry
+	unchanged := 0
+	// deleted holds the destination directories already being deleted, so
+	// their contents are not listed again
+	var deleted []string
+	isDeleted := func(path string) bool {
+		for _, dir := range deleted {
+			if strings.HasPrefix(path, dir+string(filepath.Separator)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, src := range source {
+		dst, ok := inDest[src.Path]
+		switch {
+		case !ok || isDeleted(src.Path):
+			transfers = append(transfers, SyncEntry{Action: SyncCopy, Job: src})
+		case src.IsDir != dst.IsDir:
+			// A file where a directory goes or the reverse: replace it
+			deletes = append(deletes, SyncEntry{Action: SyncDelete, Job: dst})
+			if dst.IsDir {
+				deleted = append(deleted, dst.Path)
+			}
+			transfers = append(transfers, SyncEntry{Action: SyncCopy, Job: src})
+		case src.IsDir:
+			// Directory already there
+		default:
+			changed := src.Size != dst.Size
+			if !changed && opts.Checksum {
+				equal, err := same(src, dst)
+				if err != nil {
+					return nil, 0, fmt.Errorf("failed to compare %s: %w", src.Path, err)
+				}
+				changed = !equal
+			} else if !changed {
+				diff := src.ModTime - dst.ModTime
+				changed = diff > mtimeWindow || diff < -mtimeWindow
+			}
+			if changed {
+				transfers = append(transfers, SyncEntry{Action: SyncUpdate, Job: src})
+			} else {
+				unchanged++
+			}
+		}
+	}
+
+	if opts.Delete {
+		for _, dst := range dest {
+			if inSource[dst.Path] || isDeleted(dst.Path) {
+				continue
+			}
+			deletes = append(deletes, SyncEntry{Action: SyncDelete, Job: dst})
+			if dst.IsDir {
+				deleted = append(deleted, dst.Path)
+			}
+		}
+	}
+
+	return append(deletes, transfers...), unchanged, nil
+}
+
+// PlanSync compares the source and destination of a directory transfer and
+// returns what syncing them would change, without changing anything
+func (q *TaskQueue) PlanSync(ctx context.Context, taskType TaskType, source, dest, name string, opts SyncOptions) (*SyncPlan, error) {
+	client := q.currentClient()
+	if client == nil {
+		return nil, fmt.Errorf("client not initialized")
+	}
+
+	scanner := NewDirectoryScanner(client)
+	scanner.Filter = opts.Filter
+	var sourceJobs, destJobs []FileJob
+	var err error
+	var same func(src, dst FileJob) (bool, error)
+
+	switch taskType {
+	case TaskUploadDirectory:
+		if sourceJobs, _, err = scanner.ScanLocal(ctx, source, filepath.Dir(dest), nil); err != nil {
+			return nil, err
+		}
+		// A missing destination comes back empty
+		if destJobs, _, err = scanner.ScanRemote(ctx, dest, filepath.Dir(source), nil); err != nil {
+			return nil, err
+		}
+		same = func(src, dst FileJob) (bool, error) {
+			return client.sameContent(src.AbsPath, dst.AbsPath)
+		}
+	case TaskDownloadDirectory:
+		if sourceJobs, _, err = scanner.ScanRemote(ctx, source, filepath.Dir(dest), nil); err != nil {
+			return nil, err
+		}
+		destJobs, _, err = scanner.ScanLocal(ctx, dest, filepath.Dir(source), nil)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		same = func(src, dst FileJob) (bool, error) {
+			return client.sameContent(dst.AbsPath, src.AbsPath)
+		}
+	default:
+		return nil, fmt.Errorf("only directories can be synced")
+	}
+
+	// Hashing stops with the scans when the plan is no longer wanted
+	compare := same
+	same = func(src, dst FileJob) (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		return compare(src, dst)
+	}
+
+	entries, unchanged, err := diffTrees(sourceJobs, destJobs, opts, same)
+	if err != nil {
+		return nil, err
+	}
+	return &SyncPlan{
+		Type:      taskType,
+		Source:    source,
+		Dest:      dest,
+		Name:      name,
+		Options:   opts,
+		Entries:   entries,
+		Unchanged: unchanged,
+	}, nil
+}
+
+// QueueSync queues a task that carries out plan: deletions first, then the
+// copies and updates, which keep their source's modification time so the
+// next sync sees them as up to date
+func (q *TaskQueue) QueueSync(plan *SyncPlan) (*Task, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	task := &Task{
+		Type:          plan.Type,
+		Source:        plan.Source,
+		Dest:          plan.Dest,
+		Name:          plan.Name,
+		planned:       true,
+		preserveTimes: true,
+	}
+	for _, entry := range plan.Entries {
+		if entry.Action == SyncDelete {
+			task.deletes = append(task.deletes, entry.Job)
+		} else {
+			task.jobs = append(task.jobs, entry.Job)
+		}
+	}
+	return q.queue(task)
+}
+
+// deleteExtraneous removes the destination entries of a sync
+func (q *TaskQueue) deleteExtraneous(task *Task) error {
+	for _, job := range task.deletes {
+		if task.ctx.Err() != nil {
+			return task.ctx.Err()
+		}
+
+		var err error
+		switch {
+		case task.Type == TaskDownloadDirectory:
+			err = os.RemoveAll(job.AbsPath)
+		case job.IsDir:
+			err = task.client.RemoveDirectory(job.AbsPath)
+		default:
+			err = task.client.Delete(job.AbsPath)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete %s: %w", job.Path, err)
+		}
+		log.Printf("[INFO] Task %d: deleted %s", task.ID, job.AbsPath)
+	}
+	return nil
+}
+
+// sameContent reports whether a local and a remote file have the same SHA-256
+func (c *Client) sameContent(localPath, remotePath string) (bool, error) {
+	err := c.Verify(localPath, remotePath)
+	if errors.Is(err, ErrChecksumMismatch) {
+		return false, nil
+	}
+	return err == nil, err
+}
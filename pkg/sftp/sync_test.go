@@ -0,0 +1,171 @@
+package sftp
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func dirJob(path string) FileJob {
+	return FileJob{Path: filepath.FromSlash(path), AbsPath: "/" + path, IsDir: true}
+}
+
+func fileJob(path string, size, mtime int64) FileJob {
+	return FileJob{Path: filepath.FromSlash(path), AbsPath: "/" + path, Size: size, ModTime: mtime}
+}
+
+// actions maps each planned path to its action
+func actions(entries []SyncEntry) map[string]SyncAction {
+	out := make(map[string]SyncAction, len(entries))
+	for _, entry := range entries {
+		out[filepath.ToSlash(entry.Job.Path)] = entry.Action
+	}
+	return out
+}
+
+func TestDiffTrees(t *testing.T) {
+	source := []FileJob{
+		dirJob("site"),
+		fileJob("site/index.html", 100, 1000),
+		fileJob("site/app.js", 200, 1000),
+		fileJob("site/logo.png", 300, 1000),
+		dirJob("site/css"),
+		fileJob("site/css/main.css", 50, 1000),
+	}
+	dest := []FileJob{
+		dirJob("site"),
+		fileJob("site/index.html", 100, 1000), // Same
+		fileJob("site/app.js", 200, 900),      // Older
+		fileJob("site/logo.png", 250, 1000),   // Other size
+		fileJob("site/old.html", 10, 500),     // Not in source
+		dirJob("site/tmp"),
+		fileJob("site/tmp/cache", 10, 500),
+	}
+	noChecksum := func(src, dst FileJob) (bool, error) {
+		t.Fatal("Checksums should not be compared")
+		return false, nil
+	}
+
+	t.Run("Core Functionality: Only changed files are transferred", func(t *testing.T) {
+		entries, unchanged, err := diffTrees(source, dest, SyncOptions{}, noChecksum)
+		if err != nil {
+			t.Fatalf("diffTrees failed: %v", err)
+		}
+		want := map[string]SyncAction{
+			"site/app.js":       SyncUpdate,
+			"site/logo.png":     SyncUpdate,
+			"site/css":          SyncCopy,
+			"site/css/main.css": SyncCopy,
+		}
+		got := actions(entries)
+		if len(got) != len(want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+		for path, action := range want {
+			if got[path] != action {
+				t.Errorf("%s: expected action %d, got %d (present %v)", path, action, got[path], got)
+			}
+		}
+		if unchanged != 1 {
+			t.Errorf("Expected 1 unchanged file, got %d", unchanged)
+		}
+	})
+
+	t.Run("Core Functionality: Mirror deletes extraneous entries once", func(t *testing.T) {
+		entries, _, err := diffTrees(source, dest, SyncOptions{Delete: true}, noChecksum)
+		if err != nil {
+			t.Fatalf("diffTrees failed: %v", err)
+		}
+		got := actions(entries)
+		if got["site/old.html"] != SyncDelete || got["site/tmp"] != SyncDelete {
+			t.Errorf("Expected old.html and tmp to be deleted, got %v", got)
+		}
+		if _, ok := got["site/tmp/cache"]; ok {
+			t.Error("Contents of a deleted directory should not be listed")
+		}
+		if entries[0].Action != SyncDelete {
+			t.Error("Expected deletions to come first")
+		}
+	})
+
+	t.Run("Core Functionality: Checksums decide for files of the same size", func(t *testing.T) {
+		var compared []string
+		same := func(src, dst FileJob) (bool, error) {
+			compared = append(compared, filepath.ToSlash(src.Path))
+			return src.Path != filepath.FromSlash("site/index.html"), nil
+		}
+		entries, unchanged, err := diffTrees(source, dest, SyncOptions{Checksum: true}, same)
+		if err != nil {
+			t.Fatalf("diffTrees failed: %v", err)
+		}
+		got := actions(entries)
+		if got["site/index.html"] != SyncUpdate {
+			t.Error("Expected index.html to be updated after a checksum mismatch")
+		}
+		if _, ok := got["site/app.js"]; ok {
+			t.Error("Expected app.js to be kept, its checksum matches despite the older time")
+		}
+		if len(compared) != 2 || unchanged != 1 {
+			t.Errorf("Expected 2 checksum comparisons and 1 unchanged file, got %v and %d", compared, unchanged)
+		}
+	})
+
+	t.Run("Edge Case: Entry of the other kind is replaced", func(t *testing.T) {
+		src := []FileJob{dirJob("site"), dirJob("site/docs"), fileJob("site/docs/a.md", 1, 1)}
+		dst := []FileJob{dirJob("site"), fileJob("site/docs", 5, 1)}
+		entries, _, err := diffTrees(src, dst, SyncOptions{}, noChecksum)
+		if err != nil {
+			t.Fatalf("diffTrees failed: %v", err)
+		}
+		if len(entries) != 3 || entries[0].Action != SyncDelete || !entries[1].Job.IsDir || entries[1].Action != SyncCopy {
+			t.Errorf("Expected the file to be deleted before the directory is copied, got %+v", entries)
+		}
+	})
+
+	t.Run("Edge Case: Missing destination copies everything", func(t *testing.T) {
+		entries, _, _ := diffTrees(source, nil, SyncOptions{Delete: true}, noChecksum)
+		plan := &SyncPlan{Entries: entries}
+		if plan.Count(SyncCopy) != len(source) {
+			t.Errorf("Expected %d copies, got %d", len(source), plan.Count(SyncCopy))
+		}
+		if plan.Bytes() != 650 {
+			t.Errorf("Expected 650 bytes to transfer, got %d", plan.Bytes())
+		}
+	})
+
+	t.Run("Error Handling: Checksum failure", func(t *testing.T) {
+		same := func(src, dst FileJob) (bool, error) { return false, errors.New("read failed") }
+		if _, _, err := diffTrees(source, dest, SyncOptions{Checksum: true}, same); err == nil {
+			t.Error("Expected the comparison error")
+		}
+	})
+}
+
+func TestRemoveCommand_HostileName(t *testing.T) {
+	dir := t.TempDir()
+
+	// A directory a sync would delete, named to run commands if unquoted
+	name := "-f $(touch marker) `touch marker` 'q'"
+	for _, d := range []string{filepath.Join(name, "sub"), "keep"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command("sh", "-c", removeCommand(name))
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Command failed: %v: %s", err, out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "marker")); err == nil {
+		t.Fatal("The directory name was run as a command")
+	}
+	if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+		t.Errorf("Expected the directory to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "keep")); err != nil {
+		t.Errorf("Expected the other directory to stay: %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package sftp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -53,6 +54,18 @@ type Task struct {
 	totalSize  int64
 	totalFiles int
 
+	// Retries of failed files and syncs are planned up front, so their
+	// jobs are not found by scanning
+	planned       bool
+	deletes       []FileJob // Destination entries a sync removes before transferring
+	preserveTimes bool      // Give transferred files their source's modification time
+
+	filter Filter  // What directory scans and rsync leave out
+	client *Client // The queue's client when the task started
+
+	failedFiles int64 // atomic
+	failures    []JobFailure
+
 	// Speed calculation
 	lastBytes int64
 	lastCheck time.Time
@@ -71,8 +84,9 @@ type TaskProgress struct {
 	TotalSize        int64
 	CurrentSpeed     float64
 	Percentage       int
-	LastLog          string // Output from underlying engine (e.g. rsync)
-	Error            string // Error message if failed
+	LastLog          string       // Output from underlying engine (e.g. rsync)
+	Error            string       // Error message if failed
+	Failures         []JobFailure // Files that failed, once the task has ended
 }
 
 // TaskQueue manages concurrent tasks
@@ -81,6 +95,9 @@ type TaskQueue struct {
 	sshConfig *ssh.SSHConfig
 	settings  *storage.Settings
 
+	// retryDelay is the first wait before retrying a failed file
+	retryDelay time.Duration
+
 	maxTasks   int
 	tasks      []*Task
 	taskChan   chan *Task
@@ -108,6 +125,7 @@ func NewTaskQueue(client *Client, sshConfig *ssh.SSHConfig, settings *storage.Se
 		taskChan:   make(chan *Task, maxTasks*2),
 		updateChan: updateChan,
 		sem:        make(chan struct{}, maxTasks),
+		retryDelay: DefaultRetryDelay,
 		nextID:     1,
 	}
 
@@ -124,22 +142,81 @@ func (q *TaskQueue) UpdateSettings(settings *storage.Settings) {
 	q.settings = settings
 }
 
-func (q *TaskQueue) QueueTask(taskType TaskType, source, dest, name string) (*Task, error) {
+// SetClient moves the queue to a new SFTP client, as after a reconnect.
+// Running tasks keep the client they started with.
+func (q *TaskQueue) SetClient(client *Client) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
+	q.client = client
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// currentClient returns the client new tasks start with
+func (q *TaskQueue) currentClient() *Client {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.client
+}
 
-	task := &Task{
-		ID:     q.nextID,
+func (q *TaskQueue) QueueTask(taskType TaskType, source, dest, name string) (*Task, error) {
+	return q.QueueFilteredTask(taskType, source, dest, name, Filter{})
+}
+
+// QueueFilteredTask queues a transfer that leaves out what filter excludes
+// when it copies a directory
+func (q *TaskQueue) QueueFilteredTask(taskType TaskType, source, dest, name string, filter Filter) (*Task, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.queue(&Task{
 		Type:   taskType,
 		Source: source,
 		Dest:   dest,
 		Name:   name,
-		State:  TaskPending,
-		ctx:    ctx,
-		cancel: cancel,
+		filter: filter,
+	})
+}
+
+// RetryFailed queues a new task that runs only the files that failed in
+// task taskID, resuming them from their partial copies
+func (q *TaskQueue) RetryFailed(taskID int) (*Task, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var failed *Task
+	for _, t := range q.tasks {
+		if t.ID == taskID {
+			failed = t
+			break
+		}
+	}
+	if failed == nil {
+		return nil, fmt.Errorf("task %d not found", taskID)
+	}
+	if failed.State != TaskFailed || len(failed.failures) == 0 {
+		return nil, fmt.Errorf("task %d has no failed files to retry", taskID)
 	}
+
+	retry := &Task{
+		Type:          failed.Type,
+		Source:        failed.Source,
+		Dest:          failed.Dest,
+		Name:          failed.Name,
+		planned:       true,
+		preserveTimes: failed.preserveTimes,
+	}
+	for _, failure := range failed.failures {
+		job := failure.Job
+		job.Attempt = 1
+		retry.jobs = append(retry.jobs, job)
+	}
+	return q.queue(retry)
+}
+
+// queue numbers task and hands it to the dispatcher. q.mu must be held.
+func (q *TaskQueue) queue(task *Task) (*Task, error) {
+	task.ID = q.nextID
+	task.State = TaskPending
+	task.ctx, task.cancel = context.WithCancel(context.Background())
 	q.nextID++
 
 	q.tasks = append(q.tasks, task)
@@ -171,7 +248,8 @@ func (q *TaskQueue) dispatcher() {
 }
 
 func (q *TaskQueue) processTask(task *Task) {
-	if q.client == nil {
+	task.client = q.currentClient()
+	if task.client == nil {
 		log.Printf("[ERROR] TaskQueue client is nil, skipping task processing (likely test environment)")
 		task.State = TaskFailed
 		task.err = fmt.Errorf("client not initialized")
@@ -185,9 +263,15 @@ func (q *TaskQueue) processTask(task *Task) {
 		log.Printf("[INFO] Task %d (%s) cancelled before start", task.ID, task.Name)
 		return
 	}
-	engine := NewTransferEngine(q.client, q.sshConfig, q.settings)
-	// If rsync is enabled and it's a directory transfer, skip scanning and delegate entirely to engine
-	useRsync := !q.settings.DisableRsync && (task.Type == TaskUploadDirectory || task.Type == TaskDownloadDirectory)
+	engine := NewTransferEngine(task.client, q.sshConfig, q.settings)
+	// If rsync was selected and it's a directory transfer, skip scanning and delegate entirely to engine.
+	// The engine falls back to SFTP when rsync is not installed, even if enabled.
+	// Retries of failed files go file by file like the transfer that failed
+	rsync, isRsync := engine.(*RsyncEngine)
+	useRsync := isRsync && !task.planned && (task.Type == TaskUploadDirectory || task.Type == TaskDownloadDirectory)
+	if useRsync {
+		rsync.Filter = task.filter
+	}
 	var result error
 	defer func() {
 		if result != nil {
@@ -200,6 +284,10 @@ func (q *TaskQueue) processTask(task *Task) {
 				log.Printf("[ERROR] Task %d (%s) transfer failed: %v", task.ID, task.Name, result)
 			}
 
+			var partial *JobFailuresError
+			if errors.As(result, &partial) {
+				task.failures = partial.Failures
+			}
 		} else {
 			task.State = TaskCompleted
 			task.Progress.CompletedFiles = task.totalFiles
@@ -225,7 +313,11 @@ func (q *TaskQueue) processTask(task *Task) {
 		// Transfer Phase
 		task.State = TaskTransferring
 		q.notify(task)
-		result = engine.UploadFile(task.ctx, task.Source, task.Dest, func(bytes int64, output string) error {
+		transfer := engine.UploadFile
+		if task.Type == TaskDownloadDirectory {
+			transfer = engine.DownloadFile
+		}
+		result = transfer(task.ctx, task.Source, task.Dest, func(bytes int64, output string) error {
 			// For rsync, bytes might be 0, output is the line
 			if output != "" {
 				q.updateChan <- TaskProgress{
@@ -239,20 +331,31 @@ func (q *TaskQueue) processTask(task *Task) {
 		return
 	}
 
-	task.State = TaskScanning
-	q.notify(task)
-	log.Printf("[INFO] Task %d (%s) scanning started", task.ID, task.Name)
+	if !task.planned {
+		task.State = TaskScanning
+		q.notify(task)
+		log.Printf("[INFO] Task %d (%s) scanning started", task.ID, task.Name)
+	}
 
 	// Scanner
-	scanner := NewDirectoryScanner(q.client)
+	scanner := NewDirectoryScanner(task.client)
+	scanner.Filter = task.filter
 
 	var jobs []FileJob
 	var err error
 	var totalSize int64
 
 	// Scanning Phase
-	switch task.Type {
-	case TaskUploadDirectory:
+	switch {
+	case task.planned:
+		// Retrying failed files or syncing, there is nothing to scan
+		jobs = task.jobs
+		for _, job := range jobs {
+			if !job.IsDir {
+				totalSize += job.Size
+			}
+		}
+	case task.Type == TaskUploadDirectory:
 		jobs, totalSize, err = scanner.ScanLocal(task.ctx, task.Source, filepath.Dir(task.Dest), func(count int) {
 			if count%500 == 0 {
 				q.updateChan <- TaskProgress{
@@ -262,7 +365,7 @@ func (q *TaskQueue) processTask(task *Task) {
 				}
 			}
 		})
-	case TaskDownloadDirectory:
+	case task.Type == TaskDownloadDirectory:
 		jobs, totalSize, err = scanner.ScanRemote(task.ctx, task.Source, filepath.Dir(task.Dest), func(count int) {
 			if count%500 == 0 {
 				q.updateChan <- TaskProgress{
@@ -272,7 +375,7 @@ func (q *TaskQueue) processTask(task *Task) {
 				}
 			}
 		})
-	case TaskUploadFile:
+	case task.Type == TaskUploadFile:
 		// Single file
 		info, sErr := os.Stat(task.Source)
 		if sErr == nil {
@@ -285,9 +388,9 @@ func (q *TaskQueue) processTask(task *Task) {
 			Size:     totalSize,
 			IsDir:    false,
 		}}
-	case TaskDownloadFile:
+	case task.Type == TaskDownloadFile:
 		// Single remote file
-		stat, sErr := q.client.sftpClient.Stat(task.Source)
+		stat, sErr := task.client.sftpClient.Stat(task.Source)
 		if sErr == nil {
 			totalSize = stat.Size()
 		}
@@ -303,10 +406,8 @@ func (q *TaskQueue) processTask(task *Task) {
 	}
 
 	if err != nil {
-		task.State = TaskFailed
-		task.err = err
-		q.notify(task)
 		log.Printf("[ERROR] Task %d (%s) scanning failed: %v", task.ID, task.Name, err)
+		result = err
 		return
 	}
 
@@ -321,14 +422,35 @@ func (q *TaskQueue) processTask(task *Task) {
 	q.notify(task)
 	log.Printf("[INFO] Task %d (%s) scanning done. Files: %d, Size: %d. Starting transfer.", task.ID, task.Name, task.totalFiles, task.totalSize)
 
+	// A sync removes what is in the way first
+	if len(task.deletes) > 0 {
+		q.updateChan <- TaskProgress{
+			TaskID:  task.ID,
+			State:   TaskTransferring,
+			LastLog: fmt.Sprintf("Deleting %d entries...", len(task.deletes)),
+		}
+		if err := q.deleteExtraneous(task); err != nil {
+			result = err
+			return
+		}
+	}
+
 	// Create Level 2 Queue (FileQueue)
 	fq := NewFileQueue(128) // 64 concurrent files
+	fq.Retries = q.settings.TransferRetries
+	fq.RetryDelay = q.retryDelay
+	fq.ContinueOnError = q.settings.ContinueOnError
+	fq.OnFailure = func(failure JobFailure) {
+		atomic.AddInt64(&task.failedFiles, 1)
+		log.Printf("[ERROR] Task %d: %s failed: %v", task.ID, failure.Job.Path, failure.Err)
+	}
 
 	// Define executor based on task type
 	executor := q.makeExecutor(task, useRsync, engine)
 
 	// Monitor progress
 	stopMonitor := make(chan struct{})
+	defer close(stopMonitor)
 	go func() {
 		ticker := time.NewTicker(500 * time.Millisecond)
 		defer ticker.Stop()
@@ -357,6 +479,7 @@ func (q *TaskQueue) processTask(task *Task) {
 	}
 
 	// Execute directory creation first
+	var dirFailures *JobFailuresError
 	if len(dirJobs) > 0 {
 		log.Printf("[INFO] Creating %d directories...", len(dirJobs))
 		q.updateChan <- TaskProgress{
@@ -366,19 +489,32 @@ func (q *TaskQueue) processTask(task *Task) {
 		}
 
 		err = fq.ProcessJobs(task.ctx, dirJobs, executor, nil) // No progress update for dirs usually, or maybe?
-		if err != nil {
+		if err != nil && (!errors.As(err, &dirFailures) || !fq.ContinueOnError) {
 			result = err
 			return
 		}
 	}
+	dirsDone := len(dirJobs)
+	if dirFailures != nil {
+		dirsDone -= len(dirFailures.Failures)
+	}
 
 	// Execute file transfers
 	result = fq.ProcessJobs(task.ctx, fileJobs, executor, func(filesDone int, bytesDone int64) {
 		atomic.StoreInt64(&task.Progress.BytesTransferred, bytesDone)
-		task.Progress.CompletedFiles = filesDone + len(dirJobs) // Include dirs in count?
+		task.Progress.CompletedFiles = filesDone + dirsDone // Include dirs in count?
 	})
 
-	close(stopMonitor)
+	// Report the directories that could not be created with the files
+	if dirFailures != nil {
+		var fileFailures *JobFailuresError
+		switch {
+		case result == nil:
+			result = &JobFailuresError{Failures: dirFailures.Failures, Total: len(jobs)}
+		case errors.As(result, &fileFailures):
+			result = &JobFailuresError{Failures: append(dirFailures.Failures, fileFailures.Failures...), Total: len(jobs)}
+		}
+	}
 }
 
 func (q *TaskQueue) makeExecutor(task *Task, useRsync bool, engine TransferEngine) JobExecutor {
@@ -407,15 +543,23 @@ func (q *TaskQueue) makeExecutor(task *Task, useRsync bool, engine TransferEngin
 					return 0, err
 				}
 				// We rely on parent dirs being created or implicit creation for now
-				if err := q.client.sftpClient.MkdirAll(job.DestPath); err != nil {
+				if err := task.client.sftpClient.MkdirAll(job.DestPath); err != nil {
 					log.Printf("[ERROR] Job Upload Mkdir failed: %s -> %v", job.DestPath, err)
 					return 0, err
 				}
 				return 0, nil
 			}
-			err := engine.UploadFile(ctx, job.AbsPath, job.DestPath, nil)
+			var err error
+			if job.Attempt > 0 {
+				q.logRetry(task, job)
+				err = engine.ResumeUploadFile(ctx, job.AbsPath, job.DestPath, nil)
+			} else {
+				err = engine.UploadFile(ctx, job.AbsPath, job.DestPath, nil)
+			}
 			if err != nil {
 				log.Printf("[ERROR] Job Upload failed: %s -> %s: %v", job.AbsPath, job.DestPath, err)
+			} else if task.preserveTimes {
+				err = task.client.Chtimes(job.DestPath, time.Unix(job.ModTime, 0))
 			}
 			return job.Size, err
 		}
@@ -447,15 +591,37 @@ func (q *TaskQueue) makeExecutor(task *Task, useRsync bool, engine TransferEngin
 			}
 			return 0, nil
 		}
-		err := engine.DownloadFile(ctx, job.AbsPath, job.DestPath, nil)
+		var err error
+		if job.Attempt > 0 {
+			q.logRetry(task, job)
+			err = engine.ResumeDownloadFile(ctx, job.AbsPath, job.DestPath, nil)
+		} else {
+			err = engine.DownloadFile(ctx, job.AbsPath, job.DestPath, nil)
+		}
 		if err != nil {
 			log.Printf("[ERROR] Job Download failed: %s -> %s: %v", job.AbsPath, job.DestPath, err)
+		} else if task.preserveTimes {
+			mtime := time.Unix(job.ModTime, 0)
+			err = os.Chtimes(job.DestPath, mtime, mtime)
 		}
 		return job.Size, err
 	}
 	return executor
 }
 
+// logRetry reports that a failed file is being retried from its partial copy
+func (q *TaskQueue) logRetry(task *Task, job FileJob) {
+	log.Printf("[WARN] Task %d: resuming %s (attempt %d)", task.ID, job.Path, job.Attempt+1)
+	select {
+	case q.updateChan <- TaskProgress{
+		TaskID:  task.ID,
+		State:   task.State,
+		LastLog: fmt.Sprintf("Resuming %s (attempt %d)", job.Path, job.Attempt+1),
+	}:
+	default:
+	}
+}
+
 func (q *TaskQueue) notify(task *Task) {
 	// Build progress object
 	prog := TaskProgress{
@@ -465,12 +631,16 @@ func (q *TaskQueue) notify(task *Task) {
 		CompletedFiles:   task.Progress.CompletedFiles,
 		BytesTransferred: atomic.LoadInt64(&task.Progress.BytesTransferred),
 		TotalSize:        task.totalSize,
+		FailedFiles:      int(atomic.LoadInt64(&task.failedFiles)),
 		Percentage:       0,
 	}
 
 	if task.err != nil {
 		prog.Error = task.err.Error()
 	}
+	if task.State == TaskFailed && len(task.failures) > 0 {
+		prog.Failures = append([]JobFailure(nil), task.failures...)
+	}
 
 	if prog.TotalSize > 0 {
 		prog.Percentage = int((float64(prog.BytesTransferred) / float64(prog.TotalSize)) * 100)
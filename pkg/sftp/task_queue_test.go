@@ -1,7 +1,10 @@
 package sftp
 
 import (
+	"errors"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/quocson95/marix/pkg/ssh"
 	"github.com/quocson95/marix/pkg/storage"
@@ -126,3 +129,77 @@ func TestTaskProgress_Calculations(t *testing.T) {
 		// but since we can't export it easily without changing code, we will rely on integration tests for progress.
 	})
 }
+
+func TestTaskQueue_RetryFailed(t *testing.T) {
+	t.Run("Core Functionality: Queue only the failed files", func(t *testing.T) {
+		q := newMockTaskQueue()
+		failed := &Task{ID: 7, Type: TaskUploadDirectory, State: TaskFailed, Name: "photos"}
+		failed.failures = []JobFailure{
+			{Job: FileJob{Path: "photos/a.jpg", Size: 100}, Err: errors.New("connection lost")},
+			{Job: FileJob{Path: "photos/b.jpg", Size: 200}, Err: errors.New("connection lost")},
+		}
+		q.tasks = append(q.tasks, failed)
+
+		retry, err := q.RetryFailed(7)
+		if err != nil {
+			t.Fatalf("RetryFailed failed: %v", err)
+		}
+		if retry.ID == failed.ID || retry.Type != TaskUploadDirectory {
+			t.Errorf("Expected a new upload task, got #%d of type %v", retry.ID, retry.Type)
+		}
+		if len(retry.jobs) != 2 {
+			t.Fatalf("Expected 2 jobs, got %d", len(retry.jobs))
+		}
+		for _, job := range retry.jobs {
+			if job.Attempt != 1 {
+				t.Errorf("Expected %s to resume, got attempt %d", job.Path, job.Attempt)
+			}
+		}
+	})
+
+	t.Run("Core Functionality: Failures are kept when stopping at the first one", func(t *testing.T) {
+		updates := make(chan TaskProgress, 100)
+		settings := &storage.Settings{DisableRsync: true, ContinueOnError: false}
+		q := NewTaskQueue(&Client{}, &ssh.SSHConfig{}, settings, 1, updates)
+
+		// The local file is missing, so the upload fails before reaching the server
+		missing := filepath.Join(t.TempDir(), "missing.txt")
+		task, err := q.QueueTask(TaskUploadFile, missing, "/remote/missing.txt", "missing.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var final TaskProgress
+		timeout := time.After(5 * time.Second)
+		for final.State != TaskFailed {
+			select {
+			case final = <-updates:
+			case <-timeout:
+				t.Fatalf("Timed out waiting for the task to fail, last state %v", final.State)
+			}
+		}
+		if final.FailedFiles != 1 || len(final.Failures) != 1 || final.Failures[0].Job.Path != "missing.txt" {
+			t.Errorf("Expected the failed file to be reported, got %d failed, %+v", final.FailedFiles, final.Failures)
+		}
+
+		retry, err := q.RetryFailed(task.ID)
+		if err != nil {
+			t.Fatalf("RetryFailed failed: %v", err)
+		}
+		if len(retry.jobs) != 1 || retry.jobs[0].AbsPath != missing {
+			t.Errorf("Expected the missing file to be retried, got %+v", retry.jobs)
+		}
+	})
+
+	t.Run("Error Handling: Nothing to retry", func(t *testing.T) {
+		q := newMockTaskQueue()
+		q.tasks = append(q.tasks, &Task{ID: 3, State: TaskCompleted})
+
+		if _, err := q.RetryFailed(3); err == nil {
+			t.Error("Expected an error for a task without failed files")
+		}
+		if _, err := q.RetryFailed(42); err == nil {
+			t.Error("Expected an error for an unknown task")
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// DialAgent connects to the ssh-agent listening on SSH_AUTH_SOCK
+func DialAgent() (net.Conn, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, errors.New("SSH_AUTH_SOCK is not set")
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ssh-agent: %w", err)
+	}
+	return conn, nil
+}
+
+// SetAgent sets the agent used for authentication and forwarding instead of
+// dialing SSH_AUTH_SOCK. Useful for hardware-backed or in-process keyrings.
+func (c *Client) SetAgent(a agent.ExtendedAgent) {
+	c.agent = a
+}
+
+// ensureAgent returns the configured agent, dialing SSH_AUTH_SOCK if needed.
+// The connection stays open for the life of the client because agent signers
+// and forwarded requests talk to it lazily.
+func (c *Client) ensureAgent() (agent.ExtendedAgent, error) {
+	if c.agent != nil {
+		return c.agent, nil
+	}
+
+	conn, err := DialAgent()
+	if err != nil {
+		return nil, err
+	}
+
+	c.agentConn = conn
+	c.agent = agent.NewClient(conn)
+	return c.agent, nil
+}
+
+// forwardAgent enables agent forwarding for a session. The channel handler
+// is registered once per connection; each session still has to ask for it.
+func (c *Client) forwardAgent(session *ssh.Session) error {
+	if !c.agentForwarding {
+		a, err := c.ensureAgent()
+		if err != nil {
+			return err
+		}
+		if err := agent.ForwardToAgent(c.client, a); err != nil {
+			return err
+		}
+		c.agentForwarding = true
+	}
+
+	return agent.RequestAgentForwarding(session)
+}
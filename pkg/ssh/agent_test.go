@@ -0,0 +1,175 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// newTestAgent returns an in-process keyring holding one key
+func newTestAgent(t *testing.T) (agent.ExtendedAgent, ssh.PublicKey) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring := agent.NewKeyring().(agent.ExtendedAgent)
+	if err := keyring.Add(agent.AddedKey{PrivateKey: priv}); err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyring, signer.PublicKey()
+}
+
+// newPublicKeyServer starts a server that only accepts the given key
+func newPublicKeyServer(t *testing.T, allowed ssh.PublicKey) *testServer {
+	t.Helper()
+	return newTestServer(t, &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), allowed.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("key not allowed")
+		},
+	})
+}
+
+func TestClientAgentAuth(t *testing.T) {
+	t.Run("Authenticates with agent keys", func(t *testing.T) {
+		keyring, pub := newTestAgent(t)
+		server := newPublicKeyServer(t, pub)
+
+		config := server.sshConfig(t)
+		config.UseAgent = true
+
+		client := NewClient(config)
+		client.SetHostKeyVerifier(acceptAllVerifier{})
+		client.SetAgent(keyring)
+
+		if err := client.Connect(); err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		defer client.Close()
+
+		if !client.IsConnected() {
+			t.Error("Expected client to be connected")
+		}
+	})
+
+	t.Run("Agent is ignored unless enabled", func(t *testing.T) {
+		keyring, pub := newTestAgent(t)
+		server := newPublicKeyServer(t, pub)
+
+		client := NewClient(server.sshConfig(t))
+		client.SetHostKeyVerifier(acceptAllVerifier{})
+		client.SetAgent(keyring)
+
+		if err := client.Connect(); err == nil {
+			client.Close()
+			t.Fatal("Expected authentication to fail without UseAgent")
+		}
+	})
+
+	t.Run("Agent keys are tried after the configured key", func(t *testing.T) {
+		keyring, pub := newTestAgent(t)
+		server := newPublicKeyServer(t, pub)
+
+		// An unrelated key is configured first and gets rejected
+		_, otherPriv, _ := ed25519.GenerateKey(rand.Reader)
+		block, err := ssh.MarshalPrivateKey(otherPriv, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		config := server.sshConfig(t)
+		config.KeyContent = pem.EncodeToMemory(block)
+		config.UseAgent = true
+
+		client := NewClient(config)
+		client.SetHostKeyVerifier(acceptAllVerifier{})
+		client.SetAgent(keyring)
+
+		if err := client.Connect(); err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		client.Close()
+	})
+
+	t.Run("Missing SSH_AUTH_SOCK is reported", func(t *testing.T) {
+		t.Setenv("SSH_AUTH_SOCK", "")
+		_, pub := newTestAgent(t)
+		server := newPublicKeyServer(t, pub)
+
+		config := server.sshConfig(t)
+		config.UseAgent = true
+
+		client := NewClient(config)
+		client.SetHostKeyVerifier(acceptAllVerifier{})
+
+		if err := client.Connect(); err == nil {
+			client.Close()
+			t.Fatal("Expected error when no agent is available")
+		}
+	})
+}
+
+func TestClientAgentForwarding(t *testing.T) {
+	keyring, pub := newTestAgent(t)
+	server := newPublicKeyServer(t, pub)
+
+	forwarded := make(chan []*agent.Key, 1)
+	server.onSessionRequest = func(conn *ssh.ServerConn, req *ssh.Request) bool {
+		if req.Type != "shell" {
+			return true
+		}
+		// Like sshd, reach back to the client's agent once the shell starts
+		go func() {
+			ch, reqs, err := conn.OpenChannel("auth-agent@openssh.com", nil)
+			if err != nil {
+				forwarded <- nil
+				return
+			}
+			go ssh.DiscardRequests(reqs)
+			defer ch.Close()
+			keys, _ := agent.NewClient(ch).List()
+			forwarded <- keys
+		}()
+		return true
+	}
+
+	config := server.sshConfig(t)
+	config.UseAgent = true
+	config.ForwardAgent = true
+
+	client := NewClient(config)
+	client.SetHostKeyVerifier(acceptAllVerifier{})
+	client.SetAgent(keyring)
+
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.CreateShell(80, 24); err != nil {
+		t.Fatalf("CreateShell failed: %v", err)
+	}
+
+	select {
+	case keys := <-forwarded:
+		if len(keys) != 1 || !bytes.Equal(keys[0].Blob, pub.Marshal()) {
+			t.Errorf("Expected forwarded agent to expose the test key, got %v", keys)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for forwarded agent")
+	}
+}
@@ -1,21 +1,22 @@
 package ssh
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"os"
-	"path/filepath"
+	"net"
 	"sync"
 	"time"
 
 	"golang.org/x/crypto/ssh"
-	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/crypto/ssh/agent"
 )
 
 // Client manages SSH connections
 type Client struct {
 	config    *SSHConfig
 	client    *ssh.Client
+	hops      []*ssh.Client // Jump host connections, first hop first
 	session   *ssh.Session
 	stdin     io.WriteCloser
 	stdout    io.Reader
@@ -24,6 +25,17 @@ type Client struct {
 	connected bool
 	onData    func([]byte)
 	onClose   func()
+	verifier  HostKeyVerifier
+	challenge ChallengeFunc
+
+	keepAliveInterval time.Duration // Zero disables keepalives
+	keepAliveCountMax int           // Unanswered keepalives before the connection is dropped
+	lost              error         // Why the last shell ended, when not by exit or Close
+	shellDone         chan struct{} // Closed once the last shell has ended and been reported
+
+	agent           agent.ExtendedAgent
+	agentConn       net.Conn
+	agentForwarding bool
 }
 
 // NewClient creates a new SSH client
@@ -33,42 +45,23 @@ func NewClient(config *SSHConfig) *Client {
 	}
 }
 
-// getHostKeyCallback returns a proper host key verification callback
-func getHostKeyCallback() ssh.HostKeyCallback {
-	// Try to use known_hosts file
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		// Fallback to insecure if can't get home dir
-		return ssh.InsecureIgnoreHostKey()
-	}
-
-	knownHostsPath := filepath.Join(homeDir, ".ssh", "known_hosts")
-
-	// Create .ssh directory if it doesn't exist
-	sshDir := filepath.Join(homeDir, ".ssh")
-	if _, err := os.Stat(sshDir); os.IsNotExist(err) {
-		os.MkdirAll(sshDir, 0700)
-	}
+// SetHostKeyVerifier sets the verifier used to check the server's host key.
+// Without one, Connect checks ~/.ssh/known_hosts and rejects unknown hosts.
+func (c *Client) SetHostKeyVerifier(verifier HostKeyVerifier) {
+	c.verifier = verifier
+}
 
-	// Create known_hosts file if it doesn't exist
-	if _, err := os.Stat(knownHostsPath); os.IsNotExist(err) {
-		// Create empty known_hosts file
-		f, err := os.OpenFile(knownHostsPath, os.O_CREATE|os.O_WRONLY, 0600)
-		if err != nil {
-			// Fallback to insecure if can't create file
-			return ssh.InsecureIgnoreHostKey()
-		}
-		f.Close()
+// hostKeyVerifier returns the configured verifier or the strict default
+func (c *Client) hostKeyVerifier() (HostKeyVerifier, error) {
+	if c.verifier != nil {
+		return c.verifier, nil
 	}
 
-	// Use known_hosts for verification
-	hostKeyCallback, err := knownhosts.New(knownHostsPath)
+	knownHostsPath, err := DefaultKnownHostsPath()
 	if err != nil {
-		// Fallback to insecure if known_hosts is invalid
-		return ssh.InsecureIgnoreHostKey()
+		return nil, err
 	}
-
-	return hostKeyCallback
+	return NewKnownHostsVerifier(knownHostsPath, nil), nil
 }
 
 // Connect establishes SSH connection
@@ -80,49 +73,139 @@ func (c *Client) Connect() error {
 		return fmt.Errorf("invalid config: %w", err)
 	}
 
+	// Each jump host is reached through the previous one
+	dial := directDial
+	var hops []*ssh.Client
+	closeHops := func() {
+		for i := len(hops) - 1; i >= 0; i-- {
+			hops[i].Close()
+		}
+	}
+
+	for _, hopConfig := range c.config.JumpHosts {
+		hop, err := c.dialSSH(dial, hopConfig)
+		if err != nil {
+			closeHops()
+			return fmt.Errorf("jump host %s: %w", hopConfig.ConnectionID(), err)
+		}
+		hops = append(hops, hop)
+		dial = hop.Dial
+	}
+
+	client, err := c.dialSSH(dial, c.config)
+	if err != nil {
+		closeHops()
+		return err
+	}
+
+	c.client = client
+	c.hops = hops
+	c.connected = true
+	c.lost = nil
+	return nil
+}
+
+// dialSSH opens a connection with dial and performs the SSH handshake for config
+func (c *Client) dialSSH(dial DialFunc, config *SSHConfig) (*ssh.Client, error) {
 	// Load private key if specified
-	if err := c.config.LoadPrivateKey(); err != nil {
-		return fmt.Errorf("failed to load private key: %w", err)
+	if err := config.LoadPrivateKey(); err != nil {
+		return nil, fmt.Errorf("failed to load private key: %w", err)
 	}
 
 	// Configure SSH authentication
+	authMethods, err := c.authMethods(config)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, err := c.hostKeyVerifier()
+	if err != nil {
+		return nil, fmt.Errorf("host key verification unavailable: %w", err)
+	}
+
+	addr := fmt.Sprintf("%s:%d", config.Host, config.Port)
+
+	// SSH client config
+	sshConfig := &ssh.ClientConfig{
+		User:            config.Username,
+		Auth:            authMethods,
+		HostKeyCallback: verifier.Verify,
+		Timeout:         30 * time.Second,
+	}
+
+	// Prefer key types we already know for this host
+	if kh, ok := verifier.(interface{ HostKeyAlgorithms(string) []string }); ok {
+		sshConfig.HostKeyAlgorithms = kh.HostKeyAlgorithms(addr)
+	}
+
+	// Connect to SSH server
+	conn, err := dial("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial: %w", err)
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, sshConfig)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to dial: %w", err)
+	}
+
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// authMethods builds the auth methods for the credentials in config
+func (c *Client) authMethods(config *SSHConfig) ([]ssh.AuthMethod, error) {
 	var authMethods []ssh.AuthMethod
-	if c.config.Password != "" {
-		authMethods = append(authMethods, ssh.Password(c.config.Password))
+	if config.Password != "" {
+		authMethods = append(authMethods, ssh.Password(config.Password))
 	}
-	if len(c.config.KeyContent) > 0 {
-		signer, err := ssh.ParsePrivateKey(c.config.KeyContent)
+
+	// The client only tries one "publickey" method, so the configured key
+	// and the agent keys have to share a single callback
+	var signers []ssh.Signer
+	if len(config.KeyContent) > 0 {
+		signer, err := ssh.ParsePrivateKey(config.KeyContent)
 		if err != nil {
 			// Try with KeyPassword if available
-			if c.config.KeyPassword != "" {
-				signer, err = ssh.ParsePrivateKeyWithPassphrase(c.config.KeyContent, []byte(c.config.KeyPassword))
+			if config.KeyPassword != "" {
+				signer, err = ssh.ParsePrivateKeyWithPassphrase(config.KeyContent, []byte(config.KeyPassword))
 			}
 
 			if err != nil {
-				return fmt.Errorf("failed to parse private key: %w", err)
+				return nil, fmt.Errorf("failed to parse private key: %w", err)
 			}
 		}
-		authMethods = append(authMethods, ssh.PublicKeys(signer))
+		signers = append(signers, signer)
 	}
 
-	// SSH client config
-	sshConfig := &ssh.ClientConfig{
-		User:            c.config.Username,
-		Auth:            authMethods,
-		HostKeyCallback: getHostKeyCallback(),
-		Timeout:         30 * time.Second,
+	var agentClient agent.ExtendedAgent
+	if config.UseAgent {
+		a, err := c.ensureAgent()
+		if err != nil {
+			return nil, fmt.Errorf("ssh-agent unavailable: %w", err)
+		}
+		agentClient = a
 	}
 
-	// Connect to SSH server
-	addr := fmt.Sprintf("%s:%d", c.config.Host, c.config.Port)
-	client, err := ssh.Dial("tcp", addr, sshConfig)
-	if err != nil {
-		return fmt.Errorf("failed to dial: %w", err)
+	if len(signers) > 0 || agentClient != nil {
+		authMethods = append(authMethods, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+			if agentClient == nil {
+				return signers, nil
+			}
+			agentSigners, err := agentClient.Signers()
+			if err != nil {
+				return nil, fmt.Errorf("failed to list agent keys: %w", err)
+			}
+			return append(signers, agentSigners...), nil
+		}))
 	}
 
-	c.client = client
-	c.connected = true
-	return nil
+	// OTP and other PAM prompts arrive as keyboard-interactive challenges
+	if ki := c.keyboardInteractive(config); ki != nil {
+		authMethods = append(authMethods, ki)
+	}
+
+	return authMethods, nil
 }
 
 // CreateShell creates an interactive shell session
@@ -146,6 +229,14 @@ func (c *Client) CreateShell(cols, rows int) error {
 		ssh.TTY_OP_OSPEED: 14400,
 	}
 
+	// Agent forwarding must be requested before the shell starts
+	if c.config.ForwardAgent {
+		if err := c.forwardAgent(session); err != nil {
+			session.Close()
+			return fmt.Errorf("failed to forward agent: %w", err)
+		}
+	}
+
 	// Request PTY
 	if err := session.RequestPty("xterm-256color", rows, cols, modes); err != nil {
 		session.Close()
@@ -183,15 +274,24 @@ func (c *Client) CreateShell(cols, rows int) error {
 	c.stderr = stderr
 
 	// Start reading output in goroutines
-	go c.readOutput(stdout)
-	go c.readOutput(stderr)
-	go c.waitForExit()
+	var readers sync.WaitGroup
+	readers.Add(2)
+	go c.readOutput(stdout, &readers)
+	go c.readOutput(stderr, &readers)
+
+	done := make(chan struct{})
+	c.shellDone = done
+	go c.waitForExit(session, &readers, done)
+	if c.keepAliveInterval > 0 {
+		go c.keepAlive(c.client, done)
+	}
 
 	return nil
 }
 
 // readOutput reads from output stream and calls onData callback
-func (c *Client) readOutput(r io.Reader) {
+func (c *Client) readOutput(r io.Reader, readers *sync.WaitGroup) {
+	defer readers.Done()
 	buf := make([]byte, 1024)
 	for {
 		n, err := r.Read(buf)
@@ -204,16 +304,25 @@ func (c *Client) readOutput(r io.Reader) {
 	}
 }
 
-// waitForExit waits for session to exit
-func (c *Client) waitForExit() {
-	if c.session != nil {
-		c.session.Wait()
-		c.mu.Lock()
-		c.connected = false
-		c.mu.Unlock()
-		if c.onClose != nil {
-			c.onClose()
-		}
+// waitForExit waits for session to exit. The close callback runs once all
+// output has been delivered.
+func (c *Client) waitForExit(session *ssh.Session, readers *sync.WaitGroup, done chan struct{}) {
+	defer close(done)
+
+	err := session.Wait()
+	readers.Wait()
+
+	c.mu.Lock()
+	// Close clears connected first; a shell that exits sends its status
+	var exitErr *ssh.ExitError
+	if c.connected && c.lost == nil && err != nil && !errors.As(err, &exitErr) {
+		c.lost = fmt.Errorf("%w: %v", ErrConnectionLost, err)
+	}
+	c.connected = false
+	c.mu.Unlock()
+
+	if c.onClose != nil {
+		c.onClose()
 	}
 }
 
@@ -242,7 +351,8 @@ func (c *Client) Resize(cols, rows int) error {
 	return c.session.WindowChange(rows, cols)
 }
 
-// Execute runs a command and returns the output
+// Execute runs a command and returns the combined output. When the command
+// fails the output is returned along with the error.
 func (c *Client) Execute(command string) (string, error) {
 	c.mu.Lock()
 	if !c.connected {
@@ -259,7 +369,7 @@ func (c *Client) Execute(command string) (string, error) {
 
 	output, err := session.CombinedOutput(command)
 	if err != nil {
-		return "", fmt.Errorf("command failed: %w", err)
+		return string(output), fmt.Errorf("command failed: %w", err)
 	}
 
 	return string(output), nil
@@ -281,18 +391,38 @@ func (c *Client) Close() error {
 	defer c.mu.Unlock()
 
 	c.connected = false
+	c.closeConnection()
 
+	if c.agentConn != nil {
+		c.agentConn.Close()
+		c.agentConn = nil
+		c.agent = nil
+	}
+
+	return nil
+}
+
+// closeConnection closes the shell, the connection and its jump hosts.
+// Callers hold c.mu.
+func (c *Client) closeConnection() {
 	if c.session != nil {
 		c.session.Close()
 		c.session = nil
 	}
+	c.stdin = nil
 
 	if c.client != nil {
 		c.client.Close()
 		c.client = nil
 	}
 
-	return nil
+	for i := len(c.hops) - 1; i >= 0; i-- {
+		c.hops[i].Close()
+	}
+	c.hops = nil
+
+	// Forwarding is registered per connection
+	c.agentForwarding = false
 }
 
 // IsConnected returns true if connected
@@ -302,6 +432,16 @@ func (c *Client) IsConnected() bool {
 	return c.connected
 }
 
+// KeepAlive sends an OpenSSH keepalive request and waits up to timeout for
+// the reply, detecting connections that died without being closed
+func (c *Client) KeepAlive(timeout time.Duration) error {
+	raw := c.GetRawClient()
+	if raw == nil {
+		return fmt.Errorf("not connected")
+	}
+	return sendKeepAlive(raw, timeout)
+}
+
 // GetRawClient returns the underlying SSH client for SFTP usage
 func (c *Client) GetRawClient() *ssh.Client {
 	c.mu.Lock()
@@ -15,6 +15,11 @@ type SSHConfig struct {
 	PrivateKey  string // Path to private key file or PEM content
 	KeyContent  []byte // Parsed private key content
 	KeyPassword string // Password for decrypting encrypted private keys (not stored)
+
+	UseAgent     bool // Authenticate with keys held by ssh-agent
+	ForwardAgent bool // Forward the agent to shell sessions
+
+	JumpHosts []*SSHConfig // Bastions to tunnel through, first hop first
 }
 
 // Validate checks if the SSH configuration is valid
@@ -30,6 +35,11 @@ func (c *SSHConfig) Validate() error {
 	}
 	// Allow connection with just password OR just private key OR both
 	// No error if one of them is provided
+	for i, hop := range c.JumpHosts {
+		if err := hop.Validate(); err != nil {
+			return fmt.Errorf("jump host %d: %w", i+1, err)
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,265 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Output streams of a remote command
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// Run executes command in a new session, copying its output to stdout and
+// stderr. It returns the remote exit code; err is only set when the command
+// could not be run or ended without an exit status (killed by a signal,
+// connection lost).
+func (c *Client) Run(command string, stdout, stderr io.Writer) (int, error) {
+	c.mu.Lock()
+	if !c.connected {
+		c.mu.Unlock()
+		return -1, fmt.Errorf("not connected")
+	}
+	client := c.client
+	c.mu.Unlock()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return -1, fmt.Errorf("failed to create session: %w", err)
+	}
+	defer session.Close()
+
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	err = session.Run(command)
+	var exitErr *ssh.ExitError
+	switch {
+	case err == nil:
+		return 0, nil
+	case errors.As(err, &exitErr):
+		if exitErr.Signal() != "" {
+			return exitErr.ExitStatus(), fmt.Errorf("killed by signal %s", exitErr.Signal())
+		}
+		return exitErr.ExitStatus(), nil
+	default:
+		return -1, fmt.Errorf("command failed: %w", err)
+	}
+}
+
+// ExecTarget is a host to run a command on
+type ExecTarget struct {
+	Name   string
+	Config *SSHConfig
+}
+
+// ExecLine is one line of output from a host
+type ExecLine struct {
+	Target string
+	Stream string // StreamStdout or StreamStderr
+	Text   string
+}
+
+// ExecResult is the outcome of a command on one host
+type ExecResult struct {
+	Target   string
+	ExitCode int   // -1 when the command did not finish
+	Err      error // Connection or session failure
+	Output   []ExecLine
+	Started  time.Time
+	Finished time.Time
+}
+
+// OK reports whether the command ran and exited with status 0
+func (r ExecResult) OK() bool {
+	return r.Err == nil && r.ExitCode == 0
+}
+
+// ParallelExec runs one command on many hosts with bounded concurrency
+type ParallelExec struct {
+	// Concurrency limits the hosts running at once; values below 1 mean 1
+	Concurrency int
+	// NewClient creates the client for a target, defaults to NewClient
+	NewClient func(*SSHConfig) *Client
+	// OnLine is called for every line of output as it arrives
+	OnLine func(ExecLine)
+	// OnResult is called when a host finishes
+	OnResult func(ExecResult)
+}
+
+// Run executes command on every target and returns the results in target
+// order. Cancelling ctx disconnects the hosts still running.
+func (p *ParallelExec) Run(ctx context.Context, targets []ExecTarget, command string) []ExecResult {
+	newClient := p.NewClient
+	if newClient == nil {
+		newClient = NewClient
+	}
+
+	results := make([]ExecResult, len(targets))
+	sem := make(chan struct{}, max(p.Concurrency, 1))
+	var wg sync.WaitGroup
+
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results[i] = ExecResult{Target: target.Name, ExitCode: -1, Err: ctx.Err()}
+				p.done(results[i])
+				return
+			}
+
+			results[i] = p.runOne(ctx, newClient(target.Config), target.Name, command)
+			p.done(results[i])
+		}()
+	}
+
+	wg.Wait()
+	return results
+}
+
+func (p *ParallelExec) done(result ExecResult) {
+	if p.OnResult != nil {
+		p.OnResult(result)
+	}
+}
+
+func (p *ParallelExec) runOne(ctx context.Context, client *Client, name, command string) (result ExecResult) {
+	result = ExecResult{Target: name, ExitCode: -1, Started: time.Now()}
+	defer func() { result.Finished = time.Now() }()
+
+	if err := ctx.Err(); err != nil {
+		result.Err = err
+		return result
+	}
+	if err := client.Connect(); err != nil {
+		result.Err = err
+		return result
+	}
+	defer client.Close()
+
+	// Closing the client ends the session when the run is cancelled
+	stop := context.AfterFunc(ctx, func() { client.Close() })
+	defer stop()
+
+	var mu sync.Mutex
+	emit := func(line ExecLine) {
+		mu.Lock()
+		result.Output = append(result.Output, line)
+		mu.Unlock()
+		if p.OnLine != nil {
+			p.OnLine(line)
+		}
+	}
+	stdout := &lineWriter{emit: func(s string) { emit(ExecLine{Target: name, Stream: StreamStdout, Text: s}) }}
+	stderr := &lineWriter{emit: func(s string) { emit(ExecLine{Target: name, Stream: StreamStderr, Text: s}) }}
+
+	result.ExitCode, result.Err = client.Run(command, stdout, stderr)
+	stdout.Flush()
+	stderr.Flush()
+
+	if ctx.Err() != nil {
+		result.Err = ctx.Err()
+	}
+	return result
+}
+
+// lineWriter splits a stream into lines
+type lineWriter struct {
+	buf  bytes.Buffer
+	emit func(string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// Keep the partial line for the next write
+			w.buf.WriteString(line)
+			return len(p), nil
+		}
+		w.emit(strings.TrimRight(line, "\r\n"))
+	}
+}
+
+// Flush emits a final line without a trailing newline
+func (w *lineWriter) Flush() {
+	if w.buf.Len() > 0 {
+		w.emit(strings.TrimRight(w.buf.String(), "\r"))
+		w.buf.Reset()
+	}
+}
+
+// WriteExecReport writes a summary table followed by each host's output
+func WriteExecReport(w io.Writer, command string, results []ExecResult) error {
+	sorted := append([]ExecResult(nil), results...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		// Failures first, they are what the reader is looking for
+		if sorted[i].OK() != sorted[j].OK() {
+			return !sorted[i].OK()
+		}
+		return sorted[i].Target < sorted[j].Target
+	})
+
+	failed := 0
+	for _, r := range sorted {
+		if !r.OK() {
+			failed++
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Command: %s\n", command)
+	fmt.Fprintf(&b, "Hosts: %d, succeeded: %d, failed: %d\n\n", len(sorted), len(sorted)-failed, failed)
+
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOST\tEXIT\tDURATION\tERROR")
+	for _, r := range sorted {
+		exit := fmt.Sprint(r.ExitCode)
+		if r.ExitCode < 0 {
+			exit = "-"
+		}
+		duration := "-"
+		if !r.Started.IsZero() {
+			duration = r.Finished.Sub(r.Started).Round(time.Millisecond).String()
+		}
+		errText := ""
+		if r.Err != nil {
+			errText = r.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Target, exit, duration, errText)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, r := range sorted {
+		fmt.Fprintf(&b, "\n== %s ==\n", r.Target)
+		for _, line := range r.Output {
+			if line.Stream == StreamStderr {
+				b.WriteString("! ")
+			}
+			b.WriteString(line.Text)
+			b.WriteString("\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,219 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// newExecServer starts a server where "fail N" exits with N after writing to
+// stderr, "sleep" blocks until the client goes away and anything else is
+// echoed over two lines
+func newExecServer(t *testing.T, running *atomic.Int32, peak *atomic.Int32) *testServer {
+	t.Helper()
+	server := newPasswordServer(t, "secret")
+	server.onExec = func(command string, ch ssh.Channel) uint32 {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		var status uint32
+		switch {
+		case strings.HasPrefix(command, "fail "):
+			fmt.Sscanf(command, "fail %d", &status)
+			io.WriteString(ch.Stderr(), "something broke\n")
+		case command == "sleep":
+			// Ticks until the client goes away
+			for {
+				if _, err := io.WriteString(ch, "."); err != nil {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		default:
+			io.WriteString(ch, "ran: "+command+"\npartial")
+		}
+		return status
+	}
+	return server
+}
+
+func newExecClient(t *testing.T, server *testServer) *Client {
+	t.Helper()
+	config := server.sshConfig(t)
+	config.Password = "secret"
+	client := NewClient(config)
+	client.SetHostKeyVerifier(acceptAllVerifier{})
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestClientRun(t *testing.T) {
+	var running, peak atomic.Int32
+	client := newExecClient(t, newExecServer(t, &running, &peak))
+
+	var stdout, stderr bytes.Buffer
+	code, err := client.Run("uptime", &stdout, &stderr)
+	if err != nil || code != 0 {
+		t.Fatalf("Expected exit 0, got %d, %v", code, err)
+	}
+	if stdout.String() != "ran: uptime\npartial" {
+		t.Errorf("Unexpected stdout %q", stdout.String())
+	}
+
+	stdout.Reset()
+	code, err = client.Run("fail 3", &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("A non-zero exit should not be an error: %v", err)
+	}
+	if code != 3 {
+		t.Errorf("Expected exit 3, got %d", code)
+	}
+	if stderr.String() != "something broke\n" {
+		t.Errorf("Unexpected stderr %q", stderr.String())
+	}
+}
+
+func TestParallelExec(t *testing.T) {
+	var running, peak atomic.Int32
+	server := newExecServer(t, &running, &peak)
+
+	config := func() *SSHConfig {
+		config := server.sshConfig(t)
+		config.Password = "secret"
+		return config
+	}
+	badConfig := config()
+	badConfig.Password = "wrong"
+
+	targets := []ExecTarget{
+		{Name: "web1", Config: config()},
+		{Name: "web2", Config: config()},
+		{Name: "web3", Config: config()},
+		{Name: "web4", Config: config()},
+		{Name: "broken", Config: badConfig},
+	}
+
+	var mu sync.Mutex
+	var lines []ExecLine
+	var finished []string
+	exec := &ParallelExec{
+		Concurrency: 2,
+		NewClient: func(config *SSHConfig) *Client {
+			client := NewClient(config)
+			client.SetHostKeyVerifier(acceptAllVerifier{})
+			return client
+		},
+		OnLine: func(line ExecLine) {
+			mu.Lock()
+			lines = append(lines, line)
+			mu.Unlock()
+		},
+		OnResult: func(r ExecResult) {
+			mu.Lock()
+			finished = append(finished, r.Target)
+			mu.Unlock()
+		},
+	}
+
+	results := exec.Run(context.Background(), targets, "hostname")
+
+	if len(results) != len(targets) || len(finished) != len(targets) {
+		t.Fatalf("Expected %d results, got %d (%d reported)", len(targets), len(results), len(finished))
+	}
+	if got := peak.Load(); got > 2 {
+		t.Errorf("Expected at most 2 hosts at once, got %d", got)
+	}
+
+	for i, r := range results[:4] {
+		if r.Target != targets[i].Name || !r.OK() {
+			t.Errorf("Expected %s to succeed, got %+v", targets[i].Name, r)
+		}
+		want := []ExecLine{
+			{Target: r.Target, Stream: StreamStdout, Text: "ran: hostname"},
+			{Target: r.Target, Stream: StreamStdout, Text: "partial"},
+		}
+		if fmt.Sprint(r.Output) != fmt.Sprint(want) {
+			t.Errorf("Unexpected output for %s: %+v", r.Target, r.Output)
+		}
+	}
+	if broken := results[4]; broken.Err == nil || broken.ExitCode != -1 {
+		t.Errorf("Expected a connection error for the broken host, got %+v", broken)
+	}
+	if len(lines) != 8 {
+		t.Errorf("Expected 8 streamed lines, got %d", len(lines))
+	}
+
+	t.Run("Report", func(t *testing.T) {
+		var b strings.Builder
+		if err := WriteExecReport(&b, "hostname", results); err != nil {
+			t.Fatal(err)
+		}
+		report := b.String()
+		for _, want := range []string{
+			"Hosts: 5, succeeded: 4, failed: 1",
+			"== web1 ==\nran: hostname\npartial\n",
+		} {
+			if !strings.Contains(report, want) {
+				t.Errorf("Report is missing %q:\n%s", want, report)
+			}
+		}
+		// Failures are listed first
+		if strings.Index(report, "broken") > strings.Index(report, "web1") {
+			t.Errorf("Expected the failed host first:\n%s", report)
+		}
+	})
+}
+
+func TestParallelExecCancel(t *testing.T) {
+	var running, peak atomic.Int32
+	server := newExecServer(t, &running, &peak)
+
+	config := server.sshConfig(t)
+	config.Password = "secret"
+	targets := []ExecTarget{{Name: "a", Config: config}, {Name: "b", Config: config}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	exec := &ParallelExec{
+		Concurrency: 1,
+		NewClient: func(config *SSHConfig) *Client {
+			client := NewClient(config)
+			client.SetHostKeyVerifier(acceptAllVerifier{})
+			return client
+		},
+	}
+
+	done := make(chan []ExecResult)
+	go func() { done <- exec.Run(ctx, targets, "sleep") }()
+
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+
+	select {
+	case results := <-done:
+		for _, r := range results {
+			if r.Err == nil {
+				t.Errorf("Expected %s to be cancelled, got %+v", r.Target, r)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
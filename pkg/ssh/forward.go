@@ -0,0 +1,358 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ForwardType is the kind of port forward, as in ssh -L, -R and -D
+type ForwardType string
+
+const (
+	ForwardLocal   ForwardType = "local"   // Listen locally, connect from the server
+	ForwardRemote  ForwardType = "remote"  // Listen on the server, connect from here
+	ForwardDynamic ForwardType = "dynamic" // Local SOCKS5 proxy through the server
+)
+
+// Forward describes a port forward
+type Forward struct {
+	Type   ForwardType
+	Listen string // host:port to listen on (on the server for remote forwards)
+	Target string // host:port to connect to; empty for dynamic forwards
+}
+
+// ParseForward parses a forward in ssh flag syntax, prefixed by its type:
+//
+//	L [bind:]port:host:hostport
+//	R [bind:]port:host:hostport
+//	D [bind:]port
+//
+// The bind address defaults to localhost.
+func ParseForward(s string) (Forward, error) {
+	kind, spec, _ := strings.Cut(strings.TrimSpace(s), " ")
+	spec = strings.TrimSpace(spec)
+
+	var f Forward
+	switch strings.ToUpper(kind) {
+	case "L":
+		f.Type = ForwardLocal
+	case "R":
+		f.Type = ForwardRemote
+	case "D":
+		f.Type = ForwardDynamic
+	default:
+		return f, fmt.Errorf("invalid forward %q: must start with L, R or D", s)
+	}
+
+	parts := splitForwardSpec(spec)
+	if f.Type == ForwardDynamic {
+		switch len(parts) {
+		case 1:
+			f.Listen = net.JoinHostPort("localhost", parts[0])
+		case 2:
+			f.Listen = net.JoinHostPort(parts[0], parts[1])
+		default:
+			return f, fmt.Errorf("invalid dynamic forward %q: expected [bind:]port", spec)
+		}
+		return f, validatePorts(f.Listen)
+	}
+
+	switch len(parts) {
+	case 3:
+		f.Listen = net.JoinHostPort("localhost", parts[0])
+		f.Target = net.JoinHostPort(parts[1], parts[2])
+	case 4:
+		f.Listen = net.JoinHostPort(parts[0], parts[1])
+		f.Target = net.JoinHostPort(parts[2], parts[3])
+	default:
+		return f, fmt.Errorf("invalid forward %q: expected [bind:]port:host:hostport", spec)
+	}
+	return f, validatePorts(f.Listen, f.Target)
+}
+
+// String formats the forward in the syntax accepted by ParseForward
+func (f Forward) String() string {
+	kind := map[ForwardType]string{ForwardLocal: "L", ForwardRemote: "R", ForwardDynamic: "D"}[f.Type]
+	listen := f.Listen
+	if host, port, err := net.SplitHostPort(listen); err == nil && host == "localhost" {
+		listen = port
+	} else if err == nil && strings.Contains(host, ":") {
+		listen = "[" + host + "]:" + port
+	}
+	if f.Target == "" {
+		return fmt.Sprintf("%s %s", kind, listen)
+	}
+	return fmt.Sprintf("%s %s:%s", kind, listen, f.Target)
+}
+
+// splitForwardSpec splits on ':' except inside [IPv6] brackets
+func splitForwardSpec(spec string) []string {
+	var parts []string
+	var current strings.Builder
+	inBrackets := false
+	for _, r := range spec {
+		switch {
+		case r == '[':
+			inBrackets = true
+		case r == ']':
+			inBrackets = false
+		case r == ':' && !inBrackets:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	return append(parts, current.String())
+}
+
+func validatePorts(addrs ...string) error {
+	for _, addr := range addrs {
+		host, portStr, err := net.SplitHostPort(addr)
+		if err != nil {
+			return fmt.Errorf("invalid address %q: %w", addr, err)
+		}
+		if host == "" {
+			return fmt.Errorf("invalid address %q: missing host", addr)
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil || port < 0 || port > 65535 {
+			return fmt.Errorf("invalid port in %q", addr)
+		}
+	}
+	return nil
+}
+
+// TunnelStats is a snapshot of a running forward
+type TunnelStats struct {
+	Forward       Forward
+	Addr          string // Address actually listened on
+	ActiveConns   int
+	BytesSent     int64 // From accepted connections to the target
+	BytesReceived int64 // From the target back
+	Err           error // Why the tunnel stopped, if it did on its own
+}
+
+// Tunnel is a running port forward
+type Tunnel struct {
+	forward  Forward
+	listener net.Listener
+	connect  func(conn net.Conn) (net.Conn, error)
+
+	sent     atomic.Int64
+	received atomic.Int64
+
+	mu      sync.Mutex
+	conns   map[net.Conn]struct{}
+	stopped bool
+	err     error
+}
+
+// Stats returns the tunnel's current counters
+func (t *Tunnel) Stats() TunnelStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return TunnelStats{
+		Forward:       t.forward,
+		Addr:          t.listener.Addr().String(),
+		ActiveConns:   len(t.conns),
+		BytesSent:     t.sent.Load(),
+		BytesReceived: t.received.Load(),
+		Err:           t.err,
+	}
+}
+
+// Stop closes the listener and every open connection
+func (t *Tunnel) Stop() {
+	t.mu.Lock()
+	t.stopped = true
+	conns := t.conns
+	t.conns = make(map[net.Conn]struct{})
+	t.mu.Unlock()
+
+	t.listener.Close()
+	for conn := range conns {
+		conn.Close()
+	}
+}
+
+func (t *Tunnel) serve() {
+	for {
+		conn, err := t.listener.Accept()
+		if err != nil {
+			t.mu.Lock()
+			if !t.stopped {
+				t.err = err
+			}
+			t.mu.Unlock()
+			return
+		}
+		go t.handle(conn)
+	}
+}
+
+func (t *Tunnel) handle(conn net.Conn) {
+	if !t.track(conn, true) {
+		conn.Close()
+		return
+	}
+	defer t.track(conn, false)
+	defer conn.Close()
+
+	remote, err := t.connect(conn)
+	if err != nil {
+		return
+	}
+	defer remote.Close()
+
+	pipe(&countingConn{Conn: conn, read: &t.sent, written: &t.received}, remote)
+}
+
+// track adds or removes an open connection; adding fails once stopped
+func (t *Tunnel) track(conn net.Conn, add bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !add {
+		delete(t.conns, conn)
+		return true
+	}
+	if t.stopped {
+		return false
+	}
+	t.conns[conn] = struct{}{}
+	return true
+}
+
+// countingConn counts the bytes read from and written to a connection
+type countingConn struct {
+	net.Conn
+	read, written *atomic.Int64
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.written.Add(int64(n))
+	return n, err
+}
+
+func (c *countingConn) CloseWrite() error {
+	closeWrite(c.Conn)
+	return nil
+}
+
+// ForwardManager runs the port forwards of one connected client
+type ForwardManager struct {
+	client *Client
+
+	mu      sync.Mutex
+	tunnels []*Tunnel
+}
+
+// NewForwardManager creates a forward manager for a connected client
+func NewForwardManager(client *Client) *ForwardManager {
+	return &ForwardManager{client: client}
+}
+
+// Start opens the listener for f and begins forwarding
+func (m *ForwardManager) Start(f Forward) (*Tunnel, error) {
+	raw := m.client.GetRawClient()
+	if raw == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	t := &Tunnel{forward: f, conns: make(map[net.Conn]struct{})}
+
+	var err error
+	switch f.Type {
+	case ForwardLocal:
+		t.listener, err = net.Listen("tcp", f.Listen)
+		t.connect = func(net.Conn) (net.Conn, error) {
+			return raw.Dial("tcp", f.Target)
+		}
+	case ForwardRemote:
+		t.listener, err = raw.Listen("tcp", f.Listen)
+		t.connect = func(net.Conn) (net.Conn, error) {
+			return net.DialTimeout("tcp", f.Target, 30*time.Second)
+		}
+	case ForwardDynamic:
+		t.listener, err = net.Listen("tcp", f.Listen)
+		t.connect = func(conn net.Conn) (net.Conn, error) {
+			return socks5Handshake(conn, raw.Dial)
+		}
+	default:
+		return nil, fmt.Errorf("unknown forward type: %s", f.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", f.Listen, err)
+	}
+
+	m.mu.Lock()
+	m.tunnels = append(m.tunnels, t)
+	m.mu.Unlock()
+
+	go t.serve()
+	return t, nil
+}
+
+// Stop stops a tunnel and removes it from the manager
+func (m *ForwardManager) Stop(t *Tunnel) {
+	t.Stop()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, existing := range m.tunnels {
+		if existing == t {
+			m.tunnels = append(m.tunnels[:i], m.tunnels[i+1:]...)
+			break
+		}
+	}
+}
+
+// StopAll stops every tunnel
+func (m *ForwardManager) StopAll() {
+	m.mu.Lock()
+	tunnels := m.tunnels
+	m.tunnels = nil
+	m.mu.Unlock()
+
+	for _, t := range tunnels {
+		t.Stop()
+	}
+}
+
+// Restart starts every forward again on the client's current connection,
+// once Reconnect has replaced the one they were started on
+func (m *ForwardManager) Restart() error {
+	m.mu.Lock()
+	tunnels := m.tunnels
+	m.tunnels = nil
+	m.mu.Unlock()
+
+	var errs []error
+	for _, t := range tunnels {
+		t.Stop()
+		if _, err := m.Start(t.forward); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.forward, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Tunnels returns the running tunnels in start order
+func (m *ForwardManager) Tunnels() []*Tunnel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Tunnel(nil), m.tunnels...)
+}
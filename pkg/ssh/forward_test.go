@@ -0,0 +1,276 @@
+package ssh
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseForward(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Forward
+		wantErr bool
+	}{
+		{input: "L 8080:db:5432", want: Forward{Type: ForwardLocal, Listen: "localhost:8080", Target: "db:5432"}},
+		{input: "l 0.0.0.0:8080:db:5432", want: Forward{Type: ForwardLocal, Listen: "0.0.0.0:8080", Target: "db:5432"}},
+		{input: "R 9000:localhost:3000", want: Forward{Type: ForwardRemote, Listen: "localhost:9000", Target: "localhost:3000"}},
+		{input: "D 1080", want: Forward{Type: ForwardDynamic, Listen: "localhost:1080"}},
+		{input: "D [::1]:1080", want: Forward{Type: ForwardDynamic, Listen: "[::1]:1080"}},
+		{input: "L 8080:[fe80::1]:22", want: Forward{Type: ForwardLocal, Listen: "localhost:8080", Target: "[fe80::1]:22"}},
+		{input: "X 8080:db:5432", wantErr: true},
+		{input: "L 8080", wantErr: true},
+		{input: "L 8080:db:http", wantErr: true},
+		{input: "D 1080:db:22", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseForward(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseForward(%q) expected error, got %+v", tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseForward(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseForward(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+
+		// String round-trips through ParseForward
+		again, err := ParseForward(got.String())
+		if err != nil || again != got {
+			t.Errorf("Round trip of %q via %q gave %+v, %v", tt.input, got.String(), again, err)
+		}
+	}
+}
+
+// newEchoServer starts a TCP server that echoes everything back
+func newEchoServer(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// expectEcho sends a message over conn and checks it comes back
+func expectEcho(t *testing.T, conn net.Conn) {
+	t.Helper()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if string(buf) != "ping" {
+		t.Fatalf("Expected echo 'ping', got %q", buf)
+	}
+}
+
+func newForwardClient(t *testing.T) *Client {
+	t.Helper()
+	server := newPasswordServer(t, "secret")
+	config := server.sshConfig(t)
+	config.Password = "secret"
+
+	client := NewClient(config)
+	client.SetHostKeyVerifier(acceptAllVerifier{})
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestForwardManager(t *testing.T) {
+	echo := newEchoServer(t)
+	client := newForwardClient(t)
+
+	manager := NewForwardManager(client)
+	defer manager.StopAll()
+
+	t.Run("Local forward", func(t *testing.T) {
+		tunnel, err := manager.Start(Forward{Type: ForwardLocal, Listen: "127.0.0.1:0", Target: echo})
+		if err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+
+		conn, err := net.Dial("tcp", tunnel.Stats().Addr)
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		expectEcho(t, conn)
+
+		stats := tunnel.Stats()
+		if stats.BytesSent != 4 || stats.BytesReceived != 4 || stats.ActiveConns != 1 {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+		conn.Close()
+
+		manager.Stop(tunnel)
+		if _, err := net.Dial("tcp", stats.Addr); err == nil {
+			t.Error("Expected listener to be closed after Stop")
+		}
+		if len(manager.Tunnels()) != 0 {
+			t.Errorf("Expected no tunnels after Stop, got %d", len(manager.Tunnels()))
+		}
+	})
+
+	t.Run("Local forward answers after a half-close", func(t *testing.T) {
+		tunnel, err := manager.Start(Forward{Type: ForwardLocal, Listen: "127.0.0.1:0", Target: echo})
+		if err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		defer manager.Stop(tunnel)
+
+		conn, err := net.Dial("tcp", tunnel.Stats().Addr)
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		// The echo server only finishes once it has read everything
+		conn.Write([]byte("ping"))
+		conn.(*net.TCPConn).CloseWrite()
+		got, err := io.ReadAll(conn)
+		if err != nil || string(got) != "ping" {
+			t.Errorf("Expected ping back after closing the write side, got %q: %v", got, err)
+		}
+	})
+
+	t.Run("Remote forward", func(t *testing.T) {
+		tunnel, err := manager.Start(Forward{Type: ForwardRemote, Listen: "127.0.0.1:0", Target: echo})
+		if err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		defer manager.Stop(tunnel)
+
+		// The test server listens in-process, so its port is reachable here
+		conn, err := net.Dial("tcp", tunnel.Stats().Addr)
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		defer conn.Close()
+		expectEcho(t, conn)
+	})
+
+	t.Run("Dynamic forward", func(t *testing.T) {
+		tunnel, err := manager.Start(Forward{Type: ForwardDynamic, Listen: "127.0.0.1:0"})
+		if err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		defer manager.Stop(tunnel)
+
+		conn, err := net.Dial("tcp", tunnel.Stats().Addr)
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		// Greeting with no-auth, then CONNECT by IPv4 address
+		conn.Write([]byte{5, 1, 0})
+		reply := make([]byte, 2)
+		if _, err := io.ReadFull(conn, reply); err != nil || !bytes.Equal(reply, []byte{5, 0}) {
+			t.Fatalf("Unexpected greeting reply %v: %v", reply, err)
+		}
+
+		addr := echoAddr(t, echo)
+		request := append([]byte{5, 1, 0, 1}, addr.IP.To4()...)
+		request = binary.BigEndian.AppendUint16(request, uint16(addr.Port))
+		conn.Write(request)
+
+		resp := make([]byte, 10)
+		if _, err := io.ReadFull(conn, resp); err != nil || resp[1] != 0 {
+			t.Fatalf("Unexpected connect reply %v: %v", resp, err)
+		}
+		expectEcho(t, conn)
+	})
+
+	t.Run("Dynamic forward rejects authentication-only clients", func(t *testing.T) {
+		tunnel, err := manager.Start(Forward{Type: ForwardDynamic, Listen: "127.0.0.1:0"})
+		if err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		defer manager.Stop(tunnel)
+
+		conn, err := net.Dial("tcp", tunnel.Stats().Addr)
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		// Only username/password offered
+		conn.Write([]byte{5, 1, 2})
+		reply := make([]byte, 2)
+		if _, err := io.ReadFull(conn, reply); err != nil || reply[1] != 0xff {
+			t.Fatalf("Expected no acceptable methods, got %v: %v", reply, err)
+		}
+	})
+
+	t.Run("Dynamic forward drops clients that stall the handshake", func(t *testing.T) {
+		defer func(timeout time.Duration) { socks5HandshakeTimeout = timeout }(socks5HandshakeTimeout)
+		socks5HandshakeTimeout = 100 * time.Millisecond
+
+		tunnel, err := manager.Start(Forward{Type: ForwardDynamic, Listen: "127.0.0.1:0"})
+		if err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		defer manager.Stop(tunnel)
+
+		conn, err := net.Dial("tcp", tunnel.Stats().Addr)
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		// Half a greeting, then nothing
+		conn.Write([]byte{5})
+		if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+			t.Errorf("Expected the tunnel to close the connection, got %v", err)
+		}
+	})
+
+	t.Run("Start fails when disconnected", func(t *testing.T) {
+		offline := NewForwardManager(NewClient(&SSHConfig{}))
+		if _, err := offline.Start(Forward{Type: ForwardLocal, Listen: "127.0.0.1:0", Target: echo}); err == nil {
+			t.Error("Expected error for disconnected client")
+		}
+	})
+}
+
+func echoAddr(t *testing.T, addr string) *net.TCPAddr {
+	t.Helper()
+	tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tcpAddr
+}
@@ -0,0 +1,295 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// HostKeyVerifier decides whether a server's host key is trusted.
+// Verify has the signature of ssh.HostKeyCallback so it can be plugged
+// straight into the client config.
+type HostKeyVerifier interface {
+	Verify(hostname string, remote net.Addr, key ssh.PublicKey) error
+}
+
+// HostKeyStatus describes how a presented key relates to known_hosts
+type HostKeyStatus int
+
+const (
+	// HostKeyUnknown means the host has no entry in known_hosts
+	HostKeyUnknown HostKeyStatus = iota
+	// HostKeyChanged means the host is known but presented a different key
+	HostKeyChanged
+)
+
+// HostKeyDecision is the user's answer to a host key prompt
+type HostKeyDecision int
+
+const (
+	HostKeyReject  HostKeyDecision = iota
+	HostKeyAccept                  // Trust an unknown host and save it to known_hosts
+	HostKeyReplace                 // Drop the stale entries and save the new key
+)
+
+// HostKeyPrompt holds everything the user needs to decide on a host key
+type HostKeyPrompt struct {
+	Hostname    string
+	RemoteAddr  string
+	KeyType     string
+	Fingerprint string // SHA256 fingerprint, as printed by OpenSSH
+	Status      HostKeyStatus
+	KnownHosts  string   // Path of the known_hosts file that will be updated
+	KnownKeys   []string // "file:line" of entries that conflict with the new key
+}
+
+// HostKeyPromptFunc asks the user about a host key and blocks until answered
+type HostKeyPromptFunc func(prompt HostKeyPrompt) HostKeyDecision
+
+// HostKeyError is returned when a host key was not trusted
+type HostKeyError struct {
+	Prompt HostKeyPrompt
+}
+
+func (e *HostKeyError) Error() string {
+	if e.Prompt.Status == HostKeyChanged {
+		return fmt.Sprintf("host key for %s has changed (%s %s) - possible man-in-the-middle attack",
+			e.Prompt.Hostname, e.Prompt.KeyType, e.Prompt.Fingerprint)
+	}
+	return fmt.Sprintf("host key for %s is not trusted (%s %s)",
+		e.Prompt.Hostname, e.Prompt.KeyType, e.Prompt.Fingerprint)
+}
+
+// KnownHostsVerifier verifies host keys against an OpenSSH known_hosts file.
+// Unknown or changed keys are passed to the prompt function; without one
+// they are rejected.
+type KnownHostsVerifier struct {
+	path   string
+	prompt HostKeyPromptFunc
+	mu     sync.Mutex
+}
+
+// NewKnownHostsVerifier creates a verifier backed by the given known_hosts file
+func NewKnownHostsVerifier(path string, prompt HostKeyPromptFunc) *KnownHostsVerifier {
+	return &KnownHostsVerifier{
+		path:   path,
+		prompt: prompt,
+	}
+}
+
+// DefaultKnownHostsPath returns ~/.ssh/known_hosts
+func DefaultKnownHostsPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".ssh", "known_hosts"), nil
+}
+
+// ensureFile creates the known_hosts file (and its directory) if missing
+func (v *KnownHostsVerifier) ensureFile() error {
+	if err := os.MkdirAll(filepath.Dir(v.path), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(v.path), err)
+	}
+
+	f, err := os.OpenFile(v.path, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create known_hosts: %w", err)
+	}
+	return f.Close()
+}
+
+// callback loads known_hosts into a fresh callback so edits made by other
+// processes (or by a previous prompt) are always picked up
+func (v *KnownHostsVerifier) callback() (ssh.HostKeyCallback, error) {
+	if err := v.ensureFile(); err != nil {
+		return nil, err
+	}
+
+	cb, err := knownhosts.New(v.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known_hosts: %w", err)
+	}
+	return cb, nil
+}
+
+// Verify implements HostKeyVerifier
+func (v *KnownHostsVerifier) Verify(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	cb, err := v.callback()
+	if err != nil {
+		return err
+	}
+
+	err = cb(hostname, remote, key)
+	if err == nil {
+		return nil
+	}
+
+	var keyErr *knownhosts.KeyError
+	if !errors.As(err, &keyErr) {
+		// Revoked keys and malformed addresses are never negotiable
+		return err
+	}
+
+	prompt := HostKeyPrompt{
+		Hostname:    hostname,
+		KeyType:     key.Type(),
+		Fingerprint: ssh.FingerprintSHA256(key),
+		Status:      HostKeyUnknown,
+		KnownHosts:  v.path,
+	}
+	if remote != nil {
+		prompt.RemoteAddr = remote.String()
+	}
+	if len(keyErr.Want) > 0 {
+		prompt.Status = HostKeyChanged
+		for _, known := range keyErr.Want {
+			prompt.KnownKeys = append(prompt.KnownKeys, fmt.Sprintf("%s:%d", known.Filename, known.Line))
+		}
+	}
+
+	if v.prompt == nil {
+		return &HostKeyError{Prompt: prompt}
+	}
+
+	switch decision := v.prompt(prompt); {
+	case decision == HostKeyAccept && prompt.Status == HostKeyUnknown:
+		return v.appendKey(hostname, key)
+	case decision == HostKeyReplace && prompt.Status == HostKeyChanged:
+		if err := v.removeLines(keyErr.Want); err != nil {
+			return err
+		}
+		return v.appendKey(hostname, key)
+	default:
+		// Accepting a changed key requires an explicit replace
+		return &HostKeyError{Prompt: prompt}
+	}
+}
+
+// appendKey adds a known_hosts line for hostname
+func (v *KnownHostsVerifier) appendKey(hostname string, key ssh.PublicKey) error {
+	f, err := os.OpenFile(v.path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open known_hosts: %w", err)
+	}
+	defer f.Close()
+
+	line := knownhosts.Line([]string{hostname}, key)
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	return nil
+}
+
+// removeLines drops the given entries from known_hosts. Entries listing
+// several hosts on one line are removed as a whole.
+func (v *KnownHostsVerifier) removeLines(keys []knownhosts.KnownKey) error {
+	drop := make(map[int]bool)
+	for _, k := range keys {
+		if k.Filename == v.path {
+			drop[k.Line] = true
+		}
+	}
+
+	data, err := os.ReadFile(v.path)
+	if err != nil {
+		return fmt.Errorf("failed to read known_hosts: %w", err)
+	}
+
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		if drop[lineNum] {
+			continue
+		}
+		out.Write(scanner.Bytes())
+		out.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read known_hosts: %w", err)
+	}
+
+	if err := os.WriteFile(v.path, out.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	return nil
+}
+
+// HostKeyAlgorithms returns the host key algorithms to offer for hostname.
+// Types already recorded in known_hosts come first so the handshake picks a
+// key we can verify instead of reporting a mismatch just because the server
+// offered another type first.
+func (v *KnownHostsVerifier) HostKeyAlgorithms(hostname string) []string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	cb, err := v.callback()
+	if err != nil {
+		return nil
+	}
+
+	// A probe key never matches, so the error lists every known key
+	var keyErr *knownhosts.KeyError
+	if err := cb(hostname, probeAddr(hostname), probeKey{}); !errors.As(err, &keyErr) || len(keyErr.Want) == 0 {
+		return nil
+	}
+
+	var algos []string
+	seen := make(map[string]bool)
+	add := func(algo string) {
+		if !seen[algo] {
+			seen[algo] = true
+			algos = append(algos, algo)
+		}
+	}
+	for _, known := range keyErr.Want {
+		for _, algo := range algorithmsForKeyType(known.Key.Type()) {
+			add(algo)
+		}
+	}
+	for _, algo := range ssh.SupportedAlgorithms().HostKeys {
+		add(algo)
+	}
+	return algos
+}
+
+// algorithmsForKeyType maps a key type to the signature algorithms it supports
+func algorithmsForKeyType(keyType string) []string {
+	if keyType == ssh.KeyAlgoRSA {
+		return []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+	}
+	return []string{keyType}
+}
+
+// probeAddr builds a net.Addr for hostname, which knownhosts requires
+// even though it prefers the hostname when matching
+func probeAddr(hostname string) net.Addr {
+	host, port, err := net.SplitHostPort(hostname)
+	if err != nil {
+		host, port = hostname, "22"
+	}
+	portNum, _ := strconv.Atoi(port)
+	return &net.TCPAddr{IP: net.ParseIP(strings.Trim(host, "[]")), Port: portNum}
+}
+
+// probeKey is a public key that cannot match any known_hosts entry
+type probeKey struct{}
+
+func (probeKey) Type() string                        { return "marix-probe" }
+func (probeKey) Marshal() []byte                     { return []byte("marix-probe") }
+func (probeKey) Verify([]byte, *ssh.Signature) error { return errors.New("probe key") }
@@ -0,0 +1,152 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func newTestHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestKnownHostsVerifier(t *testing.T) {
+	remote := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 2222}
+	hostname := "example.com:2222"
+
+	t.Run("Unknown host is rejected without a prompt", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ssh", "known_hosts")
+		v := NewKnownHostsVerifier(path, nil)
+
+		err := v.Verify(hostname, remote, newTestHostKey(t))
+		var hkErr *HostKeyError
+		if !errors.As(err, &hkErr) {
+			t.Fatalf("Expected HostKeyError, got %v", err)
+		}
+		if hkErr.Prompt.Status != HostKeyUnknown {
+			t.Errorf("Expected HostKeyUnknown, got %v", hkErr.Prompt.Status)
+		}
+
+		// known_hosts should have been created rather than skipping verification
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("known_hosts was not created: %v", err)
+		}
+	})
+
+	t.Run("Accepted key is saved and trusted", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "known_hosts")
+		key := newTestHostKey(t)
+
+		var seen HostKeyPrompt
+		v := NewKnownHostsVerifier(path, func(p HostKeyPrompt) HostKeyDecision {
+			seen = p
+			return HostKeyAccept
+		})
+
+		if err := v.Verify(hostname, remote, key); err != nil {
+			t.Fatalf("Verify failed: %v", err)
+		}
+		if seen.Fingerprint != ssh.FingerprintSHA256(key) {
+			t.Errorf("Expected fingerprint %s, got %s", ssh.FingerprintSHA256(key), seen.Fingerprint)
+		}
+
+		// A strict verifier must now accept the same key
+		if err := NewKnownHostsVerifier(path, nil).Verify(hostname, remote, key); err != nil {
+			t.Errorf("Saved key not trusted: %v", err)
+		}
+	})
+
+	t.Run("Changed key cannot be accepted without replace", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "known_hosts")
+		oldKey := newTestHostKey(t)
+		line := knownhosts.Line([]string{hostname}, oldKey) + "\n"
+		if err := os.WriteFile(path, []byte(line), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		v := NewKnownHostsVerifier(path, func(p HostKeyPrompt) HostKeyDecision {
+			if p.Status != HostKeyChanged {
+				t.Errorf("Expected HostKeyChanged, got %v", p.Status)
+			}
+			return HostKeyAccept
+		})
+
+		err := v.Verify(hostname, remote, newTestHostKey(t))
+		var hkErr *HostKeyError
+		if !errors.As(err, &hkErr) {
+			t.Fatalf("Expected HostKeyError, got %v", err)
+		}
+
+		data, _ := os.ReadFile(path)
+		if string(data) != line {
+			t.Error("known_hosts should be untouched after a rejected change")
+		}
+	})
+
+	t.Run("Replace drops stale entries only", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "known_hosts")
+		otherLine := knownhosts.Line([]string{"other.example.com"}, newTestHostKey(t))
+		staleLine := knownhosts.Line([]string{hostname}, newTestHostKey(t))
+		content := "# comment\n" + otherLine + "\n" + staleLine + "\n"
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		newKey := newTestHostKey(t)
+		v := NewKnownHostsVerifier(path, func(p HostKeyPrompt) HostKeyDecision {
+			return HostKeyReplace
+		})
+		if err := v.Verify(hostname, remote, newKey); err != nil {
+			t.Fatalf("Verify failed: %v", err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := string(data)
+		if strings.Contains(text, staleLine) {
+			t.Error("Stale entry was not removed")
+		}
+		if !strings.Contains(text, otherLine) || !strings.Contains(text, "# comment") {
+			t.Error("Unrelated entries were removed")
+		}
+		if err := NewKnownHostsVerifier(path, nil).Verify(hostname, remote, newKey); err != nil {
+			t.Errorf("Replaced key not trusted: %v", err)
+		}
+	})
+
+	t.Run("Known key types are preferred", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "known_hosts")
+		line := knownhosts.Line([]string{hostname}, newTestHostKey(t)) + "\n"
+		if err := os.WriteFile(path, []byte(line), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		algos := NewKnownHostsVerifier(path, nil).HostKeyAlgorithms(hostname)
+		if len(algos) == 0 || algos[0] != ssh.KeyAlgoED25519 {
+			t.Errorf("Expected %s first, got %v", ssh.KeyAlgoED25519, algos)
+		}
+
+		if algos := NewKnownHostsVerifier(path, nil).HostKeyAlgorithms("unknown.example.com:22"); algos != nil {
+			t.Errorf("Expected no preference for unknown host, got %v", algos)
+		}
+	})
+}
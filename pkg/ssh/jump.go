@@ -0,0 +1,95 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// DialFunc opens a network connection, directly or through an SSH client
+type DialFunc func(network, addr string) (net.Conn, error)
+
+// directDial connects without any jump host
+func directDial(network, addr string) (net.Conn, error) {
+	return net.DialTimeout(network, addr, 30*time.Second)
+}
+
+// JumpDialer returns a dialer that opens connections from the last jump host,
+// or nil when the client connects directly. The dialer follows Reconnect,
+// always using the jump hosts of the current connection.
+func (c *Client) JumpDialer() DialFunc {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.hops) == 0 {
+		return nil
+	}
+	return c.dialJump
+}
+
+// dialJump opens a connection from the last jump host of the current connection
+func (c *Client) dialJump(network, addr string) (net.Conn, error) {
+	c.mu.Lock()
+	var hop *ssh.Client
+	if len(c.hops) > 0 {
+		hop = c.hops[len(c.hops)-1]
+	}
+	c.mu.Unlock()
+
+	if hop == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+	return hop.Dial(network, addr)
+}
+
+// ServeTunnel accepts connections on listener and forwards each one to
+// target using dial, until the listener is closed
+func ServeTunnel(listener net.Listener, dial DialFunc, target string) {
+	for {
+		local, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer local.Close()
+			remote, err := dial("tcp", target)
+			if err != nil {
+				return
+			}
+			defer remote.Close()
+			pipe(local, remote)
+		}()
+	}
+}
+
+// pipe copies in both directions until both sides are done. When one side
+// reaches EOF only the write half of the other is closed, so a peer can still
+// answer after it has sent everything.
+func pipe(a, b net.Conn) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		io.Copy(a, b)
+		closeWrite(a)
+	}()
+	go func() {
+		defer wg.Done()
+		io.Copy(b, a)
+		closeWrite(b)
+	}()
+	wg.Wait()
+}
+
+// closeWrite shuts down the writing side of conn, or closes it when the
+// connection cannot be half-closed
+func closeWrite(conn net.Conn) {
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		cw.CloseWrite()
+		return
+	}
+	conn.Close()
+}
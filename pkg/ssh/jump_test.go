@@ -0,0 +1,194 @@
+package ssh
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// newPasswordServer starts a server that only accepts the given password
+func newPasswordServer(t *testing.T, password string) *testServer {
+	t.Helper()
+	return newTestServer(t, &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, pw []byte) (*ssh.Permissions, error) {
+			if string(pw) == password {
+				return nil, nil
+			}
+			return nil, errors.New("wrong password")
+		},
+	})
+}
+
+// expectTunnel waits for a jump server to forward a connection to target
+func expectTunnel(t *testing.T, server *testServer, target string) {
+	t.Helper()
+	select {
+	case got := <-server.tunnels:
+		if got != target {
+			t.Errorf("Expected tunnel to %s, got %s", target, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for tunnel to %s", target)
+	}
+}
+
+func TestClientJumpHosts(t *testing.T) {
+	t.Run("Connects through a chain of jump hosts", func(t *testing.T) {
+		first := newPasswordServer(t, "first")
+		second := newPasswordServer(t, "second")
+		target := newPasswordServer(t, "target")
+
+		firstConfig := first.sshConfig(t)
+		firstConfig.Password = "first"
+		secondConfig := second.sshConfig(t)
+		secondConfig.Password = "second"
+
+		config := target.sshConfig(t)
+		config.Password = "target"
+		config.JumpHosts = []*SSHConfig{firstConfig, secondConfig}
+
+		client := NewClient(config)
+		client.SetHostKeyVerifier(acceptAllVerifier{})
+
+		if err := client.Connect(); err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		defer client.Close()
+
+		expectTunnel(t, first, second.addr)
+		expectTunnel(t, second, target.addr)
+
+		if client.JumpDialer() == nil {
+			t.Error("Expected a jump dialer for a tunnelled connection")
+		}
+	})
+
+	t.Run("Jump host failure is reported", func(t *testing.T) {
+		jump := newPasswordServer(t, "jump")
+		target := newPasswordServer(t, "target")
+
+		jumpConfig := jump.sshConfig(t)
+		jumpConfig.Password = "wrong"
+
+		config := target.sshConfig(t)
+		config.Password = "target"
+		config.JumpHosts = []*SSHConfig{jumpConfig}
+
+		client := NewClient(config)
+		client.SetHostKeyVerifier(acceptAllVerifier{})
+
+		if err := client.Connect(); err == nil {
+			client.Close()
+			t.Fatal("Expected Connect to fail when the jump host rejects auth")
+		}
+		if client.IsConnected() {
+			t.Error("Expected client to stay disconnected")
+		}
+	})
+
+	t.Run("Direct connection has no jump dialer", func(t *testing.T) {
+		target := newPasswordServer(t, "target")
+
+		config := target.sshConfig(t)
+		config.Password = "target"
+
+		client := NewClient(config)
+		client.SetHostKeyVerifier(acceptAllVerifier{})
+
+		if err := client.Connect(); err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		defer client.Close()
+
+		if client.JumpDialer() != nil {
+			t.Error("Expected no jump dialer for a direct connection")
+		}
+	})
+}
+
+func TestServeTunnel(t *testing.T) {
+	jump := newPasswordServer(t, "jump")
+	target := newPasswordServer(t, "target")
+
+	jumpConfig := jump.sshConfig(t)
+	jumpConfig.Password = "jump"
+
+	config := target.sshConfig(t)
+	config.Password = "target"
+	config.JumpHosts = []*SSHConfig{jumpConfig}
+
+	client := NewClient(config)
+	client.SetHostKeyVerifier(acceptAllVerifier{})
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer client.Close()
+	expectTunnel(t, jump, target.addr)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go ServeTunnel(listener, client.JumpDialer(), target.addr)
+
+	// A second SSH session reaches the target through the local tunnel,
+	// the way an external rsync/ssh process would
+	tunnelled, err := ssh.Dial("tcp", listener.Addr().String(), &ssh.ClientConfig{
+		User:            "tester",
+		Auth:            []ssh.AuthMethod{ssh.Password("target")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Dial through tunnel failed: %v", err)
+	}
+	tunnelled.Close()
+
+	expectTunnel(t, jump, target.addr)
+}
+
+func TestJumpDialerFollowsReconnect(t *testing.T) {
+	jump := newPasswordServer(t, "jump")
+	target := newPasswordServer(t, "target")
+
+	jumpConfig := jump.sshConfig(t)
+	jumpConfig.Password = "jump"
+
+	config := target.sshConfig(t)
+	config.Password = "target"
+	config.JumpHosts = []*SSHConfig{jumpConfig}
+
+	client := NewClient(config)
+	client.SetHostKeyVerifier(acceptAllVerifier{})
+	closed := make(chan struct{}, 4)
+	client.OnClose(func() { closed <- struct{}{} })
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer client.Close()
+	if err := client.CreateShell(80, 24); err != nil {
+		t.Fatalf("CreateShell failed: %v", err)
+	}
+	expectTunnel(t, jump, target.addr)
+
+	// Taken before the connection is replaced, as SFTP engines do
+	dial := client.JumpDialer()
+
+	jump.dropConnections()
+	waitForClose(t, closed)
+	if err := client.Reconnect(80, 24); err != nil {
+		t.Fatalf("Reconnect failed: %v", err)
+	}
+	expectTunnel(t, jump, target.addr)
+
+	conn, err := dial("tcp", target.addr)
+	if err != nil {
+		t.Fatalf("Dial after reconnect failed: %v", err)
+	}
+	conn.Close()
+	expectTunnel(t, jump, target.addr)
+}
@@ -0,0 +1,120 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrConnectionLost is returned by Err when a shell ended because the
+// connection died rather than because the shell exited
+var ErrConnectionLost = errors.New("connection lost")
+
+// SetKeepAlive makes shells send a keepalive@openssh.com request every
+// interval and drop the connection after countMax go unanswered, like
+// OpenSSH's ServerAliveInterval and ServerAliveCountMax, so a
+// connection that died silently (behind NAT, a suspended laptop) ends the
+// shell instead of hanging. A zero interval turns keepalives off. It applies
+// to shells created afterwards.
+func (c *Client) SetKeepAlive(interval time.Duration, countMax int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if countMax < 1 {
+		countMax = 1
+	}
+	c.keepAliveInterval = interval
+	c.keepAliveCountMax = countMax
+}
+
+// Err returns why the last shell ended when the connection was lost. It is
+// nil while connected, after the shell exited and after Close.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lost
+}
+
+// Reconnect replaces the connection with a new one and starts a fresh shell
+// of the given size. The data and close callbacks carry over to the new
+// shell. It waits for the old shell to be reported closed, so it must not be
+// called from the close callback.
+func (c *Client) Reconnect(cols, rows int) error {
+	c.mu.Lock()
+	c.connected = false
+	c.closeConnection()
+	done := c.shellDone
+	c.mu.Unlock()
+
+	if done != nil {
+		<-done
+	}
+
+	if err := c.Connect(); err != nil {
+		return err
+	}
+	if err := c.CreateShell(cols, rows); err != nil {
+		c.mu.Lock()
+		c.connected = false
+		c.closeConnection()
+		c.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// keepAlive sends keepalives on raw until done is closed. Once too many go
+// unanswered the connection is closed, which ends the shell.
+func (c *Client) keepAlive(raw *ssh.Client, done <-chan struct{}) {
+	c.mu.Lock()
+	interval, countMax := c.keepAliveInterval, c.keepAliveCountMax
+	c.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	missed := 0
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		err := sendKeepAlive(raw, interval)
+		if err == nil {
+			missed = 0
+			continue
+		}
+		if missed++; missed < countMax {
+			continue
+		}
+
+		c.mu.Lock()
+		if c.connected && c.lost == nil {
+			c.lost = fmt.Errorf("%w: %d keepalives unanswered: %v", ErrConnectionLost, missed, err)
+		}
+		c.mu.Unlock()
+		raw.Close()
+		return
+	}
+}
+
+// sendKeepAlive sends an OpenSSH keepalive request on raw and waits up to
+// timeout for the reply
+func sendKeepAlive(raw *ssh.Client, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		// Any reply, even a rejection, proves the server is alive
+		_, _, err := raw.SendRequest("keepalive@openssh.com", true, nil)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("no keepalive reply within %s", timeout)
+	}
+}
@@ -0,0 +1,148 @@
+package ssh
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newShellClient connects to server and starts a shell, reporting each
+// close on the returned channel
+func newShellClient(t *testing.T, server *testServer) (*Client, chan struct{}) {
+	t.Helper()
+	config := server.sshConfig(t)
+	config.Password = "secret"
+	client := NewClient(config)
+	client.SetHostKeyVerifier(acceptAllVerifier{})
+	t.Cleanup(func() { client.Close() })
+
+	closed := make(chan struct{}, 4)
+	client.OnClose(func() { closed <- struct{}{} })
+
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	return client, closed
+}
+
+func waitForClose(t *testing.T, closed chan struct{}) {
+	t.Helper()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the shell to close")
+	}
+}
+
+func TestClientConnectionLost(t *testing.T) {
+	server := newPasswordServer(t, "secret")
+	client, closed := newShellClient(t, server)
+
+	if err := client.CreateShell(80, 24); err != nil {
+		t.Fatalf("CreateShell failed: %v", err)
+	}
+	if err := client.Err(); err != nil {
+		t.Fatalf("Expected no error while connected, got %v", err)
+	}
+
+	server.dropConnections()
+	waitForClose(t, closed)
+
+	if err := client.Err(); !errors.Is(err, ErrConnectionLost) {
+		t.Fatalf("Expected ErrConnectionLost, got %v", err)
+	}
+	if client.IsConnected() {
+		t.Error("Expected client to be disconnected")
+	}
+
+	// A fresh connection and shell, with the same callbacks
+	if err := client.Reconnect(100, 30); err != nil {
+		t.Fatalf("Reconnect failed: %v", err)
+	}
+	if !client.IsConnected() || client.Err() != nil {
+		t.Fatalf("Expected a healthy connection, err %v", client.Err())
+	}
+	if err := client.Write([]byte("ls\r")); err != nil {
+		t.Errorf("Write after reconnect failed: %v", err)
+	}
+
+	server.dropConnections()
+	waitForClose(t, closed)
+	if err := client.Err(); !errors.Is(err, ErrConnectionLost) {
+		t.Errorf("Expected ErrConnectionLost after the second drop, got %v", err)
+	}
+}
+
+func TestClientCloseIsNotLost(t *testing.T) {
+	server := newPasswordServer(t, "secret")
+	client, closed := newShellClient(t, server)
+
+	if err := client.CreateShell(80, 24); err != nil {
+		t.Fatalf("CreateShell failed: %v", err)
+	}
+	client.Close()
+	waitForClose(t, closed)
+
+	if err := client.Err(); err != nil {
+		t.Errorf("Expected no error after Close, got %v", err)
+	}
+}
+
+func TestClientKeepAlive(t *testing.T) {
+	server := newPasswordServer(t, "secret")
+	client, closed := newShellClient(t, server)
+	client.SetKeepAlive(20*time.Millisecond, 2)
+
+	if err := client.CreateShell(80, 24); err != nil {
+		t.Fatalf("CreateShell failed: %v", err)
+	}
+
+	// Answered keepalives keep the shell open
+	select {
+	case <-closed:
+		t.Fatal("Shell closed while keepalives were answered")
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	// A peer that stops answering is detected without any traffic
+	server.silent.Store(true)
+	waitForClose(t, closed)
+
+	err := client.Err()
+	if !errors.Is(err, ErrConnectionLost) || !strings.Contains(err.Error(), "keepalive") {
+		t.Errorf("Expected a keepalive failure, got %v", err)
+	}
+}
+
+func TestForwardManagerRestart(t *testing.T) {
+	server := newPasswordServer(t, "secret")
+	client, closed := newShellClient(t, server)
+	if err := client.CreateShell(80, 24); err != nil {
+		t.Fatalf("CreateShell failed: %v", err)
+	}
+
+	forwards := NewForwardManager(client)
+	f, err := ParseForward("L 127.0.0.1:0:" + server.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := forwards.Start(f); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	server.dropConnections()
+	waitForClose(t, closed)
+	if err := client.Reconnect(80, 24); err != nil {
+		t.Fatalf("Reconnect failed: %v", err)
+	}
+
+	if err := forwards.Restart(); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	tunnels := forwards.Tunnels()
+	if len(tunnels) != 1 || tunnels[0].Stats().Forward != f {
+		t.Fatalf("Expected the forward to be running again, got %d tunnels", len(tunnels))
+	}
+	forwards.StopAll()
+}
@@ -0,0 +1,73 @@
+package ssh
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Challenge is one keyboard-interactive round sent by the server,
+// e.g. a PAM password prompt followed by a one-time code
+type Challenge struct {
+	User        string
+	Name        string
+	Instruction string
+	Questions   []string
+	Echos       []bool // Whether the answer to each question may be shown
+}
+
+// ChallengeFunc answers a challenge, returning one answer per question
+type ChallengeFunc func(challenge Challenge) ([]string, error)
+
+// SetChallengeHandler sets the handler for keyboard-interactive challenges.
+// Without one, keyboard-interactive is only used to answer password prompts.
+func (c *Client) SetChallengeHandler(handler ChallengeFunc) {
+	c.challenge = handler
+}
+
+// keyboardInteractive builds the keyboard-interactive auth method, or nil if
+// there is nothing to answer challenges with
+func (c *Client) keyboardInteractive(config *SSHConfig) ssh.AuthMethod {
+	if c.challenge == nil && config.Password == "" {
+		return nil
+	}
+
+	passwordUsed := false
+	return ssh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		// Servers may send empty rounds (e.g. just an instruction)
+		if len(questions) == 0 {
+			return []string{}, nil
+		}
+
+		// Many PAM setups ask for the password this way; answer it once
+		// from the saved password so only the second factor is prompted
+		if config.Password != "" && !passwordUsed && isPasswordPrompt(questions, echos) {
+			passwordUsed = true
+			return []string{config.Password}, nil
+		}
+
+		if c.challenge == nil {
+			return nil, errChallengeUnanswered
+		}
+
+		return c.challenge(Challenge{
+			User:        config.Username,
+			Name:        name,
+			Instruction: instruction,
+			Questions:   questions,
+			Echos:       echos,
+		})
+	})
+}
+
+// errChallengeUnanswered aborts keyboard-interactive when nobody can answer
+var errChallengeUnanswered = errors.New("no handler for keyboard-interactive challenge")
+
+// isPasswordPrompt reports whether a round is a single hidden password question
+func isPasswordPrompt(questions []string, echos []bool) bool {
+	if len(questions) != 1 || (len(echos) > 0 && echos[0]) {
+		return false
+	}
+	return strings.Contains(strings.ToLower(questions[0]), "password")
+}
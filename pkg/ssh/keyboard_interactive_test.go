@@ -0,0 +1,98 @@
+package ssh
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// newOTPServer starts a server that asks for a password and then an OTP code
+func newOTPServer(t *testing.T, password, code string) *testServer {
+	t.Helper()
+	return newTestServer(t, &ssh.ServerConfig{
+		KeyboardInteractiveCallback: func(conn ssh.ConnMetadata, client ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+			answers, err := client("", "", []string{"Password: "}, []bool{false})
+			if err != nil {
+				return nil, err
+			}
+			if len(answers) != 1 || answers[0] != password {
+				return nil, errors.New("wrong password")
+			}
+
+			answers, err = client("2FA", "Open your authenticator app", []string{"Verification code: "}, []bool{true})
+			if err != nil {
+				return nil, err
+			}
+			if len(answers) != 1 || answers[0] != code {
+				return nil, errors.New("wrong code")
+			}
+			return nil, nil
+		},
+	})
+}
+
+func TestClientKeyboardInteractive(t *testing.T) {
+	t.Run("Password is answered automatically, OTP is prompted", func(t *testing.T) {
+		server := newOTPServer(t, "secret", "123456")
+
+		config := server.sshConfig(t)
+		config.Password = "secret"
+
+		var challenges []Challenge
+		client := NewClient(config)
+		client.SetHostKeyVerifier(acceptAllVerifier{})
+		client.SetChallengeHandler(func(c Challenge) ([]string, error) {
+			challenges = append(challenges, c)
+			return []string{"123456"}, nil
+		})
+
+		if err := client.Connect(); err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		defer client.Close()
+
+		if len(challenges) != 1 {
+			t.Fatalf("Expected 1 prompted challenge, got %d", len(challenges))
+		}
+		if challenges[0].Questions[0] != "Verification code: " || !challenges[0].Echos[0] {
+			t.Errorf("Unexpected challenge: %+v", challenges[0])
+		}
+		if challenges[0].User != "tester" {
+			t.Errorf("Expected user 'tester', got '%s'", challenges[0].User)
+		}
+	})
+
+	t.Run("Cancelled challenge fails authentication", func(t *testing.T) {
+		server := newOTPServer(t, "secret", "123456")
+
+		config := server.sshConfig(t)
+		config.Password = "secret"
+
+		client := NewClient(config)
+		client.SetHostKeyVerifier(acceptAllVerifier{})
+		client.SetChallengeHandler(func(c Challenge) ([]string, error) {
+			return nil, errors.New("cancelled")
+		})
+
+		if err := client.Connect(); err == nil {
+			client.Close()
+			t.Fatal("Expected Connect to fail")
+		}
+	})
+
+	t.Run("Without a handler only the password is answered", func(t *testing.T) {
+		server := newOTPServer(t, "secret", "123456")
+
+		config := server.sshConfig(t)
+		config.Password = "secret"
+
+		client := NewClient(config)
+		client.SetHostKeyVerifier(acceptAllVerifier{})
+
+		if err := client.Connect(); err == nil {
+			client.Close()
+			t.Fatal("Expected Connect to fail without an OTP handler")
+		}
+	})
+}
@@ -1,47 +1,141 @@
 package ssh
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"strconv"
 	"sync"
 )
 
 // Manager manages multiple SSH connections
 type Manager struct {
 	connections map[string]*Client
+	refs        map[*Client]int         // Holders of clients handed out by Acquire
+	dialing     map[string]*pendingDial // Connections being dialed, by key
+	newClient   func(config *SSHConfig) *Client
 	mu          sync.RWMutex
 }
 
+// pendingDial is a connection attempt other callers can wait on
+type pendingDial struct {
+	done chan struct{}
+	err  error
+}
+
 // NewManager creates a new SSH connection manager
 func NewManager() *Manager {
 	return &Manager{
 		connections: make(map[string]*Client),
+		refs:        make(map[*Client]int),
+		dialing:     make(map[string]*pendingDial),
+		newClient:   NewClient,
 	}
 }
 
-// Connect creates a new SSH connection
-func (m *Manager) Connect(config *SSHConfig) (string, error) {
-	connectionID := config.ConnectionID()
-
+// SetClientFactory sets how new clients are created, e.g. to wire in a host
+// key verifier. Defaults to NewClient.
+func (m *Manager) SetClientFactory(newClient func(config *SSHConfig) *Client) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.newClient = newClient
+}
+
+// Connect creates a new SSH connection and returns the ID to look it up by
+func (m *Manager) Connect(config *SSHConfig) (string, error) {
+	if _, err := m.connect(config, false); err != nil {
+		return "", err
+	}
+	return connectionKey(config), nil
+}
+
+// connectionKey identifies a shareable connection: the destination plus the
+// route and credentials used to reach it, so a profile whose jump hosts or
+// auth were edited gets a fresh connection instead of the old one.
+func connectionKey(config *SSHConfig) string {
+	auth := sha256.New()
+	for _, part := range []string{
+		config.Password,
+		config.PrivateKey,
+		string(config.KeyContent),
+		config.KeyPassword,
+		strconv.FormatBool(config.UseAgent),
+		strconv.FormatBool(config.ForwardAgent),
+	} {
+		auth.Write([]byte(part))
+		auth.Write([]byte{0})
+	}
+
+	key := fmt.Sprintf("%s#%x", config.ConnectionID(), auth.Sum(nil)[:8])
+	for _, hop := range config.JumpHosts {
+		key += " via " + connectionKey(hop)
+	}
+	return key
+}
+
+// connect returns the live client for config, dialing a new one if needed.
+// m.mu is only held around the map updates, so a slow handshake or prompt
+// doesn't stall other connections; callers asking for a connection that is
+// already being dialed wait for that attempt instead of dialing again. With
+// hold set, the client is counted as acquired before the lock is released.
+func (m *Manager) connect(config *SSHConfig, hold bool) (*Client, error) {
+	connectionID := connectionKey(config)
+
+	for {
+		m.mu.Lock()
+		// Check if already connected
+		if client, exists := m.connections[connectionID]; exists {
+			if client.IsConnected() {
+				if hold {
+					m.refs[client]++
+				}
+				m.mu.Unlock()
+				return client, nil
+			}
+			// Clean up old connection
+			delete(m.connections, connectionID)
+		}
+
+		pending, dialing := m.dialing[connectionID]
+		if !dialing {
+			break
+		}
+		m.mu.Unlock()
 
-	// Check if already connected
-	if client, exists := m.connections[connectionID]; exists {
-		if client.IsConnected() {
-			return connectionID, nil
+		// Wait for the other caller's attempt, then pick up its client
+		<-pending.done
+		if pending.err != nil {
+			return nil, pending.err
 		}
-		// Clean up old connection
-		delete(m.connections, connectionID)
 	}
 
+	pending := &pendingDial{done: make(chan struct{})}
+	m.dialing[connectionID] = pending
+	newClient := m.newClient
+	m.mu.Unlock()
+
 	// Create new client
-	client := NewClient(config)
-	if err := client.Connect(); err != nil {
-		return "", fmt.Errorf("connection failed: %w", err)
+	client := newClient(config)
+	err := client.Connect()
+	if err != nil {
+		err = fmt.Errorf("connection failed: %w", err)
+	}
+
+	m.mu.Lock()
+	delete(m.dialing, connectionID)
+	if err == nil {
+		m.connections[connectionID] = client
+		if hold {
+			m.refs[client]++
+		}
 	}
+	m.mu.Unlock()
 
-	m.connections[connectionID] = client
-	return connectionID, nil
+	pending.err = err
+	close(pending.done)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
 }
 
 // GetClient returns the SSH client for a connection ID
@@ -97,3 +191,30 @@ func (m *Manager) ListConnections() []string {
 	}
 	return ids
 }
+
+// Acquire returns a connected client for config, sharing a live connection
+// to the same destination over the same route and credentials. Every Acquire
+// must be paired with a Release.
+func (m *Manager) Acquire(config *SSHConfig) (*Client, error) {
+	return m.connect(config, true)
+}
+
+// Release gives back a client from Acquire, closing the connection once no
+// one holds it anymore
+func (m *Manager) Release(client *Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.refs[client] > 1 {
+		m.refs[client]--
+		return
+	}
+	delete(m.refs, client)
+
+	for id, existing := range m.connections {
+		if existing == client {
+			delete(m.connections, id)
+		}
+	}
+	client.Close()
+}
@@ -0,0 +1,265 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TunnelState is the health of a persistent tunnel
+type TunnelState string
+
+const (
+	TunnelDown         TunnelState = "down"         // Not running, or stopped
+	TunnelUp           TunnelState = "up"           // Connected with every forward listening
+	TunnelReconnecting TunnelState = "reconnecting" // Waiting to retry after a failure
+)
+
+// ReconnectPolicy controls how a persistent tunnel watches and restores its connection
+type ReconnectPolicy struct {
+	KeepAlive  time.Duration // Interval between keepalives; also the reply timeout
+	MinBackoff time.Duration // First retry delay, doubled after every failure
+	MaxBackoff time.Duration // Upper bound for the retry delay
+}
+
+// DefaultReconnectPolicy suits long-lived tunnels over flaky networks
+var DefaultReconnectPolicy = ReconnectPolicy{
+	KeepAlive:  15 * time.Second,
+	MinBackoff: time.Second,
+	MaxBackoff: time.Minute,
+}
+
+// TunnelHealth is a snapshot of a persistent tunnel
+type TunnelHealth struct {
+	State     TunnelState
+	Since     time.Time // When State last changed
+	LastError error     // Most recent failure, kept after reconnecting
+	Attempts  int       // Failed connection attempts since the tunnel was last up
+	NextRetry time.Time // When the next attempt starts while reconnecting
+	Tunnels   []TunnelStats
+}
+
+// PersistentTunnel keeps a set of forwards alive, reconnecting with
+// exponential backoff whenever the connection drops
+type PersistentTunnel struct {
+	manager  *Manager
+	config   *SSHConfig
+	forwards []Forward
+	policy   ReconnectPolicy
+
+	mu       sync.Mutex
+	health   TunnelHealth
+	active   *ForwardManager
+	onChange func(TunnelHealth)
+	running  bool
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewPersistentTunnel creates a stopped tunnel that connects through manager
+func NewPersistentTunnel(manager *Manager, config *SSHConfig, forwards []Forward, policy ReconnectPolicy) *PersistentTunnel {
+	return &PersistentTunnel{
+		manager:  manager,
+		config:   config,
+		forwards: forwards,
+		policy:   policy,
+		health:   TunnelHealth{State: TunnelDown, Since: time.Now()},
+	}
+}
+
+// OnChange sets a callback invoked whenever the tunnel changes state
+func (p *PersistentTunnel) OnChange(callback func(TunnelHealth)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onChange = callback
+}
+
+// Start begins connecting in the background; it does nothing if already running
+func (p *PersistentTunnel) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running {
+		return
+	}
+	p.running = true
+	previous := p.done
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go p.run(previous, p.stop, p.done)
+}
+
+// Stop closes the forwards and the connection. It returns immediately; use
+// Done to wait until everything is torn down.
+func (p *PersistentTunnel) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.running {
+		return
+	}
+	p.running = false
+	close(p.stop)
+}
+
+// Done returns a channel closed once the last Start has fully stopped
+func (p *PersistentTunnel) Done() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done == nil {
+		done := make(chan struct{})
+		close(done)
+		return done
+	}
+	return p.done
+}
+
+// Running reports whether the tunnel has been started and not stopped
+func (p *PersistentTunnel) Running() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.running
+}
+
+// Health returns the tunnel's current state
+func (p *PersistentTunnel) Health() TunnelHealth {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	health := p.health
+	if p.active != nil {
+		for _, t := range p.active.Tunnels() {
+			health.Tunnels = append(health.Tunnels, t.Stats())
+		}
+	}
+	return health
+}
+
+func (p *PersistentTunnel) run(previous, stop, done chan struct{}) {
+	defer close(done)
+
+	// A quick restart must not overlap the teardown of the last run
+	if previous != nil {
+		<-previous
+	}
+
+	backoff := p.policy.MinBackoff
+	for {
+		err := p.connectOnce(stop)
+
+		select {
+		case <-stop:
+			p.update(func(h *TunnelHealth) {
+				h.State = TunnelDown
+				h.NextRetry = time.Time{}
+			})
+			return
+		default:
+		}
+
+		if p.Health().State == TunnelUp {
+			// The connection worked for a while, start over with short delays
+			backoff = p.policy.MinBackoff
+		}
+		p.update(func(h *TunnelHealth) {
+			h.State = TunnelReconnecting
+			h.LastError = err
+			h.Attempts++
+			h.NextRetry = time.Now().Add(backoff)
+		})
+
+		select {
+		case <-stop:
+			p.update(func(h *TunnelHealth) {
+				h.State = TunnelDown
+				h.NextRetry = time.Time{}
+			})
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > p.policy.MaxBackoff {
+			backoff = p.policy.MaxBackoff
+		}
+	}
+}
+
+// connectOnce connects, starts the forwards and watches the connection
+// until it fails or stop is closed
+func (p *PersistentTunnel) connectOnce(stop chan struct{}) error {
+	client, err := p.manager.Acquire(p.config)
+	if err != nil {
+		return err
+	}
+	defer p.manager.Release(client)
+
+	forwards := NewForwardManager(client)
+	defer func() {
+		p.mu.Lock()
+		p.active = nil
+		p.mu.Unlock()
+		forwards.StopAll()
+	}()
+
+	for _, f := range p.forwards {
+		if _, err := forwards.Start(f); err != nil {
+			return fmt.Errorf("%s: %w", f, err)
+		}
+	}
+
+	raw := client.GetRawClient()
+	if raw == nil {
+		return fmt.Errorf("not connected")
+	}
+	closed := make(chan error, 1)
+	go func() {
+		closed <- raw.Wait()
+	}()
+
+	p.mu.Lock()
+	p.active = forwards
+	p.mu.Unlock()
+	p.update(func(h *TunnelHealth) {
+		h.State = TunnelUp
+		h.Attempts = 0
+		h.NextRetry = time.Time{}
+	})
+
+	ticker := time.NewTicker(p.policy.KeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return nil
+		case err := <-closed:
+			client.Close()
+			if err == nil {
+				err = errors.New("closed by server")
+			}
+			return fmt.Errorf("connection lost: %w", err)
+		case <-ticker.C:
+			if err := client.KeepAlive(p.policy.KeepAlive); err != nil {
+				// Close so the manager dials a fresh connection next time
+				client.Close()
+				return fmt.Errorf("keepalive failed: %w", err)
+			}
+		}
+	}
+}
+
+// update changes the health under the lock and notifies the callback
+func (p *PersistentTunnel) update(change func(h *TunnelHealth)) {
+	p.mu.Lock()
+	previous := p.health.State
+	change(&p.health)
+	if p.health.State != previous {
+		p.health.Since = time.Now()
+	}
+	health := p.health
+	callback := p.onChange
+	p.mu.Unlock()
+
+	if callback != nil {
+		callback(health)
+	}
+}
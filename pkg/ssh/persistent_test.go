@@ -0,0 +1,326 @@
+package ssh
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testReconnectPolicy = ReconnectPolicy{
+	KeepAlive:  100 * time.Millisecond,
+	MinBackoff: 20 * time.Millisecond,
+	MaxBackoff: 80 * time.Millisecond,
+}
+
+func newTestManager() *Manager {
+	manager := NewManager()
+	manager.SetClientFactory(func(config *SSHConfig) *Client {
+		client := NewClient(config)
+		client.SetHostKeyVerifier(acceptAllVerifier{})
+		return client
+	})
+	return manager
+}
+
+// freeAddr returns a local address that is free to listen on
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	return addr
+}
+
+// waitForState polls until the tunnel reaches state and check passes
+func waitForState(t *testing.T, p *PersistentTunnel, state TunnelState, check func(TunnelHealth) bool) TunnelHealth {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		health := p.Health()
+		if health.State == state && (check == nil || check(health)) {
+			return health
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for state %s, last health: %+v", state, health)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestManagerAcquireRelease(t *testing.T) {
+	server := newPasswordServer(t, "secret")
+	config := server.sshConfig(t)
+	config.Password = "secret"
+
+	manager := newTestManager()
+	first, err := manager.Acquire(config)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	second, err := manager.Acquire(config)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if first != second {
+		t.Error("Expected both holders to share the connection")
+	}
+
+	manager.Release(first)
+	if !second.IsConnected() {
+		t.Fatal("Connection closed while still held")
+	}
+	manager.Release(second)
+	if second.IsConnected() {
+		t.Error("Expected connection to close after the last release")
+	}
+	if ids := manager.ListConnections(); len(ids) != 0 {
+		t.Errorf("Expected no connections, got %v", ids)
+	}
+}
+
+func TestManagerAcquireConfigChange(t *testing.T) {
+	server := newPasswordServer(t, "secret")
+	jump := newPasswordServer(t, "jump")
+
+	direct := server.sshConfig(t)
+	direct.Password = "secret"
+
+	jumpConfig := jump.sshConfig(t)
+	jumpConfig.Password = "jump"
+	viaJump := server.sshConfig(t)
+	viaJump.Password = "secret"
+	viaJump.JumpHosts = []*SSHConfig{jumpConfig}
+
+	manager := newTestManager()
+	first, err := manager.Acquire(direct)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer manager.Release(first)
+
+	t.Run("Edge Case: A new jump chain gets its own connection", func(t *testing.T) {
+		second, err := manager.Acquire(viaJump)
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+		defer manager.Release(second)
+
+		if second == first {
+			t.Fatal("Expected a new connection after the jump hosts changed")
+		}
+		expectTunnel(t, jump, server.addr)
+	})
+
+	t.Run("Error Handling: New credentials are checked instead of reusing the connection", func(t *testing.T) {
+		changed := *direct
+		changed.Password = "wrong"
+
+		if second, err := manager.Acquire(&changed); err == nil {
+			manager.Release(second)
+			t.Fatal("Expected the changed password to be rejected")
+		}
+		if !first.IsConnected() {
+			t.Error("Expected the original connection to stay up")
+		}
+	})
+}
+
+func TestManagerAcquireConcurrent(t *testing.T) {
+	t.Run("A stalled handshake does not block other connections", func(t *testing.T) {
+		// Accepts connections but never speaks SSH, so the handshake hangs
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		accepted := make(chan net.Conn, 1)
+		go func() {
+			if conn, err := listener.Accept(); err == nil {
+				accepted <- conn
+			}
+		}()
+		_, port, _ := net.SplitHostPort(listener.Addr().String())
+		portNum, _ := strconv.Atoi(port)
+		stalled := &SSHConfig{Host: "127.0.0.1", Port: portNum, Username: "user", Password: "secret"}
+
+		server := newPasswordServer(t, "secret")
+		config := server.sshConfig(t)
+		config.Password = "secret"
+
+		manager := newTestManager()
+		go manager.Acquire(stalled)
+
+		var conn net.Conn
+		select {
+		case conn = <-accepted:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the stalled dial")
+		}
+		defer conn.Close()
+
+		acquired := make(chan error, 1)
+		go func() {
+			client, err := manager.Acquire(config)
+			if err == nil {
+				manager.Release(client)
+			}
+			acquired <- err
+		}()
+		select {
+		case err := <-acquired:
+			if err != nil {
+				t.Fatalf("Acquire failed: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Acquire blocked behind another connection's handshake")
+		}
+	})
+
+	t.Run("Concurrent callers share a single dial", func(t *testing.T) {
+		server := newPasswordServer(t, "secret")
+		config := server.sshConfig(t)
+		config.Password = "secret"
+
+		var dials int32
+		manager := NewManager()
+		manager.SetClientFactory(func(config *SSHConfig) *Client {
+			atomic.AddInt32(&dials, 1)
+			client := NewClient(config)
+			client.SetHostKeyVerifier(acceptAllVerifier{})
+			return client
+		})
+
+		const callers = 5
+		clients := make([]*Client, callers)
+		var wg sync.WaitGroup
+		for i := range clients {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				client, err := manager.Acquire(config)
+				if err != nil {
+					t.Errorf("Acquire failed: %v", err)
+					return
+				}
+				clients[i] = client
+			}(i)
+		}
+		wg.Wait()
+
+		if n := atomic.LoadInt32(&dials); n != 1 {
+			t.Errorf("Expected one dial, got %d", n)
+		}
+		for _, client := range clients {
+			if client != clients[0] {
+				t.Fatal("Expected all callers to share the connection")
+			}
+		}
+		for _, client := range clients {
+			manager.Release(client)
+		}
+		if clients[0].IsConnected() {
+			t.Error("Expected connection to close after the last release")
+		}
+	})
+}
+
+func TestPersistentTunnel(t *testing.T) {
+	echo := newEchoServer(t)
+	server := newPasswordServer(t, "secret")
+	config := server.sshConfig(t)
+	config.Password = "secret"
+	listen := freeAddr(t)
+
+	tunnel := NewPersistentTunnel(newTestManager(), config,
+		[]Forward{{Type: ForwardLocal, Listen: listen, Target: echo}}, testReconnectPolicy)
+
+	changes := make(chan TunnelState, 64)
+	tunnel.OnChange(func(h TunnelHealth) {
+		select {
+		case changes <- h.State:
+		default:
+		}
+	})
+
+	tunnel.Start()
+	up := waitForState(t, tunnel, TunnelUp, nil)
+	if len(up.Tunnels) != 1 || up.Tunnels[0].Addr != listen {
+		t.Fatalf("Expected one forward on %s, got %+v", listen, up.Tunnels)
+	}
+
+	conn, err := net.Dial("tcp", listen)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	expectEcho(t, conn)
+	conn.Close()
+
+	t.Run("Reconnects after the connection drops", func(t *testing.T) {
+		server.dropConnections()
+		health := waitForState(t, tunnel, TunnelUp, func(h TunnelHealth) bool {
+			return h.LastError != nil && h.Since.After(up.Since)
+		})
+		if health.Attempts != 0 {
+			t.Errorf("Expected attempts to reset once up, got %d", health.Attempts)
+		}
+
+		conn, err := net.Dial("tcp", listen)
+		if err != nil {
+			t.Fatalf("Dial after reconnect failed: %v", err)
+		}
+		defer conn.Close()
+		expectEcho(t, conn)
+
+		sawReconnecting := false
+		for len(changes) > 0 {
+			if <-changes == TunnelReconnecting {
+				sawReconnecting = true
+			}
+		}
+		if !sawReconnecting {
+			t.Error("Expected a reconnecting state change")
+		}
+	})
+
+	t.Run("Stop closes the forward", func(t *testing.T) {
+		tunnel.Stop()
+		<-tunnel.Done()
+
+		if state := tunnel.Health().State; state != TunnelDown {
+			t.Errorf("Expected down after stop, got %s", state)
+		}
+		if _, err := net.Dial("tcp", listen); err == nil {
+			t.Error("Expected listener to be closed after Stop")
+		}
+	})
+}
+
+func TestPersistentTunnelBackoff(t *testing.T) {
+	// Nothing listens here, so every attempt fails
+	_, port, _ := net.SplitHostPort(freeAddr(t))
+	portNum, _ := strconv.Atoi(port)
+	config := &SSHConfig{Host: "127.0.0.1", Port: portNum, Username: "user", Password: "secret"}
+
+	tunnel := NewPersistentTunnel(newTestManager(), config, nil, testReconnectPolicy)
+	tunnel.Start()
+	defer func() {
+		tunnel.Stop()
+		<-tunnel.Done()
+	}()
+
+	health := waitForState(t, tunnel, TunnelReconnecting, func(h TunnelHealth) bool {
+		return h.Attempts >= 3
+	})
+	if health.LastError == nil {
+		t.Error("Expected the connection error to be reported")
+	}
+	if wait := time.Until(health.NextRetry); wait > testReconnectPolicy.MaxBackoff {
+		t.Errorf("Retry delay %s exceeds the maximum backoff", wait)
+	}
+}
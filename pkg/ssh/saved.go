@@ -0,0 +1,122 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/quocson95/marix/pkg/storage"
+)
+
+// ServerSSHConfig builds the SSH config for a saved server, including its
+// jump host chain. keyPassword decrypts encrypted private keys.
+func ServerSSHConfig(store *storage.Store, server *storage.Server, keyPassword string) (*SSHConfig, error) {
+	config, err := serverSSHConfig(server, keyPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	hops, err := store.JumpChain(server)
+	if err != nil {
+		return nil, err
+	}
+	for _, hop := range hops {
+		hopConfig, err := serverSSHConfig(hop, keyPassword)
+		if err != nil {
+			return nil, fmt.Errorf("jump host %s: %w", hop.Name, err)
+		}
+		config.JumpHosts = append(config.JumpHosts, hopConfig)
+	}
+
+	return config, nil
+}
+
+// NeedsMasterPassword reports whether connecting to server needs to decrypt a key
+func NeedsMasterPassword(store *storage.Store, server *storage.Server) bool {
+	if len(server.PrivateKeyEncrypted) > 0 {
+		return true
+	}
+	hops, _ := store.JumpChain(server)
+	for _, hop := range hops {
+		if len(hop.PrivateKeyEncrypted) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// serverSSHConfig builds the SSH config for a single saved server
+func serverSSHConfig(server *storage.Server, keyPassword string) (*SSHConfig, error) {
+	// Expand tilde in private key path
+	privateKey := server.PrivateKey
+	if len(privateKey) > 0 && privateKey[0] == '~' {
+		home := os.Getenv("HOME")
+		if home != "" {
+			privateKey = home + privateKey[1:]
+		}
+	}
+
+	// Create SSH config from server
+	config := &SSHConfig{
+		Host:         server.Host,
+		Port:         server.Port,
+		Username:     server.Username,
+		Password:     server.Password,
+		KeyPassword:  keyPassword,
+		UseAgent:     server.UseAgent,
+		ForwardAgent: server.ForwardAgent,
+	}
+
+	// Handle encrypted private key
+	if len(server.PrivateKeyEncrypted) > 0 {
+		// Decrypt the private key
+		decrypted, err := storage.DecryptPrivateKey(
+			server.PrivateKeyEncrypted,
+			server.KeyEncryptionSalt,
+			keyPassword,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt private key: %w", err)
+		}
+		// Set the decrypted key content directly
+		config.KeyContent = decrypted
+	} else if privateKey != "" {
+		// Legacy: use file path
+		config.PrivateKey = privateKey
+	}
+
+	return config, nil
+}
+
+// NewProfileTunnel prepares a persistent tunnel for a saved profile. The
+// master password is only needed when the server uses an encrypted key.
+func NewProfileTunnel(manager *Manager, store *storage.Store, profile *storage.TunnelProfile, masterPassword string) (*PersistentTunnel, error) {
+	server, err := store.Get(profile.ServerID)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %w", profile.Name, err)
+	}
+	if len(profile.Forwards) == 0 {
+		return nil, fmt.Errorf("profile %s has no forwards", profile.Name)
+	}
+	if NeedsMasterPassword(store, server) && masterPassword == "" {
+		return nil, fmt.Errorf("profile %s: master password required to decrypt the private key", profile.Name)
+	}
+
+	config, err := ServerSSHConfig(store, server, masterPassword)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %w", profile.Name, err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("profile %s: %w", profile.Name, err)
+	}
+
+	forwards := make([]Forward, 0, len(profile.Forwards))
+	for _, pf := range profile.Forwards {
+		forwards = append(forwards, ForwardFromStorage(pf))
+	}
+	return NewPersistentTunnel(manager, config, forwards, DefaultReconnectPolicy), nil
+}
+
+// ForwardFromStorage converts a saved port forward
+func ForwardFromStorage(pf storage.PortForward) Forward {
+	return Forward{Type: ForwardType(pf.Type), Listen: pf.Listen, Target: pf.Target}
+}
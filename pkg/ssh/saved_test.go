@@ -0,0 +1,72 @@
+package ssh
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/quocson95/marix/pkg/storage"
+)
+
+func TestServerSSHConfig(t *testing.T) {
+	store, err := storage.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, salt, err := storage.EncryptPrivateKey([]byte("-----BEGIN KEY-----"), "master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, srv := range []*storage.Server{
+		{ID: "bastion", Name: "bastion", Host: "bastion.example.com", Port: 22, Username: "ops", PrivateKeyEncrypted: encrypted, KeyEncryptionSalt: salt},
+		{ID: "db", Name: "db", Host: "10.0.1.5", Port: 5022, Username: "postgres", Password: "secret", JumpHosts: []string{"bastion"}},
+	} {
+		if err := store.Add(srv); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db, _ := store.Get("db")
+
+	t.Run("Core Functionality: Jump hosts are chained", func(t *testing.T) {
+		if !NeedsMasterPassword(store, db) {
+			t.Error("Expected the encrypted jump host key to need the master password")
+		}
+		config, err := ServerSSHConfig(store, db, "master")
+		if err != nil {
+			t.Fatalf("ServerSSHConfig failed: %v", err)
+		}
+		if config.Host != "10.0.1.5" || config.Port != 5022 || config.Password != "secret" {
+			t.Errorf("Unexpected config %+v", config)
+		}
+		if len(config.JumpHosts) != 1 || string(config.JumpHosts[0].KeyContent) != "-----BEGIN KEY-----" {
+			t.Errorf("Expected the decrypted bastion key in the jump chain, got %+v", config.JumpHosts)
+		}
+	})
+
+	t.Run("Error Handling: Wrong master password", func(t *testing.T) {
+		if _, err := ServerSSHConfig(store, db, "wrong"); err == nil || !strings.Contains(err.Error(), "jump host bastion") {
+			t.Errorf("Expected a jump host decryption error, got %v", err)
+		}
+	})
+
+	t.Run("Error Handling: Profile tunnels", func(t *testing.T) {
+		manager := NewManager()
+		forwards := []storage.PortForward{{Type: "local", Listen: "127.0.0.1:0", Target: "db:5432"}}
+		for _, tt := range []struct {
+			profile  storage.TunnelProfile
+			password string
+			want     string
+		}{
+			{storage.TunnelProfile{Name: "gone", ServerID: "missing", Forwards: forwards}, "master", "profile gone"},
+			{storage.TunnelProfile{Name: "empty", ServerID: "db"}, "master", "has no forwards"},
+			{storage.TunnelProfile{Name: "locked", ServerID: "db", Forwards: forwards}, "", "master password required"},
+		} {
+			if _, err := NewProfileTunnel(manager, store, &tt.profile, tt.password); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("%s: expected %q, got %v", tt.profile.Name, tt.want, err)
+			}
+		}
+		profile := storage.TunnelProfile{Name: "db", ServerID: "db", Forwards: forwards}
+		if _, err := NewProfileTunnel(manager, store, &profile, "master"); err != nil {
+			t.Errorf("NewProfileTunnel failed: %v", err)
+		}
+	})
+}
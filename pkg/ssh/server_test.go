@@ -0,0 +1,266 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// acceptAllVerifier trusts every host key, for in-process test servers only
+type acceptAllVerifier struct{}
+
+func (acceptAllVerifier) Verify(string, net.Addr, ssh.PublicKey) error { return nil }
+
+// testServer is a minimal in-process SSH server
+type testServer struct {
+	addr   string
+	config *ssh.ServerConfig
+
+	// onSessionRequest is called for every session request; the returned
+	// value is sent as the reply. conn is the server side of the connection.
+	onSessionRequest func(conn *ssh.ServerConn, req *ssh.Request) bool
+
+	// onExec runs exec requests: output goes to ch and ch.Stderr(), the
+	// returned value is sent as the exit status
+	onExec func(command string, ch ssh.Channel) uint32
+
+	// tunnels receives the target of every accepted direct-tcpip channel
+	tunnels chan string
+
+	// silent stops answering global requests, as a peer that vanished would
+	silent atomic.Bool
+
+	mu    sync.Mutex
+	conns []*ssh.ServerConn
+}
+
+func newTestSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+// newTestServer starts a server with the given config on a random port
+func newTestServer(t *testing.T, config *ssh.ServerConfig) *testServer {
+	t.Helper()
+	config.AddHostKey(newTestSigner(t))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &testServer{
+		addr:    listener.Addr().String(),
+		config:  config,
+		tunnels: make(chan string, 16),
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *testServer) handle(netConn net.Conn) {
+	conn, chans, reqs, err := ssh.NewServerConn(netConn, s.config)
+	if err != nil {
+		netConn.Close()
+		return
+	}
+	defer conn.Close()
+	s.mu.Lock()
+	s.conns = append(s.conns, conn)
+	s.mu.Unlock()
+	go s.handleGlobalRequests(conn, reqs)
+
+	for newChan := range chans {
+		if newChan.ChannelType() == "direct-tcpip" {
+			go s.handleDirectTCPIP(newChan)
+			continue
+		}
+		if newChan.ChannelType() != "session" {
+			newChan.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+		ch, chReqs, err := newChan.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			defer ch.Close()
+			for req := range chReqs {
+				if req.Type == "exec" && s.onExec != nil {
+					var payload struct{ Command string }
+					ssh.Unmarshal(req.Payload, &payload)
+					req.Reply(true, nil)
+					status := s.onExec(payload.Command, ch)
+					ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+					return
+				}
+				ok := true
+				if s.onSessionRequest != nil {
+					ok = s.onSessionRequest(conn, req)
+				}
+				if req.WantReply {
+					req.Reply(ok, nil)
+				}
+			}
+		}()
+	}
+}
+
+// dropConnections closes every client connection, as a network outage would
+func (s *testServer) dropConnections() {
+	s.mu.Lock()
+	conns := s.conns
+	s.conns = nil
+	s.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+// handleDirectTCPIP forwards a channel to the requested address, as for ProxyJump
+func (s *testServer) handleDirectTCPIP(newChan ssh.NewChannel) {
+	var payload struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := ssh.Unmarshal(newChan.ExtraData(), &payload); err != nil {
+		newChan.Reject(ssh.ConnectionFailed, "bad payload")
+		return
+	}
+
+	target := net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port)))
+	conn, err := net.Dial("tcp", target)
+	if err != nil {
+		newChan.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	ch, reqs, err := newChan.Accept()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	s.tunnels <- target
+
+	done := make(chan struct{})
+	go func() {
+		io.Copy(ch, conn)
+		ch.CloseWrite()
+		close(done)
+	}()
+	io.Copy(conn, ch)
+	conn.(*net.TCPConn).CloseWrite()
+	<-done
+	conn.Close()
+	ch.Close()
+}
+
+// handleGlobalRequests implements tcpip-forward, as for ssh -R
+func (s *testServer) handleGlobalRequests(conn *ssh.ServerConn, reqs <-chan *ssh.Request) {
+	for req := range reqs {
+		if s.silent.Load() {
+			continue
+		}
+		if req.Type != "tcpip-forward" {
+			if req.WantReply {
+				req.Reply(false, nil)
+			}
+			continue
+		}
+
+		var payload struct {
+			Addr string
+			Port uint32
+		}
+		if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+			req.Reply(false, nil)
+			continue
+		}
+		listener, err := net.Listen("tcp", net.JoinHostPort(payload.Addr, strconv.Itoa(int(payload.Port))))
+		if err != nil {
+			req.Reply(false, nil)
+			continue
+		}
+		go func() {
+			conn.Wait()
+			listener.Close()
+		}()
+
+		port := uint32(listener.Addr().(*net.TCPAddr).Port)
+		req.Reply(true, ssh.Marshal(struct{ Port uint32 }{port}))
+
+		go func() {
+			for {
+				local, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				origin := local.RemoteAddr().(*net.TCPAddr)
+				ch, chReqs, err := conn.OpenChannel("forwarded-tcpip", ssh.Marshal(struct {
+					Addr       string
+					Port       uint32
+					OriginAddr string
+					OriginPort uint32
+				}{payload.Addr, port, origin.IP.String(), uint32(origin.Port)}))
+				if err != nil {
+					local.Close()
+					continue
+				}
+				go ssh.DiscardRequests(chReqs)
+				go func() {
+					io.Copy(ch, local)
+					ch.CloseWrite()
+				}()
+				go func() {
+					io.Copy(local, ch)
+					local.Close()
+					ch.Close()
+				}()
+			}
+		}()
+	}
+}
+
+// sshConfig returns a client config pointing at the server
+func (s *testServer) sshConfig(t *testing.T) *SSHConfig {
+	t.Helper()
+	host, portStr, err := net.SplitHostPort(s.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(portStr)
+	return &SSHConfig{
+		Host:     host,
+		Port:     port,
+		Username: "tester",
+	}
+}
@@ -0,0 +1,133 @@
+package ssh
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// socks5HandshakeTimeout bounds the handshake, so clients that connect and
+// send nothing do not hold a connection open
+var socks5HandshakeTimeout = 30 * time.Second
+
+// SOCKS5 protocol constants (RFC 1928)
+const (
+	socks5Version = 0x05
+
+	socks5NoAuth       = 0x00
+	socks5NoAcceptable = 0xff
+
+	socks5CmdConnect = 0x01
+
+	socks5AddrIPv4   = 0x01
+	socks5AddrDomain = 0x03
+	socks5AddrIPv6   = 0x04
+
+	socks5Succeeded          = 0x00
+	socks5HostUnreachable    = 0x04
+	socks5CommandUnsupported = 0x07
+	socks5AddrUnsupported    = 0x08
+)
+
+// socks5Handshake performs the server side of a SOCKS5 CONNECT handshake on
+// conn and returns the connection dialed to the requested target
+func socks5Handshake(conn net.Conn, dial DialFunc) (net.Conn, error) {
+	conn.SetDeadline(time.Now().Add(socks5HandshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	// Greeting: version, method count, methods
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, fmt.Errorf("socks5 greeting: %w", err)
+	}
+	if header[0] != socks5Version {
+		return nil, fmt.Errorf("unsupported socks version %d", header[0])
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return nil, fmt.Errorf("socks5 greeting: %w", err)
+	}
+
+	noAuth := false
+	for _, method := range methods {
+		if method == socks5NoAuth {
+			noAuth = true
+		}
+	}
+	if !noAuth {
+		conn.Write([]byte{socks5Version, socks5NoAcceptable})
+		return nil, errors.New("socks5 client requires authentication")
+	}
+	if _, err := conn.Write([]byte{socks5Version, socks5NoAuth}); err != nil {
+		return nil, err
+	}
+
+	// Request: version, command, reserved, address
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return nil, fmt.Errorf("socks5 request: %w", err)
+	}
+	if request[1] != socks5CmdConnect {
+		socks5Reply(conn, socks5CommandUnsupported)
+		return nil, fmt.Errorf("unsupported socks5 command %d", request[1])
+	}
+
+	host, err := readSocks5Addr(conn, request[3])
+	if err != nil {
+		socks5Reply(conn, socks5AddrUnsupported)
+		return nil, err
+	}
+	portBytes := make([]byte, 2)
+	if _, err := io.ReadFull(conn, portBytes); err != nil {
+		return nil, fmt.Errorf("socks5 request: %w", err)
+	}
+	port := binary.BigEndian.Uint16(portBytes)
+
+	target, err := dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		socks5Reply(conn, socks5HostUnreachable)
+		return nil, err
+	}
+	if err := socks5Reply(conn, socks5Succeeded); err != nil {
+		target.Close()
+		return nil, err
+	}
+	return target, nil
+}
+
+func readSocks5Addr(conn net.Conn, addrType byte) (string, error) {
+	switch addrType {
+	case socks5AddrIPv4, socks5AddrIPv6:
+		size := net.IPv4len
+		if addrType == socks5AddrIPv6 {
+			size = net.IPv6len
+		}
+		ip := make([]byte, size)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return "", fmt.Errorf("socks5 address: %w", err)
+		}
+		return net.IP(ip).String(), nil
+	case socks5AddrDomain:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return "", fmt.Errorf("socks5 address: %w", err)
+		}
+		domain := make([]byte, length[0])
+		if _, err := io.ReadFull(conn, domain); err != nil {
+			return "", fmt.Errorf("socks5 address: %w", err)
+		}
+		return string(domain), nil
+	default:
+		return "", fmt.Errorf("unsupported socks5 address type %d", addrType)
+	}
+}
+
+// socks5Reply sends a reply; the bound address is not meaningful here
+func socks5Reply(conn net.Conn, status byte) error {
+	_, err := conn.Write([]byte{socks5Version, status, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
@@ -0,0 +1,291 @@
+// Package sshconfig reads host entries from OpenSSH client config files
+package sshconfig
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxIncludeDepth guards against Include loops
+const maxIncludeDepth = 16
+
+// Host is a concrete host alias with every matching block applied
+type Host struct {
+	Alias        string
+	HostName     string
+	User         string
+	Port         int
+	IdentityFile string
+	ProxyJump    []string // Jump specs ([user@]host[:port]), first hop first
+}
+
+// Config is a parsed config file, including anything it Includes
+type Config struct {
+	blocks []block
+}
+
+// block is one Host section; options before the first Host apply to all hosts
+type block struct {
+	patterns []string
+	options  []option
+}
+
+type option struct {
+	key   string // Lower-cased keyword
+	value string
+}
+
+// DefaultPath returns the user's OpenSSH config path (~/.ssh/config)
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".ssh", "config"), nil
+}
+
+// ParseFile parses the config file at path and the files it Includes
+func ParseFile(path string) (*Config, error) {
+	c := &Config{blocks: []block{{patterns: []string{"*"}}}}
+	if err := c.parseFile(path, 0); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Config) parseFile(path string, depth int) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("too many nested includes at %s", path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open ssh config: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		key, args, err := splitLine(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		if key == "" {
+			continue
+		}
+
+		switch key {
+		case "host":
+			c.blocks = append(c.blocks, block{patterns: args})
+		case "match":
+			// Match criteria are not evaluated; its options apply to nothing
+			c.blocks = append(c.blocks, block{})
+		case "include":
+			enclosing := len(c.blocks) - 1
+			for _, pattern := range args {
+				if err := c.include(pattern, depth); err != nil {
+					return fmt.Errorf("%s:%d: %w", path, lineNo, err)
+				}
+			}
+			// Later options belong to the block holding the Include, not to
+			// the last Host of the included files
+			if len(c.blocks)-1 != enclosing {
+				c.blocks = append(c.blocks, block{patterns: c.blocks[enclosing].patterns})
+			}
+		default:
+			if len(args) == 0 {
+				continue
+			}
+			current := &c.blocks[len(c.blocks)-1]
+			current.options = append(current.options, option{key: key, value: strings.Join(args, " ")})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read ssh config: %w", err)
+	}
+	return nil
+}
+
+// include parses every file matching pattern; relative paths are under ~/.ssh
+func (c *Config) include(pattern string, depth int) error {
+	pattern = expandHome(pattern)
+	if !filepath.IsAbs(pattern) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to get home directory: %w", err)
+		}
+		pattern = filepath.Join(home, ".ssh", pattern)
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid include pattern %q: %w", pattern, err)
+	}
+	for _, match := range matches {
+		if err := c.parseFile(match, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Hosts returns every concrete (non-wildcard) host alias, in file order
+func (c *Config) Hosts() []Host {
+	var hosts []Host
+	seen := make(map[string]bool)
+	for _, b := range c.blocks {
+		for _, pattern := range b.patterns {
+			if seen[pattern] || strings.ContainsAny(pattern, "*?!") {
+				continue
+			}
+			seen[pattern] = true
+			hosts = append(hosts, c.Resolve(pattern))
+		}
+	}
+	return hosts
+}
+
+// Resolve applies every block matching alias. As in OpenSSH, the first
+// value found for an option wins.
+func (c *Config) Resolve(alias string) Host {
+	values := make(map[string]string)
+	for _, b := range c.blocks {
+		if !matches(b.patterns, alias) {
+			continue
+		}
+		for _, opt := range b.options {
+			if _, ok := values[opt.key]; !ok {
+				values[opt.key] = opt.value
+			}
+		}
+	}
+
+	host := Host{
+		Alias:    alias,
+		HostName: alias,
+		User:     values["user"],
+		Port:     22,
+	}
+	if hostName := values["hostname"]; hostName != "" {
+		host.HostName = strings.ReplaceAll(hostName, "%h", alias)
+	}
+	if port, err := strconv.Atoi(values["port"]); err == nil && port > 0 {
+		host.Port = port
+	}
+	if identity := values["identityfile"]; identity != "" && !strings.EqualFold(identity, "none") {
+		host.IdentityFile = expandHome(identity)
+	}
+	if jump := values["proxyjump"]; jump != "" && !strings.EqualFold(jump, "none") {
+		for _, hop := range strings.Split(jump, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				host.ProxyJump = append(host.ProxyJump, hop)
+			}
+		}
+	}
+	return host
+}
+
+// matches reports whether alias matches a Host line: at least one pattern
+// matches and no negated pattern does
+func matches(patterns []string, alias string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+		ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(alias))
+		if ok && negated {
+			return false
+		}
+		if ok {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// splitLine returns the lower-cased keyword and arguments of a config line.
+// Keywords may be separated from arguments by whitespace or '='.
+func splitLine(line string) (string, []string, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil, nil
+	}
+
+	end := strings.IndexAny(line, " \t=")
+	if end < 0 {
+		return strings.ToLower(line), nil, nil
+	}
+	key := strings.ToLower(line[:end])
+	rest := strings.TrimLeft(line[end:], " \t")
+	rest = strings.TrimPrefix(rest, "=")
+
+	args, err := splitArgs(rest)
+	if err != nil {
+		return "", nil, err
+	}
+	return key, args, nil
+}
+
+// splitArgs splits on whitespace, honouring double quotes
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inQuotes, hasArg := false, false
+
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			hasArg = true
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if hasArg {
+				args = append(args, current.String())
+				current.Reset()
+				hasArg = false
+			}
+		default:
+			current.WriteRune(r)
+			hasArg = true
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if hasArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// expandHome replaces a leading ~ with the user's home directory
+func expandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, p[1:])
+		}
+	}
+	return p
+}
+
+// SplitJump splits a ProxyJump spec ([user@]host[:port]) into its parts;
+// port is 0 when not given
+func SplitJump(spec string) (user, host string, port int) {
+	host = spec
+	if at := strings.LastIndex(host, "@"); at >= 0 {
+		user, host = host[:at], host[at+1:]
+	}
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		if n, err := strconv.Atoi(p); err == nil {
+			host, port = h, n
+		}
+	}
+	return user, host, port
+}
@@ -0,0 +1,163 @@
+package sshconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseFile(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "work.conf", `
+Host db
+    HostName 10.0.1.5
+    ProxyJump bastion,inner
+`)
+	path := writeConfig(t, dir, "config", `
+# Global defaults
+User deploy
+
+Host bastion
+    HostName bastion.example.com
+    Port 2222
+    IdentityFile "/keys/bastion key"
+
+Host inner
+    HostName=10.0.0.2
+    User admin
+
+Include `+filepath.Join(dir, "*.conf")+`
+
+Host *.internal !skip.internal
+    User internal
+    Port 2200
+
+Host web.internal skip.internal
+
+Host *
+    User fallback
+    IdentityFile /keys/default
+`)
+
+	cfg, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	hosts := make(map[string]Host)
+	var order []string
+	for _, h := range cfg.Hosts() {
+		hosts[h.Alias] = h
+		order = append(order, h.Alias)
+	}
+
+	want := []string{"bastion", "inner", "db", "web.internal", "skip.internal"}
+	if len(order) != len(want) {
+		t.Fatalf("Expected hosts %v, got %v", want, order)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("Expected hosts %v, got %v", want, order)
+		}
+	}
+
+	t.Run("Explicit options and first value wins", func(t *testing.T) {
+		b := hosts["bastion"]
+		if b.HostName != "bastion.example.com" || b.Port != 2222 || b.User != "deploy" {
+			t.Errorf("Unexpected bastion: %+v", b)
+		}
+		if b.IdentityFile != "/keys/bastion key" {
+			t.Errorf("Expected quoted identity file, got %q", b.IdentityFile)
+		}
+	})
+
+	t.Run("Key=value syntax", func(t *testing.T) {
+		if h := hosts["inner"]; h.HostName != "10.0.0.2" || h.User != "deploy" {
+			t.Errorf("Unexpected inner: %+v", h)
+		}
+	})
+
+	t.Run("Included hosts with ProxyJump", func(t *testing.T) {
+		db := hosts["db"]
+		if db.HostName != "10.0.1.5" || len(db.ProxyJump) != 2 || db.ProxyJump[0] != "bastion" || db.ProxyJump[1] != "inner" {
+			t.Errorf("Unexpected db: %+v", db)
+		}
+		if db.IdentityFile != "/keys/default" {
+			t.Errorf("Expected wildcard identity file, got %q", db.IdentityFile)
+		}
+	})
+
+	t.Run("Wildcard defaults and negation", func(t *testing.T) {
+		web := hosts["web.internal"]
+		if web.HostName != "web.internal" || web.Port != 2200 {
+			t.Errorf("Unexpected web.internal: %+v", web)
+		}
+		skip := hosts["skip.internal"]
+		if skip.Port != 22 {
+			t.Errorf("Expected negated pattern to be skipped, got %+v", skip)
+		}
+	})
+}
+
+func TestParseFileOptionsAfterInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "extra.conf", `
+Host extra
+    HostName extra.example.com
+`)
+	path := writeConfig(t, dir, "config", `
+Host app
+    HostName app.example.com
+    Include `+filepath.Join(dir, "extra.conf")+`
+    User deploy
+    Port 2222
+`)
+
+	cfg, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+	if app := cfg.Resolve("app"); app.User != "deploy" || app.Port != 2222 {
+		t.Errorf("Expected the options after Include to apply to app, got %+v", app)
+	}
+	if extra := cfg.Resolve("extra"); extra.User == "deploy" || extra.Port != 22 {
+		t.Errorf("Expected the options after Include not to apply to extra, got %+v", extra)
+	}
+}
+
+func TestParseFileIncludeLoop(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	writeConfig(t, dir, "config", "Include "+path+"\n")
+
+	if _, err := ParseFile(path); err == nil {
+		t.Error("Expected error for recursive include")
+	}
+}
+
+func TestSplitJump(t *testing.T) {
+	tests := []struct {
+		spec, user, host string
+		port             int
+	}{
+		{"bastion", "", "bastion", 0},
+		{"ops@bastion", "ops", "bastion", 0},
+		{"ops@bastion:2222", "ops", "bastion", 2222},
+		{"[::1]:22", "", "::1", 22},
+	}
+	for _, tt := range tests {
+		user, host, port := SplitJump(tt.spec)
+		if user != tt.user || host != tt.host || port != tt.port {
+			t.Errorf("SplitJump(%q) = %q, %q, %d", tt.spec, user, host, port)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package storage
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExportFormat selects the output of Store.Export
+type ExportFormat string
+
+const (
+	ExportSSHConfig ExportFormat = "ssh-config" // OpenSSH client config snippet
+	ExportJSON      ExportFormat = "json"       // Server records, same shape as servers.json
+	ExportCSV       ExportFormat = "csv"        // One row per server
+)
+
+// ExportOptions controls what Store.Export writes
+type ExportOptions struct {
+	Format ExportFormat
+
+	// IncludeSecrets adds passwords and private keys (JSON and CSV only;
+	// ssh_config cannot hold them). Encrypted keys are decrypted when
+	// MasterPassword is set, otherwise JSON keeps them encrypted.
+	IncludeSecrets bool
+	MasterPassword string
+}
+
+// Export writes every saved server in the requested format, sorted by name
+func (s *Store) Export(w io.Writer, opts ExportOptions) error {
+	servers, err := s.exportServers(opts)
+	if err != nil {
+		return err
+	}
+
+	switch opts.Format {
+	case ExportSSHConfig:
+		return writeSSHConfig(w, servers)
+	case ExportJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(servers); err != nil {
+			return fmt.Errorf("failed to encode servers: %w", err)
+		}
+		return nil
+	case ExportCSV:
+		return writeCSV(w, servers, opts.IncludeSecrets)
+	default:
+		return fmt.Errorf("unknown export format: %s", opts.Format)
+	}
+}
+
+// exportServers copies the servers with secrets stripped or decrypted
+func (s *Store) exportServers(opts ExportOptions) ([]*Server, error) {
+	servers := s.List()
+	sort.Slice(servers, func(i, j int) bool {
+		if servers[i].Name != servers[j].Name {
+			return servers[i].Name < servers[j].Name
+		}
+		return servers[i].ID < servers[j].ID
+	})
+
+	exported := make([]*Server, 0, len(servers))
+	for _, srv := range servers {
+		cp := *srv
+		cp.JumpHosts = append([]string(nil), srv.JumpHosts...)
+		cp.Tags = append([]string(nil), srv.Tags...)
+		cp.Forwards = append([]PortForward(nil), srv.Forwards...)
+
+		switch {
+		case !opts.IncludeSecrets:
+			cp.Password = ""
+			if isKeyContent(cp.PrivateKey) {
+				cp.PrivateKey = ""
+			}
+			cp.PrivateKeyEncrypted = nil
+			cp.KeyEncryptionSalt = nil
+
+		case len(cp.PrivateKeyEncrypted) > 0 && opts.MasterPassword != "":
+			key, err := DecryptPrivateKey(cp.PrivateKeyEncrypted, cp.KeyEncryptionSalt, opts.MasterPassword)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt private key for %s: %w", cp.Name, err)
+			}
+			cp.PrivateKey = string(key)
+			cp.PrivateKeyEncrypted = nil
+			cp.KeyEncryptionSalt = nil
+		}
+
+		exported = append(exported, &cp)
+	}
+	return exported, nil
+}
+
+// isKeyContent reports whether PrivateKey holds key material rather than a
+// legacy file path
+func isKeyContent(privateKey string) bool {
+	return strings.HasPrefix(strings.TrimSpace(privateKey), "-----BEGIN")
+}
+
+// hostAlias turns a server name into a usable ssh_config Host alias
+func hostAlias(srv *Server) string {
+	alias := strings.Join(strings.Fields(srv.Name), "-")
+	if alias == "" {
+		return srv.ID
+	}
+	return alias
+}
+
+func writeSSHConfig(w io.Writer, servers []*Server) error {
+	// ssh only uses the first matching Host block, so colliding aliases get
+	// a suffix ("web", "web-2") to keep every server reachable
+	aliases := make(map[string]string, len(servers))
+	taken := make(map[string]bool, len(servers))
+	for _, srv := range servers {
+		base := hostAlias(srv)
+		alias := base
+		for n := 2; taken[strings.ToLower(alias)]; n++ {
+			alias = fmt.Sprintf("%s-%d", base, n)
+		}
+		taken[strings.ToLower(alias)] = true
+		aliases[srv.ID] = alias
+	}
+
+	var b strings.Builder
+	b.WriteString("# Exported from Marix\n")
+	for _, srv := range servers {
+		fmt.Fprintf(&b, "\nHost %s\n", aliases[srv.ID])
+		fmt.Fprintf(&b, "    HostName %s\n", srv.Host)
+		if srv.Port != 0 && srv.Port != 22 {
+			fmt.Fprintf(&b, "    Port %d\n", srv.Port)
+		}
+		if srv.Username != "" {
+			fmt.Fprintf(&b, "    User %s\n", srv.Username)
+		}
+		if srv.PrivateKey != "" && !isKeyContent(srv.PrivateKey) {
+			fmt.Fprintf(&b, "    IdentityFile %s\n", quoteConfigValue(srv.PrivateKey))
+		}
+		if srv.ForwardAgent {
+			b.WriteString("    ForwardAgent yes\n")
+		}
+		for _, pf := range srv.Forwards {
+			switch pf.Type {
+			case "local":
+				fmt.Fprintf(&b, "    LocalForward %s %s\n", pf.Listen, pf.Target)
+			case "remote":
+				fmt.Fprintf(&b, "    RemoteForward %s %s\n", pf.Listen, pf.Target)
+			case "dynamic":
+				fmt.Fprintf(&b, "    DynamicForward %s\n", pf.Listen)
+			}
+		}
+		if len(srv.JumpHosts) > 0 {
+			hops := make([]string, 0, len(srv.JumpHosts))
+			for _, id := range srv.JumpHosts {
+				if alias, ok := aliases[id]; ok {
+					hops = append(hops, alias)
+				}
+			}
+			if len(hops) > 0 {
+				fmt.Fprintf(&b, "    ProxyJump %s\n", strings.Join(hops, ","))
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func quoteConfigValue(v string) string {
+	if strings.ContainsAny(v, " \t") {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+func writeCSV(w io.Writer, servers []*Server, includeSecrets bool) error {
+	names := make(map[string]string, len(servers))
+	for _, srv := range servers {
+		names[srv.ID] = srv.Name
+	}
+
+	header := []string{"name", "host", "port", "username", "protocol", "identity_file", "jump_hosts", "tags", "group", "description"}
+	if includeSecrets {
+		header = append(header, "password", "private_key")
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+
+	for _, srv := range servers {
+		identityFile := ""
+		if !isKeyContent(srv.PrivateKey) {
+			identityFile = srv.PrivateKey
+		}
+
+		hops := make([]string, 0, len(srv.JumpHosts))
+		for _, id := range srv.JumpHosts {
+			if name, ok := names[id]; ok {
+				hops = append(hops, name)
+			}
+		}
+
+		row := []string{
+			srv.Name,
+			srv.Host,
+			strconv.Itoa(srv.Port),
+			srv.Username,
+			srv.Protocol,
+			identityFile,
+			strings.Join(hops, ";"),
+			strings.Join(srv.Tags, ";"),
+			srv.Group,
+			srv.Description,
+		}
+		if includeSecrets {
+			privateKey := ""
+			if isKeyContent(srv.PrivateKey) {
+				privateKey = srv.PrivateKey
+			}
+			row = append(row, srv.Password, privateKey)
+		}
+
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("failed to write csv: %w", err)
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Protocols of a connection history entry
+const (
+	HistorySSH      = "ssh"      // Terminal session in Marix
+	HistorySFTP     = "sftp"     // SFTP browser
+	HistoryExternal = "external" // SSH in an external terminal, its end is not known
+)
+
+// maxHistoryEntries is how many connections are kept, older ones are dropped
+const maxHistoryEntries = 500
+
+// HistoryEntry is a connection attempt
+type HistoryEntry struct {
+	ID         string `json:"id"`
+	ServerID   string `json:"serverId,omitempty"` // Empty for quick connects
+	Name       string `json:"name,omitempty"`
+	Host       string `json:"host"`
+	Port       int    `json:"port"`
+	Username   string `json:"username"`
+	Protocol   string `json:"protocol"`
+	StartedAt  int64  `json:"startedAt"`
+	EndedAt    int64  `json:"endedAt,omitempty"`    // 0 while the session is open, or when its end is not known
+	Error      string `json:"error,omitempty"`      // Why the connection failed
+	Disconnect string `json:"disconnect,omitempty"` // Why an open session was lost
+}
+
+// Failed reports whether the connection could not be made
+func (e *HistoryEntry) Failed() bool {
+	return e.Error != ""
+}
+
+// Duration returns how long the session lasted, 0 when it is unknown
+func (e *HistoryEntry) Duration() time.Duration {
+	if e.EndedAt == 0 || e.EndedAt < e.StartedAt {
+		return 0
+	}
+	return time.Duration(e.EndedAt-e.StartedAt) * time.Second
+}
+
+// HistoryStore records connection attempts, oldest first
+type HistoryStore struct {
+	entries  []*HistoryEntry
+	filePath string
+	mu       sync.RWMutex
+}
+
+// NewHistoryStore creates a new history store
+func NewHistoryStore(dataDir string) (*HistoryStore, error) {
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	store := &HistoryStore{
+		filePath: filepath.Join(dataDir, "history.json"),
+	}
+
+	if err := store.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// load reads the history from disk
+func (s *HistoryStore) load() error {
+	data, err := os.ReadFile(s.filePath)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		return fmt.Errorf("failed to parse history file: %w", err)
+	}
+	sort.SliceStable(s.entries, func(i, j int) bool {
+		return s.entries[i].StartedAt < s.entries[j].StartedAt
+	})
+
+	return nil
+}
+
+// save writes the history to disk
+func (s *HistoryStore) save() error {
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal history: %w", err)
+	}
+
+	return os.WriteFile(s.filePath, data, 0600)
+}
+
+// Add records a connection attempt, filling in its ID and start time when
+// they are empty. The oldest entries are dropped past maxHistoryEntries.
+func (s *HistoryStore) Add(entry *HistoryEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if entry.ID == "" {
+		entry.ID = fmt.Sprintf("conn-%d", now.UnixNano())
+	}
+	if entry.StartedAt == 0 {
+		entry.StartedAt = now.Unix()
+	}
+
+	s.entries = append(s.entries, entry)
+	if extra := len(s.entries) - maxHistoryEntries; extra > 0 {
+		s.entries = append([]*HistoryEntry(nil), s.entries[extra:]...)
+	}
+	return s.save()
+}
+
+// Finish records the end of a session. err is why it was lost, nil when it
+// was closed. Sessions already finished are left alone.
+func (s *HistoryStore) Finish(id string, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range s.entries {
+		if entry.ID != id {
+			continue
+		}
+		if entry.EndedAt != 0 {
+			return nil
+		}
+		entry.EndedAt = time.Now().Unix()
+		if err != nil {
+			entry.Disconnect = err.Error()
+		}
+		return s.save()
+	}
+	return fmt.Errorf("history entry not found: %s", id)
+}
+
+// List returns the history, newest first
+func (s *HistoryStore) List() []*HistoryEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]*HistoryEntry, 0, len(s.entries))
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		entries = append(entries, s.entries[i])
+	}
+	return entries
+}
+
+// Delete removes an entry
+func (s *HistoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, entry := range s.entries {
+		if entry.ID == id {
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+			return s.save()
+		}
+	}
+	return fmt.Errorf("history entry not found: %s", id)
+}
+
+// Clear removes every entry
+func (s *HistoryStore) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = nil
+	return s.save()
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestHistoryStore(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewHistoryStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok := &HistoryEntry{ServerID: "1", Name: "web", Host: "10.0.0.1", Port: 22, Username: "root", Protocol: HistorySSH, StartedAt: 100}
+	failed := &HistoryEntry{Host: "10.0.0.2", Port: 22, Username: "admin", Protocol: HistorySFTP, StartedAt: 200, Error: "connection refused"}
+	for _, entry := range []*HistoryEntry{ok, failed} {
+		if err := store.Add(entry); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if ok.ID == "" || ok.ID == failed.ID {
+		t.Fatalf("Expected distinct IDs, got %q and %q", ok.ID, failed.ID)
+	}
+
+	if err := store.Finish(ok.ID, errors.New("connection reset")); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	ended := ok.EndedAt
+	// A second finish keeps the first end
+	if err := store.Finish(ok.ID, nil); err != nil || ok.EndedAt != ended {
+		t.Errorf("Expected a finished entry to be left alone, got %v, %d", err, ok.EndedAt)
+	}
+	if err := store.Finish("missing", nil); err == nil {
+		t.Error("Expected error finishing a missing entry")
+	}
+
+	// Reload from disk
+	store, err = NewHistoryStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := store.List()
+	if len(entries) != 2 || entries[0].Host != "10.0.0.2" || entries[1].Host != "10.0.0.1" {
+		t.Fatalf("Expected newest first, got %+v", entries)
+	}
+	if !entries[0].Failed() || entries[1].Failed() {
+		t.Errorf("Failed() = %v, %v; want true, false", entries[0].Failed(), entries[1].Failed())
+	}
+	if entries[1].Disconnect != "connection reset" || entries[1].Duration() <= 0 {
+		t.Errorf("End not persisted: %+v", entries[1])
+	}
+	if entries[0].Duration() != 0 {
+		t.Errorf("Expected no duration for an entry that never ended, got %v", entries[0].Duration())
+	}
+
+	if err := store.Delete(entries[0].ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if len(store.List()) != 1 {
+		t.Errorf("Expected 1 entry after Delete, got %d", len(store.List()))
+	}
+	if err := store.Clear(); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if len(store.List()) != 0 {
+		t.Errorf("Expected no entries after Clear, got %d", len(store.List()))
+	}
+}
+
+func TestHistoryStoreLimit(t *testing.T) {
+	store, err := NewHistoryStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxHistoryEntries+5; i++ {
+		if err := store.Add(&HistoryEntry{ID: fmt.Sprint(i), Host: "h", StartedAt: int64(i + 1)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries := store.List()
+	if len(entries) != maxHistoryEntries {
+		t.Fatalf("Expected %d entries, got %d", maxHistoryEntries, len(entries))
+	}
+	if entries[len(entries)-1].ID != "5" {
+		t.Errorf("Expected the oldest entries to be dropped, oldest kept is %s", entries[len(entries)-1].ID)
+	}
+}
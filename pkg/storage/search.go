@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ServerOrder is how a server list is sorted
+type ServerOrder string
+
+const (
+	OrderByName      ServerOrder = ""         // Alphabetical
+	OrderByLastUsed  ServerOrder = "recent"   // Most recently connected first
+	OrderByFrequency ServerOrder = "frequent" // Most connected first
+)
+
+// ServerOrders lists every order, in the order they are cycled through
+var ServerOrders = []ServerOrder{OrderByName, OrderByLastUsed, OrderByFrequency}
+
+// String returns the order as shown to the user
+func (o ServerOrder) String() string {
+	switch o {
+	case OrderByLastUsed:
+		return "last used"
+	case OrderByFrequency:
+		return "most used"
+	}
+	return "name"
+}
+
+// SortServers sorts servers in place by order, then by name
+func SortServers(servers []*Server, order ServerOrder) {
+	sort.SliceStable(servers, func(i, j int) bool {
+		a, b := servers[i], servers[j]
+		switch {
+		case order == OrderByLastUsed && a.LastConnectedAt != b.LastConnectedAt:
+			return a.LastConnectedAt > b.LastConnectedAt
+		case order == OrderByFrequency && a.ConnectCount != b.ConnectCount:
+			return a.ConnectCount > b.ConnectCount
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+}
+
+// Bonuses of a fuzzy match, on top of a point per matched character
+const (
+	fuzzyConsecutive = 5 // Character right after the previous match
+	fuzzyWordStart   = 8 // Character starting a word, as in "db" for "data-backup"
+	fuzzyPrefix      = 5 // Match starting at the first character
+	fuzzyMaxGap      = 3 // Most points lost to a gap between matches
+)
+
+// FuzzyScore reports whether the characters of query appear in text in
+// order, ignoring case, and scores the match: consecutive characters, word
+// starts and prefixes score higher, gaps lower. An empty query matches
+// everything with a score of 0.
+func FuzzyScore(query, text string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	t := []rune(strings.ToLower(text))
+	if len(q) == 0 {
+		return 0, true
+	}
+
+	best, found := 0, false
+	// Try every place the match could start, greedily matching the rest
+	for start := range t {
+		if t[start] != q[0] {
+			continue
+		}
+		score, qi, last := 0, 0, -1
+		for ti := start; ti < len(t) && qi < len(q); ti++ {
+			if t[ti] != q[qi] {
+				continue
+			}
+			score++
+			switch {
+			case ti == 0:
+				score += fuzzyPrefix + fuzzyWordStart
+			case !unicode.IsLetter(t[ti-1]) && !unicode.IsDigit(t[ti-1]):
+				score += fuzzyWordStart
+			}
+			if last >= 0 {
+				if ti == last+1 {
+					score += fuzzyConsecutive
+				} else {
+					score -= min(ti-last-1, fuzzyMaxGap)
+				}
+			}
+			last = ti
+			qi++
+		}
+		if qi == len(q) && (!found || score > best) {
+			best, found = score, true
+		}
+	}
+	return best, found
+}
+
+// Weights of the fields searched by SearchScore, added to a match's score
+const (
+	searchName  = 20
+	searchHost  = 10
+	searchOther = 0
+)
+
+// SearchScore fuzzy-matches query against the server's name, host,
+// username, tags, group and description. Every word of the query has to
+// match one of them; the score adds up each word's best match, with matches
+// on the name and host ranked higher.
+func (s *Server) SearchScore(query string) (int, bool) {
+	type field struct {
+		text   string
+		weight int
+	}
+	fields := []field{{s.Name, searchName}, {s.Host, searchHost}, {s.Username, searchOther},
+		{s.Group, searchOther}, {s.Description, searchOther}}
+	for _, tag := range s.Tags {
+		fields = append(fields, field{tag, searchOther})
+	}
+
+	total := 0
+	for _, word := range strings.Fields(query) {
+		best, found := 0, false
+		for _, f := range fields {
+			if score, ok := FuzzyScore(word, f.text); ok && (!found || score+f.weight > best) {
+				best, found = score+f.weight, true
+			}
+		}
+		if !found {
+			return 0, false
+		}
+		total += best
+	}
+	return total, true
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		query, text string
+		match       bool
+	}{
+		{"", "anything", true},
+		{"web", "web-01", true},
+		{"WEB", "web-01", true},
+		{"w01", "web-01", true},
+		{"pdb", "prod-db", true},
+		{"bew", "web", false},
+		{"webs", "web", false},
+	}
+	for _, tt := range tests {
+		if _, ok := FuzzyScore(tt.query, tt.text); ok != tt.match {
+			t.Errorf("FuzzyScore(%q, %q) matched = %v, want %v", tt.query, tt.text, ok, tt.match)
+		}
+	}
+
+	// Tighter matches rank higher
+	ranked := []string{"db", "db-primary", "prod-db", "dashboard"}
+	last := -1
+	for i, text := range ranked {
+		score, ok := FuzzyScore("db", text)
+		if !ok {
+			t.Fatalf("FuzzyScore(db, %q) did not match", text)
+		}
+		if i > 0 && score > last {
+			t.Errorf("FuzzyScore(db, %q) = %d, ranks above %q (%d)", text, score, ranked[i-1], last)
+		}
+		last = score
+	}
+	if exact, _ := FuzzyScore("db", "db"); exact <= last {
+		t.Errorf("Expected an exact match to beat a scattered one")
+	}
+}
+
+func TestServerSearchScore(t *testing.T) {
+	srv := &Server{
+		Name:        "billing-api",
+		Host:        "10.0.4.12",
+		Username:    "deploy",
+		Tags:        []string{"prod", "payments"},
+		Group:       "prod/eu",
+		Description: "Invoices and refunds",
+	}
+
+	for _, query := range []string{"bill", "10.0.4", "deploy", "payments", "eu", "refunds", "bill prod", "api inv"} {
+		if _, ok := srv.SearchScore(query); !ok {
+			t.Errorf("SearchScore(%q) did not match", query)
+		}
+	}
+	for _, query := range []string{"staging", "bill staging"} {
+		if _, ok := srv.SearchScore(query); ok {
+			t.Errorf("SearchScore(%q) matched", query)
+		}
+	}
+
+	// Name matches rank above matches in other fields
+	byName, _ := (&Server{Name: "mail"}).SearchScore("mail")
+	byDescription, _ := (&Server{Name: "relay", Description: "mail"}).SearchScore("mail")
+	if byName <= byDescription {
+		t.Errorf("Expected name match (%d) to beat description match (%d)", byName, byDescription)
+	}
+}
+
+func TestSortServers(t *testing.T) {
+	servers := func() []*Server {
+		return []*Server{
+			{Name: "charlie", LastConnectedAt: 300, ConnectCount: 1},
+			{Name: "Alpha", LastConnectedAt: 100, ConnectCount: 5},
+			{Name: "bravo", LastConnectedAt: 200, ConnectCount: 5},
+			{Name: "delta"},
+		}
+	}
+	names := func(servers []*Server) string {
+		var out []string
+		for _, srv := range servers {
+			out = append(out, srv.Name)
+		}
+		return strings.Join(out, ",")
+	}
+
+	for order, want := range map[ServerOrder]string{
+		OrderByName:      "Alpha,bravo,charlie,delta",
+		OrderByLastUsed:  "charlie,bravo,Alpha,delta",
+		OrderByFrequency: "Alpha,bravo,charlie,delta",
+	} {
+		list := servers()
+		SortServers(list, order)
+		if got := names(list); got != want {
+			t.Errorf("SortServers(%s) = %s, want %s", order, got, want)
+		}
+	}
+}
@@ -2,28 +2,120 @@ package storage
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 )
 
+// ErrServerNotFound is returned when no saved server matches a lookup
+var ErrServerNotFound = errors.New("server not found")
+
 // Server represents a saved SSH server configuration
 type Server struct {
-	ID                  string   `json:"id"`
-	Name                string   `json:"name"`
-	Host                string   `json:"host"`
-	Port                int      `json:"port"`
-	Username            string   `json:"username"`
-	Password            string   `json:"password,omitempty"`
-	PrivateKey          string   `json:"privateKey,omitempty"`          // Deprecated: file path, kept for backward compatibility
-	PrivateKeyEncrypted []byte   `json:"privateKeyEncrypted,omitempty"` // Encrypted private key content
-	KeyEncryptionSalt   []byte   `json:"keyEncryptionSalt,omitempty"`   // Salt for key encryption
-	Protocol            string   `json:"protocol"`                      // ssh, sftp, ftp, rdp
-	Tags                []string `json:"tags,omitempty"`
-	Description         string   `json:"description,omitempty"`
-	CreatedAt           int64    `json:"createdAt"`
-	UpdatedAt           int64    `json:"updatedAt"`
+	ID                  string         `json:"id"`
+	Name                string         `json:"name"`
+	Host                string         `json:"host"`
+	Port                int            `json:"port"`
+	Username            string         `json:"username"`
+	Password            string         `json:"password,omitempty"`
+	PrivateKey          string         `json:"privateKey,omitempty"`          // Deprecated: file path, kept for backward compatibility
+	PrivateKeyEncrypted []byte         `json:"privateKeyEncrypted,omitempty"` // Encrypted private key content
+	KeyEncryptionSalt   []byte         `json:"keyEncryptionSalt,omitempty"`   // Salt for key encryption
+	Protocol            string         `json:"protocol"`                      // ssh, sftp, ftp, rdp
+	UseAgent            bool           `json:"useAgent,omitempty"`            // Authenticate with ssh-agent keys
+	ForwardAgent        bool           `json:"forwardAgent,omitempty"`        // Forward ssh-agent to shell sessions
+	JumpHosts           []string       `json:"jumpHosts,omitempty"`           // IDs of saved servers to tunnel through, first hop first
+	Forwards            []PortForward  `json:"forwards,omitempty"`            // Port forwards started on connect
+	FilterPresets       []FilterPreset `json:"filterPresets,omitempty"`       // Saved include/exclude rules for directory transfers
+	Tags                []string       `json:"tags,omitempty"`
+	Group               string         `json:"group,omitempty"` // Folder path such as "prod/eu/db", empty at the top level
+	Description         string         `json:"description,omitempty"`
+	Favorite            bool           `json:"favorite,omitempty"`        // Pinned to the top of the server list
+	LastConnectedAt     int64          `json:"lastConnectedAt,omitempty"` // Unix time of the last connection
+	ConnectCount        int            `json:"connectCount,omitempty"`    // Number of connections made
+	CreatedAt           int64          `json:"createdAt"`
+	UpdatedAt           int64          `json:"updatedAt"`
+}
+
+// GroupSelectorPrefix marks a selector that picks servers by group instead
+// of by tag, e.g. "group:prod/eu"
+const GroupSelectorPrefix = "group:"
+
+// NormalizeGroup cleans a group path: surrounding spaces and empty segments
+// are dropped, so " /prod//eu/ " becomes "prod/eu"
+func NormalizeGroup(group string) string {
+	var parts []string
+	for _, part := range strings.Split(group, "/") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// ParentGroup returns the group containing group, empty at the top level
+func ParentGroup(group string) string {
+	if i := strings.LastIndex(group, "/"); i >= 0 {
+		return group[:i]
+	}
+	return ""
+}
+
+// HasTag reports whether the server has tag, ignoring case
+func (s *Server) HasTag(tag string) bool {
+	for _, t := range s.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// InGroup reports whether the server is in group or one of its subgroups.
+// Every server is in the empty (top level) group.
+func (s *Server) InGroup(group string) bool {
+	group = NormalizeGroup(group)
+	return group == "" || s.Group == group || strings.HasPrefix(s.Group, group+"/")
+}
+
+// Matches reports whether the server is picked by selector: a tag, or a
+// group prefixed with GroupSelectorPrefix
+func (s *Server) Matches(selector string) bool {
+	if group, ok := strings.CutPrefix(selector, GroupSelectorPrefix); ok {
+		return s.InGroup(group)
+	}
+	return s.HasTag(selector)
+}
+
+// PortForward is a saved port forward, started whenever the server is connected
+type PortForward struct {
+	Type   string `json:"type"`             // local, remote or dynamic
+	Listen string `json:"listen"`           // host:port to listen on
+	Target string `json:"target,omitempty"` // host:port to connect to (not for dynamic)
+}
+
+// FilterPreset is a saved set of include/exclude patterns for directory
+// transfers to and from a server
+type FilterPreset struct {
+	Name        string   `json:"name"`
+	Include     []string `json:"include,omitempty"`
+	Exclude     []string `json:"exclude,omitempty"`
+	IgnoreFiles bool     `json:"ignoreFiles,omitempty"` // Honour .gitignore and .marixignore files
+}
+
+// FilterPreset returns the server's preset called name, ignoring case
+func (s *Server) FilterPreset(name string) (FilterPreset, bool) {
+	for _, preset := range s.FilterPresets {
+		if strings.EqualFold(preset.Name, name) {
+			return preset, true
+		}
+	}
+	return FilterPreset{}, false
 }
 
 // Store manages server configurations
@@ -131,12 +223,29 @@ func (s *Store) Get(id string) (*Server, error) {
 
 	server, exists := s.servers[id]
 	if !exists {
-		return nil, fmt.Errorf("server not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrServerNotFound, id)
 	}
 
 	return server, nil
 }
 
+// Find retrieves a server by ID or case-insensitive name
+func (s *Store) Find(nameOrID string) (*Server, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if server, exists := s.servers[nameOrID]; exists {
+		return server, nil
+	}
+	for _, server := range s.servers {
+		if strings.EqualFold(server.Name, nameOrID) {
+			return server, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrServerNotFound, nameOrID)
+}
+
 // List returns all servers
 func (s *Store) List() []*Server {
 	s.mu.RLock()
@@ -156,7 +265,7 @@ func (s *Store) Update(server *Server) error {
 	defer s.mu.Unlock()
 
 	if _, exists := s.servers[server.ID]; !exists {
-		return fmt.Errorf("server not found: %s", server.ID)
+		return fmt.Errorf("%w: %s", ErrServerNotFound, server.ID)
 	}
 
 	s.servers[server.ID] = server
@@ -169,9 +278,177 @@ func (s *Store) Delete(id string) error {
 	defer s.mu.Unlock()
 
 	if _, exists := s.servers[id]; !exists {
-		return fmt.Errorf("server not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrServerNotFound, id)
 	}
 
 	delete(s.servers, id)
 	return s.save()
 }
+
+// MarkConnected records a connection to the server, for sorting by last use
+// and frequency
+func (s *Store) MarkConnected(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	server, ok := s.servers[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrServerNotFound, id)
+	}
+	// Servers handed out by Get and List are read without the lock, so the
+	// entry is replaced rather than changed
+	updated := *server
+	updated.LastConnectedAt = time.Now().Unix()
+	updated.ConnectCount++
+	s.servers[id] = &updated
+	return s.save()
+}
+
+// SaveFilterPreset adds a filter preset to a server, replacing the one with
+// the same name
+func (s *Store) SaveFilterPreset(id string, preset FilterPreset) error {
+	preset.Name = strings.TrimSpace(preset.Name)
+	if preset.Name == "" {
+		return fmt.Errorf("preset name is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	server, ok := s.servers[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrServerNotFound, id)
+	}
+	// The presets are copied, as MarkConnected copies the server
+	presets := make([]FilterPreset, 0, len(server.FilterPresets)+1)
+	replaced := false
+	for _, existing := range server.FilterPresets {
+		if strings.EqualFold(existing.Name, preset.Name) {
+			existing, replaced = preset, true
+		}
+		presets = append(presets, existing)
+	}
+	if !replaced {
+		presets = append(presets, preset)
+	}
+	updated := *server
+	updated.FilterPresets = presets
+	s.servers[id] = &updated
+	return s.save()
+}
+
+// DeleteFilterPreset removes a server's filter preset called name
+func (s *Store) DeleteFilterPreset(id, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	server, ok := s.servers[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrServerNotFound, id)
+	}
+	presets := make([]FilterPreset, 0, len(server.FilterPresets))
+	for _, preset := range server.FilterPresets {
+		if !strings.EqualFold(preset.Name, name) {
+			presets = append(presets, preset)
+		}
+	}
+	if len(presets) == len(server.FilterPresets) {
+		return fmt.Errorf("no filter preset named %q", name)
+	}
+	updated := *server
+	updated.FilterPresets = presets
+	s.servers[id] = &updated
+	return s.save()
+}
+
+// Groups returns every group in use, including the parents of nested groups, sorted
+func (s *Store) Groups() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	for _, server := range s.servers {
+		for group := server.Group; group != "" && !seen[group]; group = ParentGroup(group) {
+			seen[group] = true
+		}
+	}
+
+	groups := make([]string, 0, len(seen))
+	for group := range seen {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// MoveGroup renames group from to to, moving its subgroups and servers
+// along: with from "prod/eu", a server in "prod/eu/db" ends up in "to/db".
+// It returns the number of servers moved.
+func (s *Store) MoveGroup(from, to string) (int, error) {
+	from, to = NormalizeGroup(from), NormalizeGroup(to)
+	if from == "" {
+		return 0, fmt.Errorf("cannot move the top level")
+	}
+	if to == from {
+		return 0, nil
+	}
+	if strings.HasPrefix(to, from+"/") {
+		return 0, fmt.Errorf("cannot move group %s into itself", from)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	moved := 0
+	for id, server := range s.servers {
+		if server.Group != from && !strings.HasPrefix(server.Group, from+"/") {
+			continue
+		}
+		updated := *server
+		updated.Group = NormalizeGroup(to + strings.TrimPrefix(server.Group, from))
+		s.servers[id] = &updated
+		moved++
+	}
+	if moved == 0 {
+		return 0, fmt.Errorf("group not found: %s", from)
+	}
+
+	return moved, s.save()
+}
+
+// JumpChain resolves a server's jump hosts to saved servers, first hop first.
+// Hops that have jump hosts of their own are expanded in place, so the chain
+// is the full route to the server.
+func (s *Store) JumpChain(server *Server) ([]*Server, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := map[string]bool{server.ID: true}
+	return s.appendJumpChain(make([]*Server, 0, len(server.JumpHosts)), server, seen)
+}
+
+// appendJumpChain appends the route to server onto chain. seen holds every
+// server already on the route and is shared across the whole walk to catch
+// loops. The caller must hold s.mu.
+func (s *Store) appendJumpChain(chain []*Server, server *Server, seen map[string]bool) ([]*Server, error) {
+	for _, id := range server.JumpHosts {
+		if seen[id] {
+			return nil, fmt.Errorf("jump host loop via server: %s", id)
+		}
+		seen[id] = true
+
+		hop, exists := s.servers[id]
+		if !exists {
+			return nil, fmt.Errorf("jump host not found: %s", id)
+		}
+
+		// Reach the hop through its own jump hosts first
+		var err error
+		if chain, err = s.appendJumpChain(chain, hop, seen); err != nil {
+			return nil, err
+		}
+		chain = append(chain, hop)
+	}
+
+	return chain, nil
+}
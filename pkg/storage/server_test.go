@@ -1,8 +1,10 @@
 package storage
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -77,6 +79,225 @@ func TestServerStoreCRUD(t *testing.T) {
 	}
 }
 
+func TestServerFind(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, srv := range []*Server{{ID: "1", Name: "Web"}, {ID: "2", Name: "DB Primary"}} {
+		if err := store.Add(srv); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for key, want := range map[string]string{"1": "1", "2": "2", "web": "1", "db primary": "2", "DB PRIMARY": "2"} {
+		srv, err := store.Find(key)
+		if err != nil || srv.ID != want {
+			t.Errorf("Find(%q) = %+v, %v; want ID %s", key, srv, err, want)
+		}
+	}
+
+	if _, err := store.Find("missing"); !errors.Is(err, ErrServerNotFound) {
+		t.Errorf("Expected ErrServerNotFound, got %v", err)
+	}
+}
+
+func TestServerMarkConnected(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add(&Server{ID: "1", Name: "Web"}); err != nil {
+		t.Fatal(err)
+	}
+
+	held, _ := store.Get("1")
+	before := time.Now().Unix()
+	for i := 0; i < 2; i++ {
+		if err := store.MarkConnected("1"); err != nil {
+			t.Fatalf("MarkConnected failed: %v", err)
+		}
+	}
+
+	// Servers already handed out are not changed under their readers
+	if held.ConnectCount != 0 {
+		t.Errorf("Expected the server read before to stay unchanged, got %d connections", held.ConnectCount)
+	}
+
+	// Usage survives a reload
+	reloaded, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := reloaded.Get("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv.ConnectCount != 2 || srv.LastConnectedAt < before {
+		t.Errorf("Expected 2 connections since %d, got %d at %d", before, srv.ConnectCount, srv.LastConnectedAt)
+	}
+
+	if err := store.MarkConnected("missing"); !errors.Is(err, ErrServerNotFound) {
+		t.Errorf("Expected ErrServerNotFound, got %v", err)
+	}
+}
+
+func TestServerFilterPresets(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add(&Server{ID: "1", Name: "Web"}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Core Functionality: Save, replace and reload", func(t *testing.T) {
+		if err := store.SaveFilterPreset("1", FilterPreset{Name: "node", Exclude: []string{"node_modules/"}}); err != nil {
+			t.Fatalf("SaveFilterPreset failed: %v", err)
+		}
+		if err := store.SaveFilterPreset("1", FilterPreset{Name: " Node ", Exclude: []string{"node_modules/", ".git/"}, IgnoreFiles: true}); err != nil {
+			t.Fatalf("SaveFilterPreset failed: %v", err)
+		}
+
+		reloaded, err := NewStore(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv, err := reloaded.Get("1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(srv.FilterPresets) != 1 {
+			t.Fatalf("Expected the preset to be replaced, got %+v", srv.FilterPresets)
+		}
+		preset, ok := srv.FilterPreset("NODE")
+		if !ok || preset.Name != "Node" || len(preset.Exclude) != 2 || !preset.IgnoreFiles {
+			t.Errorf("Unexpected preset %+v", preset)
+		}
+	})
+
+	t.Run("Core Functionality: Delete", func(t *testing.T) {
+		held, _ := store.Get("1")
+		if err := store.DeleteFilterPreset("1", "node"); err != nil {
+			t.Fatalf("DeleteFilterPreset failed: %v", err)
+		}
+		srv, _ := store.Get("1")
+		if _, ok := srv.FilterPreset("node"); ok {
+			t.Error("Expected the preset to be deleted")
+		}
+		if _, ok := held.FilterPreset("node"); !ok {
+			t.Error("Expected the server read before to keep its presets")
+		}
+	})
+
+	t.Run("Error Handling: Bad input", func(t *testing.T) {
+		if err := store.SaveFilterPreset("1", FilterPreset{Name: "  "}); err == nil {
+			t.Error("Expected an error for an unnamed preset")
+		}
+		if err := store.SaveFilterPreset("missing", FilterPreset{Name: "x"}); !errors.Is(err, ErrServerNotFound) {
+			t.Errorf("Expected ErrServerNotFound, got %v", err)
+		}
+		if err := store.DeleteFilterPreset("1", "missing"); err == nil {
+			t.Error("Expected an error for an unknown preset")
+		}
+	})
+}
+
+func TestNormalizeGroup(t *testing.T) {
+	tests := map[string]string{
+		"":               "",
+		"prod":           "prod",
+		" /prod//eu/ ":   "prod/eu",
+		"prod / eu / db": "prod/eu/db",
+		"///":            "",
+	}
+	for in, want := range tests {
+		if got := NormalizeGroup(in); got != want {
+			t.Errorf("NormalizeGroup(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	if got := ParentGroup("prod/eu/db"); got != "prod/eu" {
+		t.Errorf("ParentGroup = %q, want prod/eu", got)
+	}
+	if got := ParentGroup("prod"); got != "" {
+		t.Errorf("ParentGroup = %q, want the top level", got)
+	}
+}
+
+func TestServerMatches(t *testing.T) {
+	server := &Server{Name: "db", Group: "prod/eu", Tags: []string{"Postgres"}}
+
+	for selector, want := range map[string]bool{
+		"postgres":         true,
+		"prod":             false, // A tag, not the group
+		"group:prod":       true,
+		"group:prod/eu":    true,
+		"group:/prod/eu/":  true,
+		"group:prod/e":     false,
+		"group:prod/eu/db": false,
+		"group:":           true,
+	} {
+		if got := server.Matches(selector); got != want {
+			t.Errorf("Matches(%q) = %v, want %v", selector, got, want)
+		}
+	}
+}
+
+func TestServerGroups(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, srv := range []*Server{
+		{ID: "1", Name: "web", Group: "prod/eu"},
+		{ID: "2", Name: "db", Group: "prod/eu/db"},
+		{ID: "3", Name: "us", Group: "prod/us"},
+		{ID: "4", Name: "laptop"},
+	} {
+		if err := store.Add(srv); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := strings.Join(store.Groups(), ","); got != "prod,prod/eu,prod/eu/db,prod/us" {
+		t.Errorf("Groups() = %s", got)
+	}
+
+	held, _ := store.Get("1")
+	moved, err := store.MoveGroup("prod/eu", "archive/eu")
+	if err != nil || moved != 2 {
+		t.Fatalf("MoveGroup = %d, %v; want 2 servers moved", moved, err)
+	}
+	for id, want := range map[string]string{"1": "archive/eu", "2": "archive/eu/db", "3": "prod/us", "4": ""} {
+		if srv, _ := store.Get(id); srv.Group != want {
+			t.Errorf("Server %s in %q, want %q", id, srv.Group, want)
+		}
+	}
+
+	if held.Group != "prod/eu" {
+		t.Errorf("Expected the server read before to stay in prod/eu, got %q", held.Group)
+	}
+
+	// Moves are saved
+	reloaded, err := NewStore(filepath.Dir(store.filePath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv, _ := reloaded.Get("2"); srv.Group != "archive/eu/db" {
+		t.Errorf("Move not persisted, group %q", srv.Group)
+	}
+
+	if _, err := store.MoveGroup("archive", "archive/old"); err == nil {
+		t.Error("Expected error moving a group into itself")
+	}
+	if _, err := store.MoveGroup("missing", "other"); err == nil {
+		t.Error("Expected error moving an unknown group")
+	}
+}
+
 func TestServerPersistence(t *testing.T) {
 	tempDir, err := os.MkdirTemp("", "marix-server-persist-test")
 	if err != nil {
@@ -148,3 +369,88 @@ func TestCorruptedFileHandling(t *testing.T) {
 		t.Error("Backup file wasn't created")
 	}
 }
+
+func TestServerJumpChain(t *testing.T) {
+	tempDir := t.TempDir()
+
+	store, err := NewStore(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, srv := range []*Server{
+		{ID: "bastion", Name: "Bastion", Host: "bastion.example.com", Port: 22, Username: "user"},
+		{ID: "inner", Name: "Inner", Host: "10.0.0.2", Port: 22, Username: "user"},
+		{ID: "db", Name: "DB", Host: "10.0.1.5", Port: 22, Username: "user", JumpHosts: []string{"bastion", "inner"}},
+	} {
+		if err := store.Add(srv); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, _ := store.Get("db")
+	chain, err := store.JumpChain(db)
+	if err != nil {
+		t.Fatalf("JumpChain failed: %v", err)
+	}
+	if len(chain) != 2 || chain[0].ID != "bastion" || chain[1].ID != "inner" {
+		t.Errorf("Expected chain [bastion inner], got %v", chain)
+	}
+
+	// Jump hosts survive a reload
+	reloaded, err := NewStore(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, _ = reloaded.Get("db")
+	if len(db.JumpHosts) != 2 {
+		t.Errorf("Expected 2 jump hosts after reload, got %v", db.JumpHosts)
+	}
+
+	// Missing and looping references are errors
+	db.JumpHosts = []string{"bastion", "gone"}
+	if _, err := reloaded.JumpChain(db); err == nil {
+		t.Error("Expected error for missing jump host")
+	}
+	db.JumpHosts = []string{"bastion", "db"}
+	if _, err := reloaded.JumpChain(db); err == nil {
+		t.Error("Expected error for server jumping through itself")
+	}
+}
+
+func TestServerJumpChainNested(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, srv := range []*Server{
+		{ID: "edge", Name: "Edge", Host: "edge.example.com", Port: 22, Username: "user"},
+		{ID: "bastion", Name: "Bastion", Host: "10.0.0.2", Port: 22, Username: "user", JumpHosts: []string{"edge"}},
+		{ID: "db", Name: "DB", Host: "10.0.1.5", Port: 22, Username: "user", JumpHosts: []string{"bastion"}},
+		{ID: "a", Name: "A", Host: "10.0.2.1", Port: 22, Username: "user", JumpHosts: []string{"b"}},
+		{ID: "b", Name: "B", Host: "10.0.2.2", Port: 22, Username: "user", JumpHosts: []string{"a"}},
+	} {
+		if err := store.Add(srv); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("Core Functionality: Expands a hop's own jump hosts", func(t *testing.T) {
+		db, _ := store.Get("db")
+		chain, err := store.JumpChain(db)
+		if err != nil {
+			t.Fatalf("JumpChain failed: %v", err)
+		}
+		if len(chain) != 2 || chain[0].ID != "edge" || chain[1].ID != "bastion" {
+			t.Errorf("Expected chain [edge bastion], got %v", chain)
+		}
+	})
+
+	t.Run("Error Handling: Detects a loop through a hop's jump hosts", func(t *testing.T) {
+		a, _ := store.Get("a")
+		if _, err := store.JumpChain(a); err == nil {
+			t.Error("Expected error for A -> B -> A loop")
+		}
+	})
+}
@@ -12,17 +12,26 @@ import (
 
 // Settings represents application settings
 type Settings struct {
-	DefaultPort        int    `json:"defaultPort"`
-	DefaultUsername    string `json:"defaultUsername"`
-	Theme              string `json:"theme"`
-	TerminalFont       string `json:"terminalFont"`
-	AutoSave           bool   `json:"autoSave"`
-	MasterPasswordHash string `json:"masterPasswordHash,omitempty"` // Bcrypt hash of master password
-	S3Host             string `json:"s3Host,omitempty"`             // S3 Endpoint
-	S3AccessKey        string `json:"s3AccessKey,omitempty"`        // S3 Access Key
-	S3SecretKey        string `json:"s3SecretKey,omitempty"`        // S3 Secret Key
-	AutoBackup         bool   `json:"autoBackup"`                   // Automatically backup on server add/delete
-	DisableRsync       bool   `json:"disableRsync"`                 // Disable rsync engine
+	DefaultPort        int         `json:"defaultPort"`
+	DefaultUsername    string      `json:"defaultUsername"`
+	Theme              string      `json:"theme"`
+	TerminalFont       string      `json:"terminalFont"`
+	AutoSave           bool        `json:"autoSave"`
+	MasterPasswordHash string      `json:"masterPasswordHash,omitempty"` // Bcrypt hash of master password
+	S3Host             string      `json:"s3Host,omitempty"`             // S3 Endpoint
+	S3AccessKey        string      `json:"s3AccessKey,omitempty"`        // S3 Access Key
+	S3SecretKey        string      `json:"s3SecretKey,omitempty"`        // S3 Secret Key
+	AutoBackup         bool        `json:"autoBackup"`                   // Automatically backup on server add/delete
+	DisableRsync       bool        `json:"disableRsync"`                 // Disable rsync engine
+	RecordSessions     bool        `json:"recordSessions"`               // Record every terminal session
+	KeepAliveInterval  int         `json:"keepAliveInterval"`            // Seconds between SSH keepalives, 0 to disable
+	KeepAliveCountMax  int         `json:"keepAliveCountMax"`            // Unanswered keepalives before a session is dropped
+	AutoReconnect      bool        `json:"autoReconnect"`                // Reconnect dropped terminal sessions
+	CollapsedGroups    []string    `json:"collapsedGroups,omitempty"`    // Server list folders shown closed
+	ServerOrder        ServerOrder `json:"serverOrder,omitempty"`        // Order of the server list
+	TransferRetries    int         `json:"transferRetries"`              // Times a failed file transfer is retried, resuming it
+	ContinueOnError    bool        `json:"continueOnError"`              // Keep transferring the other files when one fails
+	VerifyTransfers    bool        `json:"verifyTransfers"`              // Compare SHA-256 checksums after each file transfer
 }
 
 // SettingsStore manages application settings
@@ -69,6 +78,11 @@ func getDefaultSettings() Settings {
 		MasterPasswordHash: "", // Empty means no encryption by default
 		AutoBackup:         false,
 		DisableRsync:       false,
+		KeepAliveInterval:  30,
+		KeepAliveCountMax:  3,
+		AutoReconnect:      false,
+		TransferRetries:    3,
+		ContinueOnError:    false,
 	}
 }
 
@@ -157,6 +171,24 @@ func (s *SettingsStore) SetDisableRsync(disable bool) error {
 	return s.save()
 }
 
+// SetCollapsedGroups saves which server groups are folded in the server list
+func (s *SettingsStore) SetCollapsedGroups(groups []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.settings.CollapsedGroups = groups
+	return s.save()
+}
+
+// SetServerOrder saves the order of the server list
+func (s *SettingsStore) SetServerOrder(order ServerOrder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.settings.ServerOrder = order
+	return s.save()
+}
+
 // Reset resets settings to defaults
 func (s *SettingsStore) Reset() error {
 	s.mu.Lock()
@@ -142,3 +142,35 @@ func TestMasterPassword(t *testing.T) {
 		t.Error("VerifyMasterPassword failed after reload")
 	}
 }
+
+func TestKeepAliveDefaults(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "marix-keepalive-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Settings saved before keepalives existed
+	if err := os.WriteFile(filepath.Join(tempDir, "settings.json"), []byte(`{"defaultPort": 2222}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewSettingsStore(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settings := store.Get()
+	if settings.DefaultPort != 2222 {
+		t.Errorf("Expected saved port 2222, got %d", settings.DefaultPort)
+	}
+	if settings.KeepAliveInterval != 30 || settings.KeepAliveCountMax != 3 {
+		t.Errorf("Expected keepalive defaults 30s x 3, got %ds x %d", settings.KeepAliveInterval, settings.KeepAliveCountMax)
+	}
+	if settings.AutoReconnect {
+		t.Error("Expected reconnect to be opt-in")
+	}
+	if settings.TransferRetries != 3 || settings.ContinueOnError {
+		t.Errorf("Expected 3 transfer retries stopping at the first failure, got %d (continue %v)", settings.TransferRetries, settings.ContinueOnError)
+	}
+}
@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SnippetVariable is a value asked for before a snippet is used
+type SnippetVariable struct {
+	Name    string `json:"name"`
+	Default string `json:"default,omitempty"`
+}
+
+// Snippet is a saved command. {{name}} placeholders in the command are
+// replaced before it is sent.
+type Snippet struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Command   string            `json:"command"`
+	Variables []SnippetVariable `json:"variables,omitempty"`
+	Tags      []string          `json:"tags,omitempty"`
+	CreatedAt int64             `json:"createdAt"`
+	UpdatedAt int64             `json:"updatedAt"`
+}
+
+var snippetPlaceholder = regexp.MustCompile(`{{\s*([A-Za-z_][A-Za-z0-9_-]*)\s*}}`)
+
+// Placeholders returns the variable names used in the command, in order of
+// first use
+func (s *Snippet) Placeholders() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range snippetPlaceholder.FindAllStringSubmatch(s.Command, -1) {
+		if name := match[1]; !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Default returns the saved default of a variable
+func (s *Snippet) Default(name string) string {
+	for _, v := range s.Variables {
+		if v.Name == name {
+			return v.Default
+		}
+	}
+	return ""
+}
+
+// Expand returns the command with every placeholder replaced by its value.
+// A placeholder without a value is an error, so half-filled commands are
+// never sent.
+func (s *Snippet) Expand(values map[string]string) (string, error) {
+	var missing []string
+	command := snippetPlaceholder.ReplaceAllStringFunc(s.Command, func(placeholder string) string {
+		name := snippetPlaceholder.FindStringSubmatch(placeholder)[1]
+		value, ok := values[name]
+		if !ok {
+			missing = append(missing, name)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("no value for %s", strings.Join(missing, ", "))
+	}
+	return command, nil
+}
+
+// SnippetStore manages command snippets
+type SnippetStore struct {
+	snippets map[string]*Snippet
+	filePath string
+	mu       sync.RWMutex
+}
+
+// NewSnippetStore creates a new snippet store
+func NewSnippetStore(dataDir string) (*SnippetStore, error) {
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	store := &SnippetStore{
+		snippets: make(map[string]*Snippet),
+		filePath: filepath.Join(dataDir, "snippets.json"),
+	}
+
+	if err := store.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// load reads snippets from disk
+func (s *SnippetStore) load() error {
+	data, err := os.ReadFile(s.filePath)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	var snippets []*Snippet
+	if err := json.Unmarshal(data, &snippets); err != nil {
+		return fmt.Errorf("failed to parse snippets file: %w", err)
+	}
+
+	for _, snippet := range snippets {
+		s.snippets[snippet.ID] = snippet
+	}
+
+	return nil
+}
+
+// save writes snippets to disk
+func (s *SnippetStore) save() error {
+	snippets := make([]*Snippet, 0, len(s.snippets))
+	for _, snippet := range s.snippets {
+		snippets = append(snippets, snippet)
+	}
+
+	data, err := json.MarshalIndent(snippets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snippets: %w", err)
+	}
+
+	return os.WriteFile(s.filePath, data, 0600)
+}
+
+// Add adds a new snippet
+func (s *SnippetStore) Add(snippet *Snippet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snippets[snippet.ID] = snippet
+	return s.save()
+}
+
+// Get retrieves a snippet by ID
+func (s *SnippetStore) Get(id string) (*Snippet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snippet, exists := s.snippets[id]
+	if !exists {
+		return nil, fmt.Errorf("snippet not found: %s", id)
+	}
+
+	return snippet, nil
+}
+
+// List returns all snippets sorted by name
+func (s *SnippetStore) List() []*Snippet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snippets := make([]*Snippet, 0, len(s.snippets))
+	for _, snippet := range s.snippets {
+		snippets = append(snippets, snippet)
+	}
+	sort.Slice(snippets, func(i, j int) bool {
+		if snippets[i].Name != snippets[j].Name {
+			return snippets[i].Name < snippets[j].Name
+		}
+		return snippets[i].ID < snippets[j].ID
+	})
+
+	return snippets
+}
+
+// Update updates a snippet
+func (s *SnippetStore) Update(snippet *Snippet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.snippets[snippet.ID]; !exists {
+		return fmt.Errorf("snippet not found: %s", snippet.ID)
+	}
+
+	s.snippets[snippet.ID] = snippet
+	return s.save()
+}
+
+// Delete removes a snippet
+func (s *SnippetStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.snippets[id]; !exists {
+		return fmt.Errorf("snippet not found: %s", id)
+	}
+
+	delete(s.snippets, id)
+	return s.save()
+}
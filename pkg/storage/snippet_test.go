@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSnippetStore(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewSnippetStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []*Snippet{
+		{ID: "2", Name: "tail logs", Command: "tail -f /var/log/{{service}}.log", Variables: []SnippetVariable{{Name: "service", Default: "syslog"}}},
+		{ID: "1", Name: "disk usage", Command: "df -h", Tags: []string{"ops"}},
+	} {
+		if err := store.Add(s); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	// Reload from disk
+	store, err = NewSnippetStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snippets := store.List()
+	if len(snippets) != 2 || snippets[0].Name != "disk usage" || snippets[1].Name != "tail logs" {
+		t.Fatalf("Expected snippets sorted by name, got %+v", snippets)
+	}
+	if snippets[1].Default("service") != "syslog" || snippets[0].Tags[0] != "ops" {
+		t.Errorf("Fields not persisted: %+v %+v", snippets[0], snippets[1])
+	}
+
+	snippets[0].Command = "df -h /"
+	if err := store.Update(snippets[0]); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if err := store.Delete("2"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Get("2"); err == nil {
+		t.Error("Expected deleted snippet to be gone")
+	}
+	if err := store.Update(&Snippet{ID: "missing"}); err == nil {
+		t.Error("Expected error updating a missing snippet")
+	}
+}
+
+func TestSnippetExpand(t *testing.T) {
+	snippet := &Snippet{Command: "ssh {{ user }}@{{host}} 'journalctl -u {{unit}} -n {{lines}}' # {{host}}"}
+
+	if got, want := snippet.Placeholders(), []string{"user", "host", "unit", "lines"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Placeholders() = %v, want %v", got, want)
+	}
+
+	command, err := snippet.Expand(map[string]string{"user": "root", "host": "web1", "unit": "nginx", "lines": "50"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "ssh root@web1 'journalctl -u nginx -n 50' # web1"; command != want {
+		t.Errorf("Expand() = %q, want %q", command, want)
+	}
+
+	if _, err := snippet.Expand(map[string]string{"user": "root"}); err == nil {
+		t.Error("Expected an error for missing values")
+	}
+
+	// Braces that are not placeholders are left alone
+	plain := &Snippet{Command: "awk '{print $1}' {{ not valid }}"}
+	if got, err := plain.Expand(nil); err != nil || got != plain.Command {
+		t.Errorf("Expected command unchanged, got %q, %v", got, err)
+	}
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TunnelProfile is a saved set of port forwards kept alive on one server
+type TunnelProfile struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	ServerID  string        `json:"serverId"` // Saved server to connect through
+	Forwards  []PortForward `json:"forwards"`
+	CreatedAt int64         `json:"createdAt"`
+	UpdatedAt int64         `json:"updatedAt"`
+}
+
+// TunnelStore manages tunnel profiles
+type TunnelStore struct {
+	profiles map[string]*TunnelProfile
+	filePath string
+	mu       sync.RWMutex
+}
+
+// NewTunnelStore creates a new tunnel profile store
+func NewTunnelStore(dataDir string) (*TunnelStore, error) {
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	store := &TunnelStore{
+		profiles: make(map[string]*TunnelProfile),
+		filePath: filepath.Join(dataDir, "tunnels.json"),
+	}
+
+	if err := store.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// load reads profiles from disk
+func (s *TunnelStore) load() error {
+	data, err := os.ReadFile(s.filePath)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	var profiles []*TunnelProfile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return fmt.Errorf("failed to parse tunnels file: %w", err)
+	}
+
+	for _, p := range profiles {
+		s.profiles[p.ID] = p
+	}
+
+	return nil
+}
+
+// save writes profiles to disk
+func (s *TunnelStore) save() error {
+	profiles := make([]*TunnelProfile, 0, len(s.profiles))
+	for _, p := range s.profiles {
+		profiles = append(profiles, p)
+	}
+
+	data, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal tunnels: %w", err)
+	}
+
+	return os.WriteFile(s.filePath, data, 0600)
+}
+
+// Add adds a new profile
+func (s *TunnelStore) Add(profile *TunnelProfile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.profiles[profile.ID] = profile
+	return s.save()
+}
+
+// Get retrieves a profile by ID
+func (s *TunnelStore) Get(id string) (*TunnelProfile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	profile, exists := s.profiles[id]
+	if !exists {
+		return nil, fmt.Errorf("tunnel profile not found: %s", id)
+	}
+
+	return profile, nil
+}
+
+// Find retrieves a profile by ID or case-insensitive name
+func (s *TunnelStore) Find(nameOrID string) (*TunnelProfile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if profile, exists := s.profiles[nameOrID]; exists {
+		return profile, nil
+	}
+	for _, profile := range s.profiles {
+		if strings.EqualFold(profile.Name, nameOrID) {
+			return profile, nil
+		}
+	}
+
+	return nil, fmt.Errorf("tunnel profile not found: %s", nameOrID)
+}
+
+// List returns all profiles sorted by name
+func (s *TunnelStore) List() []*TunnelProfile {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	profiles := make([]*TunnelProfile, 0, len(s.profiles))
+	for _, p := range s.profiles {
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		if profiles[i].Name != profiles[j].Name {
+			return profiles[i].Name < profiles[j].Name
+		}
+		return profiles[i].ID < profiles[j].ID
+	})
+
+	return profiles
+}
+
+// Update updates a profile
+func (s *TunnelStore) Update(profile *TunnelProfile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.profiles[profile.ID]; !exists {
+		return fmt.Errorf("tunnel profile not found: %s", profile.ID)
+	}
+
+	s.profiles[profile.ID] = profile
+	return s.save()
+}
+
+// Delete removes a profile
+func (s *TunnelStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.profiles[id]; !exists {
+		return fmt.Errorf("tunnel profile not found: %s", id)
+	}
+
+	delete(s.profiles, id)
+	return s.save()
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"testing"
+)
+
+func TestTunnelStore(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewTunnelStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []*TunnelProfile{
+		{ID: "2", Name: "reporting", ServerID: "srv", Forwards: []PortForward{{Type: "local", Listen: "localhost:5433", Target: "replica:5432"}}},
+		{ID: "1", Name: "Prod DB", ServerID: "srv", Forwards: []PortForward{{Type: "local", Listen: "localhost:5432", Target: "db:5432"}}},
+	} {
+		if err := store.Add(p); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	// Reload from disk
+	store, err = NewTunnelStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profiles := store.List()
+	if len(profiles) != 2 || profiles[0].Name != "Prod DB" || profiles[1].Name != "reporting" {
+		t.Fatalf("Expected profiles sorted by name, got %+v", profiles)
+	}
+	if profiles[0].Forwards[0].Target != "db:5432" {
+		t.Errorf("Forwards not persisted: %+v", profiles[0].Forwards)
+	}
+
+	t.Run("Find by ID or name", func(t *testing.T) {
+		for _, key := range []string{"1", "prod db", "Prod DB"} {
+			p, err := store.Find(key)
+			if err != nil || p.ID != "1" {
+				t.Errorf("Find(%q) = %+v, %v", key, p, err)
+			}
+		}
+		if _, err := store.Find("missing"); err == nil {
+			t.Error("Expected error for unknown profile")
+		}
+	})
+
+	t.Run("Update and delete", func(t *testing.T) {
+		p, _ := store.Get("2")
+		p.Name = "Reporting"
+		if err := store.Update(p); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		if err := store.Update(&TunnelProfile{ID: "missing"}); err == nil {
+			t.Error("Expected error updating unknown profile")
+		}
+
+		if err := store.Delete("1"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if _, err := store.Get("1"); err == nil {
+			t.Error("Expected Get to fail after delete")
+		}
+		if len(store.List()) != 1 {
+			t.Errorf("Expected 1 profile after delete, got %d", len(store.List()))
+		}
+	})
+}
@@ -1,10 +1,12 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/quocson95/marix/pkg/ssh"
@@ -19,13 +21,26 @@ const (
 	StateConnect
 	StateServers
 	StateServerEdit
+	StateServerImport
 	StateSettings
 	StateBackup
 	StateSFTP
 	StateTerminal
 	StatePasswordPrompt
+	StateHostKeyPrompt
+	StateChallengePrompt
+	StateTunnels
+	StateProfiles
+	StateBroadcast
+	StateRun
+	StateSnippets
+	StateRecordings
+	StateRecent
 )
 
+// ClientFactory creates SSH clients wired to the app's interactive prompts
+type ClientFactory func(config *ssh.SSHConfig) *ssh.Client
+
 // AppModel is the root model that manages all screens
 type AppModel struct {
 	state               AppState
@@ -33,15 +48,45 @@ type AppModel struct {
 	connectModel        *ConnectModel
 	serversModel        *ServersModel
 	serverEditModel     *ServerEditModel
+	importModel         *ImportModel
+	tunnelsModel        *TunnelsModel
+	profilesModel       *ProfilesModel
+	broadcastModel      *BroadcastModel
+	runModel            *RunModel
+	snippetsModel       *SnippetsModel
+	recordingsModel     *RecordingsModel
+	recentModel         *RecentModel
 	settingsModel       *SettingsModel
 	backupModel         *BackupModel
 	sftpModel           *SFTPDualModel
-	termModel           *TerminalModel
+	sftpHistoryID       string            // History entry of a direct SFTP connection
+	tabs                []*terminalTab    // Terminal tabs, kept alive in the background
+	pendingSplit        *terminalSplitMsg // Where the next connection opens; nil for a new tab
+	activeTab           int
+	broadcasting        bool // Keys typed in a member session go to every member
 	passwordPrompt      *PasswordPromptModel
+	hostKeyPrompt       *HostKeyPromptModel
+	challengePrompt     *PasswordPromptModel
 	pendingServer       *storage.Server
+	pendingHostKey      *hostKeyRequest
+	pendingChallenge    *challengeRequest
+	challengeAnswers    []string
+	hostKeyReturn       AppState            // Screen to restore once the host key prompt is answered
+	challengeReturn     AppState            // Screen to restore once the challenge prompt is answered
+	tunnelsReturnState  AppState            // Screen to restore when leaving the tunnels screen
+	forwards            *ssh.ForwardManager // Port forwards of the active connection
+	forwardsServer      *storage.Server     // Saved server of the active connection, if any
+	profiles            *profileRunner      // Tunnel profiles running in the background
+	hostKeyRequests     chan hostKeyRequest
+	challengeRequests   chan challengeRequest
+	newClient           ClientFactory
 	store               *storage.Store
+	tunnelStore         *storage.TunnelStore
+	snippetStore        *storage.SnippetStore
+	historyStore        *storage.HistoryStore
 	settingsStore       *storage.SettingsStore
 	masterPasswordCache string // Cached valid password for session
+	dataDir             string
 	width               int
 	height              int
 }
@@ -65,6 +110,34 @@ func NewAppModel() (*AppModel, error) {
 		return nil, fmt.Errorf("failed to initialize settings: %w", err)
 	}
 
+	tunnelStore, err := storage.NewTunnelStore(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tunnel profiles: %w", err)
+	}
+
+	snippetStore, err := storage.NewSnippetStore(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize snippets: %w", err)
+	}
+
+	historyStore, err := storage.NewHistoryStore(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize connection history: %w", err)
+	}
+
+	// Host key and keyboard-interactive prompts from connecting goroutines
+	// are routed through these channels
+	hostKeyRequests := make(chan hostKeyRequest)
+	challengeRequests := make(chan challengeRequest)
+	hostKeyVerifier := newHostKeyVerifier(filepath.Join(homeDir, ".ssh", "known_hosts"), hostKeyRequests)
+	challengeHandler := newChallengeHandler(challengeRequests)
+	newClient := func(config *ssh.SSHConfig) *ssh.Client {
+		client := ssh.NewClient(config)
+		client.SetHostKeyVerifier(hostKeyVerifier)
+		client.SetChallengeHandler(challengeHandler)
+		return client
+	}
+
 	// Determine initial state
 	initialState := StateMenu
 	var passwordPrompt *PasswordPromptModel
@@ -79,19 +152,31 @@ func NewAppModel() (*AppModel, error) {
 	}
 
 	return &AppModel{
-		state:          initialState,
-		menuModel:      InitialModel(),
-		store:          store,
-		settingsStore:  settingsStore,
-		passwordPrompt: passwordPrompt,
+		state:             initialState,
+		menuModel:         InitialModel(),
+		store:             store,
+		dataDir:           dataDir,
+		tunnelStore:       tunnelStore,
+		snippetStore:      snippetStore,
+		historyStore:      historyStore,
+		settingsStore:     settingsStore,
+		passwordPrompt:    passwordPrompt,
+		hostKeyRequests:   hostKeyRequests,
+		challengeRequests: challengeRequests,
+		newClient:         newClient,
+		profiles:          newProfileRunner(store, newClient),
 	}, nil
 }
 
 func (m AppModel) Init() tea.Cmd {
+	waitPrompts := tea.Batch(
+		waitForHostKeyRequest(m.hostKeyRequests),
+		waitForChallengeRequest(m.challengeRequests),
+	)
 	if m.state == StatePasswordPrompt && m.passwordPrompt != nil {
-		return m.passwordPrompt.Init()
+		return tea.Batch(m.passwordPrompt.Init(), waitPrompts)
 	}
-	return nil
+	return waitPrompts
 }
 
 func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -99,13 +184,143 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		// Every terminal pane follows the window size
+		m.layoutTabs()
+
+	case terminalOutputMsg:
+		// Output keeps arriving for background tabs and while other screens are open
+		if index, _ := m.findSession(msg.term); index != m.activeTab {
+			msg.term.unread = true
+		}
+		_, cmd := msg.term.Update(msg)
+		return m, cmd
+
+	case runLineMsg, runResultMsg, runDoneMsg:
+		// A run keeps streaming while a host key prompt is shown
+		if m.state != StateRun && m.runModel != nil {
+			_, cmd := m.runModel.Update(msg)
+			return m, cmd
+		}
+
+	case terminalCloseMsg:
+		index, _ := m.findSession(msg.term)
+		if index < 0 {
+			// The pane was closed by the user
+			return m, nil
+		}
+		_, cmd := msg.term.Update(msg)
+		if msg.term.reconnecting {
+			// The pane stays open behind a banner while it reconnects
+			return m, cmd
+		}
+		m.finishConnection(msg.term.historyID, msg.term.client.Err())
+		// Panes on screen go away, background tabs stay marked as disconnected
+		if index == m.activeTab && m.state == StateTerminal {
+			m.closeSession(msg.term)
+		}
+		return m, nil
+
+	case terminalShellMsg:
+		if index, _ := m.findSession(msg.term); index < 0 {
+			return m, nil
+		}
+		_, cmd := msg.term.Update(msg)
+		return m, cmd
+
+	case terminalReconnectMsg:
+		// Reconnects go on in background tabs and behind other screens
+		if index, _ := m.findSession(msg.term); index < 0 {
+			// The pane was closed while the attempt was running
+			if msg.err == nil {
+				msg.term.client.Close()
+			}
+			return m, nil
+		}
+		_, cmd := msg.term.Update(msg)
+		if msg.err == nil && m.sftpModel != nil && m.sftpModel.sshClient == msg.term.client {
+			// The browser still holds an SFTP session on the old connection
+			if err := m.sftpModel.reopen(); err != nil {
+				m.sftpModel.err = err
+			}
+		}
+		return m, cmd
+
+	case terminalRetryMsg:
+		if index, _ := m.findSession(msg.term); index < 0 {
+			return m, nil
+		}
+		_, cmd := msg.term.Update(msg)
+		return m, cmd
+
+	case serversConnectedMsg:
+		// Sessions open in new tabs, failures are shown on the screen that asked
+		from := m.state
+		cmd, err := m.openServerSessions(msg)
+		switch {
+		case from == StateRecent:
+			m.recentModel.err = err
+			m.recentModel.refresh()
+		case m.serversModel != nil:
+			m.serversModel.statusMsg = ""
+			m.serversModel.err = err
+		}
+		return m, cmd
+
+	case SFTPConnectMsg:
+		// Record the attempt, then open the browser or show why it failed
+		id := m.recordConnection(serverHistory(msg.server, storage.HistorySFTP), msg.err)
+		m.pendingServer = nil
+		if m.state == StatePasswordPrompt {
+			// Connected after asking for the master password
+			m.state = StateMenu
+			if m.serversModel != nil {
+				m.state = StateServers
+			}
+		}
+		if msg.err != nil {
+			switch {
+			case m.state == StateRecent:
+				m.recentModel.err = msg.err
+				m.recentModel.refresh()
+			case m.serversModel != nil:
+				m.serversModel.err = msg.err
+			}
+			return m, nil
+		}
+		m.setForwards(msg.forwards, msg.server)
+		m.sftpModel = msg.sftpModel
+		m.sftpHistoryID = id
+		if len(msg.forwardErrs) > 0 {
+			m.sftpModel.err = fmt.Errorf("port forward failed: %w", errors.Join(msg.forwardErrs...))
+		}
+		m.state = StateSFTP
+		return m, m.sftpModel.Init()
 
 	case tea.KeyMsg:
-		// Global quit
-		if msg.String() == "ctrl+c" {
+		// Global quit, except in the terminal where ctrl+c belongs to the remote shell
+		if msg.String() == "ctrl+c" && m.state != StateTerminal {
 			return m, tea.Quit
 		}
 
+	case hostKeyRequestMsg:
+		// A connection is blocked on a host key decision, take over the screen
+		req := msg.req
+		m.pendingHostKey = &req
+		m.hostKeyReturn = m.state
+		m.hostKeyPrompt = NewHostKeyPromptModel(req.prompt)
+		m.state = StateHostKeyPrompt
+		return m, m.hostKeyPrompt.Init()
+
+	case challengeRequestMsg:
+		// The server wants answers (OTP, PAM prompts), ask one question at a time
+		req := msg.req
+		m.pendingChallenge = &req
+		m.challengeAnswers = nil
+		m.challengeReturn = m.state
+		m.challengePrompt = newChallengeQuestionPrompt(req.challenge, 0)
+		m.state = StateChallengePrompt
+		return m, m.challengePrompt.Init()
+
 	case RestoreMsg:
 		// Handle global restore event (restart app)
 		if msg.err == nil {
@@ -116,6 +331,19 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				newStore, err := storage.NewStore(dataDir)
 				if err == nil {
 					m.store = newStore
+					m.profiles.store = newStore
+				}
+				newTunnelStore, err := storage.NewTunnelStore(dataDir)
+				if err == nil {
+					m.tunnelStore = newTunnelStore
+				}
+				newSnippetStore, err := storage.NewSnippetStore(dataDir)
+				if err == nil {
+					m.snippetStore = newSnippetStore
+				}
+				newHistoryStore, err := storage.NewHistoryStore(dataDir)
+				if err == nil {
+					m.historyStore = newHistoryStore
 				}
 				newSettingsStore, err := storage.NewSettingsStore(dataDir)
 				if err == nil {
@@ -151,6 +379,8 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updateServers(msg)
 	case StateServerEdit:
 		return m.updateServerEdit(msg)
+	case StateServerImport:
+		return m.updateServerImport(msg)
 	case StateSettings:
 		return m.updateSettings(msg)
 	case StateBackup:
@@ -161,6 +391,24 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updateTerminal(msg)
 	case StatePasswordPrompt:
 		return m.updatePasswordPrompt(msg)
+	case StateHostKeyPrompt:
+		return m.updateHostKeyPrompt(msg)
+	case StateChallengePrompt:
+		return m.updateChallengePrompt(msg)
+	case StateTunnels:
+		return m.updateTunnels(msg)
+	case StateProfiles:
+		return m.updateProfiles(msg)
+	case StateBroadcast:
+		return m.updateBroadcast(msg)
+	case StateRun:
+		return m.updateRun(msg)
+	case StateSnippets:
+		return m.updateSnippets(msg)
+	case StateRecordings:
+		return m.updateRecordings(msg)
+	case StateRecent:
+		return m.updateRecent(msg)
 	default:
 		return m, nil
 	}
@@ -175,7 +423,7 @@ func (m AppModel) updateMenu(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch m.menuModel.selected {
 	case MenuConnect:
 		m.state = StateConnect
-		connectModel := NewConnectModel()
+		connectModel := NewConnectModel(m.newClient)
 		m.connectModel = connectModel
 		m.menuModel.selected = MenuNone // Reset
 		return m, m.connectModel.Init()
@@ -195,6 +443,36 @@ func (m AppModel) updateMenu(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.menuModel.selected = MenuNone
 		return m, m.serversModel.Init()
 
+	case MenuTunnelProfiles:
+		m.state = StateProfiles
+		m.profilesModel = NewProfilesModel(m.tunnelStore, m.store, m.profiles, m.masterPasswordCache)
+		m.menuModel.selected = MenuNone
+		return m, m.profilesModel.Init()
+
+	case MenuRunCommand:
+		m.state = StateRun
+		m.runModel = NewRunModel(m.store, m.newClient, m.masterPasswordCache, filepath.Join(m.dataDir, "reports"))
+		m.runModel.width = m.width
+		m.runModel.height = m.height
+		m.menuModel.selected = MenuNone
+		return m, m.runModel.Init()
+
+	case MenuRecent:
+		m.state = StateRecent
+		m.recentModel = NewRecentModel(m.historyStore)
+		m.recentModel.width = m.width
+		m.recentModel.height = m.height
+		m.menuModel.selected = MenuNone
+		return m, m.recentModel.Init()
+
+	case MenuRecordings:
+		m.state = StateRecordings
+		m.recordingsModel = NewRecordingsModel(m.recordingsDir())
+		m.recordingsModel.width = m.width
+		m.recordingsModel.height = m.height
+		m.menuModel.selected = MenuNone
+		return m, m.recordingsModel.Init()
+
 	case MenuBackup:
 		// Backup & Restore
 		m.state = StateBackup
@@ -221,14 +499,31 @@ func (m AppModel) updateConnect(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		if msg.String() == "esc" {
+			// Back to the open tabs when this was a new tab or pane
+			m.pendingSplit = nil
+			if len(m.tabs) > 0 {
+				m.state = StateTerminal
+				return m, nil
+			}
 			m.state = StateMenu
 			return m, nil
 		}
+	case connectFailedMsg:
+		m.recordConnection(m.configHistory(msg.config, storage.HistorySSH), msg.err)
 	case ConnectSuccessMsg:
-		// Transition to terminal
-		m.state = StateTerminal
-		m.termModel = msg.termModel
-		return m, m.termModel.Init()
+		// Open the session in a new pane or tab
+		config := msg.termModel.client.GetConfig()
+		if server := m.savedServer(config); server != nil {
+			msg.termModel.tags = server.Tags
+			m.store.MarkConnected(server.ID)
+		}
+		msg.termModel.historyID = m.recordConnection(m.configHistory(config, storage.HistorySSH), nil)
+		if split := m.pendingSplit; split != nil && len(m.tabs) > 0 {
+			m.pendingSplit = nil
+			return m, m.splitSession(msg.termModel, split.vertical)
+		}
+		m.pendingSplit = nil
+		return m, m.addSession(msg.termModel)
 	}
 
 	var cmd tea.Cmd
@@ -240,10 +535,19 @@ func (m AppModel) updateConnect(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m AppModel) updateServers(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		if msg.String() == "esc" {
+		if msg.String() == "esc" && !m.serversModel.IsInputActive() {
 			m.state = StateMenu
 			return m, nil
 		}
+	case ServerGroupConnectMsg:
+		return m, m.connectServers(msg.servers)
+	case ServerGroupRunMsg:
+		m.state = StateRun
+		m.runModel = NewRunModel(m.store, m.newClient, m.masterPasswordCache, filepath.Join(m.dataDir, "reports"))
+		m.runModel.SetTarget(storage.GroupSelectorPrefix + msg.group)
+		m.runModel.width = m.width
+		m.runModel.height = m.height
+		return m, m.runModel.Init()
 	case ServerSelectedMsg:
 		// No longer used - servers always launch external terminal
 		return m, nil
@@ -253,20 +557,16 @@ func (m AppModel) updateServers(msg tea.Msg) (tea.Model, tea.Cmd) {
 		serverEditModel := NewServerEditModel(m.store, m.settingsStore, msg.server, msg.isNew, m.masterPasswordCache)
 		m.serverEditModel = serverEditModel
 		return m, m.serverEditModel.Init()
+	case ServerImportMsg:
+		m.state = StateServerImport
+		m.importModel = NewImportModel(m.store, m.settingsStore, m.masterPasswordCache)
+		return m, m.importModel.Init()
 	case ServerSFTPMsg:
 		// Connect to server and open SFTP
 		return m, m.connectToSFTP(msg.server)
-	case SFTPConnectMsg:
-		// Handle SFTP connection result
-		if msg.err != nil {
-			// Show error in servers screen
-			m.serversModel.err = msg.err
-			return m, nil
-		}
-		// Success - transition to SFTP
-		m.sftpModel = msg.sftpModel
-		m.state = StateSFTP
-		return m, m.sftpModel.Init()
+	case ServerLaunchedMsg:
+		m.recordConnection(serverHistory(msg.server, storage.HistoryExternal), msg.err)
+		return m, nil
 	}
 
 	var cmd tea.Cmd
@@ -275,6 +575,36 @@ func (m AppModel) updateServers(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+func (m AppModel) updateServerImport(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "esc" {
+			m.state = StateServers
+			m.serversModel = NewServersModel(m.store, m.settingsStore, m.masterPasswordCache)
+			return m, m.serversModel.Init()
+		}
+
+	case ServersImportedMsg:
+		m.state = StateServers
+		m.serversModel = NewServersModel(m.store, m.settingsStore, m.masterPasswordCache)
+		m.serversModel.statusMsg = fmt.Sprintf("Imported %d server(s)", msg.imported)
+		if len(msg.notes) > 0 {
+			m.serversModel.err = errors.New(strings.Join(msg.notes, "; "))
+		}
+		if msg.imported == 0 {
+			return m, m.serversModel.Init()
+		}
+
+		backupCmd := RunAutoBackup(m.settingsStore, m.masterPasswordCache, "imported")
+		return m, tea.Batch(m.serversModel.Init(), backupCmd)
+	}
+
+	var cmd tea.Cmd
+	updatedModel, cmd := m.importModel.Update(msg)
+	m.importModel = updatedModel.(*ImportModel)
+	return m, cmd
+}
+
 func (m AppModel) updateServerEdit(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
@@ -322,20 +652,35 @@ func (m AppModel) updateSFTP(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		if msg.String() == "esc" {
-			// If SFTP model is filtering/searching, let it handle Esc
-			if m.sftpModel.IsSearchActive() {
+			// If SFTP model is filtering/searching or showing results, let it handle Esc
+			if m.sftpModel.IsInputActive() {
 				break // Pass to m.sftpModel.Update
 			}
 
+			m.sftpModel.cancelSyncPlan()
+			m.finishConnection(m.sftpHistoryID, nil)
+			m.sftpHistoryID = ""
+
 			// If we have an active terminal session, return to it
-			if m.termModel != nil {
+			if len(m.tabs) > 0 {
 				m.state = StateTerminal
 				return m, nil
 			}
 			// Otherwise return to menu (direct SFTP connection)
+			m.setForwards(nil, nil)
 			m.state = StateMenu
 			return m, nil
 		}
+
+	case OpenTunnelsMsg:
+		// SFTP opened from a terminal tab shares that tab's forwards
+		if forwards := m.sessionForwards(m.sftpModel.sshClient); forwards != nil {
+			return m.openTunnels(forwards, nil)
+		}
+		if m.forwards == nil {
+			m.setForwards(ssh.NewForwardManager(m.sftpModel.sshClient), nil)
+		}
+		return m.openTunnels(m.forwards, m.forwardsServer)
 	}
 
 	var cmd tea.Cmd
@@ -345,32 +690,96 @@ func (m AppModel) updateSFTP(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *AppModel) updateTerminal(msg tea.Msg) (tea.Model, tea.Cmd) {
+	term := m.activeSession()
+
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		// Already applied to every pane
+		return m, nil
+
+	case terminalDisconnectMsg:
+		// Close the pane, the menu comes back after the last one
+		m.closeSession(term)
+		return m, nil
+
+	case terminalNewTabMsg, terminalSplitMsg:
+		if split, ok := msg.(terminalSplitMsg); ok {
+			m.pendingSplit = &split
+		}
+		m.state = StateConnect
+		m.connectModel = NewConnectModel(m.newClient)
+		return m, m.connectModel.Init()
+
+	case terminalTabMsg:
+		m.switchSession(msg.index)
+		return m, nil
+
+	case terminalCycleTabMsg:
+		n := len(m.tabs)
+		m.switchSession(((m.activeTab+msg.step)%n + n) % n)
+		return m, nil
+
+	case terminalFocusMsg:
+		m.focusPane(msg.step)
+		return m, nil
+
+	case terminalBroadcastMsg:
+		m.broadcastModel = NewBroadcastModel(m.allSessions())
+		m.state = StateBroadcast
+		return m, m.broadcastModel.Init()
+
+	case terminalSnippetsMsg:
+		m.snippetsModel = NewSnippetsModel(m.snippetStore, term)
+		m.state = StateSnippets
+		return m, m.snippetsModel.Init()
+
+	case terminalRecordMsg:
+		m.toggleRecording(term)
+		return m, nil
+
+	case terminalOptOutMsg:
+		if !term.closed {
+			term.broadcast = !term.broadcast
+		}
+		return m, nil
+
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "esc":
-			// Disconnect and return to menu
-			if m.termModel != nil && m.termModel.client != nil {
-				m.termModel.client.Close()
-			}
-			m.state = StateMenu
-			return m, nil
-		case "ctrl+f":
-			// Open SFTP browser
-			if m.termModel != nil && m.termModel.client != nil {
-				sftpModel, err := NewSFTPDualModel(m.termModel.client, m.settingsStore)
-				if err == nil {
-					m.sftpModel = sftpModel
-					m.state = StateSFTP
-					return m, m.sftpModel.Init()
+		// Fan the key out before the focused session handles it
+		if m.broadcasting && term.broadcast && !term.capturesInput() && msg.Type != tea.KeyCtrlCloseBracket {
+			for _, other := range m.allSessions() {
+				if other != term && other.broadcast {
+					other.SendKey(msg)
 				}
 			}
 		}
+
+	case OpenTunnelsMsg:
+		// Manage port forwards
+		if term.closed {
+			return m, nil
+		}
+		return m.openTunnels(term.Forwards(), nil)
+
+	case terminalSFTPMsg:
+		// Open SFTP browser
+		if term.closed {
+			return m, nil
+		}
+		sftpModel, err := NewSFTPDualModel(term.client, m.settingsStore)
+		if err != nil {
+			term.err = err
+			return m, nil
+		}
+		if server := m.savedServer(term.client.GetConfig()); server != nil {
+			sftpModel.SetServer(m.store, server)
+		}
+		m.sftpModel = sftpModel
+		m.state = StateSFTP
+		return m, m.sftpModel.Init()
 	}
 
 	var cmd tea.Cmd
-	updatedModel, cmd := m.termModel.Update(msg)
-	m.termModel = updatedModel.(*TerminalModel)
+	_, cmd = term.Update(msg)
 	return m, cmd
 }
 
@@ -384,6 +793,8 @@ func (m AppModel) View() string {
 		return m.serversModel.View()
 	case StateServerEdit:
 		return m.serverEditModel.View()
+	case StateServerImport:
+		return m.importModel.View()
 	case StateSettings:
 		return m.settingsModel.View()
 	case StateBackup:
@@ -391,9 +802,27 @@ func (m AppModel) View() string {
 	case StateSFTP:
 		return m.sftpModel.View()
 	case StateTerminal:
-		return m.termModel.View()
+		return m.terminalView()
 	case StatePasswordPrompt:
 		return m.passwordPrompt.View()
+	case StateHostKeyPrompt:
+		return m.hostKeyPrompt.View()
+	case StateChallengePrompt:
+		return m.challengePrompt.View()
+	case StateTunnels:
+		return m.tunnelsModel.View()
+	case StateProfiles:
+		return m.profilesModel.View()
+	case StateBroadcast:
+		return m.broadcastModel.View()
+	case StateRun:
+		return m.runModel.View()
+	case StateSnippets:
+		return m.snippetsModel.View()
+	case StateRecordings:
+		return m.recordingsModel.View()
+	case StateRecent:
+		return m.recentModel.View()
 	default:
 		return "Unknown state"
 	}
@@ -437,16 +866,74 @@ func (m *AppModel) updatePasswordPrompt(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+func (m *AppModel) updateHostKeyPrompt(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case HostKeyDecisionMsg:
+		// Unblock the connecting goroutine and go back to where we were
+		if m.pendingHostKey != nil {
+			m.pendingHostKey.reply <- msg.Decision
+			m.pendingHostKey = nil
+		}
+		m.hostKeyPrompt = nil
+		m.state = m.hostKeyReturn
+		return m, waitForHostKeyRequest(m.hostKeyRequests)
+	}
+
+	var cmd tea.Cmd
+	updatedModel, cmd := m.hostKeyPrompt.Update(msg)
+	m.hostKeyPrompt = updatedModel.(*HostKeyPromptModel)
+	return m, cmd
+}
+
+func (m *AppModel) updateChallengePrompt(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case PasswordSubmittedMsg:
+		if m.pendingChallenge == nil {
+			m.state = m.challengeReturn
+			return m, waitForChallengeRequest(m.challengeRequests)
+		}
+
+		challenge := m.pendingChallenge.challenge
+		if !msg.Cancelled {
+			m.challengeAnswers = append(m.challengeAnswers, msg.Password)
+			if len(m.challengeAnswers) < len(challenge.Questions) {
+				// More questions in this round
+				m.challengePrompt = newChallengeQuestionPrompt(challenge, len(m.challengeAnswers))
+				return m, m.challengePrompt.Init()
+			}
+		}
+
+		reply := challengeReply{answers: m.challengeAnswers}
+		if msg.Cancelled {
+			reply = challengeReply{err: errChallengeCancelled}
+		}
+		m.pendingChallenge.reply <- reply
+		m.pendingChallenge = nil
+		m.challengeAnswers = nil
+		m.challengePrompt = nil
+		m.state = m.challengeReturn
+		return m, waitForChallengeRequest(m.challengeRequests)
+	}
+
+	var cmd tea.Cmd
+	updatedModel, cmd := m.challengePrompt.Update(msg)
+	m.challengePrompt = updatedModel.(*PasswordPromptModel)
+	return m, cmd
+}
+
 // SFTPConnectMsg is sent when SFTP connection is established
 type SFTPConnectMsg struct {
-	sftpModel *SFTPDualModel
-	err       error
+	sftpModel   *SFTPDualModel
+	server      *storage.Server
+	forwards    *ssh.ForwardManager
+	forwardErrs []error // Saved forwards that failed to start
+	err         error
 }
 
 // connectToSFTP connects to SSH server and opens SFTP manager
 func (m *AppModel) connectToSFTP(server *storage.Server) tea.Cmd {
-	// Check if server uses encrypted private key
-	if len(server.PrivateKeyEncrypted) > 0 {
+	// Check if server or one of its jump hosts uses an encrypted private key
+	if ssh.NeedsMasterPassword(m.store, server) {
 		// 1. Try cached password first
 		if m.masterPasswordCache != "" {
 			return m.connectToSFTPWithPassword(server, m.masterPasswordCache)
@@ -474,68 +961,188 @@ func (m *AppModel) connectToSFTP(server *storage.Server) tea.Cmd {
 // connectToSFTPWithPassword handles the actual connection with optional key decryption
 func (m *AppModel) connectToSFTPWithPassword(server *storage.Server, keyPassword string) tea.Cmd {
 	return func() tea.Msg {
-		// Expand tilde in private key path
-		privateKey := server.PrivateKey
-		if len(privateKey) > 0 && privateKey[0] == '~' {
-			home := os.Getenv("HOME")
-			if home != "" {
-				privateKey = home + privateKey[1:]
-			}
-		}
-
-		// Create SSH config from server
-		config := &ssh.SSHConfig{
-			Host:        server.Host,
-			Port:        server.Port,
-			Username:    server.Username,
-			Password:    server.Password,
-			KeyPassword: keyPassword,
-		}
-
-		// Handle encrypted private key
-		if len(server.PrivateKeyEncrypted) > 0 {
-			// Decrypt the private key
-			decrypted, err := storage.DecryptPrivateKey(
-				server.PrivateKeyEncrypted,
-				server.KeyEncryptionSalt,
-				keyPassword,
-			)
-			if err != nil {
-				log.Printf("Failed to decrypt private key for %s: %v\n", server.Name, err)
-				return SFTPConnectMsg{err: fmt.Errorf("failed to decrypt private key: %w", err)}
-			}
-			// Set the decrypted key content directly
-			config.KeyContent = decrypted
-		} else if privateKey != "" {
-			// Legacy: use file path
-			config.PrivateKey = privateKey
+		config, err := ssh.ServerSSHConfig(m.store, server, keyPassword)
+		if err != nil {
+			log.Printf("Failed to prepare SSH config for %s: %v\n", server.Name, err)
+			return SFTPConnectMsg{server: server, err: err}
 		}
 
 		// Validate config
 		if err := config.Validate(); err != nil {
 			log.Printf("SSH config validation failed for %s: %v\n", server.Name, err)
-			return SFTPConnectMsg{err: err}
+			return SFTPConnectMsg{server: server, err: err}
 		}
 
 		// Create SSH client
-		sshClient := ssh.NewClient(config)
+		sshClient := m.newClient(config)
 		if err := sshClient.Connect(); err != nil {
 			log.Printf("SSH connection failed for %s: %v\n", server.Name, err)
-			return SFTPConnectMsg{err: err}
+			return SFTPConnectMsg{server: server, err: err}
 		}
+		m.store.MarkConnected(server.ID)
 
 		// Create SFTP model
 		sftpModel, err := NewSFTPDualModel(sshClient, m.settingsStore)
 		if err != nil {
 			log.Printf("SFTP initialization failed for %s: %v\n", server.Name, err)
 			sshClient.Close()
-			return SFTPConnectMsg{err: err}
+			return SFTPConnectMsg{server: server, err: err}
 		}
 
+		sftpModel.SetServer(m.store, server)
+
 		// Set initial dimensions from app model
 		sftpModel.width = m.width
 		sftpModel.height = m.height
 
-		return SFTPConnectMsg{sftpModel: sftpModel}
+		// Auto-start the server's saved port forwards
+		forwards := ssh.NewForwardManager(sshClient)
+		forwardErrs := startSavedForwards(forwards, server)
+
+		return SFTPConnectMsg{
+			sftpModel:   sftpModel,
+			server:      server,
+			forwards:    forwards,
+			forwardErrs: forwardErrs,
+		}
+	}
+}
+
+// setForwards replaces the active connection's forwards, stopping the old ones
+func (m *AppModel) setForwards(forwards *ssh.ForwardManager, server *storage.Server) {
+	if m.forwards != nil && m.forwards != forwards {
+		m.forwards.StopAll()
+	}
+	m.forwards = forwards
+	m.forwardsServer = server
+}
+
+// openTunnels shows forwards; server, when known, is where they can be saved
+func (m *AppModel) openTunnels(forwards *ssh.ForwardManager, server *storage.Server) (tea.Model, tea.Cmd) {
+	m.tunnelsModel = NewTunnelsModel(forwards, m.store, server)
+	m.tunnelsReturnState = m.state
+	m.state = StateTunnels
+	return m, m.tunnelsModel.Init()
+}
+
+func (m *AppModel) updateTunnels(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok && msg.String() == "esc" && !m.tunnelsModel.IsInputActive() {
+		m.state = m.tunnelsReturnState
+		if m.state == StateTerminal && len(m.tabs) == 0 {
+			m.state = StateMenu
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	updatedModel, cmd := m.tunnelsModel.Update(msg)
+	m.tunnelsModel = updatedModel.(*TunnelsModel)
+	return m, cmd
+}
+
+func (m AppModel) updateBroadcast(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "esc" && !m.broadcastModel.IsInputActive() {
+			m.state = StateTerminal
+			return m, nil
+		}
+
+	case BroadcastStartMsg:
+		for _, term := range m.allSessions() {
+			term.broadcast = false
+		}
+		for _, term := range msg.targets {
+			term.broadcast = true
+		}
+		m.broadcasting = true
+		m.state = StateTerminal
+		return m, nil
+
+	case BroadcastStopMsg:
+		m.broadcasting = false
+		m.state = StateTerminal
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	updatedModel, cmd := m.broadcastModel.Update(msg)
+	m.broadcastModel = updatedModel.(*BroadcastModel)
+	return m, cmd
+}
+
+func (m AppModel) updateRun(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok && msg.String() == "esc" && !m.runModel.IsRunning() {
+		m.state = StateMenu
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	updatedModel, cmd := m.runModel.Update(msg)
+	m.runModel = updatedModel.(*RunModel)
+	return m, cmd
+}
+
+func (m AppModel) updateSnippets(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "esc" && !m.snippetsModel.IsInputActive() {
+			m.state = StateTerminal
+			return m, nil
+		}
+
+	case snippetPasteMsg:
+		// Pasted, not executed: the user reviews the line and presses enter
+		if !msg.term.closed {
+			msg.term.SendKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(msg.text), Paste: true})
+		}
+		m.state = StateTerminal
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	updatedModel, cmd := m.snippetsModel.Update(msg)
+	m.snippetsModel = updatedModel.(*SnippetsModel)
+	return m, cmd
+}
+
+func (m AppModel) updateRecordings(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok && msg.String() == "esc" && !m.recordingsModel.IsPlaying() {
+		m.state = StateMenu
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	updatedModel, cmd := m.recordingsModel.Update(msg)
+	m.recordingsModel = updatedModel.(*RecordingsModel)
+	return m, cmd
+}
+
+func (m AppModel) updateRecent(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "esc" && !m.recentModel.IsInputActive() {
+			m.state = StateMenu
+			return m, nil
+		}
+	case RecentReconnectMsg:
+		return m.reconnect(msg.entry)
+	}
+
+	var cmd tea.Cmd
+	updatedModel, cmd := m.recentModel.Update(msg)
+	m.recentModel = updatedModel.(*RecentModel)
+	return m, cmd
+}
+
+func (m AppModel) updateProfiles(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok && msg.String() == "esc" && !m.profilesModel.IsInputActive() {
+		m.state = StateMenu
+		return m, nil
 	}
+
+	var cmd tea.Cmd
+	updatedModel, cmd := m.profilesModel.Update(msg)
+	m.profilesModel = updatedModel.(*ProfilesModel)
+	return m, cmd
 }
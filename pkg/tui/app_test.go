@@ -0,0 +1,76 @@
+package tui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/quocson95/marix/pkg/ssh"
+)
+
+// promptsApp returns an app on the menu with a host key and a challenge
+// request, whose replies are buffered
+func promptsApp() (AppModel, hostKeyRequestMsg, challengeRequestMsg) {
+	app := AppModel{state: StateMenu}
+	hostKey := hostKeyRequestMsg{req: hostKeyRequest{
+		prompt: ssh.HostKeyPrompt{Hostname: "web", KeyType: "ssh-ed25519", Status: ssh.HostKeyUnknown},
+		reply:  make(chan ssh.HostKeyDecision, 1),
+	}}
+	challenge := challengeRequestMsg{req: challengeRequest{
+		challenge: ssh.Challenge{Questions: []string{"OTP: "}, Echos: []bool{false}},
+		reply:     make(chan challengeReply, 1),
+	}}
+	return app, hostKey, challenge
+}
+
+func updateApp(t *testing.T, app AppModel, msg tea.Msg) AppModel {
+	t.Helper()
+	model, _ := app.Update(msg)
+	switch m := model.(type) {
+	case AppModel:
+		app = m
+	case *AppModel:
+		app = *m
+	}
+	if app.state == StateHostKeyPrompt || app.state == StateChallengePrompt {
+		app.View() // Panics when the prompt shown has no model
+	}
+	return app
+}
+
+func TestApp_NestedConnectionPrompts(t *testing.T) {
+	t.Run("Core Functionality: Challenge over host key prompt", func(t *testing.T) {
+		app, hostKey, challenge := promptsApp()
+		app = updateApp(t, app, hostKey)
+		app = updateApp(t, app, challenge)
+
+		app = updateApp(t, app, PasswordSubmittedMsg{Password: "123456"})
+		if app.state != StateHostKeyPrompt || app.hostKeyPrompt == nil {
+			t.Fatalf("Expected the host key prompt back, got state %d", app.state)
+		}
+		app = updateApp(t, app, HostKeyDecisionMsg{Decision: ssh.HostKeyAccept})
+		if app.state != StateMenu {
+			t.Errorf("Expected the menu once both prompts are answered, got state %d", app.state)
+		}
+		if got := <-challenge.req.reply; len(got.answers) != 1 || got.answers[0] != "123456" {
+			t.Errorf("Unexpected challenge reply %+v", got)
+		}
+	})
+
+	t.Run("Core Functionality: Host key over challenge prompt", func(t *testing.T) {
+		app, hostKey, challenge := promptsApp()
+		app = updateApp(t, app, challenge)
+		app = updateApp(t, app, hostKey)
+
+		app = updateApp(t, app, HostKeyDecisionMsg{Decision: ssh.HostKeyReject})
+		if app.state != StateChallengePrompt || app.challengePrompt == nil {
+			t.Fatalf("Expected the challenge prompt back, got state %d", app.state)
+		}
+		app = updateApp(t, app, PasswordSubmittedMsg{Cancelled: true})
+		if app.state != StateMenu {
+			t.Errorf("Expected the menu once both prompts are answered, got state %d", app.state)
+		}
+		if got := <-hostKey.req.reply; got != ssh.HostKeyReject {
+			t.Errorf("Expected the host key to be rejected, got %v", got)
+		}
+	})
+}
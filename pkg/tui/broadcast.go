@@ -0,0 +1,209 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var broadcastStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#FFFFFF")).
+	Background(lipgloss.Color("#D7263D")).
+	Bold(true).
+	Padding(0, 1)
+
+// BroadcastStartMsg turns broadcast on for the selected sessions
+type BroadcastStartMsg struct {
+	targets []*TerminalModel
+}
+
+// BroadcastStopMsg turns broadcast off
+type BroadcastStopMsg struct{}
+
+// BroadcastModel picks the sessions that receive broadcast input, one by one
+// or by server tag
+type BroadcastModel struct {
+	sessions []*TerminalModel
+	selected map[*TerminalModel]bool
+	cursor   int
+	tagging  bool
+	tagInput textinput.Model
+	err      error
+}
+
+// NewBroadcastModel creates the broadcast picker for the open sessions; the
+// current broadcast set is preselected
+func NewBroadcastModel(sessions []*TerminalModel) *BroadcastModel {
+	ti := textinput.New()
+	ti.Placeholder = "prod"
+	ti.CharLimit = 64
+	ti.Width = 30
+	ti.Prompt = "Tag: "
+
+	selected := make(map[*TerminalModel]bool)
+	for _, term := range sessions {
+		if term.broadcast && !term.closed {
+			selected[term] = true
+		}
+	}
+
+	return &BroadcastModel{
+		sessions: sessions,
+		selected: selected,
+		tagInput: ti,
+	}
+}
+
+func (m *BroadcastModel) Init() tea.Cmd {
+	return nil
+}
+
+// IsInputActive reports whether the tag input is open, so esc closes it
+func (m *BroadcastModel) IsInputActive() bool {
+	return m.tagging
+}
+
+func (m *BroadcastModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	if m.tagging {
+		return m, m.updateTag(keyMsg)
+	}
+
+	switch keyMsg.String() {
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.sessions)-1 {
+			m.cursor++
+		}
+	case " ", "x":
+		if m.cursor < len(m.sessions) {
+			m.toggle(m.sessions[m.cursor])
+		}
+	case "a":
+		for _, term := range m.sessions {
+			if !term.closed {
+				m.selected[term] = true
+			}
+		}
+	case "n":
+		m.selected = make(map[*TerminalModel]bool)
+	case "t":
+		m.tagging = true
+		m.tagInput.SetValue("")
+		return m, m.tagInput.Focus()
+	case "s":
+		return m, func() tea.Msg { return BroadcastStopMsg{} }
+	case "enter":
+		var targets []*TerminalModel
+		for _, term := range m.sessions {
+			if m.selected[term] {
+				targets = append(targets, term)
+			}
+		}
+		if len(targets) == 0 {
+			m.err = fmt.Errorf("select at least one session")
+			return m, nil
+		}
+		return m, func() tea.Msg { return BroadcastStartMsg{targets: targets} }
+	}
+
+	return m, nil
+}
+
+func (m *BroadcastModel) toggle(term *TerminalModel) {
+	if term.closed {
+		return
+	}
+	if m.selected[term] {
+		delete(m.selected, term)
+	} else {
+		m.selected[term] = true
+	}
+	m.err = nil
+}
+
+// updateTag adds every session whose server carries the entered tag
+func (m *BroadcastModel) updateTag(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		m.tagging = false
+		m.tagInput.Blur()
+		return nil
+	case "enter":
+		tag := strings.TrimSpace(m.tagInput.Value())
+		m.tagging = false
+		m.tagInput.Blur()
+
+		matched := 0
+		for _, term := range m.sessions {
+			if !term.closed && term.hasTag(tag) {
+				m.selected[term] = true
+				matched++
+			}
+		}
+		if matched == 0 {
+			m.err = fmt.Errorf("no open session is tagged %q", tag)
+		} else {
+			m.err = nil
+		}
+		return nil
+	}
+
+	var cmd tea.Cmd
+	m.tagInput, cmd = m.tagInput.Update(msg)
+	return cmd
+}
+
+func (m *BroadcastModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("📢 Broadcast Input"))
+	b.WriteString("\n\n")
+
+	for i, term := range m.sessions {
+		cursor := "  "
+		style := itemStyle
+		if m.cursor == i {
+			cursor = "→ "
+			style = selectedItemStyle
+		}
+
+		check := "[ ]"
+		if m.selected[term] {
+			check = "[x]"
+		}
+		line := fmt.Sprintf("%s %s  %s", check, term.Name(), term.connectionID)
+		if len(term.tags) > 0 {
+			line += "  #" + strings.Join(term.tags, " #")
+		}
+		if term.closed {
+			line += "  (disconnected)"
+		}
+		b.WriteString(cursor + style.Render(line) + "\n")
+	}
+
+	b.WriteString("\n")
+	if m.tagging {
+		b.WriteString(m.tagInput.View())
+		b.WriteString("\n")
+		b.WriteString(helpStyle.Render("enter: select sessions with this tag • esc: cancel"))
+	} else {
+		b.WriteString(helpStyle.Render("↑/k up • ↓/j down • space: toggle • t: by tag • a: all • n: none • enter: broadcast • s: stop • esc: back"))
+	}
+
+	if m.err != nil {
+		b.WriteString("\n\n")
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", m.err)))
+	}
+
+	return boxStyle.Render(b.String())
+}
@@ -0,0 +1,66 @@
+package tui
+
+import (
+	"errors"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/quocson95/marix/pkg/ssh"
+)
+
+// challengeRequest is a pending keyboard-interactive round from a connecting goroutine
+type challengeRequest struct {
+	challenge ssh.Challenge
+	reply     chan challengeReply
+}
+
+type challengeReply struct {
+	answers []string
+	err     error
+}
+
+// challengeRequestMsg is delivered to the app when the server asks questions
+type challengeRequestMsg struct {
+	req challengeRequest
+}
+
+// errChallengeCancelled is returned to the server when the user presses esc
+var errChallengeCancelled = errors.New("authentication cancelled")
+
+// newChallengeHandler routes keyboard-interactive challenges to the TUI and
+// blocks the connecting goroutine until every question is answered
+func newChallengeHandler(requests chan<- challengeRequest) ssh.ChallengeFunc {
+	return func(challenge ssh.Challenge) ([]string, error) {
+		reply := make(chan challengeReply, 1)
+		requests <- challengeRequest{challenge: challenge, reply: reply}
+		r := <-reply
+		return r.answers, r.err
+	}
+}
+
+// waitForChallengeRequest listens for the next keyboard-interactive round
+func waitForChallengeRequest(requests <-chan challengeRequest) tea.Cmd {
+	return func() tea.Msg {
+		return challengeRequestMsg{req: <-requests}
+	}
+}
+
+// newChallengeQuestionPrompt builds the prompt for one question of a challenge
+func newChallengeQuestionPrompt(challenge ssh.Challenge, index int) *PasswordPromptModel {
+	title := "🔐 Verification Required"
+	if challenge.Name != "" {
+		title = "🔐 " + challenge.Name
+	}
+
+	var desc []string
+	if challenge.Instruction != "" {
+		desc = append(desc, challenge.Instruction)
+	}
+	desc = append(desc, strings.TrimSpace(challenge.Questions[index]))
+
+	prompt := NewPasswordPromptModel(title, strings.Join(desc, "\n"))
+	if index < len(challenge.Echos) && challenge.Echos[index] {
+		prompt.SetEcho(true)
+	}
+	return prompt
+}
@@ -17,6 +17,12 @@ type ConnectSuccessMsg struct {
 	termModel *TerminalModel
 }
 
+// connectFailedMsg is sent when the connection to config could not be made
+type connectFailedMsg struct {
+	config *ssh.SSHConfig
+	err    error
+}
+
 // ConnectModel handles SSH connection
 type ConnectModel struct {
 	inputs  []textinput.Model
@@ -25,6 +31,8 @@ type ConnectModel struct {
 	width   int
 	height  int
 	server  *storage.Server // Pre-filled server if connecting from server list
+
+	newClient ClientFactory
 }
 
 const (
@@ -36,16 +44,16 @@ const (
 )
 
 // NewConnectModel creates a new connection model
-func NewConnectModel() *ConnectModel {
-	return newConnectModel(nil)
+func NewConnectModel(newClient ClientFactory) *ConnectModel {
+	return newConnectModel(nil, newClient)
 }
 
 // NewConnectModelWithServer creates a connection model pre-filled with server data
-func NewConnectModelWithServer(server *storage.Server) *ConnectModel {
-	return newConnectModel(server)
+func NewConnectModelWithServer(server *storage.Server, newClient ClientFactory) *ConnectModel {
+	return newConnectModel(server, newClient)
 }
 
-func newConnectModel(server *storage.Server) *ConnectModel {
+func newConnectModel(server *storage.Server, newClient ClientFactory) *ConnectModel {
 	inputs := make([]textinput.Model, 5)
 
 	inputs[inputHost] = textinput.New()
@@ -82,9 +90,10 @@ func newConnectModel(server *storage.Server) *ConnectModel {
 	inputs[inputPrivateKey].Prompt = "Private Key: "
 
 	m := &ConnectModel{
-		inputs:  inputs,
-		focused: 0,
-		server:  server,
+		inputs:    inputs,
+		focused:   0,
+		server:    server,
+		newClient: newClient,
 	}
 
 	// Pre-fill if server provided
@@ -112,6 +121,10 @@ func (m *ConnectModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 		return m, nil
 
+	case connectFailedMsg:
+		m.err = fmt.Errorf("connection failed: %w", msg.err)
+		return m, nil
+
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "tab", "shift+tab", "up", "down":
@@ -179,6 +192,8 @@ func (m *ConnectModel) connect() tea.Cmd {
 			Username:   m.inputs[inputUsername].Value(),
 			Password:   m.inputs[inputPassword].Value(),
 			PrivateKey: keyPath,
+			// Offer agent keys whenever an agent is running, like OpenSSH
+			UseAgent: os.Getenv("SSH_AUTH_SOCK") != "",
 		}
 
 		if err := config.Validate(); err != nil {
@@ -187,10 +202,9 @@ func (m *ConnectModel) connect() tea.Cmd {
 		}
 
 		// Create terminal model
-		termModel, err := NewTerminalModel(config)
+		termModel, err := NewTerminalModel(m.newClient(config))
 		if err != nil {
-			m.err = fmt.Errorf("connection failed: %w", err)
-			return nil
+			return connectFailedMsg{config: config, err: err}
 		}
 
 		return ConnectSuccessMsg{termModel: termModel}
@@ -7,10 +7,15 @@ import (
 	"os/exec"
 	"os/user"
 	"runtime"
+	"strings"
+
+	"github.com/quocson95/marix/pkg/ssh"
 )
 
-// LaunchExternalTerminal opens an SSH connection in the OS's default terminal
-func LaunchExternalTerminal(host string, port int, username, password, privateKey string) error {
+// LaunchExternalTerminal opens an SSH connection in the OS's default terminal.
+// jumpHosts is an ssh -J spec ("user@host:port,..."), empty for direct connections;
+// forwardArgs are extra -L/-R/-D flags.
+func LaunchExternalTerminal(host string, port int, username, password, privateKey, jumpHosts string, forwardArgs []string) error {
 	var cmd *exec.Cmd
 
 	// Handle internal private key content vs path
@@ -98,10 +103,17 @@ func LaunchExternalTerminal(host string, port int, username, password, privateKe
 	}
 
 	// Build SSH command string for Linux/macOS
-	sshCmd := fmt.Sprintf("ssh -p %d %s@%s", port, username, host)
+	sshCmd := "ssh"
+	if jumpHosts != "" {
+		sshCmd += " -J " + jumpHosts
+	}
 	if keyPath != "" {
-		sshCmd = fmt.Sprintf("ssh -i %s -p %d %s@%s", keyPath, port, username, host)
+		sshCmd += " -i " + keyPath
 	}
+	if len(forwardArgs) > 0 {
+		sshCmd += " " + strings.Join(forwardArgs, " ")
+	}
+	sshCmd += fmt.Sprintf(" -p %d %s@%s", port, username, host)
 
 	// Detect OS and use appropriate terminal
 	switch runtime.GOOS {
@@ -159,6 +171,10 @@ func LaunchExternalTerminal(host string, port int, username, password, privateKe
 		if keyPath != "" {
 			args = append(args, "-i", keyPath)
 		}
+		if jumpHosts != "" {
+			args = append(args, "-J", jumpHosts)
+		}
+		args = append(args, forwardArgs...)
 
 		args = append(args, fmt.Sprintf("%s@%s", username, host))
 
@@ -184,3 +200,17 @@ func LaunchExternalTerminal(host string, port int, username, password, privateKe
 		return fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
 	}
 }
+
+// sshForwardArgs converts a forward to the equivalent ssh command-line flags
+func sshForwardArgs(f ssh.Forward) []string {
+	switch f.Type {
+	case ssh.ForwardLocal:
+		return []string{"-L", f.Listen + ":" + f.Target}
+	case ssh.ForwardRemote:
+		return []string{"-R", f.Listen + ":" + f.Target}
+	case ssh.ForwardDynamic:
+		return []string{"-D", f.Listen}
+	default:
+		return nil
+	}
+}
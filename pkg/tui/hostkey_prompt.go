@@ -0,0 +1,155 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/quocson95/marix/pkg/ssh"
+)
+
+// hostKeyRequest is a pending host key decision from a connecting goroutine
+type hostKeyRequest struct {
+	prompt ssh.HostKeyPrompt
+	reply  chan ssh.HostKeyDecision
+}
+
+// hostKeyRequestMsg is delivered to the app when a host key needs a decision
+type hostKeyRequestMsg struct {
+	req hostKeyRequest
+}
+
+// HostKeyDecisionMsg is sent when the user answers a host key prompt
+type HostKeyDecisionMsg struct {
+	Decision ssh.HostKeyDecision
+}
+
+// newHostKeyVerifier creates a known_hosts verifier whose prompts are routed
+// to the TUI. Connect runs inside a tea.Cmd, so the prompt blocks that
+// goroutine until the user answers through the app's request channel.
+func newHostKeyVerifier(knownHostsPath string, requests chan<- hostKeyRequest) *ssh.KnownHostsVerifier {
+	return ssh.NewKnownHostsVerifier(knownHostsPath, func(prompt ssh.HostKeyPrompt) ssh.HostKeyDecision {
+		reply := make(chan ssh.HostKeyDecision, 1)
+		requests <- hostKeyRequest{prompt: prompt, reply: reply}
+		return <-reply
+	})
+}
+
+// waitForHostKeyRequest listens for the next host key prompt
+func waitForHostKeyRequest(requests <-chan hostKeyRequest) tea.Cmd {
+	return func() tea.Msg {
+		return hostKeyRequestMsg{req: <-requests}
+	}
+}
+
+// HostKeyPromptModel asks the user to trust an unknown or changed host key
+type HostKeyPromptModel struct {
+	prompt ssh.HostKeyPrompt
+	width  int
+	height int
+}
+
+// NewHostKeyPromptModel creates a new host key prompt
+func NewHostKeyPromptModel(prompt ssh.HostKeyPrompt) *HostKeyPromptModel {
+	return &HostKeyPromptModel{
+		prompt: prompt,
+	}
+}
+
+func (m *HostKeyPromptModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *HostKeyPromptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		return m, nil
+
+	case tea.KeyMsg:
+		decision := ssh.HostKeyReject
+		switch msg.String() {
+		case "y":
+			// Plain "yes" only trusts hosts we have never seen
+			if m.prompt.Status != ssh.HostKeyUnknown {
+				return m, nil
+			}
+			decision = ssh.HostKeyAccept
+		case "R":
+			// Replacing a changed key needs a deliberate capital R
+			if m.prompt.Status != ssh.HostKeyChanged {
+				return m, nil
+			}
+			decision = ssh.HostKeyReplace
+		case "n", "esc":
+			decision = ssh.HostKeyReject
+		default:
+			return m, nil
+		}
+
+		return m, func() tea.Msg {
+			return HostKeyDecisionMsg{Decision: decision}
+		}
+	}
+
+	return m, nil
+}
+
+func (m *HostKeyPromptModel) View() string {
+	if m.prompt.Status == ssh.HostKeyChanged {
+		return m.viewChanged()
+	}
+
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("🔑 Unknown Host"))
+	b.WriteString("\n\n")
+	b.WriteString(fmt.Sprintf("The authenticity of host '%s' can't be established.\n", m.prompt.Hostname))
+	if m.prompt.RemoteAddr != "" && m.prompt.RemoteAddr != m.prompt.Hostname {
+		b.WriteString(fmt.Sprintf("Remote address: %s\n", m.prompt.RemoteAddr))
+	}
+	b.WriteString(fmt.Sprintf("%s key fingerprint is:\n\n", m.prompt.KeyType))
+	b.WriteString(selectedItemStyle.Render(m.prompt.Fingerprint))
+	b.WriteString("\n\n")
+	b.WriteString(fmt.Sprintf("Accepting will add the key to %s", m.prompt.KnownHosts))
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render("y: trust and connect • n/esc: reject"))
+
+	return boxStyle.Render(b.String())
+}
+
+func (m *HostKeyPromptModel) viewChanged() string {
+	var b strings.Builder
+
+	warnStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#FFFFFF")).
+		Background(lipgloss.Color("#FF0000")).
+		Padding(0, 1)
+
+	b.WriteString(warnStyle.Render("⚠️  WARNING: REMOTE HOST IDENTIFICATION HAS CHANGED!"))
+	b.WriteString("\n\n")
+	b.WriteString(errorStyle.Render("IT IS POSSIBLE THAT SOMEONE IS DOING SOMETHING NASTY!"))
+	b.WriteString("\n\n")
+	b.WriteString("Someone could be eavesdropping on you right now (man-in-the-middle attack).\n")
+	b.WriteString("It is also possible that the host key has just been changed.\n\n")
+	b.WriteString(fmt.Sprintf("Host: %s\n", m.prompt.Hostname))
+	b.WriteString(fmt.Sprintf("The %s key fingerprint sent by the remote host is:\n\n", m.prompt.KeyType))
+	b.WriteString(errorStyle.Render(m.prompt.Fingerprint))
+	b.WriteString("\n\n")
+	if len(m.prompt.KnownKeys) > 0 {
+		b.WriteString("Offending known_hosts entries:\n")
+		for _, entry := range m.prompt.KnownKeys {
+			b.WriteString("  " + entry + "\n")
+		}
+	}
+	b.WriteString("\n")
+	b.WriteString("Only replace the key if you have verified the new fingerprint out of band.")
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render("R: replace key and connect • n/esc: abort"))
+
+	box := boxStyle.BorderForeground(lipgloss.Color("#FF0000"))
+	return box.Render(b.String())
+}
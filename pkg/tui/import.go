@@ -0,0 +1,359 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os/user"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/quocson95/marix/pkg/ssh"
+	"github.com/quocson95/marix/pkg/sshconfig"
+	"github.com/quocson95/marix/pkg/storage"
+)
+
+// ServerImportMsg opens the ~/.ssh/config import screen
+type ServerImportMsg struct{}
+
+// ServersImportedMsg is sent when the selected hosts have been saved
+type ServersImportedMsg struct {
+	imported int
+	notes    []string // Hosts skipped or imported with problems
+}
+
+// importCandidate is one host from the config file
+type importCandidate struct {
+	host      sshconfig.Host
+	user      string
+	selected  bool
+	duplicate bool // Already saved with the same connection ID
+}
+
+func (c importCandidate) connectionID() string {
+	return connectionID(c.user, c.host.HostName, c.host.Port)
+}
+
+// ImportModel previews ~/.ssh/config hosts and imports the selected ones
+type ImportModel struct {
+	store          *storage.Store
+	settingsStore  *storage.SettingsStore
+	masterPassword string
+	config         *sshconfig.Config
+	path           string
+	candidates     []importCandidate
+	cursor         int
+	err            error
+	width          int
+	height         int
+}
+
+// NewImportModel parses the user's ssh config and marks already saved hosts
+func NewImportModel(store *storage.Store, settingsStore *storage.SettingsStore, masterPassword string) *ImportModel {
+	m := &ImportModel{
+		store:          store,
+		settingsStore:  settingsStore,
+		masterPassword: masterPassword,
+	}
+
+	path, err := sshconfig.DefaultPath()
+	if err != nil {
+		m.err = err
+		return m
+	}
+	m.path = path
+
+	config, err := sshconfig.ParseFile(path)
+	if err != nil {
+		m.err = err
+		return m
+	}
+	m.config = config
+
+	saved := make(map[string]bool)
+	for _, srv := range store.List() {
+		saved[connectionID(srv.Username, srv.Host, srv.Port)] = true
+	}
+
+	for _, host := range config.Hosts() {
+		c := importCandidate{host: host, user: host.User}
+		if c.user == "" {
+			c.user = defaultUsername()
+		}
+		c.duplicate = saved[c.connectionID()]
+		c.selected = !c.duplicate
+		m.candidates = append(m.candidates, c)
+	}
+
+	return m
+}
+
+func (m *ImportModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *ImportModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+
+		case "down", "j":
+			if m.cursor < len(m.candidates)-1 {
+				m.cursor++
+			}
+
+		case " ":
+			if m.cursor < len(m.candidates) {
+				m.candidates[m.cursor].selected = !m.candidates[m.cursor].selected
+			}
+
+		case "a":
+			// Select all new hosts, or clear the selection if they already are
+			allSelected := true
+			for _, c := range m.candidates {
+				if !c.duplicate && !c.selected {
+					allSelected = false
+				}
+			}
+			for i := range m.candidates {
+				m.candidates[i].selected = !allSelected && !m.candidates[i].duplicate
+			}
+
+		case "enter":
+			return m, m.importSelected()
+		}
+	}
+
+	return m, nil
+}
+
+// importSelected saves the selected hosts, wiring ProxyJump to saved servers
+func (m *ImportModel) importSelected() tea.Cmd {
+	return func() tea.Msg {
+		keyPassword, err := keyEncryptionPassword(m.settingsStore, m.masterPassword)
+		if err != nil {
+			m.err = err
+			return nil
+		}
+
+		existing := m.store.List()
+		byName := make(map[string]string)
+		byConnection := make(map[string]string)
+		for _, srv := range existing {
+			byName[strings.ToLower(srv.Name)] = srv.ID
+			byConnection[connectionID(srv.Username, srv.Host, srv.Port)] = srv.ID
+		}
+
+		// Hosts of this import by alias: the ID once saved, empty until then.
+		// An alias listed twice would save two servers under one name.
+		now := time.Now()
+		batch := make(map[string]string)
+		seen := make(map[string]bool)
+		var notes []string
+		var pending []int
+		for i, c := range m.candidates {
+			if !c.selected {
+				continue
+			}
+			if seen[strings.ToLower(c.host.Alias)] {
+				notes = append(notes, fmt.Sprintf("%s skipped: alias listed more than once", c.host.Alias))
+				continue
+			}
+			seen[strings.ToLower(c.host.Alias)] = true
+			batch[c.host.Alias] = ""
+			pending = append(pending, i)
+		}
+
+		// Save hosts after the jump hosts they go through, so JumpHosts only
+		// ever holds IDs of servers that were saved
+		imported := 0
+		for len(pending) > 0 {
+			var waiting []int
+			for _, i := range pending {
+				c := m.candidates[i]
+				jumpHosts, err := m.resolveJumps(c.host.ProxyJump, batch, byName, byConnection)
+				if errors.Is(err, errJumpPending) {
+					waiting = append(waiting, i)
+					continue
+				}
+
+				var server *storage.Server
+				if err == nil {
+					server = m.newServer(c, fmt.Sprintf("server-%d-%d", now.Unix(), i), jumpHosts, keyPassword, &notes)
+					err = m.store.Add(server)
+				}
+				if err != nil {
+					// Hosts jumping through this one are skipped in turn
+					notes = append(notes, fmt.Sprintf("%s skipped: %v", c.host.Alias, err))
+					delete(batch, c.host.Alias)
+					continue
+				}
+				batch[c.host.Alias] = server.ID
+				m.candidates[i].duplicate = true
+				m.candidates[i].selected = false
+				imported++
+			}
+
+			if len(waiting) == len(pending) {
+				// Every host left jumps through another one left
+				for _, i := range waiting {
+					notes = append(notes, fmt.Sprintf("%s skipped: its jump hosts go through each other", m.candidates[i].host.Alias))
+				}
+				break
+			}
+			pending = waiting
+		}
+
+		return ServersImportedMsg{imported: imported, notes: notes}
+	}
+}
+
+// newServer builds the server saved for an imported host, noting key problems
+func (m *ImportModel) newServer(c importCandidate, id string, jumpHosts []string, keyPassword string, notes *[]string) *storage.Server {
+	now := time.Now().Unix()
+	server := &storage.Server{
+		ID:        id,
+		Name:      c.host.Alias,
+		Host:      c.host.HostName,
+		Port:      c.host.Port,
+		Username:  c.user,
+		Protocol:  "ssh",
+		JumpHosts: jumpHosts,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if c.host.IdentityFile != "" {
+		content, encrypted, salt, err := readServerKey(c.host.IdentityFile, keyPassword)
+		if err != nil {
+			*notes = append(*notes, fmt.Sprintf("%s: %v", c.host.Alias, err))
+		} else {
+			server.PrivateKey = content
+			server.PrivateKeyEncrypted = encrypted
+			server.KeyEncryptionSalt = salt
+		}
+	}
+	return server
+}
+
+// errJumpPending means a jump host from the same import is not saved yet
+var errJumpPending = errors.New("jump host not imported yet")
+
+// resolveJumps maps ProxyJump specs to server IDs: hosts saved by this
+// import, saved servers by name, then saved servers by connection ID. It
+// returns errJumpPending while a jump host of the import is still unsaved.
+func (m *ImportModel) resolveJumps(specs []string, batch, byName, byConnection map[string]string) ([]string, error) {
+	var jumpHosts []string
+	for _, spec := range specs {
+		if id, ok := batch[spec]; ok {
+			if id == "" {
+				return nil, errJumpPending
+			}
+			jumpHosts = append(jumpHosts, id)
+			continue
+		}
+		if id, ok := byName[strings.ToLower(spec)]; ok {
+			jumpHosts = append(jumpHosts, id)
+			continue
+		}
+
+		// The spec may name a config alias or a plain [user@]host[:port]
+		username, host, port := sshconfig.SplitJump(spec)
+		resolved := m.config.Resolve(host)
+		if username == "" {
+			username = resolved.User
+		}
+		if username == "" {
+			username = defaultUsername()
+		}
+		if port == 0 {
+			port = resolved.Port
+		}
+		if id, ok := byConnection[connectionID(username, resolved.HostName, port)]; ok {
+			jumpHosts = append(jumpHosts, id)
+			continue
+		}
+
+		return nil, fmt.Errorf("jump host %s is not saved", spec)
+	}
+	return jumpHosts, nil
+}
+
+func (m *ImportModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("📥 Import from SSH Config"))
+	b.WriteString("\n")
+	if m.path != "" {
+		b.WriteString(helpStyle.Render(m.path))
+	}
+	b.WriteString("\n\n")
+
+	if m.err != nil && m.config == nil {
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", m.err)))
+		b.WriteString("\n\n")
+		b.WriteString(helpStyle.Render("esc: back"))
+		return boxStyle.Render(b.String())
+	}
+
+	if len(m.candidates) == 0 {
+		b.WriteString(helpStyle.Render("No hosts found."))
+	}
+
+	for i, c := range m.candidates {
+		cursor := "  "
+		style := itemStyle
+		if m.cursor == i {
+			cursor = "→ "
+			style = selectedItemStyle
+		}
+
+		status := "☐"
+		if c.selected {
+			status = "☑"
+		}
+
+		info := fmt.Sprintf("%s %s (%s)", status, c.host.Alias, c.connectionID())
+		if len(c.host.ProxyJump) > 0 {
+			info += " via " + strings.Join(c.host.ProxyJump, " → ")
+		}
+
+		b.WriteString(cursor + style.Render(info))
+		if c.duplicate {
+			b.WriteString(helpStyle.Render("  already saved"))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render("↑/k up • ↓/j down • space: select • a: all/none • enter: import • esc: back"))
+
+	if m.err != nil {
+		b.WriteString("\n\n")
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", m.err)))
+	}
+
+	return boxStyle.Render(b.String())
+}
+
+// connectionID matches ssh.SSHConfig.ConnectionID for duplicate detection
+func connectionID(username, host string, port int) string {
+	return (&ssh.SSHConfig{Host: host, Port: port, Username: username}).ConnectionID()
+}
+
+// defaultUsername is the local login name, as OpenSSH uses when User is unset
+func defaultUsername() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "root"
+}
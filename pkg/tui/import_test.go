@@ -0,0 +1,72 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/quocson95/marix/pkg/sshconfig"
+	"github.com/quocson95/marix/pkg/storage"
+)
+
+func TestImport_JumpHostOrder(t *testing.T) {
+	dir := t.TempDir()
+	store, err := storage.NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settingsStore, err := storage.NewSettingsStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	host := func(alias string, jumps ...string) importCandidate {
+		return importCandidate{
+			host:     sshconfig.Host{Alias: alias, HostName: alias + ".example.com", Port: 22, ProxyJump: jumps},
+			user:     "deploy",
+			selected: true,
+		}
+	}
+	m := &ImportModel{
+		store:         store,
+		settingsStore: settingsStore,
+		config:        &sshconfig.Config{},
+		candidates: []importCandidate{
+			host("app", "bastion"), // Listed before its jump host
+			host("bastion"),
+			host("db", "missing"),
+			host("cache", "db"), // Jumps through a host that is skipped
+			host("Bastion"),     // Same alias twice
+			host("a", "b"),
+			host("b", "a"),
+		},
+	}
+
+	msg := m.importSelected()().(ServersImportedMsg)
+	if msg.imported != 2 {
+		t.Errorf("Expected app and bastion to be imported, got %d (%v)", msg.imported, msg.notes)
+	}
+	for _, alias := range []string{"db", "cache", "Bastion", "a", "b"} {
+		found := false
+		for _, note := range msg.notes {
+			found = found || strings.HasPrefix(note, alias+" skipped")
+		}
+		if !found {
+			t.Errorf("Expected %s to be skipped, got %v", alias, msg.notes)
+		}
+	}
+
+	ids := make(map[string]bool)
+	for _, srv := range store.List() {
+		ids[srv.ID] = true
+	}
+	for _, srv := range store.List() {
+		for _, jump := range srv.JumpHosts {
+			if !ids[jump] {
+				t.Errorf("%s jumps through %s, which was not saved", srv.Name, jump)
+			}
+		}
+		if srv.Name == "app" && len(srv.JumpHosts) != 1 {
+			t.Errorf("Expected app to jump through bastion, got %v", srv.JumpHosts)
+		}
+	}
+}
@@ -49,8 +49,12 @@ type MenuChoice int
 const (
 	MenuNone MenuChoice = iota
 	MenuConnect
+	MenuRecent
 	MenuServers
 	MenuSFTP
+	MenuTunnelProfiles
+	MenuRunCommand
+	MenuRecordings
 	MenuBackup
 	MenuSettings
 	MenuQuit
@@ -71,8 +75,12 @@ func InitialModel() Model {
 	return Model{
 		choices: []string{
 			"Connect to Server",
+			"Recent Connections",
 			"Manage Servers",
 			"SFTP Browser",
+			"Tunnel Profiles",
+			"Run Command",
+			"Recordings",
 			"Backup & Restore",
 			"Settings",
 			"Quit",
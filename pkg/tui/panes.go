@@ -0,0 +1,119 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var paneSeparatorStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#626262"))
+
+// paneNode is a node of a tab's split layout. Leaves hold a terminal
+// session; inner nodes hold two children split side by side (vertical) or
+// stacked, with a one cell separator between them.
+type paneNode struct {
+	term     *TerminalModel
+	vertical bool
+	children [2]*paneNode
+	parent   *paneNode
+}
+
+// terminals returns the sessions of the subtree in layout order
+func (n *paneNode) terminals() []*TerminalModel {
+	if n.term != nil {
+		return []*TerminalModel{n.term}
+	}
+	return append(n.children[0].terminals(), n.children[1].terminals()...)
+}
+
+// find returns the leaf holding term, or nil
+func (n *paneNode) find(term *TerminalModel) *paneNode {
+	if n.term != nil {
+		if n.term == term {
+			return n
+		}
+		return nil
+	}
+	if leaf := n.children[0].find(term); leaf != nil {
+		return leaf
+	}
+	return n.children[1].find(term)
+}
+
+// split turns the leaf n into two panes: its session first, term second
+func (n *paneNode) split(term *TerminalModel, vertical bool) {
+	n.children = [2]*paneNode{
+		{term: n.term, parent: n},
+		{term: term, parent: n},
+	}
+	n.term = nil
+	n.vertical = vertical
+}
+
+// remove takes the leaf n out of the layout; its sibling takes the place of
+// their parent. It returns false when n was the only pane.
+func (n *paneNode) remove() bool {
+	parent := n.parent
+	if parent == nil {
+		return false
+	}
+
+	sibling := parent.children[0]
+	if sibling == n {
+		sibling = parent.children[1]
+	}
+	parent.term = sibling.term
+	parent.vertical = sibling.vertical
+	parent.children = sibling.children
+	for _, child := range parent.children {
+		if child != nil {
+			child.parent = parent
+		}
+	}
+	return true
+}
+
+// splitSize divides size between two panes and the separator
+func splitSize(size int) (first, second int) {
+	first = max((size-1)/2, 1)
+	second = max(size-1-first, 1)
+	return first, second
+}
+
+// layout resizes every pane of the subtree to fit cols x rows
+func (n *paneNode) layout(cols, rows int) {
+	if n.term != nil {
+		n.term.Resize(cols, rows)
+		return
+	}
+	if n.vertical {
+		left, right := splitSize(cols)
+		n.children[0].layout(left, rows)
+		n.children[1].layout(right, rows)
+	} else {
+		top, bottom := splitSize(rows)
+		n.children[0].layout(cols, top)
+		n.children[1].layout(cols, bottom)
+	}
+}
+
+// view renders the subtree at cols x rows; focus gets the cursor
+func (n *paneNode) view(focus *TerminalModel, cols, rows int) string {
+	if n.term != nil {
+		return n.term.ScreenView(n.term == focus)
+	}
+	if n.vertical {
+		left, right := splitSize(cols)
+		separator := paneSeparatorStyle.Render(strings.TrimSuffix(strings.Repeat("│\n", rows), "\n"))
+		return lipgloss.JoinHorizontal(lipgloss.Top,
+			n.children[0].view(focus, left, rows),
+			separator,
+			n.children[1].view(focus, right, rows))
+	}
+	top, bottom := splitSize(rows)
+	return lipgloss.JoinVertical(lipgloss.Left,
+		n.children[0].view(focus, cols, top),
+		paneSeparatorStyle.Render(strings.Repeat("─", cols)),
+		n.children[1].view(focus, cols, bottom))
+}
@@ -139,6 +139,17 @@ func (m *PasswordPromptModel) View() string {
 	return boxStyle.Render(b.String())
 }
 
+// SetEcho shows the typed text, for answers such as OTP codes that aren't secret
+func (m *PasswordPromptModel) SetEcho(visible bool) {
+	if visible {
+		m.input.EchoMode = textinput.EchoNormal
+		m.input.Placeholder = "Enter response"
+	} else {
+		m.input.EchoMode = textinput.EchoPassword
+		m.input.Placeholder = "Enter password"
+	}
+}
+
 func (m *PasswordPromptModel) SetError(err error) {
 	if err != nil {
 		m.isError = true
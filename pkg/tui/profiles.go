@@ -0,0 +1,448 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/quocson95/marix/pkg/ssh"
+	"github.com/quocson95/marix/pkg/storage"
+)
+
+// profileRunner owns the tunnel profiles started from the TUI; they keep
+// running while the user moves between screens
+type profileRunner struct {
+	manager *ssh.Manager
+	store   *storage.Store
+	tunnels map[string]*ssh.PersistentTunnel // By profile ID
+}
+
+func newProfileRunner(store *storage.Store, newClient ClientFactory) *profileRunner {
+	manager := ssh.NewManager()
+	manager.SetClientFactory(newClient)
+	return &profileRunner{
+		manager: manager,
+		store:   store,
+		tunnels: make(map[string]*ssh.PersistentTunnel),
+	}
+}
+
+// start connects a profile in the background
+func (r *profileRunner) start(profile *storage.TunnelProfile, masterPassword string) error {
+	if t, ok := r.tunnels[profile.ID]; ok && t.Running() {
+		return nil
+	}
+
+	tunnel, err := ssh.NewProfileTunnel(r.manager, r.store, profile, masterPassword)
+	if err != nil {
+		return err
+	}
+	tunnel.Start()
+	r.tunnels[profile.ID] = tunnel
+	return nil
+}
+
+// stop closes a profile's forwards; the last health stays visible
+func (r *profileRunner) stop(id string) {
+	if t, ok := r.tunnels[id]; ok {
+		t.Stop()
+	}
+}
+
+func (r *profileRunner) health(id string) (ssh.TunnelHealth, bool) {
+	t, ok := r.tunnels[id]
+	if !ok {
+		return ssh.TunnelHealth{State: ssh.TunnelDown}, false
+	}
+	return t.Health(), t.Running()
+}
+
+const (
+	profileName = iota
+	profileServer
+	profileForwards
+)
+
+var (
+	tunnelUpStyle           = lipgloss.NewStyle().Foreground(lipgloss.Color("#04B575"))
+	tunnelReconnectingStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFA500"))
+	tunnelDownStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("#626262"))
+)
+
+// ProfilesModel lists tunnel profiles with their health and lets the user
+// start, stop, add, edit and delete them
+type ProfilesModel struct {
+	tunnelStore    *storage.TunnelStore
+	store          *storage.Store
+	runner         *profileRunner
+	masterPassword string
+	profiles       []*storage.TunnelProfile
+	cursor         int
+
+	editing bool
+	editID  string // Profile being edited; empty when adding
+	inputs  []textinput.Model
+	focused int
+	err     error
+	status  string
+	width   int
+	height  int
+}
+
+// NewProfilesModel creates the tunnel profiles screen
+func NewProfilesModel(tunnelStore *storage.TunnelStore, store *storage.Store, runner *profileRunner, masterPassword string) *ProfilesModel {
+	inputs := make([]textinput.Model, 3)
+
+	inputs[profileName] = textinput.New()
+	inputs[profileName].Placeholder = "Prod DB"
+	inputs[profileName].CharLimit = 64
+	inputs[profileName].Width = 50
+	inputs[profileName].Prompt = "Name: "
+
+	inputs[profileServer] = textinput.New()
+	inputs[profileServer].Placeholder = "saved server name"
+	inputs[profileServer].CharLimit = 128
+	inputs[profileServer].Width = 50
+	inputs[profileServer].Prompt = "Server: "
+
+	inputs[profileForwards] = textinput.New()
+	inputs[profileForwards].Placeholder = "L 5432:db.internal:5432, D 1080"
+	inputs[profileForwards].CharLimit = 512
+	inputs[profileForwards].Width = 50
+	inputs[profileForwards].Prompt = "Forwards: "
+
+	return &ProfilesModel{
+		tunnelStore:    tunnelStore,
+		store:          store,
+		runner:         runner,
+		masterPassword: masterPassword,
+		profiles:       tunnelStore.List(),
+		inputs:         inputs,
+	}
+}
+
+func (m *ProfilesModel) Init() tea.Cmd {
+	return tunnelTick()
+}
+
+// IsInputActive reports whether the profile form is open, so esc closes it
+func (m *ProfilesModel) IsInputActive() bool {
+	return m.editing
+}
+
+func (m *ProfilesModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		return m, nil
+
+	case tunnelTickMsg:
+		// Health changes in the background, re-render
+		return m, tunnelTick()
+
+	case tea.KeyMsg:
+		if m.editing {
+			return m.updateForm(msg)
+		}
+
+		switch msg.String() {
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.profiles)-1 {
+				m.cursor++
+			}
+		case "enter", " ":
+			m.toggle()
+		case "a":
+			return m, m.openForm(nil)
+		case "e":
+			if m.cursor < len(m.profiles) {
+				return m, m.openForm(m.profiles[m.cursor])
+			}
+		case "d":
+			m.delete()
+		}
+	}
+
+	return m, nil
+}
+
+// toggle starts the selected profile, or stops it if running
+func (m *ProfilesModel) toggle() {
+	if m.cursor >= len(m.profiles) {
+		return
+	}
+	profile := m.profiles[m.cursor]
+
+	if _, running := m.runner.health(profile.ID); running {
+		m.runner.stop(profile.ID)
+		m.err = nil
+		m.status = "Stopped " + profile.Name
+		return
+	}
+
+	if err := m.runner.start(profile, m.masterPassword); err != nil {
+		m.err = err
+		return
+	}
+	m.err = nil
+	m.status = "Started " + profile.Name
+}
+
+func (m *ProfilesModel) delete() {
+	if m.cursor >= len(m.profiles) {
+		return
+	}
+	profile := m.profiles[m.cursor]
+
+	m.runner.stop(profile.ID)
+	delete(m.runner.tunnels, profile.ID)
+	if err := m.tunnelStore.Delete(profile.ID); err != nil {
+		m.err = err
+		return
+	}
+
+	m.profiles = m.tunnelStore.List()
+	if m.cursor >= len(m.profiles) && m.cursor > 0 {
+		m.cursor--
+	}
+	m.err = nil
+	m.status = "Deleted " + profile.Name
+}
+
+func (m *ProfilesModel) openForm(profile *storage.TunnelProfile) tea.Cmd {
+	m.editing = true
+	m.editID = ""
+	for i := range m.inputs {
+		m.inputs[i].SetValue("")
+	}
+
+	if profile != nil {
+		m.editID = profile.ID
+		m.inputs[profileName].SetValue(profile.Name)
+		if server, err := m.store.Get(profile.ServerID); err == nil {
+			m.inputs[profileServer].SetValue(server.Name)
+		} else {
+			m.inputs[profileServer].SetValue(profile.ServerID)
+		}
+		m.inputs[profileForwards].SetValue(formatForwards(profile.Forwards))
+	}
+
+	m.focused = 0
+	for i := range m.inputs {
+		m.inputs[i].Blur()
+	}
+	m.inputs[0].Focus()
+	return textinput.Blink
+}
+
+func (m *ProfilesModel) updateForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.editing = false
+		return m, nil
+
+	case "tab", "shift+tab", "up", "down":
+		if msg.String() == "up" || msg.String() == "shift+tab" {
+			m.focused--
+		} else {
+			m.focused++
+		}
+		if m.focused >= len(m.inputs) {
+			m.focused = 0
+		} else if m.focused < 0 {
+			m.focused = len(m.inputs) - 1
+		}
+		for i := range m.inputs {
+			if i == m.focused {
+				m.inputs[i].Focus()
+			} else {
+				m.inputs[i].Blur()
+			}
+		}
+		return m, nil
+
+	case "enter":
+		if err := m.saveForm(); err != nil {
+			m.err = err
+			return m, nil
+		}
+		m.editing = false
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.inputs[m.focused], cmd = m.inputs[m.focused].Update(msg)
+	return m, cmd
+}
+
+func (m *ProfilesModel) saveForm() error {
+	name := strings.TrimSpace(m.inputs[profileName].Value())
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	serverName := strings.TrimSpace(m.inputs[profileServer].Value())
+	var server *storage.Server
+	for _, srv := range m.store.List() {
+		if srv.ID == serverName || strings.EqualFold(srv.Name, serverName) {
+			server = srv
+			break
+		}
+	}
+	if server == nil {
+		return fmt.Errorf("unknown server: %s", serverName)
+	}
+
+	forwards, err := parseForwards(m.inputs[profileForwards].Value())
+	if err != nil {
+		return err
+	}
+	if len(forwards) == 0 {
+		return fmt.Errorf("at least one forward is required")
+	}
+
+	now := time.Now().Unix()
+	if m.editID == "" {
+		profile := &storage.TunnelProfile{
+			ID:        fmt.Sprintf("tunnel-%d", time.Now().UnixNano()),
+			Name:      name,
+			ServerID:  server.ID,
+			Forwards:  forwards,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := m.tunnelStore.Add(profile); err != nil {
+			return err
+		}
+		m.status = "Added " + name
+	} else {
+		profile, err := m.tunnelStore.Get(m.editID)
+		if err != nil {
+			return err
+		}
+		profile.Name = name
+		profile.ServerID = server.ID
+		profile.Forwards = forwards
+		profile.UpdatedAt = now
+		if err := m.tunnelStore.Update(profile); err != nil {
+			return err
+		}
+		m.status = "Saved " + name
+		if _, running := m.runner.health(profile.ID); running {
+			m.status += " (restart it to apply)"
+		}
+	}
+
+	m.err = nil
+	m.profiles = m.tunnelStore.List()
+	return nil
+}
+
+func (m *ProfilesModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("🛡  Tunnel Profiles"))
+	b.WriteString("\n\n")
+
+	if m.editing {
+		for i := range m.inputs {
+			b.WriteString(m.inputs[i].View())
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+		b.WriteString(helpStyle.Render("tab: next field • enter: save • esc: cancel"))
+	} else {
+		if len(m.profiles) == 0 {
+			b.WriteString(helpStyle.Render("No tunnel profiles. Press 'a' to add one."))
+			b.WriteString("\n")
+		}
+
+		for i, profile := range m.profiles {
+			b.WriteString(m.renderProfile(i, profile))
+		}
+
+		b.WriteString("\n")
+		b.WriteString(helpStyle.Render("↑/k up • ↓/j down • enter: start/stop • a: add • e: edit • d: delete • esc: back"))
+		b.WriteString("\n")
+		b.WriteString(helpStyle.Render("Running profiles stay up while you use other screens."))
+	}
+
+	if m.err != nil {
+		b.WriteString("\n\n")
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", m.err)))
+	} else if m.status != "" {
+		b.WriteString("\n\n")
+		b.WriteString(successStyle.Render(m.status))
+	}
+
+	return boxStyle.Render(b.String())
+}
+
+func (m *ProfilesModel) renderProfile(i int, profile *storage.TunnelProfile) string {
+	var b strings.Builder
+
+	cursor := "  "
+	style := itemStyle
+	if m.cursor == i {
+		cursor = "→ "
+		style = selectedItemStyle
+	}
+
+	serverName := profile.ServerID
+	if server, err := m.store.Get(profile.ServerID); err == nil {
+		serverName = server.Name
+	}
+
+	health, _ := m.runner.health(profile.ID)
+	b.WriteString(cursor + renderTunnelState(health.State) + " ")
+	b.WriteString(style.Render(fmt.Sprintf("%s via %s", profile.Name, serverName)))
+	b.WriteString("\n")
+
+	switch health.State {
+	case ssh.TunnelUp:
+		for _, stats := range health.Tunnels {
+			b.WriteString(helpStyle.Render(fmt.Sprintf("      %-32s conns: %d  ↑ %s  ↓ %s",
+				stats.Forward.String(), stats.ActiveConns,
+				formatSize(stats.BytesSent), formatSize(stats.BytesReceived))))
+			b.WriteString("\n")
+		}
+		b.WriteString(helpStyle.Render("      up since " + health.Since.Format("15:04:05")))
+		b.WriteString("\n")
+	case ssh.TunnelReconnecting:
+		retry := time.Until(health.NextRetry).Round(time.Second)
+		if retry < 0 {
+			retry = 0
+		}
+		b.WriteString(helpStyle.Render(fmt.Sprintf("      %s • attempt %d, retrying in %s", formatForwards(profile.Forwards), health.Attempts, retry)))
+		b.WriteString("\n")
+	default:
+		b.WriteString(helpStyle.Render("      " + formatForwards(profile.Forwards)))
+		b.WriteString("\n")
+	}
+
+	if health.LastError != nil {
+		b.WriteString(errorStyle.Render(fmt.Sprintf("      last error: %v", health.LastError)))
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+func renderTunnelState(state ssh.TunnelState) string {
+	switch state {
+	case ssh.TunnelUp:
+		return tunnelUpStyle.Render("● up          ")
+	case ssh.TunnelReconnecting:
+		return tunnelReconnectingStyle.Render("◌ reconnecting")
+	default:
+		return tunnelDownStyle.Render("○ down        ")
+	}
+}
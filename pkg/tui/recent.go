@@ -0,0 +1,285 @@
+package tui
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/quocson95/marix/pkg/ssh"
+	"github.com/quocson95/marix/pkg/storage"
+)
+
+// RecentReconnectMsg asks to connect again the way a history entry did
+type RecentReconnectMsg struct {
+	entry *storage.HistoryEntry
+}
+
+// RecentModel lists past connections to reconnect quickly and see why
+// attempts failed
+type RecentModel struct {
+	store        *storage.HistoryStore
+	entries      []*storage.HistoryEntry
+	cursor       int
+	failuresOnly bool
+	confirmClear bool // Waiting for y/n before clearing the history
+	err          error
+	width        int
+	height       int
+}
+
+// NewRecentModel creates the recent connections screen
+func NewRecentModel(store *storage.HistoryStore) *RecentModel {
+	m := &RecentModel{store: store}
+	m.refresh()
+	return m
+}
+
+func (m *RecentModel) Init() tea.Cmd {
+	return nil
+}
+
+// IsInputActive reports whether a clear is being confirmed, so esc cancels it
+func (m *RecentModel) IsInputActive() bool {
+	return m.confirmClear
+}
+
+// refresh reloads the history, newest first
+func (m *RecentModel) refresh() {
+	m.entries = nil
+	for _, entry := range m.store.List() {
+		if !m.failuresOnly || entry.Failed() {
+			m.entries = append(m.entries, entry)
+		}
+	}
+	if m.cursor >= len(m.entries) {
+		m.cursor = max(len(m.entries)-1, 0)
+	}
+}
+
+func (m *RecentModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.confirmClear {
+			switch strings.ToLower(msg.String()) {
+			case "y":
+				m.confirmClear = false
+				m.err = m.store.Clear()
+				m.refresh()
+			case "n", "esc":
+				m.confirmClear = false
+			}
+			return m, nil
+		}
+
+		switch msg.String() {
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.entries)-1 {
+				m.cursor++
+			}
+		case "f":
+			m.failuresOnly = !m.failuresOnly
+			m.refresh()
+		case "d":
+			if m.cursor < len(m.entries) {
+				m.err = m.store.Delete(m.entries[m.cursor].ID)
+				m.refresh()
+			}
+		case "C":
+			if len(m.store.List()) > 0 {
+				m.confirmClear = true
+			}
+		case "enter":
+			if m.cursor < len(m.entries) {
+				entry := m.entries[m.cursor]
+				return m, func() tea.Msg {
+					return RecentReconnectMsg{entry: entry}
+				}
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// historyTarget returns how an entry is shown: the saved server name, or
+// user@host for quick connects
+func historyTarget(entry *storage.HistoryEntry) string {
+	if entry.Name != "" {
+		return entry.Name
+	}
+	return fmt.Sprintf("%s@%s", entry.Username, entry.Host)
+}
+
+// historyStatus sums up how a connection went
+func historyStatus(entry *storage.HistoryEntry) string {
+	switch {
+	case entry.Failed():
+		return "✗ failed"
+	case entry.Protocol == storage.HistoryExternal:
+		return "✓ launched"
+	case entry.EndedAt == 0:
+		return "● open"
+	case entry.Disconnect != "":
+		return "⚠ lost after " + entry.Duration().String()
+	}
+	return "✓ " + entry.Duration().String()
+}
+
+func (m *RecentModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("🕘 Recent Connections"))
+	if m.failuresOnly {
+		b.WriteString(helpStyle.UnsetMarginTop().Render("failures only"))
+	}
+	b.WriteString("\n\n")
+
+	if len(m.entries) == 0 {
+		b.WriteString(helpStyle.Render("No connections yet."))
+		b.WriteString("\n")
+	}
+
+	// Keep the cursor in view
+	entries := m.entries
+	offset := 0
+	if limit := max(m.height-18, 5); len(entries) > limit {
+		offset = min(max(m.cursor-limit/2, 0), len(entries)-limit)
+		entries = entries[offset : offset+limit]
+	}
+	for i, entry := range entries {
+		cursor := "  "
+		style := itemStyle
+		if m.cursor == offset+i {
+			cursor = "→ "
+			style = selectedItemStyle
+		}
+		line := fmt.Sprintf("%s  %-30s %-8s %s",
+			time.Unix(entry.StartedAt, 0).Format("2006-01-02 15:04"),
+			historyTarget(entry),
+			entry.Protocol,
+			historyStatus(entry),
+		)
+		b.WriteString(cursor + style.Render(line) + "\n")
+	}
+
+	// Details of the selected connection
+	if m.cursor < len(m.entries) {
+		entry := m.entries[m.cursor]
+		b.WriteString("\n")
+		b.WriteString(helpStyle.UnsetMarginTop().Render(fmt.Sprintf("%s@%s:%d over %s", entry.Username, entry.Host, entry.Port, entry.Protocol)))
+		if entry.EndedAt != 0 {
+			b.WriteString("\n")
+			b.WriteString(helpStyle.UnsetMarginTop().Render(fmt.Sprintf("ended %s", time.Unix(entry.EndedAt, 0).Format("2006-01-02 15:04:05"))))
+		}
+		switch {
+		case entry.Failed():
+			b.WriteString("\n")
+			b.WriteString(errorStyle.Render(entry.Error))
+		case entry.Disconnect != "":
+			b.WriteString("\n")
+			b.WriteString(errorStyle.Render("Connection lost: " + entry.Disconnect))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	if m.confirmClear {
+		b.WriteString(errorStyle.Render("Clear the whole connection history? (y/n)"))
+	} else {
+		b.WriteString(helpStyle.Render("enter: reconnect • f: failures only • d: delete • C: clear history • esc: back"))
+	}
+
+	if m.err != nil {
+		b.WriteString("\n\n")
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", m.err)))
+	}
+
+	return boxStyle.Render(b.String())
+}
+
+// serverHistory starts a history entry for a saved server
+func serverHistory(server *storage.Server, protocol string) *storage.HistoryEntry {
+	return &storage.HistoryEntry{
+		ServerID: server.ID,
+		Name:     server.Name,
+		Host:     server.Host,
+		Port:     server.Port,
+		Username: server.Username,
+		Protocol: protocol,
+	}
+}
+
+// configHistory starts a history entry for a connection to config, from
+// the saved server with the same address when there is one
+func (m *AppModel) configHistory(config *ssh.SSHConfig, protocol string) *storage.HistoryEntry {
+	if server := m.savedServer(config); server != nil {
+		return serverHistory(server, protocol)
+	}
+	return &storage.HistoryEntry{
+		Host:     config.Host,
+		Port:     config.Port,
+		Username: config.Username,
+		Protocol: protocol,
+	}
+}
+
+// recordConnection adds entry to the history, failed when err is set, and
+// returns its ID. History is best effort, failing to save it only logs.
+func (m *AppModel) recordConnection(entry *storage.HistoryEntry, err error) string {
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if err := m.historyStore.Add(entry); err != nil {
+		log.Printf("Failed to record connection to %s: %v\n", entry.Host, err)
+		return ""
+	}
+	return entry.ID
+}
+
+// finishConnection records the end of a session; err is why it was lost
+func (m *AppModel) finishConnection(id string, err error) {
+	if id == "" {
+		return
+	}
+	if err := m.historyStore.Finish(id, err); err != nil {
+		log.Printf("Failed to record the end of connection %s: %v\n", id, err)
+	}
+}
+
+// reconnect connects again the way entry did: saved servers by their
+// current settings, quick connects through the prefilled connect form
+func (m *AppModel) reconnect(entry *storage.HistoryEntry) (tea.Model, tea.Cmd) {
+	server, err := m.store.Get(entry.ServerID)
+	if entry.ServerID == "" || err != nil {
+		m.state = StateConnect
+		m.connectModel = NewConnectModelWithServer(&storage.Server{
+			Host:     entry.Host,
+			Port:     entry.Port,
+			Username: entry.Username,
+		}, m.newClient)
+		return m, m.connectModel.Init()
+	}
+
+	switch entry.Protocol {
+	case storage.HistorySFTP:
+		return m, m.connectToSFTP(server)
+	case storage.HistoryExternal:
+		err := launchServer(m.store, server, m.masterPasswordCache)
+		m.recordConnection(serverHistory(server, storage.HistoryExternal), err)
+		m.recentModel.err = err
+		m.recentModel.refresh()
+		return m, nil
+	}
+	return m, m.connectServers([]*storage.Server{server})
+}
@@ -0,0 +1,352 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/quocson95/marix/pkg/asciicast"
+	"github.com/quocson95/marix/pkg/vt"
+)
+
+const (
+	playerFrame    = 50 * time.Millisecond
+	playerSeekStep = 5.0 // Seconds skipped by left/right
+	playerJumpStep = 30.0
+	playerMinSpeed = 0.25
+	playerMaxSpeed = 16.0
+)
+
+// playerTickMsg advances playback. Ticks of an earlier play/pause cycle
+// carry a stale generation and are dropped.
+type playerTickMsg struct {
+	gen int
+}
+
+// recordingFile is a recording found in the recordings directory
+type recordingFile struct {
+	path    string
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+// player replays a recording on its own emulated screen
+type player struct {
+	rec    *asciicast.Recording
+	name   string
+	screen *vt.Terminal
+	next   int     // Index of the next event to apply
+	pos    float64 // Playback position in seconds
+	speed  float64
+	paused bool
+	gen    int
+	last   time.Time // Wall clock of the last tick
+}
+
+func newPlayer(rec *asciicast.Recording, name string) *player {
+	p := &player{rec: rec, name: name, speed: 1}
+	p.rewind()
+	return p
+}
+
+// rewind resets the screen to the start of the recording
+func (p *player) rewind() {
+	p.screen = vt.New(p.rec.Header.Width, p.rec.Header.Height)
+	p.next = 0
+	p.pos = 0
+}
+
+// advance applies every event up to pos
+func (p *player) advance(pos float64) {
+	for p.next < len(p.rec.Events) && p.rec.Events[p.next].Time <= pos {
+		event := p.rec.Events[p.next]
+		switch event.Type {
+		case asciicast.EventOutput:
+			p.screen.Write([]byte(event.Data))
+		case asciicast.EventResize:
+			if cols, rows, ok := event.Size(); ok {
+				p.screen.Resize(cols, rows)
+			}
+		}
+		p.next++
+	}
+	p.pos = min(pos, p.rec.Duration())
+}
+
+// seek moves to pos. The screen can only be rebuilt forwards, so going back
+// replays from the start.
+func (p *player) seek(pos float64) {
+	pos = max(pos, 0)
+	if pos < p.pos {
+		p.rewind()
+	}
+	p.advance(pos)
+}
+
+// done reports whether every event has been played
+func (p *player) done() bool {
+	return p.next >= len(p.rec.Events)
+}
+
+// tick schedules the next frame of the current generation
+func (p *player) tick() tea.Cmd {
+	gen := p.gen
+	return tea.Tick(playerFrame, func(time.Time) tea.Msg {
+		return playerTickMsg{gen: gen}
+	})
+}
+
+// play resumes playback, restarting from the beginning once finished
+func (p *player) play() tea.Cmd {
+	if p.done() {
+		p.rewind()
+	}
+	p.paused = false
+	p.gen++
+	p.last = time.Now()
+	return p.tick()
+}
+
+// RecordingsModel lists the recorded sessions and replays them with
+// pause, seek and speed controls
+type RecordingsModel struct {
+	dir    string
+	files  []recordingFile
+	cursor int
+	player *player
+	err    error
+	width  int
+	height int
+}
+
+// NewRecordingsModel creates the recordings screen for the recordings in dir
+func NewRecordingsModel(dir string) *RecordingsModel {
+	m := &RecordingsModel{dir: dir}
+	m.refresh()
+	return m
+}
+
+func (m *RecordingsModel) Init() tea.Cmd {
+	return nil
+}
+
+// IsPlaying reports whether a recording is open, so esc closes it instead
+// of the screen
+func (m *RecordingsModel) IsPlaying() bool {
+	return m.player != nil
+}
+
+// refresh lists the recordings, newest first
+func (m *RecordingsModel) refresh() {
+	m.files = nil
+	entries, err := os.ReadDir(m.dir)
+	if err != nil && !os.IsNotExist(err) {
+		m.err = fmt.Errorf("failed to list recordings: %w", err)
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".cast" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		m.files = append(m.files, recordingFile{
+			path:    filepath.Join(m.dir, entry.Name()),
+			name:    strings.TrimSuffix(entry.Name(), ".cast"),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	sort.Slice(m.files, func(i, j int) bool {
+		return m.files[i].modTime.After(m.files[j].modTime)
+	})
+	if m.cursor >= len(m.files) {
+		m.cursor = max(len(m.files)-1, 0)
+	}
+}
+
+func (m *RecordingsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		return m, nil
+
+	case playerTickMsg:
+		p := m.player
+		if p == nil || p.paused || msg.gen != p.gen {
+			return m, nil
+		}
+		now := time.Now()
+		p.advance(p.pos + now.Sub(p.last).Seconds()*p.speed)
+		p.last = now
+		if p.done() {
+			p.paused = true
+			return m, nil
+		}
+		return m, p.tick()
+
+	case tea.KeyMsg:
+		if m.player != nil {
+			return m.updatePlayer(msg)
+		}
+
+		switch msg.String() {
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.files)-1 {
+				m.cursor++
+			}
+		case "r":
+			m.err = nil
+			m.refresh()
+		case "enter":
+			if m.cursor >= len(m.files) {
+				return m, nil
+			}
+			file := m.files[m.cursor]
+			rec, err := asciicast.Open(file.path)
+			if err != nil {
+				m.err = err
+				return m, nil
+			}
+			m.err = nil
+			m.player = newPlayer(rec, file.name)
+			return m, m.player.play()
+		}
+	}
+
+	return m, nil
+}
+
+func (m *RecordingsModel) updatePlayer(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.player
+
+	switch msg.String() {
+	case "esc", "q":
+		m.player = nil
+		return m, nil
+
+	case " ", "p":
+		if p.paused {
+			return m, p.play()
+		}
+		p.paused = true
+
+	case "left", "h":
+		p.seek(p.pos - playerSeekStep)
+	case "right", "l":
+		p.seek(p.pos + playerSeekStep)
+	case "down", "j":
+		p.seek(p.pos - playerJumpStep)
+	case "up", "k":
+		p.seek(p.pos + playerJumpStep)
+	case "home", "0":
+		p.seek(0)
+	case "end":
+		p.seek(p.rec.Duration())
+
+	case ".":
+		// Step to the next event while paused
+		if p.paused && !p.done() {
+			p.advance(p.rec.Events[p.next].Time)
+		}
+
+	case "+", "=":
+		p.speed = min(p.speed*2, playerMaxSpeed)
+	case "-":
+		p.speed = max(p.speed/2, playerMinSpeed)
+	}
+
+	// Keep the clock in step so a seek is not followed by a jump
+	p.last = time.Now()
+	return m, nil
+}
+
+// formatPlayerTime formats seconds as m:ss
+func formatPlayerTime(seconds float64) string {
+	d := time.Duration(seconds * float64(time.Second)).Round(time.Second)
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+func (m *RecordingsModel) View() string {
+	if m.player != nil {
+		return m.playerView()
+	}
+
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("⏺ Recordings"))
+	b.WriteString(helpStyle.UnsetMarginTop().Render(m.dir))
+	b.WriteString("\n\n")
+
+	if len(m.files) == 0 {
+		b.WriteString(helpStyle.Render("No recordings. Press ctrl+] then R in a terminal, or turn on recording in Settings."))
+		b.WriteString("\n")
+	}
+
+	// Keep the cursor in view
+	files := m.files
+	offset := 0
+	if limit := max(m.height-12, 5); len(files) > limit {
+		offset = min(max(m.cursor-limit/2, 0), len(files)-limit)
+		files = files[offset : offset+limit]
+	}
+	for i, file := range files {
+		cursor := "  "
+		style := itemStyle
+		if m.cursor == offset+i {
+			cursor = "→ "
+			style = selectedItemStyle
+		}
+		line := fmt.Sprintf("%-50s %s  %6.1f KB", file.name, file.modTime.Format("2006-01-02 15:04"), float64(file.size)/1024)
+		b.WriteString(cursor + style.Render(line) + "\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render("enter: play • r: refresh • esc: back"))
+
+	if m.err != nil {
+		b.WriteString("\n\n")
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", m.err)))
+	}
+
+	return boxStyle.Render(b.String())
+}
+
+func (m *RecordingsModel) playerView() string {
+	p := m.player
+
+	state := "▶"
+	if p.paused {
+		state = "⏸"
+	}
+	if p.done() {
+		state = "⏹"
+	}
+
+	// Progress bar between the times
+	const barWidth = 30
+	filled := barWidth
+	if duration := p.rec.Duration(); duration > 0 {
+		filled = int(p.pos / duration * barWidth)
+	}
+	bar := strings.Repeat("━", filled) + strings.Repeat("─", barWidth-filled)
+
+	status := fmt.Sprintf("%s %s %s %s  %gx  %s", state,
+		formatPlayerTime(p.pos), bar, formatPlayerTime(p.rec.Duration()), p.speed, p.name)
+
+	return p.screen.Render(false) + "\n" +
+		terminalStatusStyle.Render(status) + "\n" +
+		terminalStatusStyle.Render("space: pause • ←/→: 5s • ↑/↓: 30s • home/end: start/end • .: step • +/-: speed • esc: close")
+}
@@ -0,0 +1,420 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/quocson95/marix/pkg/ssh"
+	"github.com/quocson95/marix/pkg/storage"
+)
+
+const (
+	runTag = iota
+	runCommand
+	runConcurrency
+)
+
+// runOutputLines is how much streamed output the screen keeps
+const runOutputLines = 500
+
+const defaultRunConcurrency = 5
+
+var runHostColors = []lipgloss.Color{"#7D56F4", "#04B575", "#FFA500", "#00BFFF", "#FF69B4", "#9ACD32"}
+
+// runLineMsg carries a line of output from one host
+type runLineMsg struct {
+	line ssh.ExecLine
+}
+
+// runResultMsg reports a host that finished
+type runResultMsg struct {
+	result ssh.ExecResult
+}
+
+// runDoneMsg is sent when every host finished
+type runDoneMsg struct {
+	results []ssh.ExecResult
+}
+
+// RunModel runs a command on every saved server with a tag and streams the
+// output per host
+type RunModel struct {
+	store          *storage.Store
+	newClient      ClientFactory
+	masterPassword string
+	reportsDir     string
+
+	inputs  []textinput.Model
+	focused int
+
+	running bool
+	cancel  context.CancelFunc
+	events  chan tea.Msg
+	command string
+	hosts   []string
+	status  map[string]*ssh.ExecResult // Finished hosts
+	output  []ssh.ExecLine
+	results []ssh.ExecResult
+	report  string
+	err     error
+	width   int
+	height  int
+}
+
+// NewRunModel creates the run command screen; reports are saved in reportsDir
+func NewRunModel(store *storage.Store, newClient ClientFactory, masterPassword, reportsDir string) *RunModel {
+	inputs := make([]textinput.Model, 3)
+
+	inputs[runTag] = textinput.New()
+	inputs[runTag].Placeholder = "prod or group:prod/eu"
+	inputs[runTag].CharLimit = 64
+	inputs[runTag].Width = 50
+	inputs[runTag].Prompt = "Tag: "
+
+	inputs[runCommand] = textinput.New()
+	inputs[runCommand].Placeholder = "uptime"
+	inputs[runCommand].CharLimit = 1024
+	inputs[runCommand].Width = 50
+	inputs[runCommand].Prompt = "Command: "
+
+	inputs[runConcurrency] = textinput.New()
+	inputs[runConcurrency].Placeholder = strconv.Itoa(defaultRunConcurrency)
+	inputs[runConcurrency].CharLimit = 3
+	inputs[runConcurrency].Width = 50
+	inputs[runConcurrency].Prompt = "Parallel hosts: "
+
+	inputs[runTag].Focus()
+
+	return &RunModel{
+		store:          store,
+		newClient:      newClient,
+		masterPassword: masterPassword,
+		reportsDir:     reportsDir,
+		inputs:         inputs,
+	}
+}
+
+func (m *RunModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// SetTarget fills in the tag or group selector and moves on to the command
+func (m *RunModel) SetTarget(selector string) {
+	m.inputs[runTag].SetValue(selector)
+	m.inputs[runTag].Blur()
+	m.focused = runCommand
+	m.inputs[runCommand].Focus()
+}
+
+// IsRunning reports whether hosts are still running, so esc cancels instead
+// of leaving the screen
+func (m *RunModel) IsRunning() bool {
+	return m.running
+}
+
+func (m *RunModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		return m, nil
+
+	case runLineMsg:
+		m.output = append(m.output, msg.line)
+		if len(m.output) > runOutputLines {
+			m.output = m.output[len(m.output)-runOutputLines:]
+		}
+		return m, m.waitForEvent()
+
+	case runResultMsg:
+		result := msg.result
+		m.status[result.Target] = &result
+		return m, m.waitForEvent()
+
+	case runDoneMsg:
+		m.running = false
+		m.cancel()
+		m.results = msg.results
+		m.report, m.err = m.saveReport()
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.running {
+			if msg.String() == "esc" {
+				// Hosts still running are disconnected, their results follow
+				m.cancel()
+			}
+			return m, nil
+		}
+		if m.hosts != nil {
+			switch msg.String() {
+			case "enter":
+				cmd, err := m.start()
+				m.err = err
+				return m, cmd
+			case "e":
+				// Back to the form to change the tag or command
+				m.hosts = nil
+				m.err = nil
+				return m, textinput.Blink
+			}
+			return m, nil
+		}
+		return m.updateForm(msg)
+	}
+
+	return m, nil
+}
+
+func (m *RunModel) updateForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "tab", "shift+tab", "up", "down":
+		if msg.String() == "up" || msg.String() == "shift+tab" {
+			m.focused--
+		} else {
+			m.focused++
+		}
+		if m.focused >= len(m.inputs) {
+			m.focused = 0
+		} else if m.focused < 0 {
+			m.focused = len(m.inputs) - 1
+		}
+		for i := range m.inputs {
+			if i == m.focused {
+				m.inputs[i].Focus()
+			} else {
+				m.inputs[i].Blur()
+			}
+		}
+		return m, nil
+
+	case "enter":
+		cmd, err := m.start()
+		m.err = err
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	m.inputs[m.focused], cmd = m.inputs[m.focused].Update(msg)
+	return m, cmd
+}
+
+// start resolves the tagged servers and runs the command on them in the
+// background
+func (m *RunModel) start() (tea.Cmd, error) {
+	tag := strings.TrimSpace(m.inputs[runTag].Value())
+	command := strings.TrimSpace(m.inputs[runCommand].Value())
+	if tag == "" || command == "" {
+		return nil, fmt.Errorf("tag and command are required")
+	}
+
+	concurrency := defaultRunConcurrency
+	if value := strings.TrimSpace(m.inputs[runConcurrency].Value()); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid number of parallel hosts: %s", value)
+		}
+		concurrency = n
+	}
+
+	var targets []ssh.ExecTarget
+	var failed []ssh.ExecResult // Servers whose config could not be built
+	for _, server := range m.store.List() {
+		if !server.Matches(tag) {
+			continue
+		}
+		if ssh.NeedsMasterPassword(m.store, server) && m.masterPassword == "" {
+			failed = append(failed, ssh.ExecResult{Target: server.Name, ExitCode: -1,
+				Err: fmt.Errorf("master password required to decrypt the private key")})
+			continue
+		}
+		config, err := ssh.ServerSSHConfig(m.store, server, m.masterPassword)
+		if err == nil {
+			err = config.Validate()
+		}
+		if err != nil {
+			failed = append(failed, ssh.ExecResult{Target: server.Name, ExitCode: -1, Err: err})
+			continue
+		}
+		targets = append(targets, ssh.ExecTarget{Name: server.Name, Config: config})
+	}
+	if len(targets) == 0 && len(failed) == 0 {
+		return nil, fmt.Errorf("no server matches %q", tag)
+	}
+
+	m.command = command
+	m.hosts = nil
+	m.status = make(map[string]*ssh.ExecResult)
+	m.output = nil
+	m.results = nil
+	m.report = ""
+	for _, r := range failed {
+		result := r
+		m.hosts = append(m.hosts, r.Target)
+		m.status[r.Target] = &result
+	}
+	for _, t := range targets {
+		m.hosts = append(m.hosts, t.Name)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.running = true
+	events := make(chan tea.Msg, 64)
+	m.events = events
+
+	exec := &ssh.ParallelExec{
+		Concurrency: concurrency,
+		NewClient:   m.newClient,
+		OnLine:      func(line ssh.ExecLine) { events <- runLineMsg{line: line} },
+		OnResult:    func(result ssh.ExecResult) { events <- runResultMsg{result: result} },
+	}
+	go func() {
+		results := exec.Run(ctx, targets, command)
+		events <- runDoneMsg{results: append(failed, results...)}
+	}()
+
+	return m.waitForEvent(), nil
+}
+
+// waitForEvent delivers the next output line or result from the run
+func (m *RunModel) waitForEvent() tea.Cmd {
+	events := m.events
+	return func() tea.Msg {
+		return <-events
+	}
+}
+
+// saveReport writes the summary and output of the finished run
+func (m *RunModel) saveReport() (string, error) {
+	if err := os.MkdirAll(m.reportsDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to save report: %w", err)
+	}
+	path := filepath.Join(m.reportsDir, "run-"+time.Now().Format("20060102-150405")+".txt")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to save report: %w", err)
+	}
+	defer file.Close()
+
+	if err := ssh.WriteExecReport(file, m.command, m.results); err != nil {
+		return "", fmt.Errorf("failed to save report: %w", err)
+	}
+	return path, nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *RunModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("⚡ Run Command"))
+	b.WriteString("\n\n")
+
+	if m.hosts == nil {
+		for i := range m.inputs {
+			b.WriteString(m.inputs[i].View())
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+		b.WriteString(helpStyle.Render("Runs the command on every saved server with the tag, or in the group."))
+		b.WriteString("\n")
+		b.WriteString(helpStyle.Render("tab: next field • enter: run • esc: back"))
+	} else {
+		b.WriteString(m.renderRun())
+	}
+
+	if m.err != nil {
+		b.WriteString("\n\n")
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", m.err)))
+	}
+
+	return boxStyle.Render(b.String())
+}
+
+func (m *RunModel) renderRun() string {
+	var b strings.Builder
+
+	b.WriteString(itemStyle.Render("$ " + m.command))
+	b.WriteString("\n\n")
+
+	// Host table: state, exit code and duration
+	succeeded := 0
+	for i, host := range m.hosts {
+		state := tunnelReconnectingStyle.Render("running")
+		detail := ""
+		if r, ok := m.status[host]; ok {
+			switch {
+			case r.OK():
+				succeeded++
+				state = tunnelUpStyle.Render("ok     ")
+			case r.Err != nil:
+				state = errorStyle.UnsetMarginLeft().Render("error  ")
+				detail = r.Err.Error()
+			default:
+				state = errorStyle.UnsetMarginLeft().Render(fmt.Sprintf("exit %-2d", r.ExitCode))
+			}
+			if !r.Started.IsZero() {
+				detail = strings.TrimSpace(r.Finished.Sub(r.Started).Round(time.Millisecond).String() + "  " + detail)
+			}
+		}
+		host := lipgloss.NewStyle().Foreground(runHostColors[i%len(runHostColors)]).Render(fmt.Sprintf("%-20s", host))
+		b.WriteString(fmt.Sprintf("  %s %s %s\n", host, state, helpStyle.UnsetMarginTop().UnsetMarginLeft().Render(detail)))
+	}
+	b.WriteString("\n")
+
+	// The latest output, prefixed by host
+	lines := m.output
+	if limit := max(m.height-len(m.hosts)-16, 5); len(lines) > limit {
+		lines = lines[len(lines)-limit:]
+	}
+	for _, line := range lines {
+		b.WriteString(m.hostPrefix(line.Target))
+		if line.Stream == ssh.StreamStderr {
+			b.WriteString(errorStyle.UnsetMarginLeft().UnsetBold().Render(line.Text))
+		} else {
+			b.WriteString(line.Text)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+
+	if m.running {
+		b.WriteString(helpStyle.Render(fmt.Sprintf("%d/%d hosts finished • esc: cancel", len(m.status), len(m.hosts))))
+	} else {
+		b.WriteString(successStyle.Render(fmt.Sprintf("%d/%d hosts succeeded", succeeded, len(m.hosts))))
+		if m.report != "" {
+			b.WriteString("\n")
+			b.WriteString(helpStyle.Render("Report saved to " + m.report))
+		}
+		b.WriteString("\n")
+		b.WriteString(helpStyle.Render("enter: run again • e: edit • esc: back"))
+	}
+
+	return b.String()
+}
+
+// hostPrefix colours the host name the same way as in the host table
+func (m *RunModel) hostPrefix(target string) string {
+	for i, host := range m.hosts {
+		if host == target {
+			return lipgloss.NewStyle().Foreground(runHostColors[i%len(runHostColors)]).Render(target + " │ ")
+		}
+	}
+	return target + " │ "
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/quocson95/marix/pkg/ssh"
 	"github.com/quocson95/marix/pkg/storage"
 )
 
@@ -18,6 +19,7 @@ type ServerEditModel struct {
 	settingsStore       *storage.SettingsStore
 	server              *storage.Server
 	inputs              []textinput.Model
+	toggles             [toggleCount]bool
 	focused             int
 	isNew               bool
 	err                 error
@@ -32,16 +34,31 @@ type ServerEditModel struct {
 
 const (
 	editName = iota
+	editGroup
 	editHost
 	editPort
 	editUsername
 	editPassword
 	editPrivateKey
+	editJumpHosts
+	editForwards
 )
 
+// Toggle rows follow the text inputs in the focus order
+const (
+	toggleUseAgent = iota
+	toggleForwardAgent
+	toggleCount
+)
+
+var toggleLabels = [toggleCount]string{
+	toggleUseAgent:     "Use ssh-agent",
+	toggleForwardAgent: "Forward ssh-agent to shell",
+}
+
 // NewServerEditModel creates a new server edit model
 func NewServerEditModel(store *storage.Store, settingsStore *storage.SettingsStore, server *storage.Server, isNew bool, masterPassword string) *ServerEditModel {
-	inputs := make([]textinput.Model, 6)
+	inputs := make([]textinput.Model, 9)
 
 	inputs[editName] = textinput.New()
 	inputs[editName].Placeholder = "My Server"
@@ -50,6 +67,12 @@ func NewServerEditModel(store *storage.Store, settingsStore *storage.SettingsSto
 	inputs[editName].Prompt = "Name: "
 	inputs[editName].Focus()
 
+	inputs[editGroup] = textinput.New()
+	inputs[editGroup].Placeholder = "prod/eu (optional)"
+	inputs[editGroup].CharLimit = 256
+	inputs[editGroup].Width = 50
+	inputs[editGroup].Prompt = "Group: "
+
 	inputs[editHost] = textinput.New()
 	inputs[editHost].Placeholder = "192.168.1.1"
 	inputs[editHost].CharLimit = 253
@@ -82,6 +105,18 @@ func NewServerEditModel(store *storage.Store, settingsStore *storage.SettingsSto
 	inputs[editPrivateKey].Width = 50
 	inputs[editPrivateKey].Prompt = "Private Key Path: "
 
+	inputs[editJumpHosts] = textinput.New()
+	inputs[editJumpHosts].Placeholder = "bastion, inner-bastion (saved server names, optional)"
+	inputs[editJumpHosts].CharLimit = 256
+	inputs[editJumpHosts].Width = 50
+	inputs[editJumpHosts].Prompt = "Jump Hosts: "
+
+	inputs[editForwards] = textinput.New()
+	inputs[editForwards].Placeholder = "L 8080:localhost:80, D 1080 (optional)"
+	inputs[editForwards].CharLimit = 512
+	inputs[editForwards].Width = 50
+	inputs[editForwards].Prompt = "Port Forwards: "
+
 	m := &ServerEditModel{
 		store:          store,
 		settingsStore:  settingsStore,
@@ -92,6 +127,11 @@ func NewServerEditModel(store *storage.Store, settingsStore *storage.SettingsSto
 		masterPassword: masterPassword,
 	}
 
+	// New servers start in the group they were added from
+	if server != nil {
+		m.inputs[editGroup].SetValue(server.Group)
+	}
+
 	// Pre-fill values if editing existing server
 	if !isNew && server != nil {
 		m.inputs[editName].SetValue(server.Name)
@@ -100,6 +140,10 @@ func NewServerEditModel(store *storage.Store, settingsStore *storage.SettingsSto
 		m.inputs[editUsername].SetValue(server.Username)
 		m.inputs[editPassword].SetValue(server.Password)
 		m.inputs[editPrivateKey].SetValue(server.PrivateKey)
+		m.inputs[editJumpHosts].SetValue(m.jumpHostNames(server.JumpHosts))
+		m.inputs[editForwards].SetValue(formatForwards(server.Forwards))
+		m.toggles[toggleUseAgent] = server.UseAgent
+		m.toggles[toggleForwardAgent] = server.ForwardAgent
 	}
 
 	return m
@@ -126,10 +170,12 @@ func (m *ServerEditModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.focused++
 			}
 
-			if m.focused > len(m.inputs)-1 {
+			// Inputs + toggle rows
+			maxFocus := len(m.inputs) + toggleCount - 1
+			if m.focused > maxFocus {
 				m.focused = 0
 			} else if m.focused < 0 {
-				m.focused = len(m.inputs) - 1
+				m.focused = maxFocus
 			}
 
 			// Update focus
@@ -143,6 +189,14 @@ func (m *ServerEditModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			return m, nil
 
+		case " ":
+			// Flip the focused toggle row
+			if m.focused >= len(m.inputs) {
+				toggle := m.focused - len(m.inputs)
+				m.toggles[toggle] = !m.toggles[toggle]
+				return m, nil
+			}
+
 		case "ctrl+s", "enter":
 			// Save server
 			return m, m.save()
@@ -193,24 +247,26 @@ func (m *ServerEditModel) save() tea.Cmd {
 			username = "root"
 		}
 
+		group := storage.NormalizeGroup(m.inputs[editGroup].Value())
 		password := m.inputs[editPassword].Value()
 		privateKeyPath := m.inputs[editPrivateKey].Value()
-		settings := m.settingsStore.Get()
-
-		// Use Master Password if hash is set
-		keyPassword := ""
-		if settings.MasterPasswordHash != "" {
-			// Ensure we have the password cached
-			if m.masterPassword == "" {
-				m.err = fmt.Errorf("master password required for encryption but not cached")
-				return nil
-			}
-			// Verify it just in case (optional, but good sanity check)
-			if !m.settingsStore.VerifyMasterPassword(m.masterPassword) {
-				m.err = fmt.Errorf("cached master password invalid")
-				return nil
-			}
-			keyPassword = m.masterPassword
+
+		jumpHosts, err := m.resolveJumpHosts(m.inputs[editJumpHosts].Value())
+		if err != nil {
+			m.err = err
+			return nil
+		}
+
+		forwards, err := parseForwards(m.inputs[editForwards].Value())
+		if err != nil {
+			m.err = err
+			return nil
+		}
+
+		keyPassword, err := keyEncryptionPassword(m.settingsStore, m.masterPassword)
+		if err != nil {
+			m.err = err
+			return nil
 		}
 
 		// Handle private key encryption if provided
@@ -219,34 +275,11 @@ func (m *ServerEditModel) save() tea.Cmd {
 		var privateKeyContent string
 
 		if privateKeyPath != "" {
-			// Expand tilde in path
-			if len(privateKeyPath) > 0 && privateKeyPath[0] == '~' {
-				home, err := os.UserHomeDir()
-				if err == nil {
-					privateKeyPath = filepath.Join(home, privateKeyPath[1:])
-				}
-			}
-
-			// Read private key file
-			keyContent, err := os.ReadFile(privateKeyPath)
+			privateKeyContent, privateKeyEncrypted, keyEncryptionSalt, err = readServerKey(privateKeyPath, keyPassword)
 			if err != nil {
-				m.err = fmt.Errorf("failed to read private key: %w", err)
+				m.err = err
 				return nil
 			}
-
-			if keyPassword != "" {
-				// Encrypt the private key content
-				encrypted, salt, err := storage.EncryptPrivateKey(keyContent, keyPassword)
-				if err != nil {
-					m.err = fmt.Errorf("failed to encrypt private key: %w", err)
-					return nil
-				}
-				privateKeyEncrypted = encrypted
-				keyEncryptionSalt = salt
-			} else {
-				// Save as plaintext content
-				privateKeyContent = string(keyContent)
-			}
 		}
 
 		// Update or create server
@@ -262,16 +295,26 @@ func (m *ServerEditModel) save() tea.Cmd {
 				PrivateKeyEncrypted: privateKeyEncrypted,
 				KeyEncryptionSalt:   keyEncryptionSalt,
 				Protocol:            "ssh",
+				Group:               group,
+				UseAgent:            m.toggles[toggleUseAgent],
+				ForwardAgent:        m.toggles[toggleForwardAgent],
+				JumpHosts:           jumpHosts,
+				Forwards:            forwards,
 				CreatedAt:           time.Now().Unix(),
 				UpdatedAt:           time.Now().Unix(),
 			}
 			m.store.Add(server)
 		} else {
 			m.server.Name = name
+			m.server.Group = group
 			m.server.Host = host
 			m.server.Port = port
 			m.server.Username = username
 			m.server.Password = password
+			m.server.UseAgent = m.toggles[toggleUseAgent]
+			m.server.ForwardAgent = m.toggles[toggleForwardAgent]
+			m.server.JumpHosts = jumpHosts
+			m.server.Forwards = forwards
 
 			if len(privateKeyEncrypted) > 0 {
 				m.server.PrivateKeyEncrypted = privateKeyEncrypted
@@ -293,6 +336,120 @@ func (m *ServerEditModel) save() tea.Cmd {
 	}
 }
 
+// keyEncryptionPassword returns the password used to encrypt stored keys:
+// the master password if one is set, otherwise empty (keys stored as plaintext)
+func keyEncryptionPassword(settingsStore *storage.SettingsStore, masterPassword string) (string, error) {
+	settings := settingsStore.Get()
+	if settings.MasterPasswordHash == "" {
+		return "", nil
+	}
+
+	// Ensure we have the password cached
+	if masterPassword == "" {
+		return "", fmt.Errorf("master password required for encryption but not cached")
+	}
+	// Verify it just in case (optional, but good sanity check)
+	if !settingsStore.VerifyMasterPassword(masterPassword) {
+		return "", fmt.Errorf("cached master password invalid")
+	}
+	return masterPassword, nil
+}
+
+// readServerKey reads a private key file for storage. With a keyPassword the
+// key is returned encrypted (with its salt), otherwise as plaintext content.
+func readServerKey(path, keyPassword string) (content string, encrypted, salt []byte, err error) {
+	// Expand tilde in path
+	if len(path) > 0 && path[0] == '~' {
+		home, err := os.UserHomeDir()
+		if err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+
+	// Read private key file
+	keyContent, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	if keyPassword == "" {
+		// Save as plaintext content
+		return string(keyContent), nil, nil, nil
+	}
+
+	// Encrypt the private key content
+	encrypted, salt, err = storage.EncryptPrivateKey(keyContent, keyPassword)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to encrypt private key: %w", err)
+	}
+	return "", encrypted, salt, nil
+}
+
+// jumpHostNames renders jump host IDs as the comma-separated names shown in the form
+func (m *ServerEditModel) jumpHostNames(ids []string) string {
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if hop, err := m.store.Get(id); err == nil {
+			names = append(names, hop.Name)
+		} else {
+			names = append(names, id)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// resolveJumpHosts maps comma-separated server names (or IDs) to server IDs
+func (m *ServerEditModel) resolveJumpHosts(value string) ([]string, error) {
+	var ids []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		var hop *storage.Server
+		for _, srv := range m.store.List() {
+			if srv.ID == name || strings.EqualFold(srv.Name, name) {
+				hop = srv
+				break
+			}
+		}
+		if hop == nil {
+			return nil, fmt.Errorf("unknown jump host: %s", name)
+		}
+		if !m.isNew && m.server != nil && hop.ID == m.server.ID {
+			return nil, fmt.Errorf("server cannot be its own jump host")
+		}
+		ids = append(ids, hop.ID)
+	}
+	return ids, nil
+}
+
+// formatForwards renders saved forwards as the comma-separated form input
+func formatForwards(forwards []storage.PortForward) string {
+	specs := make([]string, 0, len(forwards))
+	for _, pf := range forwards {
+		specs = append(specs, ssh.ForwardFromStorage(pf).String())
+	}
+	return strings.Join(specs, ", ")
+}
+
+// parseForwards parses comma-separated forwards such as "L 8080:localhost:80, D 1080"
+func parseForwards(value string) ([]storage.PortForward, error) {
+	var forwards []storage.PortForward
+	for _, spec := range strings.Split(value, ",") {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		f, err := ssh.ParseForward(spec)
+		if err != nil {
+			return nil, err
+		}
+		forwards = append(forwards, portForwardFromSSH(f))
+	}
+	return forwards, nil
+}
+
 func (m *ServerEditModel) View() string {
 	var b strings.Builder
 
@@ -308,8 +465,23 @@ func (m *ServerEditModel) View() string {
 		b.WriteString("\n")
 	}
 
+	for i, label := range toggleLabels {
+		cursor := "  "
+		style := itemStyle
+		if m.focused == len(m.inputs)+i {
+			cursor = "→ "
+			style = selectedItemStyle
+		}
+		status := "☐"
+		if m.toggles[i] {
+			status = "☑"
+		}
+		b.WriteString(cursor + style.Render(fmt.Sprintf("%s %s", status, label)))
+		b.WriteString("\n")
+	}
+
 	b.WriteString("\n")
-	b.WriteString(helpStyle.Render("tab: next • ctrl+s/enter: save • esc: cancel"))
+	b.WriteString(helpStyle.Render("tab: next • space: toggle • ctrl+s/enter: save • esc: cancel"))
 
 	if m.saved {
 		b.WriteString("\n\n")
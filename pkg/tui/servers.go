@@ -2,10 +2,14 @@ package tui
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/quocson95/marix/pkg/ssh"
 	"github.com/quocson95/marix/pkg/storage"
 )
 
@@ -25,13 +29,53 @@ type ServerSFTPMsg struct {
 	server *storage.Server
 }
 
-// ServersModel manages the server list
+// ServerLaunchedMsg reports a server opened in an external terminal, or why
+// it could not be
+type ServerLaunchedMsg struct {
+	server *storage.Server
+	err    error
+}
+
+// ServerGroupConnectMsg asks to open a terminal session to every server in a group
+type ServerGroupConnectMsg struct {
+	group   string
+	servers []*storage.Server
+}
+
+// ServerGroupRunMsg asks to run a command on every server in a group
+type ServerGroupRunMsg struct {
+	group string
+}
+
+// serverRow is a line of the server tree: a group folder or a server
+type serverRow struct {
+	group  string          // Path of the group row, or of the server's group
+	depth  int             // Nesting level, for indentation
+	server *storage.Server // nil for group rows
+	count  int             // Servers in a group row, subgroups included
+	pinned bool            // Favourite shown above the tree
+}
+
+// ServersModel manages the server list, shown as a tree of groups. While
+// searching or filtering by tag, matches are listed flat instead.
 type ServersModel struct {
 	store          *storage.Store
 	settingsStore  *storage.SettingsStore
 	masterPassword string
 	servers        []*storage.Server
+	rows           []serverRow
+	groupOrder     []string // Every group in display order, then the top level
+	collapsed      map[string]bool
+	order          storage.ServerOrder
 	cursor         int
+	moving         bool // Typing the group to move the selected row to
+	moveInput      textinput.Model
+	searching      bool // Typing in the search box
+	searchInput    textinput.Model
+	tags           []string        // Every tag in use, shown as filter chips
+	tagFilter      map[string]bool // Chips turned on, servers need all of them
+	choosingTags   bool            // Moving between the chips
+	tagCursor      int
 	err            error
 	statusMsg      string
 	width          int
@@ -41,32 +85,307 @@ type ServersModel struct {
 
 // NewServersModel creates a new servers model
 func NewServersModel(store *storage.Store, settingsStore *storage.SettingsStore, masterPassword string) *ServersModel {
-	return &ServersModel{
-		store:          store,
-		settingsStore:  settingsStore,
-		masterPassword: masterPassword,
-		servers:        store.List(),
-		cursor:         0,
-		sftpMode:       false,
-	}
+	return newServersModel(store, settingsStore, masterPassword, false)
 }
 
 // NewServersModelForSFTP creates servers model for SFTP selection
 func NewServersModelForSFTP(store *storage.Store, settingsStore *storage.SettingsStore, masterPassword string) *ServersModel {
-	return &ServersModel{
+	return newServersModel(store, settingsStore, masterPassword, true)
+}
+
+func newServersModel(store *storage.Store, settingsStore *storage.SettingsStore, masterPassword string, sftpMode bool) *ServersModel {
+	ti := textinput.New()
+	ti.Placeholder = "prod/eu (empty for the top level)"
+	ti.CharLimit = 256
+	ti.Width = 50
+
+	search := textinput.New()
+	search.Prompt = "🔍 "
+	search.Placeholder = "name, host, user, tag or description"
+	search.CharLimit = 128
+	search.Width = 50
+
+	m := &ServersModel{
 		store:          store,
 		settingsStore:  settingsStore,
 		masterPassword: masterPassword,
-		servers:        store.List(),
-		cursor:         0,
-		sftpMode:       true,
+		collapsed:      make(map[string]bool),
+		order:          settingsStore.Get().ServerOrder,
+		moveInput:      ti,
+		searchInput:    search,
+		tagFilter:      make(map[string]bool),
+		sftpMode:       sftpMode,
 	}
+	for _, group := range settingsStore.Get().CollapsedGroups {
+		m.collapsed[group] = true
+	}
+	m.refresh()
+	return m
 }
 
 func (m *ServersModel) Init() tea.Cmd {
 	return nil
 }
 
+// IsInputActive reports whether esc belongs to the list: it cancels a move,
+// the search or the tag chips, then clears the filters
+func (m *ServersModel) IsInputActive() bool {
+	return m.moving || m.searching || m.choosingTags || m.filtering()
+}
+
+// filtering reports whether servers are narrowed by a search or tag chips
+func (m *ServersModel) filtering() bool {
+	return strings.TrimSpace(m.searchInput.Value()) != "" || len(m.tagFilter) > 0
+}
+
+// refresh rebuilds the tree from the store, keeping the selection on the
+// same server or group
+func (m *ServersModel) refresh() {
+	var selected serverRow
+	if m.cursor < len(m.rows) {
+		selected = m.rows[m.cursor]
+	}
+
+	m.servers = m.store.List()
+	storage.SortServers(m.servers, m.order)
+
+	seen := make(map[string]bool)
+	m.tags = nil
+	for _, server := range m.servers {
+		for _, tag := range server.Tags {
+			if !seen[strings.ToLower(tag)] {
+				seen[strings.ToLower(tag)] = true
+				m.tags = append(m.tags, tag)
+			}
+		}
+	}
+	sort.Slice(m.tags, func(i, j int) bool {
+		return strings.ToLower(m.tags[i]) < strings.ToLower(m.tags[j])
+	})
+	m.tagCursor = min(m.tagCursor, max(len(m.tags)-1, 0))
+
+	m.rows = nil
+	m.groupOrder = nil
+	if m.filtering() {
+		m.addMatches()
+	} else {
+		for _, server := range m.servers {
+			if server.Favorite {
+				m.rows = append(m.rows, serverRow{group: server.Group, server: server, pinned: true})
+			}
+		}
+	}
+	m.addRows(m.store.Groups(), "", 0, !m.filtering())
+	m.groupOrder = append(m.groupOrder, "")
+
+	m.selectRow(selected)
+}
+
+// addMatches lists the servers matching the search and tag chips, best
+// match first. Without a search, favourites come first.
+func (m *ServersModel) addMatches() {
+	query := m.searchInput.Value()
+	scores := make(map[*storage.Server]int)
+	var matches []*storage.Server
+	for _, server := range m.servers {
+		if !m.hasFilterTags(server) {
+			continue
+		}
+		score, ok := server.SearchScore(query)
+		if !ok {
+			continue
+		}
+		scores[server] = score
+		matches = append(matches, server)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if scores[matches[i]] != scores[matches[j]] {
+			return scores[matches[i]] > scores[matches[j]]
+		}
+		return matches[i].Favorite && !matches[j].Favorite
+	})
+	for _, server := range matches {
+		m.rows = append(m.rows, serverRow{group: server.Group, server: server})
+	}
+}
+
+// hasFilterTags reports whether server has every tag chip turned on
+func (m *ServersModel) hasFilterTags(server *storage.Server) bool {
+	for tag := range m.tagFilter {
+		if !server.HasTag(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// addRows adds the subgroups and servers of parent, subgroups first. Rows
+// below a collapsed group are left out, but its groups still count for moves.
+func (m *ServersModel) addRows(groups []string, parent string, depth int, visible bool) {
+	for _, group := range groups {
+		if storage.ParentGroup(group) != parent {
+			continue
+		}
+		m.groupOrder = append(m.groupOrder, group)
+		if visible {
+			count := 0
+			for _, server := range m.servers {
+				if server.InGroup(group) {
+					count++
+				}
+			}
+			m.rows = append(m.rows, serverRow{group: group, depth: depth, count: count})
+		}
+		m.addRows(groups, group, depth+1, visible && !m.collapsed[group])
+	}
+
+	if !visible {
+		return
+	}
+	for _, server := range m.servers {
+		if server.Group == parent {
+			m.rows = append(m.rows, serverRow{group: parent, depth: depth, server: server})
+		}
+	}
+}
+
+// selectRow moves the cursor to the row showing the same server or group,
+// or the nearest row when it is gone. A favourite is found in its group
+// once it is no longer pinned.
+func (m *ServersModel) selectRow(row serverRow) {
+	found := -1
+	for i, r := range m.rows {
+		switch {
+		case row.server == nil && r.server == nil && r.group == row.group,
+			row.server != nil && r.server != nil && r.server.ID == row.server.ID && r.pinned == row.pinned:
+			m.cursor = i
+			return
+		case row.server != nil && r.server != nil && r.server.ID == row.server.ID && found < 0:
+			found = i
+		}
+	}
+	if found >= 0 {
+		m.cursor = found
+		return
+	}
+	m.cursor = min(m.cursor, max(len(m.rows)-1, 0))
+}
+
+// current returns the row under the cursor
+func (m *ServersModel) current() (serverRow, bool) {
+	if m.cursor < len(m.rows) {
+		return m.rows[m.cursor], true
+	}
+	return serverRow{}, false
+}
+
+// setCollapsed folds or unfolds a group and remembers it in the settings
+func (m *ServersModel) setCollapsed(group string, collapsed bool) {
+	if collapsed {
+		m.collapsed[group] = true
+	} else {
+		delete(m.collapsed, group)
+	}
+	m.saveCollapsed()
+	m.refresh()
+}
+
+func (m *ServersModel) saveCollapsed() {
+	groups := make([]string, 0, len(m.collapsed))
+	for group := range m.collapsed {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	m.settingsStore.SetCollapsedGroups(groups)
+}
+
+// reveal unfolds group and its parents so a server moved there stays in view
+func (m *ServersModel) reveal(group string) {
+	for ; group != ""; group = storage.ParentGroup(group) {
+		if m.collapsed[group] {
+			m.setCollapsed(group, false)
+		}
+	}
+}
+
+// groupServers returns the servers in group and its subgroups
+func (m *ServersModel) groupServers(group string) []*storage.Server {
+	var servers []*storage.Server
+	for _, server := range m.servers {
+		if server.InGroup(group) {
+			servers = append(servers, server)
+		}
+	}
+	return servers
+}
+
+// moveServer puts server in group and saves it
+func (m *ServersModel) moveServer(server *storage.Server, group string) tea.Cmd {
+	group = storage.NormalizeGroup(group)
+	if group == server.Group {
+		return nil
+	}
+	server.Group = group
+	server.UpdatedAt = time.Now().Unix()
+	if err := m.store.Update(server); err != nil {
+		m.err = err
+		return nil
+	}
+	m.err = nil
+	m.reveal(group)
+	m.refresh()
+	return RunAutoBackup(m.settingsStore, m.masterPassword, "moved")
+}
+
+// dragServer moves server to the group step places before or after its own
+// in display order
+func (m *ServersModel) dragServer(server *storage.Server, step int) tea.Cmd {
+	index := 0
+	for i, group := range m.groupOrder {
+		if group == server.Group {
+			index = i
+		}
+	}
+	target := index + step
+	if target < 0 || target >= len(m.groupOrder) {
+		return nil
+	}
+	return m.moveServer(server, m.groupOrder[target])
+}
+
+// finishMove applies the group typed in the move prompt
+func (m *ServersModel) finishMove() tea.Cmd {
+	row, ok := m.current()
+	if !ok {
+		return nil
+	}
+	target := m.moveInput.Value()
+
+	if row.server != nil {
+		return m.moveServer(row.server, target)
+	}
+
+	target = storage.NormalizeGroup(target)
+	if _, err := m.store.MoveGroup(row.group, target); err != nil {
+		m.err = err
+		return nil
+	}
+	m.err = nil
+	// Folded groups stay folded under their new name
+	for group := range m.collapsed {
+		if group == row.group || strings.HasPrefix(group, row.group+"/") {
+			delete(m.collapsed, group)
+			m.collapsed[storage.NormalizeGroup(target+strings.TrimPrefix(group, row.group))] = true
+		}
+	}
+	m.saveCollapsed()
+	m.reveal(storage.ParentGroup(target))
+	m.refresh()
+	m.selectRow(serverRow{group: target})
+	return RunAutoBackup(m.settingsStore, m.masterPassword, "moved")
+}
+
 func (m *ServersModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
@@ -75,61 +394,170 @@ func (m *ServersModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tea.KeyMsg:
+		if m.moving {
+			switch msg.String() {
+			case "esc":
+				m.moving = false
+				m.moveInput.Blur()
+				return m, nil
+			case "enter":
+				m.moving = false
+				m.moveInput.Blur()
+				return m, m.finishMove()
+			}
+			var cmd tea.Cmd
+			m.moveInput, cmd = m.moveInput.Update(msg)
+			return m, cmd
+		}
+		if m.searching {
+			return m, m.updateSearch(msg)
+		}
+		if m.choosingTags {
+			m.updateTagChips(msg)
+			return m, nil
+		}
+
+		row, ok := m.current()
+
 		switch msg.String() {
+		case "esc":
+			// Only reached while filtering, the app handles esc otherwise
+			m.searchInput.SetValue("")
+			m.tagFilter = make(map[string]bool)
+			m.refresh()
+
+		case "/":
+			m.searching = true
+			m.searchInput.CursorEnd()
+			m.searchInput.Focus()
+			return m, textinput.Blink
+
+		case "t":
+			if len(m.tags) > 0 {
+				m.choosingTags = true
+			}
+
+		case "s":
+			// Cycle through the sort orders
+			next := 0
+			for i, order := range storage.ServerOrders {
+				if order == m.order {
+					next = (i + 1) % len(storage.ServerOrders)
+				}
+			}
+			m.order = storage.ServerOrders[next]
+			m.settingsStore.SetServerOrder(m.order)
+			m.refresh()
+
+		case "f":
+			// Pin or unpin a favourite
+			if ok && row.server != nil {
+				row.server.Favorite = !row.server.Favorite
+				if err := m.store.Update(row.server); err != nil {
+					m.err = err
+				}
+				m.refresh()
+			}
+
 		case "up", "k":
 			if m.cursor > 0 {
 				m.cursor--
 			}
 
 		case "down", "j":
-			if m.cursor < len(m.servers)-1 {
+			if m.cursor < len(m.rows)-1 {
 				m.cursor++
 			}
 
+		case "right", "l":
+			if ok && row.server == nil {
+				if m.collapsed[row.group] {
+					m.setCollapsed(row.group, false)
+				} else if m.cursor < len(m.rows)-1 && m.rows[m.cursor+1].depth > row.depth {
+					m.cursor++
+				}
+			}
+
+		case "left", "h":
+			if !ok {
+				break
+			}
+			if row.server == nil && !m.collapsed[row.group] {
+				m.setCollapsed(row.group, true)
+				break
+			}
+			// Up to the parent group
+			parent := row.group
+			if row.server == nil {
+				parent = storage.ParentGroup(row.group)
+			}
+			if parent != "" {
+				m.selectRow(serverRow{group: parent})
+			}
+
 		case "enter", " ":
-			if len(m.servers) > 0 && m.cursor < len(m.servers) {
-				server := m.servers[m.cursor]
-
-				if m.sftpMode {
-					// Open SFTP for this server
-					return m, func() tea.Msg {
-						return ServerSFTPMsg{server: server}
-					}
-				} else {
-					// Prepare private key (decrypt if needed)
-					privateKey := server.PrivateKey
-					if len(server.PrivateKeyEncrypted) > 0 {
-						if m.masterPassword != "" {
-							decrypted, err := storage.DecryptPrivateKey(server.PrivateKeyEncrypted, server.KeyEncryptionSalt, m.masterPassword)
-							if err != nil {
-								m.err = fmt.Errorf("failed to decrypt key: %w", err)
-								return m, nil
-							}
-							privateKey = string(decrypted)
-						} else {
-							m.err = fmt.Errorf("master password required to decrypt key")
-							return m, nil
-						}
-					}
-
-					// Always launch in external terminal for SSH
-					err := LaunchExternalTerminal(server.Host, server.Port, server.Username, server.Password, privateKey)
-					if err != nil {
-						m.err = fmt.Errorf("failed to launch terminal: %w", err)
-					}
+			if !ok {
+				break
+			}
+			if row.server == nil {
+				m.setCollapsed(row.group, !m.collapsed[row.group])
+				break
+			}
+			return m, m.openServer(row.server)
+
+		case "shift+up", "K":
+			if ok && row.server != nil {
+				return m, m.dragServer(row.server, -1)
+			}
+
+		case "shift+down", "J":
+			if ok && row.server != nil {
+				return m, m.dragServer(row.server, 1)
+			}
+
+		case "m":
+			// Move the server, or the whole group, to a typed group
+			if !ok {
+				break
+			}
+			m.moving = true
+			m.moveInput.Prompt = "Move to group: "
+			m.moveInput.SetValue(row.group)
+			if row.server == nil {
+				m.moveInput.Prompt = "Rename group to: "
+			}
+			m.moveInput.CursorEnd()
+			m.moveInput.Focus()
+			return m, textinput.Blink
+
+		case "c":
+			// Connect to every server in the group
+			if ok && row.server == nil && !m.sftpMode {
+				servers := m.groupServers(row.group)
+				m.statusMsg = fmt.Sprintf("Connecting to %d server(s) in %s...", len(servers), row.group)
+				return m, func() tea.Msg {
+					return ServerGroupConnectMsg{group: row.group, servers: servers}
+				}
+			}
+
+		case "r":
+			// Run a command on every server in the group
+			if ok && row.server == nil && !m.sftpMode {
+				return m, func() tea.Msg {
+					return ServerGroupRunMsg{group: row.group}
 				}
 			}
 
 		case "e":
 			// Edit selected server
-			if len(m.servers) > 0 && m.cursor < len(m.servers) {
+			if ok && row.server != nil {
 				return m, func() tea.Msg {
-					return ServerEditMsg{server: m.servers[m.cursor], isNew: false}
+					return ServerEditMsg{server: row.server, isNew: false}
 				}
 			}
 
 		case "a":
-			// Add new server
+			// Add new server, in the group under the cursor
 			newServer := &storage.Server{
 				Name:     "New Server",
 				Host:     "192.168.1.1",
@@ -137,19 +565,23 @@ func (m *ServersModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				Username: "root",
 				Password: "",
 				Protocol: "ssh",
+				Group:    row.group,
 			}
 			return m, func() tea.Msg {
 				return ServerEditMsg{server: newServer, isNew: true}
 			}
 
+		case "i":
+			// Import hosts from ~/.ssh/config
+			return m, func() tea.Msg {
+				return ServerImportMsg{}
+			}
+
 		case "d":
 			// Delete selected server
-			if len(m.servers) > 0 && m.cursor < len(m.servers) {
-				m.store.Delete(m.servers[m.cursor].ID)
-				m.servers = m.store.List()
-				if m.cursor >= len(m.servers) && m.cursor > 0 {
-					m.cursor--
-				}
+			if ok && row.server != nil {
+				m.store.Delete(row.server.ID)
+				m.refresh()
 				// Trigger auto-backup
 				return m, RunAutoBackup(m.settingsStore, m.masterPassword, "deleted")
 			}
@@ -175,43 +607,254 @@ func (m *ServersModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// updateSearch handles keys while typing in the search box. The list
+// follows every keystroke; arrows move through the matches.
+func (m *ServersModel) updateSearch(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		m.searching = false
+		m.searchInput.Blur()
+		m.searchInput.SetValue("")
+		m.refresh()
+		return nil
+	case "enter":
+		m.searching = false
+		m.searchInput.Blur()
+		return nil
+	case "up":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+		return nil
+	case "down":
+		if m.cursor < len(m.rows)-1 {
+			m.cursor++
+		}
+		return nil
+	}
+
+	query := m.searchInput.Value()
+	var cmd tea.Cmd
+	m.searchInput, cmd = m.searchInput.Update(msg)
+	if m.searchInput.Value() != query {
+		m.refresh()
+		m.cursor = 0
+	}
+	return cmd
+}
+
+// updateTagChips handles keys while moving between the tag chips
+func (m *ServersModel) updateTagChips(msg tea.KeyMsg) {
+	switch msg.String() {
+	case "esc", "enter", "t":
+		m.choosingTags = false
+	case "left", "h":
+		if m.tagCursor > 0 {
+			m.tagCursor--
+		}
+	case "right", "l":
+		if m.tagCursor < len(m.tags)-1 {
+			m.tagCursor++
+		}
+	case " ":
+		tag := strings.ToLower(m.tags[m.tagCursor])
+		if m.tagFilter[tag] {
+			delete(m.tagFilter, tag)
+		} else {
+			m.tagFilter[tag] = true
+		}
+		m.refresh()
+		m.cursor = 0
+	}
+}
+
+// openServer opens the selected server in SFTP or an external terminal
+func (m *ServersModel) openServer(server *storage.Server) tea.Cmd {
+	if m.sftpMode {
+		// Open SFTP for this server
+		return func() tea.Msg {
+			return ServerSFTPMsg{server: server}
+		}
+	}
+
+	// Always launch in external terminal for SSH
+	err := launchServer(m.store, server, m.masterPassword)
+	m.err = err
+	m.refresh()
+	return func() tea.Msg {
+		return ServerLaunchedMsg{server: server, err: err}
+	}
+}
+
+// launchServer opens an SSH session to server in an external terminal
+func launchServer(store *storage.Store, server *storage.Server, masterPassword string) error {
+	// Prepare private key (decrypt if needed)
+	privateKey := server.PrivateKey
+	if len(server.PrivateKeyEncrypted) > 0 {
+		if masterPassword == "" {
+			return fmt.Errorf("master password required to decrypt key")
+		}
+		decrypted, err := storage.DecryptPrivateKey(server.PrivateKeyEncrypted, server.KeyEncryptionSalt, masterPassword)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt key: %w", err)
+		}
+		privateKey = string(decrypted)
+	}
+
+	// The system ssh reaches jump hosts with its own keys/agent
+	hops, err := store.JumpChain(server)
+	if err != nil {
+		return err
+	}
+	jumpSpecs := make([]string, 0, len(hops))
+	for _, hop := range hops {
+		jumpSpecs = append(jumpSpecs, fmt.Sprintf("%s@%s:%d", hop.Username, hop.Host, hop.Port))
+	}
+
+	var forwardArgs []string
+	for _, pf := range server.Forwards {
+		forwardArgs = append(forwardArgs, sshForwardArgs(ssh.ForwardFromStorage(pf))...)
+	}
+
+	err = LaunchExternalTerminal(server.Host, server.Port, server.Username, server.Password, privateKey, strings.Join(jumpSpecs, ","), forwardArgs)
+	if err != nil {
+		return fmt.Errorf("failed to launch terminal: %w", err)
+	}
+	store.MarkConnected(server.ID)
+	return nil
+}
+
 type statusClearMsg struct{}
 
+// renderTagChips shows every tag, highlighting the ones filtered on
+func (m *ServersModel) renderTagChips() string {
+	chips := make([]string, 0, len(m.tags))
+	for i, tag := range m.tags {
+		style := tabStyle
+		if m.tagFilter[strings.ToLower(tag)] {
+			style = activeTabStyle
+		}
+		if m.choosingTags && i == m.tagCursor {
+			style = style.Underline(true)
+		}
+		chips = append(chips, style.Render(tag))
+	}
+
+	// Wrap the chips to the window
+	var b strings.Builder
+	b.WriteString("  ")
+	lineWidth := 2
+	for i, chip := range chips {
+		if i > 0 && m.width > 0 && lineWidth+1+lipgloss.Width(chip) > m.width-8 {
+			b.WriteString("\n  ")
+			lineWidth = 2
+		} else if i > 0 {
+			b.WriteString(" ")
+			lineWidth++
+		}
+		b.WriteString(chip)
+		lineWidth += lipgloss.Width(chip)
+	}
+	return b.String()
+}
+
 func (m *ServersModel) View() string {
 	var b strings.Builder
 
 	b.WriteString(titleStyle.Render("📚 Saved Servers"))
 	b.WriteString("\n\n")
 
-	if len(m.servers) == 0 {
+	if m.searching || m.searchInput.Value() != "" {
+		b.WriteString(m.searchInput.View())
+		b.WriteString("\n")
+	}
+	if len(m.tags) > 0 {
+		b.WriteString(m.renderTagChips())
+		b.WriteString("\n")
+	}
+	status := "Sort: " + m.order.String()
+	if m.filtering() {
+		status = fmt.Sprintf("%d of %d servers • %s", len(m.rows), len(m.servers), status)
+	}
+	b.WriteString(helpStyle.UnsetMarginTop().Render(status))
+	b.WriteString("\n\n")
+
+	switch {
+	case len(m.servers) == 0:
 		b.WriteString(helpStyle.Render("No saved servers. Press 'a' to add one."))
-	} else {
-		for i, server := range m.servers {
-			cursor := "  "
-			style := itemStyle
+	case len(m.rows) == 0:
+		b.WriteString(helpStyle.Render("No server matches."))
+	}
 
-			if m.cursor == i {
-				cursor = "→ "
-				style = selectedItemStyle
-			}
+	// Keep the cursor in view
+	rows := m.rows
+	offset := 0
+	if limit := max(m.height-18, 5); len(rows) > limit {
+		offset = min(max(m.cursor-limit/2, 0), len(rows)-limit)
+		rows = rows[offset : offset+limit]
+	}
+	for i, row := range rows {
+		cursor := "  "
+		style := itemStyle
+		if m.cursor == offset+i {
+			cursor = "→ "
+			style = selectedItemStyle
+		}
 
-			serverInfo := fmt.Sprintf("%s (%s@%s:%d)",
-				server.Name,
-				server.Username,
-				server.Host,
-				server.Port,
+		var line string
+		if row.server == nil {
+			fold := "▾"
+			if m.collapsed[row.group] {
+				fold = "▸"
+			}
+			name := row.group[strings.LastIndex(row.group, "/")+1:]
+			line = fmt.Sprintf("%s 📁 %s (%d)", fold, name, row.count)
+		} else {
+			line = fmt.Sprintf("%s (%s@%s:%d)",
+				row.server.Name,
+				row.server.Username,
+				row.server.Host,
+				row.server.Port,
 			)
-
-			b.WriteString(cursor + style.Render(serverInfo))
-			b.WriteString("\n")
+			if row.server.Favorite {
+				line = "★ " + line
+			}
+			// Pinned and matching servers are away from their group
+			if row.server.Group != "" && (row.pinned || m.filtering()) {
+				line += " · " + row.server.Group
+			}
 		}
+
+		b.WriteString(cursor + strings.Repeat("  ", row.depth) + style.Render(line))
+		b.WriteString("\n")
 	}
 
 	b.WriteString("\n")
-	if m.sftpMode {
-		b.WriteString(helpStyle.Render("↑/k up • ↓/j down • enter: open sftp • e: edit • a: add • d: delete • esc: back"))
-	} else {
-		b.WriteString(helpStyle.Render("↑/k up • ↓/j down • enter: open terminal • e: edit • a: add • d: delete • esc: back"))
+	switch {
+	case m.moving:
+		b.WriteString(m.moveInput.View())
+		b.WriteString("\n\n")
+		b.WriteString(helpStyle.Render("enter: move • esc: cancel"))
+	case m.searching:
+		b.WriteString(helpStyle.Render("type to search • ↑/↓ move • enter: keep results • esc: clear"))
+	case m.choosingTags:
+		b.WriteString(helpStyle.Render("←/→ choose • space: toggle • enter: done"))
+	default:
+		open := "open terminal"
+		if m.sftpMode {
+			open = "open sftp"
+		}
+		back := "back"
+		if m.filtering() {
+			back = "clear filters"
+		}
+		b.WriteString(helpStyle.Render(fmt.Sprintf("↑/↓ move • ←/→ fold • enter: %s • e: edit • a: add • i: import • d: delete • esc: %s", open, back)))
+		b.WriteString("\n")
+		b.WriteString(helpStyle.UnsetMarginTop().Render("/: search • t: tag filter • s: sort • f: favourite • m: move to group • shift+↑/↓: drag to previous/next group"))
+		if !m.sftpMode {
+			b.WriteString("\n")
+			b.WriteString(helpStyle.UnsetMarginTop().Render("on a group, c: connect all • r: run command"))
+		}
 	}
 
 	if m.err != nil {
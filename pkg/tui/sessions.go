@@ -0,0 +1,375 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/quocson95/marix/pkg/ssh"
+	"github.com/quocson95/marix/pkg/storage"
+)
+
+// Tab bar styles
+var (
+	tabStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#A0A0A0")).
+			Padding(0, 1)
+
+	activeTabStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FFFFFF")).
+			Background(lipgloss.Color("#7D56F4")).
+			Bold(true).
+			Padding(0, 1)
+)
+
+// terminalChromeLines is the tab bar and status line around the panes
+const terminalChromeLines = 2
+
+// terminalTab is one tab of terminal sessions, split into panes
+type terminalTab struct {
+	root  *paneNode
+	focus *TerminalModel
+}
+
+// activeSession returns the focused pane of the tab in front, or nil when
+// there is none
+func (m *AppModel) activeSession() *TerminalModel {
+	if m.activeTab < 0 || m.activeTab >= len(m.tabs) {
+		return nil
+	}
+	return m.tabs[m.activeTab].focus
+}
+
+// findSession returns the tab index and pane of term, or -1 once it was closed
+func (m *AppModel) findSession(term *TerminalModel) (int, *paneNode) {
+	for i, tab := range m.tabs {
+		if pane := tab.root.find(term); pane != nil {
+			return i, pane
+		}
+	}
+	return -1, nil
+}
+
+// allSessions returns the sessions of every tab, in tab and pane order
+func (m *AppModel) allSessions() []*TerminalModel {
+	var sessions []*TerminalModel
+	for _, tab := range m.tabs {
+		sessions = append(sessions, tab.root.terminals()...)
+	}
+	return sessions
+}
+
+// savedServer returns the saved server matching config, if any
+func (m *AppModel) savedServer(config *ssh.SSHConfig) *storage.Server {
+	for _, server := range m.store.List() {
+		if server.Host == config.Host && server.Port == config.Port && server.Username == config.Username {
+			return server
+		}
+	}
+	return nil
+}
+
+// terminalArea is the space left for panes below the tab bar
+func (m *AppModel) terminalArea() (cols, rows int) {
+	if m.width <= 0 || m.height <= terminalChromeLines {
+		return defaultTerminalCols, defaultTerminalRows
+	}
+	return m.width, m.height - terminalChromeLines
+}
+
+// layoutTabs sizes every pane to the window
+func (m *AppModel) layoutTabs() {
+	cols, rows := m.terminalArea()
+	for _, tab := range m.tabs {
+		tab.root.layout(cols, rows)
+	}
+}
+
+// addSession opens term in a new tab and brings it to the front
+func (m *AppModel) addSession(term *TerminalModel) tea.Cmd {
+	m.tabs = append(m.tabs, &terminalTab{root: &paneNode{term: term}, focus: term})
+	m.activeTab = len(m.tabs) - 1
+	m.state = StateTerminal
+	m.layoutTabs()
+	m.applySessionSettings(term)
+	return term.Init()
+}
+
+// splitSession opens term in a pane next to the focused one
+func (m *AppModel) splitSession(term *TerminalModel, vertical bool) tea.Cmd {
+	tab := m.tabs[m.activeTab]
+	tab.root.find(tab.focus).split(term, vertical)
+	tab.focus = term
+	m.state = StateTerminal
+	m.layoutTabs()
+	m.applySessionSettings(term)
+	return term.Init()
+}
+
+// serversConnectedMsg carries the sessions opened by connectServers
+type serversConnectedMsg struct {
+	results []serverConnectResult
+}
+
+// serverConnectResult is the session opened to a server, or why it failed
+type serverConnectResult struct {
+	server *storage.Server
+	term   *TerminalModel
+	err    error
+}
+
+// connectServers connects to servers at once and opens a tab for each.
+// Host key and challenge prompts are asked one at a time.
+func (m *AppModel) connectServers(servers []*storage.Server) tea.Cmd {
+	return func() tea.Msg {
+		results := make([]serverConnectResult, len(servers))
+		var wg sync.WaitGroup
+		for i, server := range servers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i] = serverConnectResult{server: server}
+				if ssh.NeedsMasterPassword(m.store, server) && m.masterPasswordCache == "" {
+					results[i].err = fmt.Errorf("master password required to decrypt the private key")
+					return
+				}
+				config, err := ssh.ServerSSHConfig(m.store, server, m.masterPasswordCache)
+				if err == nil {
+					err = config.Validate()
+				}
+				if err != nil {
+					results[i].err = err
+					return
+				}
+				term, err := NewTerminalModel(m.newClient(config))
+				if err != nil {
+					results[i].err = err
+					return
+				}
+				term.name = server.Name
+				term.tags = server.Tags
+				results[i].term = term
+				m.store.MarkConnected(server.ID)
+			}()
+		}
+		wg.Wait()
+
+		return serversConnectedMsg{results: results}
+	}
+}
+
+// openServerSessions opens a tab for each connected server and records the
+// attempts. It returns why the others failed.
+func (m *AppModel) openServerSessions(msg serversConnectedMsg) (tea.Cmd, error) {
+	var cmds []tea.Cmd
+	var errs []error
+	for _, result := range msg.results {
+		id := m.recordConnection(serverHistory(result.server, storage.HistorySSH), result.err)
+		if result.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.server.Name, result.err))
+			continue
+		}
+		result.term.historyID = id
+		cmds = append(cmds, m.addSession(result.term))
+	}
+	return tea.Batch(cmds...), errors.Join(errs...)
+}
+
+// recordingsDir is where session recordings are saved
+func (m *AppModel) recordingsDir() string {
+	return filepath.Join(m.dataDir, "recordings")
+}
+
+// applySessionSettings sets up keepalives and reconnects for a new session
+// before its shell starts, and starts recording it when every session is to
+// be recorded. The pane is already sized, so the recording starts at its size.
+func (m *AppModel) applySessionSettings(term *TerminalModel) {
+	settings := m.settingsStore.Get()
+	term.client.SetKeepAlive(time.Duration(settings.KeepAliveInterval)*time.Second, settings.KeepAliveCountMax)
+	term.reconnect = settings.AutoReconnect
+
+	if !settings.RecordSessions {
+		return
+	}
+	if _, err := term.StartRecording(m.recordingsDir()); err != nil {
+		term.err = err
+	}
+}
+
+// toggleRecording starts or stops recording term
+func (m *AppModel) toggleRecording(term *TerminalModel) {
+	if term.Recording() {
+		term.StopRecording()
+		return
+	}
+	if term.closed {
+		return
+	}
+	if _, err := term.StartRecording(m.recordingsDir()); err != nil {
+		term.err = err
+	}
+}
+
+// switchSession brings the tab at index to the front
+func (m *AppModel) switchSession(index int) {
+	if index < 0 || index >= len(m.tabs) {
+		return
+	}
+	m.activeTab = index
+	for _, term := range m.tabs[index].root.terminals() {
+		term.unread = false
+	}
+}
+
+// focusPane moves the focus step panes forward within the tab in front
+func (m *AppModel) focusPane(step int) {
+	tab := m.tabs[m.activeTab]
+	panes := tab.root.terminals()
+	for i, term := range panes {
+		if term == tab.focus {
+			n := len(panes)
+			tab.focus = panes[((i+step)%n+n)%n]
+			return
+		}
+	}
+}
+
+// closeSession disconnects term and removes its pane. A tab goes away with
+// its last pane, and the menu is shown once the last tab is gone.
+func (m *AppModel) closeSession(term *TerminalModel) {
+	index, pane := m.findSession(term)
+	if index < 0 {
+		return
+	}
+	term.Close()
+	var lost error
+	if term.reconnecting {
+		lost = term.lostErr
+	}
+	m.finishConnection(term.historyID, lost)
+
+	tab := m.tabs[index]
+	if pane.remove() {
+		if tab.focus == term {
+			// The sibling moved up into the parent node
+			tab.focus = pane.parent.terminals()[0]
+		}
+		m.layoutTabs()
+		return
+	}
+
+	m.tabs = append(m.tabs[:index], m.tabs[index+1:]...)
+	if len(m.tabs) == 0 {
+		m.activeTab = 0
+		m.state = StateMenu
+		return
+	}
+	if m.activeTab >= index && m.activeTab > 0 {
+		m.activeTab--
+	}
+	m.switchSession(m.activeTab)
+}
+
+// sessionForwards returns the port forwards of the terminal pane using
+// client, or nil if client does not belong to a pane
+func (m *AppModel) sessionForwards(client *ssh.Client) *ssh.ForwardManager {
+	for _, tab := range m.tabs {
+		for _, term := range tab.root.terminals() {
+			if term.client == client {
+				return term.Forwards()
+			}
+		}
+	}
+	return nil
+}
+
+// tabBar renders one label per terminal tab, named after its focused pane.
+// Background tabs are marked when they have new output (●), a pane is
+// reconnecting (↻) or has disconnected (✗), and any tab with a recorded
+// pane with ⏺. While
+// broadcasting, a badge leads the bar and tabs receiving the input are
+// marked with ».
+func (m *AppModel) tabBar() string {
+	var tabs []string
+	if m.broadcasting {
+		members := 0
+		for _, term := range m.allSessions() {
+			if term.broadcast && !term.closed {
+				members++
+			}
+		}
+		tabs = append(tabs, broadcastStyle.Render(fmt.Sprintf("BROADCAST → %d", members)))
+	}
+
+	for i, tab := range m.tabs {
+		panes := tab.root.terminals()
+		label := fmt.Sprintf("%d %s", i+1, tab.focus.Name())
+		if len(panes) > 1 {
+			label += fmt.Sprintf(" [%d]", len(panes))
+		}
+
+		var closed, reconnecting, unread, member, recording bool
+		for _, term := range panes {
+			closed = closed || term.closed
+			reconnecting = reconnecting || term.reconnecting
+			unread = unread || term.unread
+			member = member || term.broadcast
+			recording = recording || term.Recording()
+		}
+		if recording {
+			label = "⏺ " + label
+		}
+		if m.broadcasting && member {
+			label = "» " + label
+		}
+		switch {
+		case closed:
+			label += " ✗"
+		case reconnecting:
+			label += " ↻"
+		case unread:
+			label += " ●"
+		}
+
+		if i == m.activeTab {
+			tabs = append(tabs, activeTabStyle.Render(label))
+		} else {
+			tabs = append(tabs, tabStyle.Render(label))
+		}
+	}
+
+	bar := strings.Join(tabs, " ")
+	if title := m.activeSession().screen.Title(); title != "" {
+		bar += " " + tabStyle.Render(title)
+	}
+	if m.width > 0 {
+		bar = lipgloss.NewStyle().MaxWidth(m.width).Render(bar)
+	}
+	return bar
+}
+
+// terminalView renders the tab bar, the panes of the tab in front and the
+// status line of the focused pane
+func (m *AppModel) terminalView() string {
+	tab := m.tabs[m.activeTab]
+	cols, rows := m.terminalArea()
+
+	status := tab.focus.StatusView()
+	if m.broadcasting && !tab.focus.capturesInput() && !tab.focus.closed {
+		if tab.focus.broadcast {
+			status = broadcastStyle.Render("Typing goes to every » session") +
+				terminalStatusStyle.Render("ctrl+] then x: opt this session out • b: change or stop")
+		} else {
+			status = terminalStatusStyle.Render("This session is opted out of the broadcast • ctrl+] then x: opt in")
+		}
+	}
+	if m.width > 0 {
+		status = lipgloss.NewStyle().MaxWidth(m.width).Render(status)
+	}
+	return m.tabBar() + "\n" + tab.root.view(tab.focus, cols, rows) + "\n" + status
+}
@@ -33,6 +33,8 @@ const (
 	settingTheme          = 2
 	settingMasterPassword = 3
 	settingOldPassword    = 4
+	settingKeepAlive      = 5
+	settingRetries        = 6
 )
 
 // BackupMsg indicates the result of a backup operation
@@ -51,8 +53,8 @@ var themes = []string{"default", "dark", "light", "monokai", "solarized"}
 func NewSettingsModel(serverStore *storage.Store, settingsStore *storage.SettingsStore) *SettingsModel {
 	settings := settingsStore.Get()
 
-	// 5 inputs: Port, User, Theme, MasterPwd, OldPwd
-	inputs := make([]textinput.Model, 5)
+	// 7 inputs: Port, User, Theme, MasterPwd, OldPwd, KeepAlive, Retries
+	inputs := make([]textinput.Model, 7)
 
 	inputs[0] = textinput.New()
 	inputs[0].Placeholder = "22"
@@ -101,6 +103,20 @@ func NewSettingsModel(serverStore *storage.Store, settingsStore *storage.Setting
 	inputs[4].EchoCharacter = '•'
 	inputs[4].SetValue("")
 
+	inputs[5] = textinput.New()
+	inputs[5].Placeholder = "30 (0 to disable)"
+	inputs[5].CharLimit = 5
+	inputs[5].Width = 40
+	inputs[5].Prompt = "SSH Keepalive (seconds): "
+	inputs[5].SetValue(fmt.Sprintf("%d", settings.KeepAliveInterval))
+
+	inputs[6] = textinput.New()
+	inputs[6].Placeholder = "3 (0 to disable)"
+	inputs[6].CharLimit = 2
+	inputs[6].Width = 40
+	inputs[6].Prompt = "Transfer Retries: "
+	inputs[6].SetValue(fmt.Sprintf("%d", settings.TransferRetries))
+
 	return &SettingsModel{
 		serverStore:   serverStore,
 		settingsStore: settingsStore,
@@ -141,8 +157,8 @@ func (m *SettingsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.cursor += direction
 
 			// Calculate max cursor index
-			// Inputs (5) + AutoSave (1) + Save (1) + Reset (1) = 8 items (0-7)
-			maxIndex := len(m.inputs) + 2
+			// Inputs (7) + AutoSave (1) + RecordSessions (1) + AutoReconnect (1) + ContinueOnError (1) + VerifyTransfers (1) + Save (1) + Reset (1) = 14 items (0-13)
+			maxIndex := len(m.inputs) + 6
 
 			// Wrap around
 			if m.cursor > maxIndex {
@@ -185,12 +201,16 @@ func (m *SettingsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case "down", "j":
-			// Inputs (5)
+			// Inputs (7)
 			// + Auto-Save Toggle (1)
+			// + Record Sessions Toggle (1)
+			// + Auto-Reconnect Toggle (1)
+			// + Continue On Error Toggle (1)
+			// + Verify Transfers Toggle (1)
 			// + Save Button (1)
 			// + Reset Button (1)
-			// Total items = 5 + 3 = 8 items (0 to 7)
-			maxCursor := len(m.inputs) + 2
+			// Total items = 7 + 7 = 14 items (0 to 13)
+			maxCursor := len(m.inputs) + 6
 			if m.cursor < maxCursor {
 				m.cursor++
 			}
@@ -205,9 +225,21 @@ func (m *SettingsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				// Toggle auto-save
 				m.settings.AutoSave = !m.settings.AutoSave
 			} else if m.cursor == len(m.inputs)+1 {
+				// Toggle session recording
+				m.settings.RecordSessions = !m.settings.RecordSessions
+			} else if m.cursor == len(m.inputs)+2 {
+				// Toggle reconnecting dropped sessions
+				m.settings.AutoReconnect = !m.settings.AutoReconnect
+			} else if m.cursor == len(m.inputs)+3 {
+				// Toggle transferring past failed files
+				m.settings.ContinueOnError = !m.settings.ContinueOnError
+			} else if m.cursor == len(m.inputs)+4 {
+				// Toggle checksums after transfers
+				m.settings.VerifyTransfers = !m.settings.VerifyTransfers
+			} else if m.cursor == len(m.inputs)+5 {
 				// Save settings
 				return m, m.saveSettings()
-			} else if m.cursor == len(m.inputs)+2 {
+			} else if m.cursor == len(m.inputs)+6 {
 				// Reset to defaults
 				return m, m.resetSettings()
 			}
@@ -248,6 +280,16 @@ func (m *SettingsModel) saveSettings() tea.Cmd {
 			m.settings.Theme = theme
 		}
 
+		// Parse keepalive interval, 0 turns keepalives off
+		if interval, err := strconv.Atoi(m.inputs[settingKeepAlive].Value()); err == nil && interval >= 0 {
+			m.settings.KeepAliveInterval = interval
+		}
+
+		// Parse transfer retries, 0 fails a file on its first error
+		if retries, err := strconv.Atoi(m.inputs[settingRetries].Value()); err == nil && retries >= 0 {
+			m.settings.TransferRetries = retries
+		}
+
 		// Handle Master Password
 		newPassword := m.inputs[settingMasterPassword].Value()
 		if newPassword != "" {
@@ -411,6 +453,8 @@ func (m *SettingsModel) resetSettings() tea.Cmd {
 		m.inputs[settingPort].SetValue(fmt.Sprintf("%d", m.settings.DefaultPort))
 		m.inputs[settingUsername].SetValue(m.settings.DefaultUsername)
 		m.inputs[settingTheme].SetValue(m.settings.Theme)
+		m.inputs[settingKeepAlive].SetValue(fmt.Sprintf("%d", m.settings.KeepAliveInterval))
+		m.inputs[settingRetries].SetValue(fmt.Sprintf("%d", m.settings.TransferRetries))
 
 		// Reset password input
 		m.inputs[settingMasterPassword].SetValue("")
@@ -459,8 +503,26 @@ func (m *SettingsModel) View() string {
 		b.WriteString("\n")
 	}
 
-	// Auto-save toggle
+	// Keepalive interval input (5)
 	cursor := "  "
+	if m.cursor == settingKeepAlive && m.focused < 0 {
+		cursor = "→ "
+	}
+	b.WriteString(cursor)
+	b.WriteString(m.inputs[settingKeepAlive].View())
+	b.WriteString("\n")
+
+	// Transfer retries input (6)
+	cursor = "  "
+	if m.cursor == settingRetries && m.focused < 0 {
+		cursor = "→ "
+	}
+	b.WriteString(cursor)
+	b.WriteString(m.inputs[settingRetries].View())
+	b.WriteString("\n")
+
+	// Auto-save toggle
+	cursor = "  "
 	if m.cursor == len(m.inputs) {
 		cursor = "→ "
 	}
@@ -473,19 +535,75 @@ func (m *SettingsModel) View() string {
 		autoSaveStyle = selectedItemStyle
 	}
 	b.WriteString(cursor + autoSaveStyle.Render(fmt.Sprintf("%s Auto-save servers", autoSaveStatus)))
+	b.WriteString("\n")
+
+	// Session recording toggle
+	cursor = "  "
+	recordStyle := itemStyle
+	if m.cursor == len(m.inputs)+1 {
+		cursor = "→ "
+		recordStyle = selectedItemStyle
+	}
+	recordStatus := "☐"
+	if m.settings.RecordSessions {
+		recordStatus = "☑"
+	}
+	b.WriteString(cursor + recordStyle.Render(fmt.Sprintf("%s Record terminal sessions", recordStatus)))
+	b.WriteString("\n")
+
+	// Reconnect toggle
+	cursor = "  "
+	reconnectStyle := itemStyle
+	if m.cursor == len(m.inputs)+2 {
+		cursor = "→ "
+		reconnectStyle = selectedItemStyle
+	}
+	reconnectStatus := "☐"
+	if m.settings.AutoReconnect {
+		reconnectStatus = "☑"
+	}
+	b.WriteString(cursor + reconnectStyle.Render(fmt.Sprintf("%s Reconnect dropped sessions", reconnectStatus)))
+	b.WriteString("\n")
+
+	// Continue on error toggle
+	cursor = "  "
+	continueStyle := itemStyle
+	if m.cursor == len(m.inputs)+3 {
+		cursor = "→ "
+		continueStyle = selectedItemStyle
+	}
+	continueStatus := "☐"
+	if m.settings.ContinueOnError {
+		continueStatus = "☑"
+	}
+	b.WriteString(cursor + continueStyle.Render(fmt.Sprintf("%s Continue transfers past failed files", continueStatus)))
+	b.WriteString("\n")
+
+	// Checksum verification toggle
+	cursor = "  "
+	verifyStyle := itemStyle
+	if m.cursor == len(m.inputs)+4 {
+		cursor = "→ "
+		verifyStyle = selectedItemStyle
+	}
+	verifyStatus := "☐"
+	if m.settings.VerifyTransfers {
+		verifyStatus = "☑"
+	}
+	b.WriteString(cursor + verifyStyle.Render(fmt.Sprintf("%s Verify transfers with SHA-256", verifyStatus)))
 	b.WriteString("\n\n")
 
 	// Main Actions (Save | Reset)
 	cursorSave := " "
 	styleSave := itemStyle
-	if m.cursor == len(m.inputs)+1 { // len(m.inputs)+1 is 6
+	if m.cursor == len(m.inputs)+5 { // len(m.inputs)+5 is 12
 		cursorSave = "→"
 		styleSave = selectedItemStyle
 	}
 
 	cursorReset := " "
 	styleReset := itemStyle
-	if m.cursor == len(m.inputs)+2 {
+	if m.cursor == len(m.inputs)+6 {
 		cursorReset = "→"
 		styleReset = selectedItemStyle
 	}
@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -75,16 +76,46 @@ type SFTPDualModel struct {
 	currentTasks []sftp.TaskProgress // Track active task progress
 	logHistory   []string            // Last 10 lines of output
 
+	// Results of tasks that ended with failed files
+	failedTasks  []sftp.TaskProgress
+	showResults  bool
+	resultCursor int
+
+	// Sync preview: what syncing the selected directory would change
+	syncPlan     *sftp.SyncPlan
+	syncOptions  sftp.SyncOptions
+	syncPlanning bool
+	syncCursor   int
+	syncCancel   context.CancelFunc // Stops the comparison in progress
+
+	// Include/exclude rules for directory transfers and syncs, and the
+	// saved server holding the presets
+	servers           *storage.Store
+	serverID          string
+	transferFilter    sftp.Filter
+	editingFilter     bool
+	filterInputs      []textinput.Model // Include, exclude and preset name
+	filterIgnoreFiles bool
+	filterFocus       int
+	presetIndex       int // Preset loaded on the filter screen, -1 for none
+
 	// Refresh status
 	refreshStatus     string
 	refreshStatusTime int64
 }
 
+// syncPlanMsg carries the dry run of a directory sync
+type syncPlanMsg struct {
+	ctx  context.Context // Cancelled when the plan is no longer wanted
+	plan *sftp.SyncPlan
+	err  error
+}
+
 // NewSFTPDualModel creates a new dual-pane SFTP model
 func NewSFTPDualModel(sshClient *ssh.Client, store *storage.SettingsStore) (*SFTPDualModel, error) {
-	sftpClient, err := sftp.NewClient(sshClient.GetRawClient())
+	sftpClient, err := newSFTPClient(sshClient)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create SFTP client: %w", err)
+		return nil, err
 	}
 
 	// Get initial directories
@@ -135,6 +166,8 @@ func NewSFTPDualModel(sshClient *ssh.Client, store *storage.SettingsStore) (*SFT
 		creatingFolder: false,
 		searchInput:    si,
 		searching:      false,
+		filterInputs:   newFilterInputs(),
+		presetIndex:    -1,
 	}
 
 	// Load initial directories
@@ -157,6 +190,32 @@ func NewSFTPDualModel(sshClient *ssh.Client, store *storage.SettingsStore) (*SFT
 	return m, nil
 }
 
+// newSFTPClient opens an SFTP session on the current connection of sshClient
+func newSFTPClient(sshClient *ssh.Client) (*sftp.Client, error) {
+	sftpClient, err := sftp.NewClient(sshClient.GetRawClient())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SFTP client: %w", err)
+	}
+	sftpClient.SetJumpDialer(sshClient.JumpDialer())
+	return sftpClient, nil
+}
+
+// reopen moves the browser to the new connection after the SSH client
+// reconnected. Queued transfers start on the new connection; the ones that
+// were running failed with the old one and can be retried.
+func (m *SFTPDualModel) reopen() error {
+	sftpClient, err := newSFTPClient(m.sshClient)
+	if err != nil {
+		return err
+	}
+	m.taskQueue.SetClient(sftpClient)
+	m.sftpClient.Close()
+	m.sftpClient = sftpClient
+	m.loadRemoteDirectory()
+	m.statusMsg = "Reconnected"
+	return nil
+}
+
 // addLog adds a message to the log history, keeping only last 10 lines
 func (m *SFTPDualModel) addLog(msg string) {
 	m.logHistory = append(m.logHistory, msg)
@@ -298,10 +357,16 @@ func (m *SFTPDualModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.loadRemoteDirectory()
 			case sftp.TaskFailed:
 				errMsg := "Unknown error"
-				if msg.Error != "" {
-					errMsg = msg.Error
+				if task.Error != "" {
+					errMsg = task.Error
 				}
 				m.addLog(fmt.Sprintf("[%d] Task failed: %s", task.TaskID, errMsg))
+				if len(task.Failures) > 0 {
+					m.failedTasks = append(m.failedTasks, task)
+					m.addLog(fmt.Sprintf("[%d] Press E to review the failed files and retry them", task.TaskID))
+					m.loadLocalDirectory()
+					m.loadRemoteDirectory()
+				}
 			case sftp.TaskCancelled:
 				m.addLog(fmt.Sprintf("[%d] Task cancelled", task.TaskID))
 			}
@@ -310,6 +375,25 @@ func (m *SFTPDualModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		return m, m.waitForTaskUpdate
 
+	case syncPlanMsg:
+		if !m.syncPlanning || msg.ctx.Err() != nil {
+			// Cancelled while comparing
+			return m, nil
+		}
+		m.syncPlanning = false
+		m.syncCancel()
+		m.syncCancel = nil
+		if msg.err != nil {
+			m.syncPlan = nil
+			m.statusMsg = fmt.Sprintf("Sync failed: %v", msg.err)
+			m.addLog(fmt.Sprintf("ERROR: %v", msg.err))
+			return m, nil
+		}
+		m.statusMsg = ""
+		m.syncPlan = msg.plan
+		m.syncCursor = 0
+		return m, nil
+
 	case tea.KeyMsg:
 		// Handle folder creation input
 		if m.creatingFolder {
@@ -416,7 +500,7 @@ func (m *SFTPDualModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						taskType = sftp.TaskDownloadDirectory
 					}
 
-					_, err := m.taskQueue.QueueTask(taskType, remotePath, localPath, m.pendingFile.Name)
+					_, err := m.taskQueue.QueueFilteredTask(taskType, remotePath, localPath, m.pendingFile.Name, m.transferFilter)
 					if err != nil {
 						m.statusMsg = fmt.Sprintf("Download failed: %v", err)
 						m.addLog(fmt.Sprintf("ERROR: %v", err))
@@ -437,6 +521,18 @@ func (m *SFTPDualModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+		if m.showResults {
+			return m, m.updateResults(msg)
+		}
+
+		if m.syncPlan != nil || m.syncPlanning {
+			return m, m.updateSync(msg)
+		}
+
+		if m.editingFilter {
+			return m, m.updateTransferFilter(msg)
+		}
+
 		if m.confirmingDelete {
 			switch strings.ToLower(msg.String()) {
 			case "y":
@@ -539,6 +635,29 @@ func (m *SFTPDualModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.toggleRsync()
 			return m, nil
 
+		case "t":
+			// Port forwards of this connection
+			return m, func() tea.Msg { return OpenTunnelsMsg{} }
+
+		case "s":
+			// Preview syncing the selected directory to the other pane
+			return m, m.syncSelected()
+
+		case "i":
+			// Include/exclude rules for directory transfers
+			m.openTransferFilter()
+			return m, nil
+
+		case "e":
+			// Files that failed to transfer
+			if len(m.failedTasks) > 0 {
+				m.showResults = true
+				m.resultCursor = 0
+			} else {
+				m.statusMsg = "No failed transfers"
+			}
+			return m, nil
+
 		case "esc":
 			if m.searchInput.Value() != "" {
 				m.searchInput.SetValue("")
@@ -632,6 +751,310 @@ func (m *SFTPDualModel) IsSearchActive() bool {
 	return m.searching || m.searchInput.Value() != ""
 }
 
+// IsInputActive returns true when esc belongs to the browser: while
+// searching, filtering, reviewing failed transfers, previewing a sync or
+// editing the transfer filter
+func (m *SFTPDualModel) IsInputActive() bool {
+	return m.IsSearchActive() || m.showResults || m.syncPlan != nil || m.syncPlanning || m.editingFilter
+}
+
+// failedRow is a failed file on the transfer results screen
+type failedRow struct {
+	taskID  int
+	failure sftp.JobFailure
+}
+
+// failedRows lists the failed files of every task, oldest task first
+func (m *SFTPDualModel) failedRows() []failedRow {
+	var rows []failedRow
+	for _, task := range m.failedTasks {
+		for _, failure := range task.Failures {
+			rows = append(rows, failedRow{taskID: task.TaskID, failure: failure})
+		}
+	}
+	return rows
+}
+
+// dropFailedTask removes a task from the results screen, closing it when
+// no failures are left
+func (m *SFTPDualModel) dropFailedTask(taskID int) {
+	for i, task := range m.failedTasks {
+		if task.TaskID == taskID {
+			m.failedTasks = append(m.failedTasks[:i], m.failedTasks[i+1:]...)
+			break
+		}
+	}
+	rows := m.failedRows()
+	if len(rows) == 0 {
+		m.showResults = false
+	}
+	if m.resultCursor >= len(rows) {
+		m.resultCursor = max(len(rows)-1, 0)
+	}
+}
+
+// updateResults handles keys on the transfer results screen
+func (m *SFTPDualModel) updateResults(msg tea.KeyMsg) tea.Cmd {
+	rows := m.failedRows()
+	switch strings.ToLower(msg.String()) {
+	case "up", "k":
+		if m.resultCursor > 0 {
+			m.resultCursor--
+		}
+	case "down", "j":
+		if m.resultCursor < len(rows)-1 {
+			m.resultCursor++
+		}
+	case "r":
+		// Retry the failed files of the selected task only
+		if m.resultCursor < len(rows) {
+			taskID := rows[m.resultCursor].taskID
+			task, err := m.taskQueue.RetryFailed(taskID)
+			if err != nil {
+				m.statusMsg = fmt.Sprintf("Retry failed: %v", err)
+				m.addLog(fmt.Sprintf("ERROR: %v", err))
+				return nil
+			}
+			m.statusMsg = fmt.Sprintf("Retrying failed files of task #%d", taskID)
+			m.addLog(fmt.Sprintf("[%d] Retrying failed files of task #%d", task.ID, taskID))
+			m.dropFailedTask(taskID)
+		}
+	case "x":
+		// Forget the selected task's failures
+		if m.resultCursor < len(rows) {
+			m.dropFailedTask(rows[m.resultCursor].taskID)
+		}
+	case "esc", "e":
+		m.showResults = false
+	}
+	return nil
+}
+
+// renderResults shows the files that failed to transfer and why
+func (m *SFTPDualModel) renderResults() string {
+	var b strings.Builder
+
+	b.WriteString("\n")
+	b.WriteString(strings.Repeat("═", m.width) + "\n")
+	b.WriteString(" ✗ FAILED TRANSFERS\n")
+	b.WriteString(strings.Repeat("─", m.width) + "\n")
+
+	rows := m.failedRows()
+	limit := max(m.height-10, 3)
+	offset := 0
+	if len(rows) > limit {
+		offset = min(max(m.resultCursor-limit/2, 0), len(rows)-limit)
+	}
+
+	lastTask := 0
+	for i := offset; i < len(rows) && i < offset+limit; i++ {
+		row := rows[i]
+		if row.taskID != lastTask {
+			for _, task := range m.failedTasks {
+				if task.TaskID == row.taskID {
+					b.WriteString(fmt.Sprintf(" Task #%d: %d of %d files failed\n", task.TaskID, len(task.Failures), task.TotalFiles))
+				}
+			}
+			lastTask = row.taskID
+		}
+
+		prefix := "   "
+		if i == m.resultCursor {
+			prefix = " ► "
+		}
+		line := fmt.Sprintf("%s%s: %v", prefix, row.failure.Job.Path, row.failure.Err)
+		if len(line) > m.width-1 {
+			line = line[:max(m.width-4, 0)] + "..."
+		}
+		b.WriteString(line + "\n")
+	}
+
+	b.WriteString(strings.Repeat("─", m.width) + "\n")
+	b.WriteString(" [R] Retry failed files of this task  [X] Dismiss task  [Esc] Close\n")
+	b.WriteString(strings.Repeat("═", m.width) + "\n")
+	return b.String()
+}
+
+// syncSelected starts comparing the selected directory with its namesake in
+// the other pane, for a preview before anything is copied
+func (m *SFTPDualModel) syncSelected() tea.Cmd {
+	var name string
+	var isDir bool
+	if m.activePane == LocalPane && m.localCursor < len(m.displayLocalFiles) {
+		file := m.displayLocalFiles[m.localCursor]
+		name, isDir = file.Name, file.IsDir
+	} else if m.activePane == RemotePane && m.remoteCursor < len(m.displayRemoteFiles) {
+		file := m.displayRemoteFiles[m.remoteCursor]
+		name, isDir = file.Name, file.IsDir
+	}
+	if name == "" || name == ".." {
+		return nil
+	}
+	if !isDir {
+		m.statusMsg = "Sync works on directories, use upload or download for files"
+		return nil
+	}
+
+	localPath := filepath.Join(m.localPath, name)
+	remotePath := filepath.Join(m.remotePath, name)
+	if m.activePane == LocalPane {
+		return m.planSync(sftp.TaskUploadDirectory, localPath, remotePath, name)
+	}
+	return m.planSync(sftp.TaskDownloadDirectory, remotePath, localPath, name)
+}
+
+// planSync compares both sides of a sync in the background
+func (m *SFTPDualModel) planSync(taskType sftp.TaskType, source, dest, name string) tea.Cmd {
+	if m.syncCancel != nil {
+		m.syncCancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.syncCancel = cancel
+	m.syncPlanning = true
+	m.statusMsg = fmt.Sprintf("Comparing %s... (esc to cancel)", name)
+	queue, opts := m.taskQueue, m.syncOptions
+	opts.Filter = m.transferFilter
+	return func() tea.Msg {
+		plan, err := queue.PlanSync(ctx, taskType, source, dest, name, opts)
+		return syncPlanMsg{ctx: ctx, plan: plan, err: err}
+	}
+}
+
+// cancelSyncPlan closes the sync preview, stopping the comparison in
+// progress so its scans and hashing do not run on unseen
+func (m *SFTPDualModel) cancelSyncPlan() {
+	if m.syncCancel != nil {
+		m.syncCancel()
+		m.syncCancel = nil
+	}
+	m.syncPlan = nil
+	m.syncPlanning = false
+}
+
+// updateSync handles keys on the sync preview
+func (m *SFTPDualModel) updateSync(msg tea.KeyMsg) tea.Cmd {
+	key := strings.ToLower(msg.String())
+	if key == "esc" || key == "n" {
+		m.cancelSyncPlan()
+		m.statusMsg = "Sync cancelled"
+		return nil
+	}
+	if m.syncPlan == nil || m.syncPlanning {
+		return nil
+	}
+
+	plan := m.syncPlan
+	switch key {
+	case "up", "k":
+		if m.syncCursor > 0 {
+			m.syncCursor--
+		}
+	case "down", "j":
+		if m.syncCursor < len(plan.Entries)-1 {
+			m.syncCursor++
+		}
+	case "m":
+		m.syncOptions.Delete = !m.syncOptions.Delete
+		return m.planSync(plan.Type, plan.Source, plan.Dest, plan.Name)
+	case "h":
+		m.syncOptions.Checksum = !m.syncOptions.Checksum
+		return m.planSync(plan.Type, plan.Source, plan.Dest, plan.Name)
+	case "enter", "y":
+		m.syncPlan = nil
+		if len(plan.Entries) == 0 {
+			m.statusMsg = fmt.Sprintf("%s is already in sync", plan.Name)
+			return nil
+		}
+		task, err := m.taskQueue.QueueSync(plan)
+		if err != nil {
+			m.statusMsg = fmt.Sprintf("Sync failed: %v", err)
+			m.addLog(fmt.Sprintf("ERROR: %v", err))
+			return nil
+		}
+		m.statusMsg = fmt.Sprintf("Syncing: %s", plan.Name)
+		m.addLog(fmt.Sprintf("[%d] Sync: %s → %s (%s)", task.ID, plan.Source, plan.Dest, syncSummary(plan)))
+	}
+	return nil
+}
+
+// syncSummary counts the changes of a sync plan
+func syncSummary(plan *sftp.SyncPlan) string {
+	return fmt.Sprintf("%d to copy, %d to update, %d to delete, %s to transfer, %d up to date",
+		plan.Count(sftp.SyncCopy), plan.Count(sftp.SyncUpdate), plan.Count(sftp.SyncDelete),
+		formatSize(plan.Bytes()), plan.Unchanged)
+}
+
+// renderSyncPlan shows the dry run of a sync
+func (m *SFTPDualModel) renderSyncPlan() string {
+	var b strings.Builder
+	plan := m.syncPlan
+
+	direction := "LOCAL → REMOTE"
+	if plan.Type == sftp.TaskDownloadDirectory {
+		direction = "REMOTE → LOCAL"
+	}
+
+	b.WriteString("\n")
+	b.WriteString(strings.Repeat("═", m.width) + "\n")
+	b.WriteString(fmt.Sprintf(" ⇄ SYNC PREVIEW (dry run) %s: %s → %s\n", direction, plan.Source, plan.Dest))
+	b.WriteString(strings.Repeat("─", m.width) + "\n")
+	b.WriteString(" " + syncSummary(plan) + "\n")
+	if !plan.Options.Filter.IsZero() {
+		b.WriteString(" Filter: " + filterSummary(plan.Options.Filter) + "\n")
+	}
+	if m.syncPlanning {
+		b.WriteString(" Comparing...\n")
+	}
+	if len(plan.Entries) == 0 {
+		b.WriteString(" Nothing to do, the destination is up to date\n")
+	}
+
+	limit := max(m.height-12, 3)
+	offset := 0
+	if len(plan.Entries) > limit {
+		offset = min(max(m.syncCursor-limit/2, 0), len(plan.Entries)-limit)
+	}
+	for i := offset; i < len(plan.Entries) && i < offset+limit; i++ {
+		entry := plan.Entries[i]
+		mark := "+"
+		switch entry.Action {
+		case sftp.SyncUpdate:
+			mark = "~"
+		case sftp.SyncDelete:
+			mark = "-"
+		}
+		path := entry.Job.Path
+		if entry.Job.IsDir {
+			path += string(filepath.Separator)
+		}
+
+		prefix := "   "
+		if i == m.syncCursor {
+			prefix = " ► "
+		}
+		line := fmt.Sprintf("%s%s %s", prefix, mark, path)
+		if !entry.Job.IsDir && entry.Action != sftp.SyncDelete {
+			line += fmt.Sprintf(" (%s)", formatSize(entry.Job.Size))
+		}
+		if len(line) > m.width-1 {
+			line = line[:max(m.width-4, 0)] + "..."
+		}
+		b.WriteString(line + "\n")
+	}
+
+	onOff := func(on bool) string {
+		if on {
+			return "on"
+		}
+		return "off"
+	}
+	b.WriteString(strings.Repeat("─", m.width) + "\n")
+	b.WriteString(fmt.Sprintf(" [Enter] Sync  [M] Mirror, delete extraneous: %s  [H] Compare checksums: %s  [Esc] Cancel\n",
+		onOff(plan.Options.Delete), onOff(plan.Options.Checksum)))
+	b.WriteString(strings.Repeat("═", m.width) + "\n")
+	return b.String()
+}
+
 func (m *SFTPDualModel) navigate() {
 	if m.activePane == LocalPane {
 		if m.localCursor >= len(m.displayLocalFiles) { // Use Display
@@ -702,7 +1125,7 @@ func (m *SFTPDualModel) uploadSelected() {
 		taskType = sftp.TaskUploadDirectory
 	}
 
-	_, err := m.taskQueue.QueueTask(taskType, localPath, remotePath, file.Name)
+	_, err := m.taskQueue.QueueFilteredTask(taskType, localPath, remotePath, file.Name, m.transferFilter)
 	if err != nil {
 		m.statusMsg = fmt.Sprintf("Upload failed: %v", err)
 		m.addLog(fmt.Sprintf("ERROR: %v", err))
@@ -817,6 +1240,16 @@ func (m *SFTPDualModel) View() string {
 
 	var b strings.Builder
 
+	if m.showResults {
+		return m.renderResults()
+	}
+	if m.syncPlan != nil {
+		return m.renderSyncPlan()
+	}
+	if m.editingFilter {
+		return m.renderTransferFilter()
+	}
+
 	// Show confirmation dialogs if active
 	if m.confirmingDelete {
 		fileName := ""
@@ -1139,13 +1572,22 @@ func (m *SFTPDualModel) renderLastOutput(height int) string {
 	b.WriteString(strings.Repeat("─", m.width) + "\n")
 
 	// Status line with controls and mode
-	controls := "Controls: [U]pload [D]ownload [R]efresh [Alt+R] Toggle Rsync"
+	filter := "[I] Filter"
+	if !m.transferFilter.IsZero() {
+		filter = "[I] Filter (on)"
+	}
+	controls := "Controls: [U]pload [D]ownload [S]ync " + filter + " [R]efresh [Alt+R] Toggle Rsync"
+	if len(m.failedTasks) > 0 {
+		controls += fmt.Sprintf(" [E] Failed (%d)", len(m.failedRows()))
+	}
 
 	// Rsync Status
 	settings := m.store.Get()
 	mode := "SFTP"
 	if !settings.DisableRsync {
 		mode = "RSYNC"
+	} else if settings.VerifyTransfers {
+		mode = "SFTP+SHA256"
 	}
 
 	b.WriteString(fmt.Sprintf(" %-60s | Mode: [%s]\n", controls, mode))
@@ -0,0 +1,38 @@
+package tui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/quocson95/marix/pkg/sftp"
+)
+
+func TestSFTPDual_CancelSyncPlan(t *testing.T) {
+	// Without a client every plan fails, which is enough to see whether its
+	// result is shown
+	m := &SFTPDualModel{taskQueue: sftp.NewTaskQueue(nil, nil, nil, 1, make(chan sftp.TaskProgress, 10))}
+
+	first := m.planSync(sftp.TaskUploadDirectory, "/local/site", "/srv/site", "site")
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.syncPlanning || m.syncCancel != nil {
+		t.Fatal("Expected esc to stop the comparison")
+	}
+
+	second := m.planSync(sftp.TaskUploadDirectory, "/local/site", "/srv/site", "site")
+	late := first().(syncPlanMsg)
+	if late.ctx.Err() == nil {
+		t.Fatal("Expected the first comparison to be cancelled")
+	}
+	m.Update(late)
+	if !m.syncPlanning {
+		t.Fatal("A cancelled comparison must not end the one running")
+	}
+
+	m.Update(second())
+	if m.syncPlanning || m.syncCancel != nil {
+		t.Error("Expected the second comparison to finish")
+	}
+	if m.statusMsg == "" {
+		t.Error("Expected the result of the second comparison to be shown")
+	}
+}
@@ -0,0 +1,262 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/quocson95/marix/pkg/sftp"
+	"github.com/quocson95/marix/pkg/storage"
+)
+
+// Fields of the transfer filter screen, in focus order
+const (
+	filterFieldInclude = iota
+	filterFieldExclude
+	filterFieldIgnore
+	filterFieldPreset
+	filterFieldName
+	filterFieldCount
+)
+
+// newFilterInputs creates the include, exclude and preset name inputs
+func newFilterInputs() []textinput.Model {
+	placeholders := []string{"*.go, src/** (empty for everything)", "node_modules/, .git/, *.log", "Preset name"}
+	inputs := make([]textinput.Model, len(placeholders))
+	for i, placeholder := range placeholders {
+		inputs[i] = textinput.New()
+		inputs[i].Placeholder = placeholder
+		inputs[i].Prompt = ""
+		inputs[i].CharLimit = 512
+		inputs[i].Width = 50
+	}
+	return inputs
+}
+
+// filterInput returns the text input of field, nil for the other fields
+func (m *SFTPDualModel) filterInput(field int) *textinput.Model {
+	switch field {
+	case filterFieldInclude:
+		return &m.filterInputs[0]
+	case filterFieldExclude:
+		return &m.filterInputs[1]
+	case filterFieldName:
+		return &m.filterInputs[2]
+	}
+	return nil
+}
+
+// SetServer tells the browser which saved server it is connected to, for
+// its filter presets
+func (m *SFTPDualModel) SetServer(servers *storage.Store, server *storage.Server) {
+	m.servers = servers
+	m.serverID = server.ID
+}
+
+// filterPresets returns the saved presets of the connected server
+func (m *SFTPDualModel) filterPresets() []storage.FilterPreset {
+	if m.servers == nil {
+		return nil
+	}
+	server, err := m.servers.Get(m.serverID)
+	if err != nil {
+		return nil
+	}
+	return server.FilterPresets
+}
+
+// openTransferFilter shows the filter screen with the current filter
+func (m *SFTPDualModel) openTransferFilter() {
+	m.editingFilter = true
+	m.filterInputs[0].SetValue(strings.Join(m.transferFilter.Include, ", "))
+	m.filterInputs[1].SetValue(strings.Join(m.transferFilter.Exclude, ", "))
+	m.filterIgnoreFiles = m.transferFilter.IgnoreFiles
+	m.presetIndex = -1
+	m.focusFilterField(filterFieldInclude)
+}
+
+// focusFilterField moves the focus of the filter screen to field
+func (m *SFTPDualModel) focusFilterField(field int) {
+	m.filterFocus = field
+	for i := range m.filterInputs {
+		m.filterInputs[i].Blur()
+	}
+	if input := m.filterInput(field); input != nil {
+		input.Focus()
+	}
+}
+
+// editedFilter returns the filter as entered on the filter screen
+func (m *SFTPDualModel) editedFilter() sftp.Filter {
+	return sftp.Filter{
+		Include:     sftp.ParsePatterns(m.filterInputs[0].Value()),
+		Exclude:     sftp.ParsePatterns(m.filterInputs[1].Value()),
+		IgnoreFiles: m.filterIgnoreFiles,
+	}
+}
+
+// loadFilterPreset fills the filter screen from a saved preset
+func (m *SFTPDualModel) loadFilterPreset(preset storage.FilterPreset) {
+	m.filterInputs[0].SetValue(strings.Join(preset.Include, ", "))
+	m.filterInputs[1].SetValue(strings.Join(preset.Exclude, ", "))
+	m.filterInputs[2].SetValue(preset.Name)
+	m.filterIgnoreFiles = preset.IgnoreFiles
+}
+
+// applyTransferFilter makes the edited filter the one for the next transfers
+func (m *SFTPDualModel) applyTransferFilter() {
+	m.editingFilter = false
+	m.transferFilter = m.editedFilter()
+	if m.transferFilter.IsZero() {
+		m.statusMsg = "Transfer filter cleared"
+		return
+	}
+	m.statusMsg = "Transfer filter: " + filterSummary(m.transferFilter)
+}
+
+// saveFilterPreset saves the edited filter as a preset of the server
+func (m *SFTPDualModel) saveFilterPreset() {
+	if m.servers == nil {
+		m.statusMsg = "Presets are saved per server, connect to a saved server to keep them"
+		return
+	}
+	filter := m.editedFilter()
+	preset := storage.FilterPreset{
+		Name:        m.filterInputs[2].Value(),
+		Include:     filter.Include,
+		Exclude:     filter.Exclude,
+		IgnoreFiles: filter.IgnoreFiles,
+	}
+	if err := m.servers.SaveFilterPreset(m.serverID, preset); err != nil {
+		m.statusMsg = fmt.Sprintf("Failed to save preset: %v", err)
+		return
+	}
+	m.applyTransferFilter()
+	m.statusMsg = fmt.Sprintf("Saved preset %s, transfer filter: %s", strings.TrimSpace(preset.Name), filterSummary(filter))
+}
+
+// updateTransferFilter handles keys on the filter screen
+func (m *SFTPDualModel) updateTransferFilter(msg tea.KeyMsg) tea.Cmd {
+	presets := m.filterPresets()
+
+	switch msg.String() {
+	case "esc":
+		m.editingFilter = false
+		return nil
+	case "tab", "down":
+		m.focusFilterField((m.filterFocus + 1) % filterFieldCount)
+		return nil
+	case "shift+tab", "up":
+		m.focusFilterField((m.filterFocus + filterFieldCount - 1) % filterFieldCount)
+		return nil
+	case "enter":
+		if m.filterFocus == filterFieldName {
+			m.saveFilterPreset()
+		} else {
+			m.applyTransferFilter()
+		}
+		return nil
+	}
+
+	switch m.filterFocus {
+	case filterFieldIgnore:
+		if msg.String() == " " || msg.String() == "left" || msg.String() == "right" {
+			m.filterIgnoreFiles = !m.filterIgnoreFiles
+		}
+		return nil
+
+	case filterFieldPreset:
+		switch msg.String() {
+		case "left", "h", "right", "l":
+			if len(presets) == 0 {
+				return nil
+			}
+			if msg.String() == "left" || msg.String() == "h" {
+				m.presetIndex = (m.presetIndex + len(presets) - 1) % len(presets)
+			} else {
+				m.presetIndex = (m.presetIndex + 1) % len(presets)
+			}
+			m.loadFilterPreset(presets[m.presetIndex])
+		case "x", "delete":
+			if m.presetIndex < 0 || m.presetIndex >= len(presets) {
+				return nil
+			}
+			name := presets[m.presetIndex].Name
+			if err := m.servers.DeleteFilterPreset(m.serverID, name); err != nil {
+				m.statusMsg = fmt.Sprintf("Failed to delete preset: %v", err)
+				return nil
+			}
+			m.presetIndex = -1
+			m.statusMsg = fmt.Sprintf("Deleted preset %s", name)
+		}
+		return nil
+	}
+
+	input := m.filterInput(m.filterFocus)
+	var cmd tea.Cmd
+	*input, cmd = input.Update(msg)
+	return cmd
+}
+
+// filterSummary describes a transfer filter in a few words
+func filterSummary(filter sftp.Filter) string {
+	var parts []string
+	if len(filter.Include) > 0 {
+		parts = append(parts, "include "+strings.Join(filter.Include, ", "))
+	}
+	if len(filter.Exclude) > 0 {
+		parts = append(parts, "exclude "+strings.Join(filter.Exclude, ", "))
+	}
+	if filter.IgnoreFiles {
+		parts = append(parts, "ignore files")
+	}
+	return strings.Join(parts, "; ")
+}
+
+// renderTransferFilter shows the filter screen
+func (m *SFTPDualModel) renderTransferFilter() string {
+	var b strings.Builder
+
+	b.WriteString("\n")
+	b.WriteString(strings.Repeat("═", m.width) + "\n")
+	b.WriteString(" ⚲ TRANSFER FILTER for directory uploads, downloads and syncs\n")
+	b.WriteString(strings.Repeat("─", m.width) + "\n")
+
+	row := func(field int, label, value string) {
+		prefix := "   "
+		if m.filterFocus == field {
+			prefix = " ► "
+		}
+		b.WriteString(fmt.Sprintf("%s%-9s %s\n", prefix, label, value))
+	}
+
+	row(filterFieldInclude, "Include:", m.filterInputs[0].View())
+	row(filterFieldExclude, "Exclude:", m.filterInputs[1].View())
+	check := "[ ]"
+	if m.filterIgnoreFiles {
+		check = "[x]"
+	}
+	row(filterFieldIgnore, "", check+" Skip what .gitignore and .marixignore files list")
+
+	presets := m.filterPresets()
+	var preset string
+	switch {
+	case m.servers == nil:
+		preset = "only for saved servers"
+	case len(presets) == 0:
+		preset = "none saved"
+	case m.presetIndex < 0:
+		preset = fmt.Sprintf("◄ %d saved ►", len(presets))
+	default:
+		preset = fmt.Sprintf("◄ %s ► (%d/%d)", presets[m.presetIndex].Name, m.presetIndex+1, len(presets))
+	}
+	row(filterFieldPreset, "Preset:", preset)
+	row(filterFieldName, "Save as:", m.filterInputs[2].View())
+
+	b.WriteString(strings.Repeat("─", m.width) + "\n")
+	b.WriteString(" Comma-separated globs: *.log at any depth, /build from the top, cache/ directories only, ** any directories\n")
+	b.WriteString(" [Tab] Next  [Space] Toggle  [←/→] Load preset  [X] Delete preset  [Enter] Apply, or save on Save as  [Esc] Cancel\n")
+	b.WriteString(strings.Repeat("═", m.width) + "\n")
+	return b.String()
+}
@@ -0,0 +1,570 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/quocson95/marix/pkg/storage"
+)
+
+const (
+	snippetName = iota
+	snippetCommand
+	snippetVariables
+	snippetTags
+)
+
+// snippetMode is what the snippets screen is showing
+type snippetMode int
+
+const (
+	snippetList snippetMode = iota
+	snippetForm
+	snippetValues
+	snippetOutput
+)
+
+// snippetAction is what happens once the variables are filled in
+type snippetAction int
+
+const (
+	snippetPaste snippetAction = iota
+	snippetRun
+)
+
+// snippetPasteMsg asks the app to paste text into the session
+type snippetPasteMsg struct {
+	term *TerminalModel
+	text string
+}
+
+// snippetRunMsg carries the result of a snippet run with Client.Execute
+type snippetRunMsg struct {
+	output string
+	err    error
+}
+
+// SnippetsModel lists saved command snippets for a terminal session. A
+// snippet can be pasted into the session or run on its host in the
+// background; {{name}} placeholders are filled in first.
+type SnippetsModel struct {
+	store    *storage.SnippetStore
+	term     *TerminalModel
+	snippets []*storage.Snippet
+	cursor   int
+	filter   textinput.Model
+
+	mode    snippetMode
+	editID  string // Snippet being edited; empty when adding
+	inputs  []textinput.Model
+	focused int
+
+	// Filling in variables
+	action  snippetAction
+	snippet *storage.Snippet
+	names   []string
+	values  []textinput.Model
+
+	running bool
+	ran     string // Command of the last background run
+	output  string
+	err     error
+	status  string
+}
+
+// NewSnippetsModel creates the snippets screen for term
+func NewSnippetsModel(store *storage.SnippetStore, term *TerminalModel) *SnippetsModel {
+	filter := textinput.New()
+	filter.Prompt = "/"
+	filter.Placeholder = "filter by name, command or tag"
+	filter.CharLimit = 64
+	filter.Width = 50
+
+	inputs := make([]textinput.Model, 4)
+
+	inputs[snippetName] = textinput.New()
+	inputs[snippetName].Placeholder = "Tail service log"
+	inputs[snippetName].CharLimit = 64
+	inputs[snippetName].Width = 50
+	inputs[snippetName].Prompt = "Name: "
+
+	inputs[snippetCommand] = textinput.New()
+	inputs[snippetCommand].Placeholder = "journalctl -u {{unit}} -n {{lines}} -f"
+	inputs[snippetCommand].CharLimit = 1024
+	inputs[snippetCommand].Width = 50
+	inputs[snippetCommand].Prompt = "Command: "
+
+	inputs[snippetVariables] = textinput.New()
+	inputs[snippetVariables].Placeholder = "unit=nginx, lines=100"
+	inputs[snippetVariables].CharLimit = 512
+	inputs[snippetVariables].Width = 50
+	inputs[snippetVariables].Prompt = "Defaults: "
+
+	inputs[snippetTags] = textinput.New()
+	inputs[snippetTags].Placeholder = "ops, logs"
+	inputs[snippetTags].CharLimit = 256
+	inputs[snippetTags].Width = 50
+	inputs[snippetTags].Prompt = "Tags: "
+
+	m := &SnippetsModel{
+		store:  store,
+		term:   term,
+		filter: filter,
+		inputs: inputs,
+	}
+	m.refresh()
+	return m
+}
+
+func (m *SnippetsModel) Init() tea.Cmd {
+	return nil
+}
+
+// IsInputActive reports whether a form or the filter has focus, so esc
+// closes it instead of the screen
+func (m *SnippetsModel) IsInputActive() bool {
+	return m.mode != snippetList || m.filter.Focused()
+}
+
+// refresh reloads the snippets matching the filter
+func (m *SnippetsModel) refresh() {
+	query := strings.ToLower(strings.TrimSpace(m.filter.Value()))
+	m.snippets = nil
+	for _, snippet := range m.store.List() {
+		if query == "" || snippetMatches(snippet, query) {
+			m.snippets = append(m.snippets, snippet)
+		}
+	}
+	if m.cursor >= len(m.snippets) {
+		m.cursor = max(len(m.snippets)-1, 0)
+	}
+}
+
+func snippetMatches(snippet *storage.Snippet, query string) bool {
+	if strings.Contains(strings.ToLower(snippet.Name), query) ||
+		strings.Contains(strings.ToLower(snippet.Command), query) {
+		return true
+	}
+	for _, tag := range snippet.Tags {
+		if strings.Contains(strings.ToLower(tag), query) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *SnippetsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case snippetRunMsg:
+		m.running = false
+		m.output = msg.output
+		m.err = msg.err
+		return m, nil
+
+	case tea.KeyMsg:
+		switch m.mode {
+		case snippetForm:
+			return m.updateForm(msg)
+		case snippetValues:
+			return m.updateValues(msg)
+		case snippetOutput:
+			if msg.String() == "esc" && !m.running {
+				m.mode = snippetList
+				m.err = nil
+			}
+			return m, nil
+		}
+		if m.filter.Focused() {
+			return m.updateFilter(msg)
+		}
+
+		switch msg.String() {
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.snippets)-1 {
+				m.cursor++
+			}
+		case "/":
+			return m, m.filter.Focus()
+		case "enter":
+			return m, m.use(snippetPaste)
+		case "r":
+			return m, m.use(snippetRun)
+		case "a":
+			return m, m.openForm(nil)
+		case "e":
+			if m.cursor < len(m.snippets) {
+				return m, m.openForm(m.snippets[m.cursor])
+			}
+		case "d":
+			m.delete()
+		}
+	}
+
+	return m, nil
+}
+
+func (m *SnippetsModel) updateFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.filter.SetValue("")
+		m.filter.Blur()
+		m.refresh()
+		return m, nil
+	case "enter", "up", "down":
+		// Keep the filter and go back to the list
+		m.filter.Blur()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.filter, cmd = m.filter.Update(msg)
+	m.refresh()
+	return m, cmd
+}
+
+// builtinValues are filled in from the session without asking
+func (m *SnippetsModel) builtinValues() map[string]string {
+	config := m.term.client.GetConfig()
+	return map[string]string{
+		"host": config.Host,
+		"port": strconv.Itoa(config.Port),
+		"user": config.Username,
+		"name": m.term.Name(),
+	}
+}
+
+// use pastes or runs the selected snippet, asking for variables first
+func (m *SnippetsModel) use(action snippetAction) tea.Cmd {
+	if m.cursor >= len(m.snippets) {
+		return nil
+	}
+	snippet := m.snippets[m.cursor]
+	builtins := m.builtinValues()
+
+	m.action = action
+	m.snippet = snippet
+	m.names = nil
+	m.values = nil
+	for _, name := range snippet.Placeholders() {
+		if _, ok := builtins[name]; ok {
+			continue
+		}
+		ti := textinput.New()
+		ti.Prompt = name + ": "
+		ti.CharLimit = 256
+		ti.Width = 40
+		ti.SetValue(snippet.Default(name))
+		m.names = append(m.names, name)
+		m.values = append(m.values, ti)
+	}
+
+	if len(m.values) == 0 {
+		return m.finish()
+	}
+	m.mode = snippetValues
+	m.focused = 0
+	return m.values[0].Focus()
+}
+
+func (m *SnippetsModel) updateValues(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = snippetList
+		return m, nil
+
+	case "tab", "shift+tab", "up", "down":
+		m.values[m.focused].Blur()
+		if msg.String() == "up" || msg.String() == "shift+tab" {
+			m.focused = (m.focused - 1 + len(m.values)) % len(m.values)
+		} else {
+			m.focused = (m.focused + 1) % len(m.values)
+		}
+		return m, m.values[m.focused].Focus()
+
+	case "enter":
+		return m, m.finish()
+	}
+
+	var cmd tea.Cmd
+	m.values[m.focused], cmd = m.values[m.focused].Update(msg)
+	return m, cmd
+}
+
+// finish substitutes the variables and pastes or runs the command
+func (m *SnippetsModel) finish() tea.Cmd {
+	values := m.builtinValues()
+	for i, name := range m.names {
+		values[name] = m.values[i].Value()
+	}
+
+	command, err := m.snippet.Expand(values)
+	if err != nil {
+		m.err = err
+		return nil
+	}
+	m.err = nil
+
+	if m.action == snippetPaste {
+		m.mode = snippetList
+		term := m.term
+		return func() tea.Msg { return snippetPasteMsg{term: term, text: command} }
+	}
+
+	m.mode = snippetOutput
+	m.running = true
+	m.output = ""
+	m.ran = command
+	client := m.term.client
+	return func() tea.Msg {
+		output, err := client.Execute(command)
+		return snippetRunMsg{output: output, err: err}
+	}
+}
+
+func (m *SnippetsModel) delete() {
+	if m.cursor >= len(m.snippets) {
+		return
+	}
+	snippet := m.snippets[m.cursor]
+	if err := m.store.Delete(snippet.ID); err != nil {
+		m.err = err
+		return
+	}
+	m.refresh()
+	m.err = nil
+	m.status = "Deleted " + snippet.Name
+}
+
+func (m *SnippetsModel) openForm(snippet *storage.Snippet) tea.Cmd {
+	m.mode = snippetForm
+	m.editID = ""
+	for i := range m.inputs {
+		m.inputs[i].SetValue("")
+	}
+
+	if snippet != nil {
+		m.editID = snippet.ID
+		m.inputs[snippetName].SetValue(snippet.Name)
+		m.inputs[snippetCommand].SetValue(snippet.Command)
+		m.inputs[snippetVariables].SetValue(formatSnippetVariables(snippet.Variables))
+		m.inputs[snippetTags].SetValue(strings.Join(snippet.Tags, ", "))
+	}
+
+	m.focused = 0
+	for i := range m.inputs {
+		m.inputs[i].Blur()
+	}
+	return m.inputs[0].Focus()
+}
+
+func (m *SnippetsModel) updateForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = snippetList
+		return m, nil
+
+	case "tab", "shift+tab", "up", "down":
+		if msg.String() == "up" || msg.String() == "shift+tab" {
+			m.focused--
+		} else {
+			m.focused++
+		}
+		if m.focused >= len(m.inputs) {
+			m.focused = 0
+		} else if m.focused < 0 {
+			m.focused = len(m.inputs) - 1
+		}
+		for i := range m.inputs {
+			if i == m.focused {
+				m.inputs[i].Focus()
+			} else {
+				m.inputs[i].Blur()
+			}
+		}
+		return m, nil
+
+	case "enter":
+		if err := m.saveForm(); err != nil {
+			m.err = err
+			return m, nil
+		}
+		m.mode = snippetList
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.inputs[m.focused], cmd = m.inputs[m.focused].Update(msg)
+	return m, cmd
+}
+
+func (m *SnippetsModel) saveForm() error {
+	name := strings.TrimSpace(m.inputs[snippetName].Value())
+	command := strings.TrimSpace(m.inputs[snippetCommand].Value())
+	if name == "" || command == "" {
+		return fmt.Errorf("name and command are required")
+	}
+
+	variables, err := parseSnippetVariables(m.inputs[snippetVariables].Value())
+	if err != nil {
+		return err
+	}
+	tags := splitList(m.inputs[snippetTags].Value())
+
+	now := time.Now().Unix()
+	if m.editID == "" {
+		snippet := &storage.Snippet{
+			ID:        fmt.Sprintf("snippet-%d", time.Now().UnixNano()),
+			Name:      name,
+			Command:   command,
+			Variables: variables,
+			Tags:      tags,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := m.store.Add(snippet); err != nil {
+			return err
+		}
+		m.status = "Added " + name
+	} else {
+		snippet, err := m.store.Get(m.editID)
+		if err != nil {
+			return err
+		}
+		snippet.Name = name
+		snippet.Command = command
+		snippet.Variables = variables
+		snippet.Tags = tags
+		snippet.UpdatedAt = now
+		if err := m.store.Update(snippet); err != nil {
+			return err
+		}
+		m.status = "Saved " + name
+	}
+
+	m.err = nil
+	m.refresh()
+	return nil
+}
+
+// parseSnippetVariables reads "name=default, other=value" defaults
+func parseSnippetVariables(s string) ([]storage.SnippetVariable, error) {
+	var variables []storage.SnippetVariable
+	for _, item := range splitList(s) {
+		name, value, _ := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("invalid default %q, expected name=value", item)
+		}
+		variables = append(variables, storage.SnippetVariable{Name: name, Default: strings.TrimSpace(value)})
+	}
+	return variables, nil
+}
+
+func formatSnippetVariables(variables []storage.SnippetVariable) string {
+	parts := make([]string, 0, len(variables))
+	for _, v := range variables {
+		parts = append(parts, v.Name+"="+v.Default)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// splitList splits a comma separated list, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (m *SnippetsModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("📋 Snippets"))
+	b.WriteString(helpStyle.UnsetMarginTop().Render("for " + m.term.Name()))
+	b.WriteString("\n\n")
+
+	switch m.mode {
+	case snippetForm:
+		for i := range m.inputs {
+			b.WriteString(m.inputs[i].View())
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+		b.WriteString(helpStyle.Render("Use {{name}} in the command for values asked on use; {{host}}, {{port}}, {{user}} and {{name}} come from the session."))
+		b.WriteString("\n")
+		b.WriteString(helpStyle.Render("tab: next field • enter: save • esc: cancel"))
+
+	case snippetValues:
+		b.WriteString(itemStyle.Render(m.snippet.Command))
+		b.WriteString("\n\n")
+		for i := range m.values {
+			b.WriteString(m.values[i].View())
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+		verb := "paste"
+		if m.action == snippetRun {
+			verb = "run"
+		}
+		b.WriteString(helpStyle.Render("tab: next value • enter: " + verb + " • esc: cancel"))
+
+	case snippetOutput:
+		b.WriteString(itemStyle.Render("$ " + m.ran))
+		b.WriteString("\n\n")
+		if m.running {
+			b.WriteString(helpStyle.Render("Running..."))
+		} else {
+			b.WriteString(m.output)
+			b.WriteString("\n")
+			b.WriteString(helpStyle.Render("esc: back"))
+		}
+
+	default:
+		if m.filter.Focused() || m.filter.Value() != "" {
+			b.WriteString(m.filter.View())
+			b.WriteString("\n\n")
+		}
+		if len(m.snippets) == 0 {
+			b.WriteString(helpStyle.Render("No snippets. Press 'a' to add one."))
+			b.WriteString("\n")
+		}
+		for i, snippet := range m.snippets {
+			cursor := "  "
+			style := itemStyle
+			if m.cursor == i {
+				cursor = "→ "
+				style = selectedItemStyle
+			}
+			line := snippet.Name
+			if len(snippet.Tags) > 0 {
+				line += "  #" + strings.Join(snippet.Tags, " #")
+			}
+			b.WriteString(cursor + style.Render(line) + "\n")
+			b.WriteString(helpStyle.UnsetMarginTop().Render("    "+snippet.Command) + "\n")
+		}
+		b.WriteString("\n")
+		b.WriteString(helpStyle.Render("enter: paste • r: run in background • /: filter • a: add • e: edit • d: delete • esc: back"))
+		if m.status != "" && m.err == nil {
+			b.WriteString("\n\n")
+			b.WriteString(successStyle.Render(m.status))
+		}
+	}
+
+	if m.err != nil {
+		b.WriteString("\n\n")
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", m.err)))
+	}
+
+	return boxStyle.Render(b.String())
+}
@@ -1,209 +1,542 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
+	"github.com/quocson95/marix/pkg/asciicast"
 	"github.com/quocson95/marix/pkg/ssh"
+	"github.com/quocson95/marix/pkg/vt"
 )
 
+// Terminal size used until the window size is known
+const (
+	defaultTerminalCols = 80
+	defaultTerminalRows = 24
+)
+
+// terminalStatusStyle is helpStyle without the top margin, so the status
+// line takes exactly one row
+var terminalStatusStyle = helpStyle.UnsetMarginTop()
+
 // TerminalModel represents an active SSH terminal session
 type TerminalModel struct {
 	client       *ssh.Client
 	connectionID string
-	output       []byte
-	outputChan   chan []byte
-	width        int
-	height       int
+	name         string              // Tab label, empty for the connection ID
+	tags         []string            // Tags of the saved server, for broadcast selection
+	broadcast    bool                // Receives broadcast input while broadcast is on
+	forwards     *ssh.ForwardManager // Port forwards of this session, created on first use
+	historyID    string              // Connection history entry, finished when the session ends
+	screen       *vt.Terminal
+	outputChan   chan struct{}
+	recordMu     sync.Mutex          // Orders screen writes and recorded events
+	recorder     *asciicast.Recorder // Set while the session is recorded
+	prefix       bool                // ctrl+] pressed, the next key is a marix command
+	renaming     bool
+	renameInput  textinput.Model
+	unread       bool // New output while the tab was in the background
 	err          error
-	quitting     bool
+	closed       bool
+	reconnect    bool      // Reconnect when the connection is lost instead of closing
+	reconnecting bool      // Connection lost, retrying until it is back or the pane is closed
+	attempts     int       // Failed reconnect attempts since the connection was lost
+	nextRetry    time.Time // When the next reconnect attempt starts
+	lostErr      error     // Why the connection was lost
 }
 
-// terminalOutputMsg contains output from SSH session
+// terminalOutputMsg signals that the screen of term changed
 type terminalOutputMsg struct {
-	data []byte
+	term *TerminalModel
+}
+
+// terminalCloseMsg indicates the SSH session of term closed
+type terminalCloseMsg struct {
+	term *TerminalModel
+}
+
+// terminalShellMsg carries the result of starting the shell of term
+type terminalShellMsg struct {
+	term *TerminalModel
+	err  error
+}
+
+// terminalReconnectMsg carries the result of a reconnect attempt of term
+type terminalReconnectMsg struct {
+	term *TerminalModel
+	err  error
+}
+
+// terminalRetryMsg starts the next reconnect attempt of term
+type terminalRetryMsg struct {
+	term *TerminalModel
 }
 
-// terminalCloseMsg indicates SSH session closed
-type terminalCloseMsg struct{}
+// terminalDisconnectMsg asks the app to close the current tab
+type terminalDisconnectMsg struct{}
+
+// terminalSFTPMsg asks the app to open the SFTP browser on this connection
+type terminalSFTPMsg struct{}
 
-// NewTerminalModel creates a new terminal session model
-func NewTerminalModel(config *ssh.SSHConfig) (*TerminalModel, error) {
-	client := ssh.NewClient(config)
+// terminalNewTabMsg asks the app to open a connection in a new tab
+type terminalNewTabMsg struct{}
+
+// terminalTabMsg switches to the tab at index
+type terminalTabMsg struct {
+	index int
+}
+
+// terminalCycleTabMsg moves step tabs to the right, wrapping around
+type terminalCycleTabMsg struct {
+	step int
+}
 
+// terminalSplitMsg asks the app to open a connection in a new pane next to
+// this one: side by side when vertical, stacked otherwise
+type terminalSplitMsg struct {
+	vertical bool
+}
+
+// terminalFocusMsg moves the focus step panes forward within the tab
+type terminalFocusMsg struct {
+	step int
+}
+
+// terminalBroadcastMsg asks the app to open the broadcast picker
+type terminalBroadcastMsg struct{}
+
+// terminalOptOutMsg toggles whether this session receives broadcast input
+type terminalOptOutMsg struct{}
+
+// terminalSnippetsMsg asks the app to open the snippets picker
+type terminalSnippetsMsg struct{}
+
+// terminalRecordMsg asks the app to start or stop recording this session
+type terminalRecordMsg struct{}
+
+// NewTerminalModel connects the given client and creates a terminal session model
+func NewTerminalModel(client *ssh.Client) (*TerminalModel, error) {
 	// Connect to SSH server
 	if err := client.Connect(); err != nil {
 		return nil, fmt.Errorf("connection failed: %w", err)
 	}
 
+	ti := textinput.New()
+	ti.Prompt = "Tab name: "
+	ti.CharLimit = 32
+	ti.Width = 32
+
 	return &TerminalModel{
 		client:       client,
-		connectionID: config.ConnectionID(),
-		output:       []byte{},
+		connectionID: client.GetConfig().ConnectionID(),
+		screen:       vt.New(defaultTerminalCols, defaultTerminalRows),
+		renameInput:  ti,
 	}, nil
 }
 
+// Name returns the tab label
+func (m *TerminalModel) Name() string {
+	if m.name != "" {
+		return m.name
+	}
+	return m.connectionID
+}
+
+func (m *TerminalModel) hasTag(tag string) bool {
+	return hasTag(m.tags, tag)
+}
+
+// Forwards returns the port forwards of this session
+func (m *TerminalModel) Forwards() *ssh.ForwardManager {
+	if m.forwards == nil {
+		m.forwards = ssh.NewForwardManager(m.client)
+	}
+	return m.forwards
+}
+
+// Close disconnects the session and stops its port forwards
+func (m *TerminalModel) Close() {
+	m.StopRecording()
+	if m.forwards != nil {
+		m.forwards.StopAll()
+	}
+	if m.client != nil {
+		m.client.Close()
+	}
+}
+
+// Resize sets the screen size of the pane and tells the remote PTY. Before
+// the shell starts this only sets the size it will be created with.
+func (m *TerminalModel) Resize(cols, rows int) {
+	oldCols, oldRows := m.screen.Size()
+	m.screen.Resize(cols, rows)
+	cols, rows = m.screen.Size()
+	if cols != oldCols || rows != oldRows {
+		m.recordMu.Lock()
+		if m.recorder != nil {
+			m.recorder.Resize(cols, rows)
+		}
+		m.recordMu.Unlock()
+	}
+	if m.client != nil && !m.closed {
+		m.client.Resize(cols, rows)
+	}
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._@-]+`)
+
+// StartRecording records the session to a new asciicast file in dir and
+// returns its path. The recording opens with the current screen, so one
+// started mid-session replays from what was visible.
+func (m *TerminalModel) StartRecording(dir string) (string, error) {
+	m.recordMu.Lock()
+	defer m.recordMu.Unlock()
+
+	if m.recorder != nil {
+		return "", fmt.Errorf("session is already recorded")
+	}
+
+	cols, rows := m.screen.Size()
+	header := asciicast.Header{
+		Width:  cols,
+		Height: rows,
+		Title:  m.connectionID,
+		Env:    map[string]string{"TERM": "xterm-256color"},
+	}
+
+	// Panes on the same host can start recording in the same second
+	base := unsafeFileChars.ReplaceAllString(m.connectionID, "-") + "-" + time.Now().Format("20060102-150405")
+	path := filepath.Join(dir, base+".cast")
+	recorder, err := asciicast.Create(path, header)
+	for i := 2; errors.Is(err, fs.ErrExist) && i < 100; i++ {
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.cast", base, i))
+		recorder, err = asciicast.Create(path, header)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := recorder.Output(m.screen.Snapshot()); err != nil {
+		recorder.Close()
+		return "", err
+	}
+	m.recorder = recorder
+	return path, nil
+}
+
+// StopRecording closes the recording, if any
+func (m *TerminalModel) StopRecording() {
+	m.recordMu.Lock()
+	defer m.recordMu.Unlock()
+
+	if m.recorder != nil {
+		m.recorder.Close()
+		m.recorder = nil
+	}
+}
+
+// Recording reports whether the session is being recorded
+func (m *TerminalModel) Recording() bool {
+	m.recordMu.Lock()
+	defer m.recordMu.Unlock()
+	return m.recorder != nil
+}
+
 func (m *TerminalModel) Init() tea.Cmd {
-	// Create channel for receiving SSH output
-	m.outputChan = make(chan []byte, 100)
+	// Redraws are coalesced: one pending signal covers any amount of output
+	m.outputChan = make(chan struct{}, 1)
 
-	// Set up callbacks BEFORE creating shell
+	// The emulator is fed straight from the SSH reader so no output is dropped
 	m.client.OnData(func(data []byte) {
-		// Send data to the TUI via channel
+		m.output(data)
 		select {
-		case m.outputChan <- data:
+		case m.outputChan <- struct{}{}:
 		default:
-			// Channel full, skip this data
 		}
 	})
 
+	// Answers to cursor position and device attribute queries go back to the PTY
+	m.screen.OnResponse(func(data []byte) {
+		m.client.Write(data)
+	})
+
 	m.client.OnClose(func() {
+		// A recording spans reconnects
+		if !m.reconnect || m.client.Err() == nil {
+			m.StopRecording()
+		}
 		close(m.outputChan)
 	})
 
-	// Create shell session (this will block until shell is ready)
-	go func() {
-		if err := m.client.CreateShell(80, 24); err != nil {
-			m.err = err
-			close(m.outputChan)
+	// Create the shell session in the background and listen for output
+	return tea.Batch(m.createShell(), m.waitForOutput())
+}
+
+// createShell starts the remote shell. A client whose shell fails is closed,
+// as nothing else would use its connection.
+func (m *TerminalModel) createShell() tea.Cmd {
+	client := m.client
+	cols, rows := m.screen.Size()
+	return func() tea.Msg {
+		err := client.CreateShell(cols, rows)
+		if err != nil {
+			client.Close()
 		}
-	}()
+		return terminalShellMsg{term: m, err: err}
+	}
+}
+
+// output feeds data to the screen and the recording
+func (m *TerminalModel) output(data []byte) {
+	m.recordMu.Lock()
+	defer m.recordMu.Unlock()
 
-	// Return a command that listens for output
-	return m.waitForOutput()
+	m.screen.Write(data)
+	if m.recorder != nil {
+		// Recording errors must not interrupt the session
+		m.recorder.Output(data)
+	}
 }
 
 // waitForOutput listens for SSH output and sends it to the TUI
 func (m *TerminalModel) waitForOutput() tea.Cmd {
 	return func() tea.Msg {
-		data, ok := <-m.outputChan
-		if !ok {
-			return terminalCloseMsg{}
+		if _, ok := <-m.outputChan; !ok {
+			return terminalCloseMsg{term: m}
 		}
-		return terminalOutputMsg{data: data}
+		return terminalOutputMsg{term: m}
 	}
 }
 
 func (m *TerminalModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
-	case tea.WindowSizeMsg:
-		m.width = msg.Width
-		m.height = msg.Height
-		// Resize SSH terminal
-		if m.client != nil {
-			m.client.Resize(msg.Width, msg.Height)
+	case tea.KeyMsg:
+		if m.renaming {
+			return m, m.updateRename(msg)
+		}
+		if m.prefix {
+			m.prefix = false
+			return m, m.command(msg)
+		}
+		if msg.Type == tea.KeyCtrlCloseBracket {
+			m.prefix = true
+			return m, nil
 		}
+
+		// Everything else, including esc and ctrl keys, belongs to the remote side
+		m.SendKey(msg)
 		return m, nil
 
-	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+c", "ctrl+d":
-			m.quitting = true
-			if m.client != nil {
-				m.client.Close()
-			}
-			return m, tea.Quit
+	case terminalOutputMsg:
+		// Continue listening for more output
+		return m, m.waitForOutput()
 
-		case "esc":
-			// Disconnect and return to menu
-			if m.client != nil {
-				m.client.Close()
-			}
-			return m, tea.Quit
+	case terminalCloseMsg:
+		if err := m.client.Err(); m.reconnect && errors.Is(err, ssh.ErrConnectionLost) {
+			m.reconnecting = true
+			m.attempts = 0
+			m.lostErr = err
+			// Leave full-screen programs so the new shell starts on a sane screen
+			m.output([]byte("\x1b[?1049l\x1b[!p\x1b[?2004l\r\n\x1b[33m*** Connection lost, reconnecting...\x1b[m\r\n"))
+			return m, m.reconnectNow()
+		}
+		m.closed = true
+		return m, nil
 
-		default:
-			// Send keystrokes to SSH session
-			if m.client != nil {
-				// Handle special keys
-				var data []byte
-				switch msg.String() {
-				case "enter":
-					data = []byte("\r")
-				case "backspace":
-					data = []byte{127}
-				case "tab":
-					data = []byte("\t")
-				case "up":
-					data = []byte{27, 91, 65}
-				case "down":
-					data = []byte{27, 91, 66}
-				case "right":
-					data = []byte{27, 91, 67}
-				case "left":
-					data = []byte{27, 91, 68}
-				default:
-					data = []byte(msg.String())
-				}
-
-				if err := m.client.Write(data); err != nil {
-					m.err = err
-				}
-			}
+	case terminalShellMsg:
+		if msg.err != nil {
+			// Without a shell the close callback never runs, so end the
+			// output listener here
+			m.err = msg.err
+			close(m.outputChan)
+		}
+		return m, nil
+
+	case terminalReconnectMsg:
+		if !m.reconnecting {
 			return m, nil
 		}
+		if msg.err != nil {
+			m.attempts++
+			m.lostErr = msg.err
+			delay := reconnectDelay(m.attempts)
+			m.nextRetry = time.Now().Add(delay)
+			return m, tea.Tick(delay, func(time.Time) tea.Msg { return terminalRetryMsg{term: m} })
+		}
 
-	case terminalOutputMsg:
-		// Received output from SSH
-		m.output = append(m.output, msg.data...)
-		// Continue listening for more output
+		m.reconnecting = false
+		m.attempts = 0
+		m.lostErr = nil
+		m.err = nil
+		m.output([]byte("\x1b[32m*** Reconnected\x1b[m\r\n"))
+		if m.forwards != nil {
+			if err := m.forwards.Restart(); err != nil {
+				m.err = err
+			}
+		}
 		return m, m.waitForOutput()
 
-	case terminalCloseMsg:
-		m.quitting = true
-		return m, tea.Quit
+	case terminalRetryMsg:
+		if !m.reconnecting {
+			return m, nil
+		}
+		return m, m.reconnectNow()
 	}
 
 	return m, nil
 }
 
-func (m *TerminalModel) View() string {
-	if m.quitting {
-		return "Disconnected.\n"
+// reconnectNow starts a reconnect attempt in the background
+func (m *TerminalModel) reconnectNow() tea.Cmd {
+	// The new shell reports to a fresh channel; the old one is closed
+	m.outputChan = make(chan struct{}, 1)
+	client := m.client
+	cols, rows := m.screen.Size()
+	return func() tea.Msg {
+		return terminalReconnectMsg{term: m, err: client.Reconnect(cols, rows)}
 	}
+}
 
-	var b strings.Builder
+// reconnectDelay returns the wait before the next attempt, doubling from
+// the tunnel policy's minimum up to its maximum
+func reconnectDelay(attempts int) time.Duration {
+	delay := ssh.DefaultReconnectPolicy.MinBackoff
+	for i := 1; i < attempts && delay < ssh.DefaultReconnectPolicy.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, ssh.DefaultReconnectPolicy.MaxBackoff)
+}
 
-	// Terminal header
-	header := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#7D56F4")).
-		Bold(true).
-		Render(fmt.Sprintf("📡 Connected to %s", m.connectionID))
+// SendKey writes a key press to the remote shell, encoded for this
+// session's terminal modes
+func (m *TerminalModel) SendKey(msg tea.KeyMsg) {
+	data := encodeKey(msg, m.screen.AppCursorKeys(), m.screen.BracketedPaste())
+	if len(data) > 0 && m.client != nil && !m.closed && !m.reconnecting {
+		m.write(data)
+	}
+}
 
-	b.WriteString(header)
-	b.WriteString(fmt.Sprintf(" (received %d bytes)", len(m.output)))
-	b.WriteString("\n\n")
+// write sends input to the remote shell and records it
+func (m *TerminalModel) write(data []byte) {
+	m.recordMu.Lock()
+	if m.recorder != nil {
+		m.recorder.Input(data)
+	}
+	m.recordMu.Unlock()
 
-	// Terminal output
-	if len(m.output) > 0 {
-		// Show last N lines
-		maxLines := m.height - 8 // Leave more room for header/footer
-		if maxLines < 5 {
-			maxLines = 20
-		}
+	if err := m.client.Write(data); err != nil {
+		m.err = err
+	}
+}
 
-		lines := strings.Split(string(m.output), "\n")
-		startIdx := 0
-		if len(lines) > maxLines {
-			startIdx = len(lines) - maxLines
-		}
+// capturesInput reports whether the next key is for marix rather than the
+// remote shell
+func (m *TerminalModel) capturesInput() bool {
+	return m.prefix || m.renaming
+}
 
-		// Safely join lines
-		if startIdx < len(lines) {
-			b.WriteString(strings.Join(lines[startIdx:], "\n"))
-		}
-	} else {
-		b.WriteString("Waiting for output from server...")
+// updateRename edits the tab name
+func (m *TerminalModel) updateRename(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "enter":
+		// An empty name goes back to the connection ID
+		m.name = strings.TrimSpace(m.renameInput.Value())
+		m.renaming = false
+		m.renameInput.Blur()
+		return nil
+	case "esc":
+		m.renaming = false
+		m.renameInput.Blur()
+		return nil
 	}
 
-	b.WriteString("\n\n")
-	b.WriteString(helpStyle.Render("ctrl+f: sftp browser • esc: disconnect • ctrl+c/ctrl+d: quit"))
+	var cmd tea.Cmd
+	m.renameInput, cmd = m.renameInput.Update(msg)
+	return cmd
+}
 
-	if m.err != nil {
-		b.WriteString("\n")
-		b.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", m.err)))
+// command runs the marix command for the key pressed after ctrl+]
+func (m *TerminalModel) command(msg tea.KeyMsg) tea.Cmd {
+	switch key := msg.String(); key {
+	case "d", "q":
+		return func() tea.Msg { return terminalDisconnectMsg{} }
+	case "c":
+		return func() tea.Msg { return terminalNewTabMsg{} }
+	case "n", "right":
+		return func() tea.Msg { return terminalCycleTabMsg{step: 1} }
+	case "p", "left":
+		return func() tea.Msg { return terminalCycleTabMsg{step: -1} }
+	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		index := int(key[0] - '1')
+		return func() tea.Msg { return terminalTabMsg{index: index} }
+	case "|":
+		return func() tea.Msg { return terminalSplitMsg{vertical: true} }
+	case "-":
+		return func() tea.Msg { return terminalSplitMsg{vertical: false} }
+	case "b":
+		return func() tea.Msg { return terminalBroadcastMsg{} }
+	case "x":
+		return func() tea.Msg { return terminalOptOutMsg{} }
+	case "s":
+		return func() tea.Msg { return terminalSnippetsMsg{} }
+	case "R":
+		return func() tea.Msg { return terminalRecordMsg{} }
+	case "o", "tab":
+		return func() tea.Msg { return terminalFocusMsg{step: 1} }
+	case "r":
+		m.renaming = true
+		m.renameInput.SetValue(m.name)
+		m.renameInput.CursorEnd()
+		return m.renameInput.Focus()
+	case "f":
+		return func() tea.Msg { return terminalSFTPMsg{} }
+	case "t":
+		return func() tea.Msg { return OpenTunnelsMsg{} }
+	case "ctrl+]":
+		// Pressed twice, send it through
+		if m.closed || m.reconnecting {
+			break
+		}
+		m.write([]byte{0x1d})
 	}
+	return nil
+}
 
-	return b.String()
+func (m *TerminalModel) View() string {
+	return m.ScreenView(true)
+}
+
+// ScreenView renders the screen; the cursor is only drawn in the focused pane
+func (m *TerminalModel) ScreenView(focused bool) string {
+	return m.screen.Render(focused && !m.closed)
+}
+
+// StatusView renders the status line shown below the panes for the focused one
+func (m *TerminalModel) StatusView() string {
+	switch {
+	case m.renaming:
+		return terminalStatusStyle.Render(m.renameInput.View() + "  (enter: save • esc: cancel)")
+	case m.prefix:
+		return terminalStatusStyle.Render("c: tab • |/-: split • o: pane • n/p/1-9: switch tab • r: rename • d: close • b: broadcast • x: opt out • s: snippets • R: record • f: sftp • t: forwards")
+	case m.reconnecting:
+		status := "Reconnecting..."
+		if m.attempts > 0 {
+			status = fmt.Sprintf("Attempt %d failed: %v. Retrying at %s", m.attempts, m.lostErr, m.nextRetry.Format("15:04:05"))
+		}
+		return errorStyle.Render("Connection lost.") + terminalStatusStyle.Render(status+" • ctrl+] then d: close pane")
+	case m.closed:
+		return errorStyle.Render("Disconnected.") + terminalStatusStyle.Render("ctrl+] then d: close pane • c: new tab")
+	case m.err != nil:
+		return errorStyle.Render(fmt.Sprintf("Error: %v", m.err))
+	default:
+		return terminalStatusStyle.Render("ctrl+] then c: new tab • |/-: split • o: next pane • n/p: switch tab • r: rename • d: close")
+	}
 }
@@ -0,0 +1,139 @@
+package tui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// xterm modifier parameters: 1 + shift(1) + alt(2) + ctrl(4)
+const (
+	modShift = 1
+	modAlt   = 2
+	modCtrl  = 4
+)
+
+// cursorKeys maps keys that end in a letter, as in ESC [ 1 ; mod A
+var cursorKeys = map[tea.KeyType]struct {
+	final byte
+	mod   int
+}{
+	tea.KeyUp:             {'A', 0},
+	tea.KeyDown:           {'B', 0},
+	tea.KeyRight:          {'C', 0},
+	tea.KeyLeft:           {'D', 0},
+	tea.KeyHome:           {'H', 0},
+	tea.KeyEnd:            {'F', 0},
+	tea.KeyShiftUp:        {'A', modShift},
+	tea.KeyShiftDown:      {'B', modShift},
+	tea.KeyShiftRight:     {'C', modShift},
+	tea.KeyShiftLeft:      {'D', modShift},
+	tea.KeyShiftHome:      {'H', modShift},
+	tea.KeyShiftEnd:       {'F', modShift},
+	tea.KeyCtrlUp:         {'A', modCtrl},
+	tea.KeyCtrlDown:       {'B', modCtrl},
+	tea.KeyCtrlRight:      {'C', modCtrl},
+	tea.KeyCtrlLeft:       {'D', modCtrl},
+	tea.KeyCtrlHome:       {'H', modCtrl},
+	tea.KeyCtrlEnd:        {'F', modCtrl},
+	tea.KeyCtrlShiftUp:    {'A', modCtrl | modShift},
+	tea.KeyCtrlShiftDown:  {'B', modCtrl | modShift},
+	tea.KeyCtrlShiftRight: {'C', modCtrl | modShift},
+	tea.KeyCtrlShiftLeft:  {'D', modCtrl | modShift},
+	tea.KeyCtrlShiftHome:  {'H', modCtrl | modShift},
+	tea.KeyCtrlShiftEnd:   {'F', modCtrl | modShift},
+	tea.KeyF1:             {'P', 0},
+	tea.KeyF2:             {'Q', 0},
+	tea.KeyF3:             {'R', 0},
+	tea.KeyF4:             {'S', 0},
+	tea.KeyF13:            {'P', modShift},
+	tea.KeyF14:            {'Q', modShift},
+	tea.KeyF15:            {'R', modShift},
+	tea.KeyF16:            {'S', modShift},
+}
+
+// tildeKeys maps keys sent as ESC [ code ; mod ~
+var tildeKeys = map[tea.KeyType]struct {
+	code int
+	mod  int
+}{
+	tea.KeyInsert:     {2, 0},
+	tea.KeyDelete:     {3, 0},
+	tea.KeyPgUp:       {5, 0},
+	tea.KeyPgDown:     {6, 0},
+	tea.KeyCtrlPgUp:   {5, modCtrl},
+	tea.KeyCtrlPgDown: {6, modCtrl},
+	tea.KeyF5:         {15, 0},
+	tea.KeyF6:         {17, 0},
+	tea.KeyF7:         {18, 0},
+	tea.KeyF8:         {19, 0},
+	tea.KeyF9:         {20, 0},
+	tea.KeyF10:        {21, 0},
+	tea.KeyF11:        {23, 0},
+	tea.KeyF12:        {24, 0},
+	tea.KeyF17:        {15, modShift},
+	tea.KeyF18:        {17, modShift},
+	tea.KeyF19:        {18, modShift},
+	tea.KeyF20:        {19, modShift},
+}
+
+// encodeKey turns a key press into the bytes an xterm would send.
+// appCursor selects SS3 cursor keys (DECCKM); bracketedPaste wraps pastes.
+func encodeKey(msg tea.KeyMsg, appCursor, bracketedPaste bool) []byte {
+	if msg.Paste {
+		if bracketedPaste {
+			return []byte("\x1b[200~" + string(msg.Runes) + "\x1b[201~")
+		}
+		return []byte(string(msg.Runes))
+	}
+
+	var data []byte
+	switch {
+	case msg.Type == tea.KeyRunes:
+		data = []byte(string(msg.Runes))
+
+	case msg.Type == tea.KeySpace:
+		data = []byte{' '}
+
+	case msg.Type == tea.KeyShiftTab:
+		data = []byte("\x1b[Z")
+
+	case msg.Type >= 0:
+		// Control characters, enter, tab, backspace and escape are their own byte
+		data = []byte{byte(msg.Type)}
+
+	default:
+		if key, ok := cursorKeys[msg.Type]; ok {
+			mod := key.mod
+			if msg.Alt {
+				mod |= modAlt
+			}
+			switch {
+			case mod != 0:
+				return []byte(fmt.Sprintf("\x1b[1;%d%c", mod+1, key.final))
+			case key.final >= 'P' || appCursor:
+				// F1-F4 always use SS3, cursor keys only in application mode
+				return []byte{0x1b, 'O', key.final}
+			default:
+				return []byte{0x1b, '[', key.final}
+			}
+		}
+		if key, ok := tildeKeys[msg.Type]; ok {
+			mod := key.mod
+			if msg.Alt {
+				mod |= modAlt
+			}
+			if mod != 0 {
+				return []byte(fmt.Sprintf("\x1b[%d;%d~", key.code, mod+1))
+			}
+			return []byte(fmt.Sprintf("\x1b[%d~", key.code))
+		}
+		return nil
+	}
+
+	// Alt sends ESC before the key, as with xterm's metaSendsEscape
+	if msg.Alt {
+		data = append([]byte{0x1b}, data...)
+	}
+	return data
+}
@@ -0,0 +1,46 @@
+package tui
+
+import (
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/quocson95/marix/pkg/ssh"
+	"github.com/quocson95/marix/pkg/vt"
+)
+
+func TestTerminal_ShellFailure(t *testing.T) {
+	// The client never connected, so creating the shell fails
+	term := &TerminalModel{
+		client: ssh.NewClient(&ssh.SSHConfig{Host: "example.com", Port: 22, Username: "deploy"}),
+		screen: vt.New(defaultTerminalCols, defaultTerminalRows),
+	}
+	batch, ok := term.Init()().(tea.BatchMsg)
+	if !ok || len(batch) != 2 {
+		t.Fatalf("Expected the shell and output commands, got %v", batch)
+	}
+
+	closed := make(chan tea.Msg, 1)
+	go func() { closed <- batch[1]() }()
+
+	msg, ok := batch[0]().(terminalShellMsg)
+	if !ok || msg.err == nil {
+		t.Fatalf("Expected a shell failure, got %#v", msg)
+	}
+	if term.err != nil {
+		t.Error("The error must only be set by Update")
+	}
+	term.Update(msg)
+	if term.err != msg.err {
+		t.Errorf("Expected %v to be shown, got %v", msg.err, term.err)
+	}
+
+	select {
+	case got := <-closed:
+		if _, ok := got.(terminalCloseMsg); !ok {
+			t.Errorf("Expected the output listener to report the close, got %#v", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Output listener still waiting after the shell failed")
+	}
+}
@@ -0,0 +1,237 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/quocson95/marix/pkg/ssh"
+	"github.com/quocson95/marix/pkg/storage"
+)
+
+// OpenTunnelsMsg asks the app to show the port forwards of the current connection
+type OpenTunnelsMsg struct{}
+
+// tunnelTickMsg refreshes the byte counters
+type tunnelTickMsg struct{}
+
+func tunnelTick() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return tunnelTickMsg{}
+	})
+}
+
+// TunnelsModel shows the running port forwards and lets the user add,
+// stop and save them
+type TunnelsModel struct {
+	manager *ssh.ForwardManager
+	store   *storage.Store
+	server  *storage.Server // Saved server of the connection; nil for quick connect
+	tunnels []*ssh.Tunnel
+	cursor  int
+	adding  bool
+	input   textinput.Model
+	err     error
+	status  string
+	width   int
+	height  int
+}
+
+// NewTunnelsModel creates the tunnels screen for a connection
+func NewTunnelsModel(manager *ssh.ForwardManager, store *storage.Store, server *storage.Server) *TunnelsModel {
+	ti := textinput.New()
+	ti.Placeholder = "L 8080:localhost:80 • R 9000:localhost:3000 • D 1080"
+	ti.CharLimit = 128
+	ti.Width = 50
+	ti.Prompt = "Forward: "
+
+	return &TunnelsModel{
+		manager: manager,
+		store:   store,
+		server:  server,
+		tunnels: manager.Tunnels(),
+		input:   ti,
+	}
+}
+
+func (m *TunnelsModel) Init() tea.Cmd {
+	return tunnelTick()
+}
+
+// IsInputActive reports whether a new forward is being typed, so esc cancels it
+func (m *TunnelsModel) IsInputActive() bool {
+	return m.adding
+}
+
+func (m *TunnelsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		return m, nil
+
+	case tunnelTickMsg:
+		m.tunnels = m.manager.Tunnels()
+		return m, tunnelTick()
+
+	case tea.KeyMsg:
+		if m.adding {
+			switch msg.String() {
+			case "esc":
+				m.adding = false
+				m.input.Blur()
+				return m, nil
+			case "enter":
+				m.adding = false
+				m.input.Blur()
+				m.startForward(m.input.Value())
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.input, cmd = m.input.Update(msg)
+			return m, cmd
+		}
+
+		switch msg.String() {
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.tunnels)-1 {
+				m.cursor++
+			}
+		case "a":
+			m.adding = true
+			m.input.SetValue("")
+			m.input.Focus()
+			return m, textinput.Blink
+		case "d":
+			if m.cursor < len(m.tunnels) {
+				m.manager.Stop(m.tunnels[m.cursor])
+				m.tunnels = m.manager.Tunnels()
+				if m.cursor >= len(m.tunnels) && m.cursor > 0 {
+					m.cursor--
+				}
+				m.status = "Forward stopped"
+			}
+		case "s":
+			m.saveForwards()
+		}
+	}
+
+	return m, nil
+}
+
+func (m *TunnelsModel) startForward(spec string) {
+	f, err := ssh.ParseForward(spec)
+	if err != nil {
+		m.err = err
+		return
+	}
+	if _, err := m.manager.Start(f); err != nil {
+		m.err = err
+		return
+	}
+	m.err = nil
+	m.tunnels = m.manager.Tunnels()
+	m.cursor = len(m.tunnels) - 1
+	m.status = "Forward started: " + f.String()
+}
+
+// saveForwards stores the running forwards on the server so they auto-start
+func (m *TunnelsModel) saveForwards() {
+	if m.server == nil {
+		m.err = fmt.Errorf("quick connections have no saved server to store forwards on")
+		return
+	}
+
+	forwards := make([]storage.PortForward, 0, len(m.tunnels))
+	for _, t := range m.tunnels {
+		forwards = append(forwards, portForwardFromSSH(t.Stats().Forward))
+	}
+	m.server.Forwards = forwards
+	m.server.UpdatedAt = time.Now().Unix()
+	if err := m.store.Update(m.server); err != nil {
+		m.err = err
+		return
+	}
+	m.err = nil
+	m.status = fmt.Sprintf("Saved %d forward(s) to %s", len(forwards), m.server.Name)
+}
+
+func (m *TunnelsModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("🔀 Port Forwards"))
+	b.WriteString("\n\n")
+
+	if len(m.tunnels) == 0 {
+		b.WriteString(helpStyle.Render("No active forwards. Press 'a' to add one."))
+		b.WriteString("\n")
+	}
+
+	for i, t := range m.tunnels {
+		stats := t.Stats()
+		cursor := "  "
+		style := itemStyle
+		if m.cursor == i {
+			cursor = "→ "
+			style = selectedItemStyle
+		}
+
+		info := fmt.Sprintf("%-32s %s  conns: %d  ↑ %s  ↓ %s",
+			stats.Forward.String(),
+			stats.Addr,
+			stats.ActiveConns,
+			formatSize(stats.BytesSent),
+			formatSize(stats.BytesReceived),
+		)
+		b.WriteString(cursor + style.Render(info))
+		if stats.Err != nil {
+			b.WriteString(" " + errorStyle.Render(fmt.Sprintf("stopped: %v", stats.Err)))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	if m.adding {
+		b.WriteString(m.input.View())
+		b.WriteString("\n\n")
+		b.WriteString(helpStyle.Render("enter: start • esc: cancel"))
+	} else {
+		help := "↑/k up • ↓/j down • a: add • d: stop"
+		if m.server != nil {
+			help += " • s: save to server"
+		}
+		b.WriteString(helpStyle.Render(help + " • esc: back"))
+	}
+
+	if m.err != nil {
+		b.WriteString("\n\n")
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", m.err)))
+	} else if m.status != "" {
+		b.WriteString("\n\n")
+		b.WriteString(successStyle.Render(m.status))
+	}
+
+	return boxStyle.Render(b.String())
+}
+
+// startSavedForwards starts a server's saved forwards, returning any failures
+func startSavedForwards(manager *ssh.ForwardManager, server *storage.Server) []error {
+	var errs []error
+	for _, pf := range server.Forwards {
+		f := ssh.ForwardFromStorage(pf)
+		if _, err := manager.Start(f); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f, err))
+		}
+	}
+	return errs
+}
+
+func portForwardFromSSH(f ssh.Forward) storage.PortForward {
+	return storage.PortForward{Type: string(f.Type), Listen: f.Listen, Target: f.Target}
+}
@@ -0,0 +1,607 @@
+package vt
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+type parserState int
+
+const (
+	stateGround parserState = iota
+	stateEscape
+	stateCSI
+	stateOSC
+	stateString // DCS, SOS, PM and APC payloads, which are ignored
+)
+
+// maxParams bounds CSI parameters and maxOSC the OSC payload, so a garbled
+// stream cannot grow them without limit
+const (
+	maxParams = 32
+	maxOSC    = 4096
+)
+
+type parser struct {
+	state         parserState
+	intermediates []byte
+	private       byte    // CSI private marker: ? > = <
+	params        [][]int // ';'-separated parameters, each with ':' sub-parameters
+	current       []int
+	hasDigits     bool
+	osc           []byte
+	stringEsc     bool   // ESC seen inside an OSC or string, expecting '\'
+	partial       []byte // Incomplete UTF-8 sequence from the last Write
+}
+
+// Write feeds PTY output to the terminal
+func (t *Terminal) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data := p
+	if len(t.parser.partial) > 0 {
+		data = append(t.parser.partial, p...)
+		t.parser.partial = nil
+	}
+
+	for i := 0; i < len(data); {
+		b := data[i]
+		if b < utf8.RuneSelf {
+			t.handleByte(b)
+			i++
+			continue
+		}
+
+		if !utf8.FullRune(data[i:]) {
+			t.parser.partial = append([]byte(nil), data[i:]...)
+			break
+		}
+		r, size := utf8.DecodeRune(data[i:])
+		i += size
+
+		switch t.parser.state {
+		case stateGround:
+			t.put(r)
+		case stateOSC:
+			if len(t.parser.osc) < maxOSC {
+				t.parser.osc = utf8.AppendRune(t.parser.osc, r)
+			}
+		}
+	}
+
+	return len(p), nil
+}
+
+func (t *Terminal) handleByte(b byte) {
+	p := &t.parser
+
+	// Strings end at BEL or ST (ESC \); everything else is payload
+	if p.state == stateOSC || p.state == stateString {
+		switch {
+		case b == 0x07 && p.state == stateOSC:
+			t.dispatchOSC()
+			p.state = stateGround
+		case p.stringEsc && b == '\\':
+			if p.state == stateOSC {
+				t.dispatchOSC()
+			}
+			p.state = stateGround
+			p.stringEsc = false
+		case p.stringEsc:
+			// ESC followed by anything else aborts the string and starts a new sequence
+			p.stringEsc = false
+			p.state = stateEscape
+			p.intermediates = p.intermediates[:0]
+			t.handleByte(b)
+		case b == 0x1b:
+			p.stringEsc = true
+		case b == 0x18 || b == 0x1a:
+			p.state = stateGround
+		default:
+			p.stringEsc = false
+			if p.state == stateOSC && len(p.osc) < maxOSC {
+				p.osc = append(p.osc, b)
+			}
+		}
+		return
+	}
+
+	switch b {
+	case 0x1b:
+		p.state = stateEscape
+		p.intermediates = p.intermediates[:0]
+		return
+	case 0x18, 0x1a: // CAN, SUB abort a sequence
+		p.state = stateGround
+		return
+	}
+
+	// C0 controls act immediately, even in the middle of a sequence
+	if b < 0x20 || b == 0x7f {
+		t.control(b)
+		return
+	}
+
+	switch p.state {
+	case stateGround:
+		t.put(rune(b))
+
+	case stateEscape:
+		switch {
+		case b >= 0x20 && b <= 0x2f:
+			p.intermediates = append(p.intermediates, b)
+		case b == '[' && len(p.intermediates) == 0:
+			p.state = stateCSI
+			p.private = 0
+			p.params = p.params[:0]
+			p.current = nil
+			p.hasDigits = false
+		case b == ']' && len(p.intermediates) == 0:
+			p.state = stateOSC
+			p.osc = p.osc[:0]
+			p.stringEsc = false
+		case (b == 'P' || b == 'X' || b == '^' || b == '_') && len(p.intermediates) == 0:
+			p.state = stateString
+			p.stringEsc = false
+		default:
+			p.state = stateGround
+			t.dispatchEscape(b)
+		}
+
+	case stateCSI:
+		switch {
+		case b >= '0' && b <= '9':
+			if p.current == nil {
+				p.current = []int{0}
+			}
+			last := len(p.current) - 1
+			if p.current[last] < 1<<16 {
+				p.current[last] = p.current[last]*10 + int(b-'0')
+			}
+			p.hasDigits = true
+		case b == ':':
+			if p.current == nil {
+				p.current = []int{-1}
+			} else if !p.hasDigits {
+				p.current[len(p.current)-1] = -1
+			}
+			p.current = append(p.current, 0)
+			p.hasDigits = false
+		case b == ';':
+			t.endParam()
+		case b >= '<' && b <= '?':
+			if len(p.params) == 0 && p.current == nil {
+				p.private = b
+			}
+		case b >= 0x20 && b <= 0x2f:
+			p.intermediates = append(p.intermediates, b)
+		case b >= 0x40 && b <= 0x7e:
+			if p.current != nil || len(p.params) > 0 {
+				t.endParam()
+			}
+			p.state = stateGround
+			t.dispatchCSI(b)
+		}
+	}
+}
+
+// endParam closes the parameter being read; missing values become -1
+func (t *Terminal) endParam() {
+	p := &t.parser
+	if p.current == nil {
+		p.current = []int{-1}
+	} else if !p.hasDigits {
+		p.current[len(p.current)-1] = -1
+	}
+	if len(p.params) < maxParams {
+		p.params = append(p.params, p.current)
+	}
+	p.current = nil
+	p.hasDigits = false
+}
+
+// param returns parameter i, or def when it is missing or zero
+func (t *Terminal) param(i, def int) int {
+	if i >= len(t.parser.params) {
+		return def
+	}
+	if v := t.parser.params[i][0]; v > 0 {
+		return v
+	}
+	return def
+}
+
+func (t *Terminal) control(b byte) {
+	switch b {
+	case 0x08: // BS
+		if t.cur.x > 0 {
+			t.cur.x--
+		}
+		t.wrapPending = false
+	case 0x09: // HT
+		t.tab(1)
+	case 0x0a, 0x0b, 0x0c: // LF, VT, FF
+		t.lineFeed()
+		t.wrapPending = false
+	case 0x0d: // CR
+		t.cur.x = 0
+		t.wrapPending = false
+	case 0x0e: // SO, shift to G1
+		t.cur.gl = 1
+	case 0x0f: // SI, shift to G0
+		t.cur.gl = 0
+	}
+}
+
+func (t *Terminal) dispatchEscape(final byte) {
+	p := &t.parser
+	if len(p.intermediates) > 0 {
+		switch p.intermediates[0] {
+		case '(', ')':
+			set := charsetASCII
+			if final == '0' {
+				set = charsetLineDrawing
+			}
+			t.cur.charsets[p.intermediates[0]-'('] = set
+		case '#':
+			if final == '8' {
+				// DECALN fills the screen with E for alignment tests
+				for _, line := range t.screen() {
+					for x := range line {
+						line[x] = Cell{Rune: 'E', Width: 1}
+					}
+				}
+			}
+		}
+		return
+	}
+
+	switch final {
+	case '7':
+		t.saveCursor()
+	case '8':
+		t.restoreCursor()
+	case 'D':
+		t.lineFeed()
+		t.wrapPending = false
+	case 'E':
+		t.cur.x = 0
+		t.lineFeed()
+		t.wrapPending = false
+	case 'M':
+		t.reverseIndex()
+		t.wrapPending = false
+	case 'H':
+		t.tabs[t.cur.x] = true
+	case 'c':
+		t.reset()
+	case '=':
+		t.appKeypad = true
+	case '>':
+		t.appKeypad = false
+	}
+}
+
+func (t *Terminal) dispatchCSI(final byte) {
+	p := &t.parser
+
+	if len(p.intermediates) > 0 {
+		// DECSTR soft reset; other intermediates (cursor style etc.) are ignored
+		if p.intermediates[0] == '!' && final == 'p' {
+			t.softReset()
+		}
+		return
+	}
+
+	switch p.private {
+	case '?':
+		switch final {
+		case 'h':
+			t.setPrivateModes(true)
+		case 'l':
+			t.setPrivateModes(false)
+		}
+		return
+	case '>':
+		if final == 'c' {
+			// Secondary device attributes: a VT220-class terminal
+			t.respond("\x1b[>1;10;0c")
+		}
+		return
+	case 0:
+	default:
+		return
+	}
+
+	switch final {
+	case '@':
+		t.insertChars(t.param(0, 1))
+	case 'A':
+		t.moveCursor(0, -t.param(0, 1))
+	case 'B', 'e':
+		t.moveCursor(0, t.param(0, 1))
+	case 'C', 'a':
+		t.moveCursor(t.param(0, 1), 0)
+	case 'D':
+		t.moveCursor(-t.param(0, 1), 0)
+	case 'E':
+		t.moveCursor(0, t.param(0, 1))
+		t.cur.x = 0
+	case 'F':
+		t.moveCursor(0, -t.param(0, 1))
+		t.cur.x = 0
+	case 'G', '`':
+		t.cur.x = min(t.param(0, 1), t.cols) - 1
+		t.wrapPending = false
+	case 'H', 'f':
+		t.setCursor(t.param(1, 1)-1, t.param(0, 1)-1)
+	case 'I':
+		t.tab(t.param(0, 1))
+	case 'J':
+		t.eraseInDisplay(max(t.param(0, 0), 0))
+	case 'K':
+		t.eraseInLine(max(t.param(0, 0), 0))
+	case 'L':
+		t.insertLines(t.param(0, 1))
+	case 'M':
+		t.deleteLines(t.param(0, 1))
+	case 'P':
+		t.deleteChars(t.param(0, 1))
+	case 'S':
+		t.scrollUp(t.param(0, 1))
+	case 'T':
+		t.scrollDown(t.param(0, 1))
+	case 'X':
+		t.eraseChars(t.param(0, 1))
+	case 'Z':
+		t.backTab(t.param(0, 1))
+	case 'b':
+		t.repeat(t.param(0, 1))
+	case 'c':
+		// Primary device attributes: VT100 with advanced video
+		t.respond("\x1b[?1;2c")
+	case 'd':
+		y := t.param(0, 1) - 1
+		if t.cur.origin {
+			y -= t.top
+		}
+		t.setCursor(t.cur.x, y)
+	case 'g':
+		switch t.param(0, 0) {
+		case 0:
+			t.tabs[t.cur.x] = false
+		case 3:
+			for x := range t.tabs {
+				t.tabs[x] = false
+			}
+		}
+	case 'h':
+		t.setModes(true)
+	case 'l':
+		t.setModes(false)
+	case 'm':
+		t.selectGraphicRendition()
+	case 'n':
+		switch t.param(0, 0) {
+		case 5:
+			t.respond("\x1b[0n")
+		case 6:
+			y := t.cur.y
+			if t.cur.origin {
+				y -= t.top
+			}
+			t.respond(fmt.Sprintf("\x1b[%d;%dR", y+1, t.cur.x+1))
+		}
+	case 'r':
+		t.setScrollRegion(t.param(0, 1), t.param(1, t.rows))
+	case 's':
+		t.saveCursor()
+	case 'u':
+		t.restoreCursor()
+	}
+}
+
+// repeat implements REP, printing the character left of the cursor n more times
+func (t *Terminal) repeat(n int) {
+	line := t.screen()[t.cur.y]
+	x := t.cur.x - 1
+	if t.wrapPending {
+		x = t.cur.x
+	}
+	if x < 0 {
+		return
+	}
+	if line[x].Width == 0 && x > 0 {
+		x--
+	}
+	r := line[x].Rune
+	for ; n > 0; n-- {
+		t.put(r)
+	}
+}
+
+func (t *Terminal) setModes(on bool) {
+	for _, param := range t.parser.params {
+		if param[0] == 4 {
+			t.insert = on
+		}
+	}
+}
+
+func (t *Terminal) setPrivateModes(on bool) {
+	for _, param := range t.parser.params {
+		switch param[0] {
+		case 1:
+			t.appCursor = on
+		case 6:
+			t.cur.origin = on
+			t.setCursor(0, 0)
+		case 7:
+			t.autowrap = on
+		case 25:
+			t.cursorVisible = on
+		case 47, 1047:
+			t.switchScreen(on, false)
+		case 1048:
+			if on {
+				t.saveCursor()
+			} else {
+				t.restoreCursor()
+			}
+		case 1049:
+			t.switchScreen(on, true)
+		case 2004:
+			t.bracketedPaste = on
+		}
+	}
+}
+
+// softReset implements DECSTR
+func (t *Terminal) softReset() {
+	t.cursorVisible = true
+	t.appCursor = false
+	t.appKeypad = false
+	t.insert = false
+	t.autowrap = true
+	t.cur.origin = false
+	t.cur.style = Style{}
+	t.cur.charsets = [2]charset{}
+	t.cur.gl = 0
+	t.top, t.bottom = 0, t.rows-1
+	t.saved = cursor{}
+}
+
+func (t *Terminal) selectGraphicRendition() {
+	params := t.parser.params
+	if len(params) == 0 {
+		t.cur.style = Style{}
+		return
+	}
+
+	style := &t.cur.style
+	for i := 0; i < len(params); i++ {
+		param := params[i]
+		code := max(param[0], 0)
+
+		switch {
+		case code == 0:
+			*style = Style{}
+		case code == 1:
+			style.Attrs |= AttrBold
+		case code == 2:
+			style.Attrs |= AttrFaint
+		case code == 3:
+			style.Attrs |= AttrItalic
+		case code == 4:
+			if len(param) > 1 && param[1] == 0 {
+				style.Attrs &^= AttrUnderline
+			} else {
+				style.Attrs |= AttrUnderline
+			}
+		case code == 5 || code == 6:
+			style.Attrs |= AttrBlink
+		case code == 7:
+			style.Attrs |= AttrReverse
+		case code == 8:
+			style.Attrs |= AttrHidden
+		case code == 9:
+			style.Attrs |= AttrStrike
+		case code == 21:
+			style.Attrs |= AttrUnderline
+		case code == 22:
+			style.Attrs &^= AttrBold | AttrFaint
+		case code == 23:
+			style.Attrs &^= AttrItalic
+		case code == 24:
+			style.Attrs &^= AttrUnderline
+		case code == 25:
+			style.Attrs &^= AttrBlink
+		case code == 27:
+			style.Attrs &^= AttrReverse
+		case code == 28:
+			style.Attrs &^= AttrHidden
+		case code == 29:
+			style.Attrs &^= AttrStrike
+		case code >= 30 && code <= 37:
+			style.FG = IndexedColor(uint8(code - 30))
+		case code == 38:
+			var c Color
+			c, i = extendedColor(params, i)
+			style.FG = c
+		case code == 39:
+			style.FG = DefaultColor
+		case code >= 40 && code <= 47:
+			style.BG = IndexedColor(uint8(code - 40))
+		case code == 48:
+			var c Color
+			c, i = extendedColor(params, i)
+			style.BG = c
+		case code == 49:
+			style.BG = DefaultColor
+		case code >= 90 && code <= 97:
+			style.FG = IndexedColor(uint8(code - 90 + 8))
+		case code >= 100 && code <= 107:
+			style.BG = IndexedColor(uint8(code - 100 + 8))
+		}
+	}
+}
+
+// extendedColor parses 38/48 colours in both the ';' form (38;5;n, 38;2;r;g;b)
+// and the ':' form (38:5:n, 38:2::r:g:b). It returns the colour and the index
+// of the last parameter consumed.
+func extendedColor(params [][]int, i int) (Color, int) {
+	var values []int
+	if sub := params[i][1:]; len(sub) > 0 {
+		values = sub
+		if len(values) >= 5 && values[0] == 2 {
+			// Colour space ID before r:g:b
+			values = append([]int{2}, values[2:]...)
+		}
+	} else {
+		for _, p := range params[i+1:] {
+			values = append(values, p[0])
+			if len(values) == 4 {
+				break
+			}
+		}
+	}
+
+	if len(values) == 0 {
+		return DefaultColor, i
+	}
+
+	consumed := func(n int) int {
+		if len(params[i]) > 1 {
+			return i
+		}
+		return i + n
+	}
+
+	switch values[0] {
+	case 5:
+		if len(values) < 2 {
+			return DefaultColor, consumed(len(values))
+		}
+		return IndexedColor(uint8(clampByte(values[1]))), consumed(2)
+	case 2:
+		if len(values) < 4 {
+			return DefaultColor, consumed(len(values))
+		}
+		return RGBColor(clampByte(values[1]), clampByte(values[2]), clampByte(values[3])), consumed(4)
+	}
+	return DefaultColor, consumed(1)
+}
+
+func clampByte(v int) uint8 {
+	return uint8(min(max(v, 0), 255))
+}
+
+func (t *Terminal) dispatchOSC() {
+	code, text, _ := strings.Cut(string(t.parser.osc), ";")
+	switch code {
+	case "0", "2":
+		t.title = text
+	}
+}
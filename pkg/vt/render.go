@@ -0,0 +1,150 @@
+package vt
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Render draws the screen as lines of text with SGR escape sequences, ready
+// to embed in a TUI view. The cursor, when visible and showCursor is set, is
+// drawn in reverse video.
+func (t *Terminal) Render(showCursor bool) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.render(showCursor)
+}
+
+func (t *Terminal) render(showCursor bool) string {
+	var b strings.Builder
+	grid := t.screen()
+	cursorShown := showCursor && t.cursorVisible
+
+	for y, line := range grid {
+		if y > 0 {
+			b.WriteByte('\n')
+		}
+
+		current := Style{}
+		for x, cell := range line {
+			if cell.Width == 0 {
+				continue
+			}
+
+			style := cell.Style
+			if cursorShown && x == t.cur.x && y == t.cur.y {
+				style.Attrs ^= AttrReverse
+			}
+			if style != current {
+				b.WriteString(sgr(style))
+				current = style
+			}
+
+			if cell.Rune == 0 {
+				b.WriteByte(' ')
+			} else {
+				b.WriteRune(cell.Rune)
+			}
+		}
+		if current != (Style{}) {
+			b.WriteString("\x1b[0m")
+		}
+	}
+
+	return b.String()
+}
+
+// Snapshot returns the escape sequences that redraw the visible screen,
+// cursor included, on a blank terminal of the same size. A recording started
+// mid-session opens with it.
+func (t *Terminal) Snapshot() []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var b strings.Builder
+	if t.altScreen {
+		b.WriteString("\x1b[?1049h")
+	}
+	b.WriteString("\x1b[0m\x1b[H\x1b[2J")
+	// Lines are placed with absolute moves so a full last line cannot scroll
+	for y, line := range strings.Split(t.render(false), "\n") {
+		b.WriteString("\x1b[" + strconv.Itoa(y+1) + ";1H")
+		b.WriteString(line)
+	}
+	b.WriteString("\x1b[" + strconv.Itoa(t.cur.y+1) + ";" + strconv.Itoa(t.cur.x+1) + "H")
+	if !t.cursorVisible {
+		b.WriteString("\x1b[?25l")
+	}
+	return []byte(b.String())
+}
+
+// String returns the screen as plain text with trailing spaces trimmed
+func (t *Terminal) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := make([]string, 0, t.rows)
+	for _, line := range t.screen() {
+		var b strings.Builder
+		for _, cell := range line {
+			if cell.Width == 0 {
+				continue
+			}
+			if cell.Rune == 0 {
+				b.WriteByte(' ')
+			} else {
+				b.WriteRune(cell.Rune)
+			}
+		}
+		lines = append(lines, strings.TrimRight(b.String(), " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sgr returns the escape sequence that switches from any style to style
+func sgr(style Style) string {
+	codes := []string{"0"}
+
+	for _, a := range []struct {
+		attr Attr
+		code string
+	}{
+		{AttrBold, "1"},
+		{AttrFaint, "2"},
+		{AttrItalic, "3"},
+		{AttrUnderline, "4"},
+		{AttrBlink, "5"},
+		{AttrReverse, "7"},
+		{AttrHidden, "8"},
+		{AttrStrike, "9"},
+	} {
+		if style.Attrs&a.attr != 0 {
+			codes = append(codes, a.code)
+		}
+	}
+
+	codes = appendColor(codes, style.FG, 30, 90, "38")
+	codes = appendColor(codes, style.BG, 40, 100, "48")
+
+	return "\x1b[" + strings.Join(codes, ";") + "m"
+}
+
+func appendColor(codes []string, c Color, base, brightBase int, extended string) []string {
+	switch c & colorKind {
+	case colorIndexed:
+		i := int(c & 0xff)
+		switch {
+		case i < 8:
+			return append(codes, strconv.Itoa(base+i))
+		case i < 16:
+			return append(codes, strconv.Itoa(brightBase+i-8))
+		default:
+			return append(codes, extended, "5", strconv.Itoa(i))
+		}
+	case colorRGB:
+		return append(codes, extended, "2",
+			strconv.Itoa(int(c>>16&0xff)),
+			strconv.Itoa(int(c>>8&0xff)),
+			strconv.Itoa(int(c&0xff)))
+	}
+	return codes
+}
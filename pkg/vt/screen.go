@@ -0,0 +1,611 @@
+// Package vt emulates a VT100/xterm screen so full-screen programs can be
+// rendered inside the TUI
+package vt
+
+import (
+	"sync"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Color is a cell colour: the terminal default, a 256-colour palette index
+// or 24-bit RGB
+type Color uint32
+
+// DefaultColor leaves the colour to the host terminal
+const DefaultColor Color = 0
+
+const (
+	colorIndexed Color = 1 << 24
+	colorRGB     Color = 2 << 24
+	colorKind    Color = 3 << 24
+)
+
+// IndexedColor returns a palette colour; 0-7 normal, 8-15 bright, 16-255 extended
+func IndexedColor(i uint8) Color {
+	return colorIndexed | Color(i)
+}
+
+// RGBColor returns a 24-bit colour
+func RGBColor(r, g, b uint8) Color {
+	return colorRGB | Color(r)<<16 | Color(g)<<8 | Color(b)
+}
+
+// Attr is a set of text attributes
+type Attr uint16
+
+const (
+	AttrBold Attr = 1 << iota
+	AttrFaint
+	AttrItalic
+	AttrUnderline
+	AttrBlink
+	AttrReverse
+	AttrHidden
+	AttrStrike
+)
+
+// Style is how a cell is drawn
+type Style struct {
+	FG, BG Color
+	Attrs  Attr
+}
+
+// Cell is one character position on the screen
+type Cell struct {
+	Rune  rune
+	Width int // 2 for wide characters, 0 for the cell they spill into
+	Style Style
+}
+
+func blankCell(style Style) Cell {
+	// Erased cells keep only the background, as xterm does
+	return Cell{Rune: ' ', Width: 1, Style: Style{BG: style.BG}}
+}
+
+type charset int
+
+const (
+	charsetASCII charset = iota
+	charsetLineDrawing
+)
+
+// cursor is the state saved and restored by DECSC/DECRC
+type cursor struct {
+	x, y     int
+	style    Style
+	origin   bool
+	charsets [2]charset
+	gl       int // Active charset, G0 or G1
+}
+
+// Terminal is an in-memory terminal screen fed with the output of a PTY
+type Terminal struct {
+	mu sync.Mutex
+
+	cols, rows int
+	primary    [][]Cell
+	alternate  [][]Cell
+	altScreen  bool
+
+	cur         cursor
+	saved       cursor // DECSC on the primary screen
+	altSaved    cursor // DECSC on the alternate screen
+	wrapPending bool   // Last column written; wrap before the next character
+	top, bottom int    // Scroll region, inclusive
+	tabs        []bool
+
+	appCursor      bool
+	appKeypad      bool
+	bracketedPaste bool
+	autowrap       bool
+	insert         bool
+	cursorVisible  bool
+
+	title      string
+	onResponse func([]byte)
+
+	parser parser
+}
+
+// New creates a terminal of the given size
+func New(cols, rows int) *Terminal {
+	t := &Terminal{}
+	t.resize(max(cols, 1), max(rows, 1))
+	t.reset()
+	return t
+}
+
+// OnResponse sets where replies to terminal queries (cursor position,
+// device attributes) are sent; normally back to the PTY
+func (t *Terminal) OnResponse(callback func([]byte)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onResponse = callback
+}
+
+// Size returns the screen size
+func (t *Terminal) Size() (cols, rows int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cols, t.rows
+}
+
+// Cursor returns the cursor position, zero-based
+func (t *Terminal) Cursor() (x, y int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cur.x, t.cur.y
+}
+
+// CursorVisible reports whether the program wants the cursor shown
+func (t *Terminal) CursorVisible() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cursorVisible
+}
+
+// Cell returns the cell at x, y of the visible screen
+func (t *Terminal) Cell(x, y int) Cell {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if x < 0 || y < 0 || x >= t.cols || y >= t.rows {
+		return Cell{}
+	}
+	return t.screen()[y][x]
+}
+
+// Title returns the window title set by the program
+func (t *Terminal) Title() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.title
+}
+
+// AltScreen reports whether the alternate screen is active
+func (t *Terminal) AltScreen() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.altScreen
+}
+
+// AppCursorKeys reports whether cursor keys should send SS3 sequences (DECCKM)
+func (t *Terminal) AppCursorKeys() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.appCursor
+}
+
+// BracketedPaste reports whether pasted text should be wrapped in
+// ESC[200~ / ESC[201~
+func (t *Terminal) BracketedPaste() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.bracketedPaste
+}
+
+// Resize changes the screen size, keeping the cursor line visible
+func (t *Terminal) Resize(cols, rows int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.resize(max(cols, 1), max(rows, 1))
+}
+
+func (t *Terminal) resize(cols, rows int) {
+	if cols == t.cols && rows == t.rows {
+		return
+	}
+
+	// Drop lines from the top so the cursor stays on screen
+	shift := 0
+	if t.cur.y >= rows {
+		shift = t.cur.y - rows + 1
+	}
+
+	t.primary = resizeGrid(t.primary, cols, rows, shift)
+	if t.altScreen {
+		t.alternate = resizeGrid(t.alternate, cols, rows, shift)
+	} else {
+		t.alternate = resizeGrid(t.alternate, cols, rows, 0)
+	}
+
+	t.cols, t.rows = cols, rows
+	t.cur.y -= shift
+	t.cur = t.clampCursor(t.cur)
+	t.saved = t.clampCursor(t.saved)
+	t.altSaved = t.clampCursor(t.altSaved)
+	t.wrapPending = false
+	t.top, t.bottom = 0, rows-1
+
+	t.tabs = make([]bool, cols)
+	for x := 8; x < cols; x += 8 {
+		t.tabs[x] = true
+	}
+}
+
+func resizeGrid(grid [][]Cell, cols, rows, shift int) [][]Cell {
+	resized := make([][]Cell, rows)
+	for y := range resized {
+		line := make([]Cell, cols)
+		for x := range line {
+			line[x] = blankCell(Style{})
+		}
+		if src := y + shift; src < len(grid) {
+			copy(line, grid[src])
+			// A wide character cut in half by the new width becomes blank
+			if last := line[cols-1]; last.Width == 2 {
+				line[cols-1] = blankCell(last.Style)
+			}
+		}
+		resized[y] = line
+	}
+	return resized
+}
+
+func (t *Terminal) clampCursor(c cursor) cursor {
+	c.x = min(max(c.x, 0), t.cols-1)
+	c.y = min(max(c.y, 0), t.rows-1)
+	return c
+}
+
+// reset restores the power-on state (RIS)
+func (t *Terminal) reset() {
+	t.altScreen = false
+	t.cur = cursor{}
+	t.saved = cursor{}
+	t.altSaved = cursor{}
+	t.wrapPending = false
+	t.appCursor = false
+	t.appKeypad = false
+	t.bracketedPaste = false
+	t.autowrap = true
+	t.insert = false
+	t.cursorVisible = true
+	t.title = ""
+	t.primary = resizeGrid(nil, t.cols, t.rows, 0)
+	t.alternate = resizeGrid(nil, t.cols, t.rows, 0)
+	t.top, t.bottom = 0, t.rows-1
+	for x := range t.tabs {
+		t.tabs[x] = x > 0 && x%8 == 0
+	}
+}
+
+func (t *Terminal) screen() [][]Cell {
+	if t.altScreen {
+		return t.alternate
+	}
+	return t.primary
+}
+
+func (t *Terminal) respond(s string) {
+	if t.onResponse != nil {
+		t.onResponse([]byte(s))
+	}
+}
+
+// put writes a printable character at the cursor
+func (t *Terminal) put(r rune) {
+	if t.cur.charsets[t.cur.gl] == charsetLineDrawing {
+		r = lineDrawing(r)
+	}
+
+	width := runewidth.RuneWidth(r)
+	if width == 0 {
+		// Combining marks and zero-width characters are not rendered separately
+		return
+	}
+
+	if t.wrapPending && t.autowrap {
+		t.cur.x = 0
+		t.lineFeed()
+	}
+	t.wrapPending = false
+
+	if width == 2 && t.cur.x == t.cols-1 {
+		if !t.autowrap {
+			return
+		}
+		// No room for both halves, wrap early
+		t.screen()[t.cur.y][t.cur.x] = blankCell(t.cur.style)
+		t.cur.x = 0
+		t.lineFeed()
+	}
+	if width > t.cols {
+		return
+	}
+
+	line := t.screen()[t.cur.y]
+	if t.insert {
+		copy(line[t.cur.x+width:], line[t.cur.x:])
+	}
+
+	t.clearWide(line, t.cur.x)
+	if width == 2 {
+		t.clearWide(line, t.cur.x+1)
+	}
+	line[t.cur.x] = Cell{Rune: r, Width: width, Style: t.cur.style}
+	if width == 2 {
+		line[t.cur.x+1] = Cell{Width: 0, Style: t.cur.style}
+	}
+
+	t.cur.x += width
+	if t.cur.x >= t.cols {
+		t.cur.x = t.cols - 1
+		t.wrapPending = true
+	}
+}
+
+// clearWide blanks the other half of a wide character about to be overwritten at x
+func (t *Terminal) clearWide(line []Cell, x int) {
+	if x >= len(line) {
+		return
+	}
+	switch line[x].Width {
+	case 0:
+		if x > 0 {
+			line[x-1] = blankCell(line[x-1].Style)
+		}
+	case 2:
+		if x+1 < len(line) {
+			line[x+1] = blankCell(line[x+1].Style)
+		}
+	}
+}
+
+// lineFeed moves down a line, scrolling at the bottom of the scroll region
+func (t *Terminal) lineFeed() {
+	switch {
+	case t.cur.y == t.bottom:
+		t.scrollUp(1)
+	case t.cur.y < t.rows-1:
+		t.cur.y++
+	}
+}
+
+// reverseIndex moves up a line, scrolling at the top of the scroll region
+func (t *Terminal) reverseIndex() {
+	switch {
+	case t.cur.y == t.top:
+		t.scrollDown(1)
+	case t.cur.y > 0:
+		t.cur.y--
+	}
+}
+
+// scrollUp moves the scroll region's lines up by n, blanking the bottom
+func (t *Terminal) scrollUp(n int) {
+	t.scrollRegion(t.top, t.bottom, n)
+}
+
+// scrollDown moves the scroll region's lines down by n, blanking the top
+func (t *Terminal) scrollDown(n int) {
+	t.scrollRegion(t.top, t.bottom, -n)
+}
+
+// scrollRegion shifts lines top..bottom up by n (down when negative)
+func (t *Terminal) scrollRegion(top, bottom, n int) {
+	grid := t.screen()
+	height := bottom - top + 1
+	if n > height {
+		n = height
+	} else if n < -height {
+		n = -height
+	}
+
+	region := grid[top : bottom+1]
+	switch {
+	case n > 0:
+		recycled := append([][]Cell(nil), region[:n]...)
+		copy(region, region[n:])
+		for i, line := range recycled {
+			t.blankLine(line)
+			region[height-n+i] = line
+		}
+	case n < 0:
+		n = -n
+		recycled := append([][]Cell(nil), region[height-n:]...)
+		copy(region[n:], region[:height-n])
+		for i, line := range recycled {
+			t.blankLine(line)
+			region[i] = line
+		}
+	}
+}
+
+func (t *Terminal) blankLine(line []Cell) {
+	for x := range line {
+		line[x] = blankCell(t.cur.style)
+	}
+}
+
+// setCursor moves the cursor; in origin mode y is relative to the scroll region
+func (t *Terminal) setCursor(x, y int) {
+	minY, maxY := 0, t.rows-1
+	if t.cur.origin {
+		y += t.top
+		minY, maxY = t.top, t.bottom
+	}
+	t.cur.x = min(max(x, 0), t.cols-1)
+	t.cur.y = min(max(y, minY), maxY)
+	t.wrapPending = false
+}
+
+// moveCursor moves relative to the current position without leaving the
+// scroll region if the cursor started inside it
+func (t *Terminal) moveCursor(dx, dy int) {
+	minY, maxY := 0, t.rows-1
+	if t.cur.y >= t.top && t.cur.y <= t.bottom {
+		minY, maxY = t.top, t.bottom
+	}
+	t.cur.x = min(max(t.cur.x+dx, 0), t.cols-1)
+	t.cur.y = min(max(t.cur.y+dy, minY), maxY)
+	t.wrapPending = false
+}
+
+func (t *Terminal) tab(n int) {
+	for ; n > 0 && t.cur.x < t.cols-1; n-- {
+		t.cur.x++
+		for t.cur.x < t.cols-1 && !t.tabs[t.cur.x] {
+			t.cur.x++
+		}
+	}
+	t.wrapPending = false
+}
+
+func (t *Terminal) backTab(n int) {
+	for ; n > 0 && t.cur.x > 0; n-- {
+		t.cur.x--
+		for t.cur.x > 0 && !t.tabs[t.cur.x] {
+			t.cur.x--
+		}
+	}
+	t.wrapPending = false
+}
+
+// eraseInDisplay implements ED: 0 below, 1 above, 2 and 3 everything
+func (t *Terminal) eraseInDisplay(mode int) {
+	grid := t.screen()
+	switch mode {
+	case 0:
+		t.eraseInLine(0)
+		for y := t.cur.y + 1; y < t.rows; y++ {
+			t.blankLine(grid[y])
+		}
+	case 1:
+		t.eraseInLine(1)
+		for y := 0; y < t.cur.y; y++ {
+			t.blankLine(grid[y])
+		}
+	case 2, 3:
+		for y := range grid {
+			t.blankLine(grid[y])
+		}
+	}
+}
+
+// eraseInLine implements EL: 0 right of the cursor, 1 left, 2 the whole line
+func (t *Terminal) eraseInLine(mode int) {
+	line := t.screen()[t.cur.y]
+	from, to := 0, t.cols
+	switch mode {
+	case 0:
+		from = t.cur.x
+	case 1:
+		to = t.cur.x + 1
+	}
+	for x := from; x < to; x++ {
+		line[x] = blankCell(t.cur.style)
+	}
+	t.wrapPending = false
+}
+
+// eraseChars implements ECH, blanking n cells from the cursor
+func (t *Terminal) eraseChars(n int) {
+	line := t.screen()[t.cur.y]
+	for x := t.cur.x; x < t.cur.x+n && x < t.cols; x++ {
+		line[x] = blankCell(t.cur.style)
+	}
+	t.wrapPending = false
+}
+
+// insertChars implements ICH, shifting the rest of the line right
+func (t *Terminal) insertChars(n int) {
+	line := t.screen()[t.cur.y]
+	n = min(n, t.cols-t.cur.x)
+	copy(line[t.cur.x+n:], line[t.cur.x:])
+	for x := t.cur.x; x < t.cur.x+n; x++ {
+		line[x] = blankCell(t.cur.style)
+	}
+	t.wrapPending = false
+}
+
+// deleteChars implements DCH, shifting the rest of the line left
+func (t *Terminal) deleteChars(n int) {
+	line := t.screen()[t.cur.y]
+	n = min(n, t.cols-t.cur.x)
+	copy(line[t.cur.x:], line[t.cur.x+n:])
+	for x := t.cols - n; x < t.cols; x++ {
+		line[x] = blankCell(t.cur.style)
+	}
+	t.wrapPending = false
+}
+
+// insertLines implements IL within the scroll region
+func (t *Terminal) insertLines(n int) {
+	if t.cur.y < t.top || t.cur.y > t.bottom {
+		return
+	}
+	t.scrollRegion(t.cur.y, t.bottom, -n)
+	t.cur.x = 0
+	t.wrapPending = false
+}
+
+// deleteLines implements DL within the scroll region
+func (t *Terminal) deleteLines(n int) {
+	if t.cur.y < t.top || t.cur.y > t.bottom {
+		return
+	}
+	t.scrollRegion(t.cur.y, t.bottom, n)
+	t.cur.x = 0
+	t.wrapPending = false
+}
+
+// setScrollRegion implements DECSTBM; top and bottom are one-based
+func (t *Terminal) setScrollRegion(top, bottom int) {
+	if top < 1 {
+		top = 1
+	}
+	if bottom < 1 || bottom > t.rows {
+		bottom = t.rows
+	}
+	if top >= bottom {
+		return
+	}
+	t.top, t.bottom = top-1, bottom-1
+	t.setCursor(0, 0)
+}
+
+func (t *Terminal) saveCursor() {
+	if t.altScreen {
+		t.altSaved = t.cur
+	} else {
+		t.saved = t.cur
+	}
+}
+
+func (t *Terminal) restoreCursor() {
+	if t.altScreen {
+		t.cur = t.clampCursor(t.altSaved)
+	} else {
+		t.cur = t.clampCursor(t.saved)
+	}
+	t.wrapPending = false
+}
+
+// switchScreen enters or leaves the alternate screen, clearing it on entry
+func (t *Terminal) switchScreen(alt, saveCursor bool) {
+	if alt == t.altScreen {
+		return
+	}
+	if alt {
+		if saveCursor {
+			t.saved = t.cur
+		}
+		t.altScreen = true
+		t.eraseInDisplay(2)
+	} else {
+		t.altScreen = false
+		if saveCursor {
+			t.cur = t.clampCursor(t.saved)
+		}
+	}
+	t.wrapPending = false
+}
+
+// lineDrawing maps the DEC special graphics set to Unicode box drawing
+func lineDrawing(r rune) rune {
+	if r < 0x5f || r > 0x7e {
+		return r
+	}
+	return []rune(" ◆▒␉␌␍␊°±␤␋┘┐┌└┼⎺⎻─⎼⎽├┤┴┬│≤≥π≠£·")[r-0x5f]
+}
@@ -0,0 +1,292 @@
+package vt
+
+import (
+	"strings"
+	"testing"
+)
+
+func write(t *testing.T, term *Terminal, s string) {
+	t.Helper()
+	if _, err := term.Write([]byte(s)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+}
+
+func expectScreen(t *testing.T, term *Terminal, want string) {
+	t.Helper()
+	if got := term.String(); got != want {
+		t.Errorf("Unexpected screen:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func expectCursor(t *testing.T, term *Terminal, x, y int) {
+	t.Helper()
+	if gx, gy := term.Cursor(); gx != x || gy != y {
+		t.Errorf("Expected cursor at %d,%d, got %d,%d", x, y, gx, gy)
+	}
+}
+
+func TestTextAndWrapping(t *testing.T) {
+	term := New(5, 3)
+
+	write(t, term, "hello")
+	expectCursor(t, term, 4, 0) // Wrap is deferred until the next character
+
+	write(t, term, "world\r\nab\tc")
+	expectScreen(t, term, "hello\nworld\nab  c")
+
+	t.Run("Scrolls at the bottom", func(t *testing.T) {
+		write(t, term, "\r\nnext")
+		expectScreen(t, term, "world\nab  c\nnext")
+	})
+
+	t.Run("No wrap when autowrap is off", func(t *testing.T) {
+		term := New(5, 2)
+		write(t, term, "\x1b[?7labcdefg")
+		expectScreen(t, term, "abcdg\n")
+	})
+}
+
+func TestCursorMovementAndErase(t *testing.T) {
+	term := New(10, 4)
+	write(t, term, "0123456789\r\n0123456789\r\n0123456789\r\n0123456789")
+
+	write(t, term, "\x1b[2;3H")
+	expectCursor(t, term, 2, 1)
+
+	write(t, term, "\x1b[K")
+	write(t, term, "\x1b[B\x1b[4G\x1b[1K")
+	write(t, term, "\x1b[4;5H\x1b[3X")
+	expectScreen(t, term, "0123456789\n01\n    456789\n0123   789")
+
+	write(t, term, "\x1b[2J\x1b[H")
+	expectScreen(t, term, "\n\n\n")
+	expectCursor(t, term, 0, 0)
+
+	t.Run("Movement stops at the edges", func(t *testing.T) {
+		write(t, term, "\x1b[5A\x1b[20C")
+		expectCursor(t, term, 9, 0)
+		write(t, term, "\x1b[99;99H")
+		expectCursor(t, term, 9, 3)
+	})
+}
+
+func TestInsertDeleteCharacters(t *testing.T) {
+	term := New(8, 1)
+	write(t, term, "abcdef\x1b[1;3H\x1b[2@XY")
+	expectScreen(t, term, "abXYcdef")
+
+	write(t, term, "\x1b[1;2H\x1b[3P")
+	expectScreen(t, term, "acdef")
+
+	write(t, term, "\x1b[4h\x1b[1;1HZ\x1b[4l")
+	expectScreen(t, term, "Zacdef")
+}
+
+func TestScrollRegion(t *testing.T) {
+	term := New(4, 5)
+	write(t, term, "a\r\nb\r\nc\r\nd\r\ne")
+
+	// Region rows 2-4: scrolling leaves the first and last lines alone
+	write(t, term, "\x1b[2;4r")
+	expectCursor(t, term, 0, 0)
+	write(t, term, "\x1b[4;1H\nX")
+	expectScreen(t, term, "a\nc\nd\nX\ne")
+
+	t.Run("Reverse index scrolls down at the top", func(t *testing.T) {
+		write(t, term, "\x1b[2;1H\x1bMY")
+		expectScreen(t, term, "a\nY\nc\nd\ne")
+	})
+
+	t.Run("Insert and delete lines", func(t *testing.T) {
+		write(t, term, "\x1b[3;1H\x1b[L")
+		expectScreen(t, term, "a\nY\n\nc\ne")
+		write(t, term, "\x1b[2;1H\x1b[2M")
+		expectScreen(t, term, "a\nc\n\n\ne")
+	})
+
+	t.Run("SU and SD", func(t *testing.T) {
+		term := New(2, 3)
+		write(t, term, "1\r\n2\r\n3\x1b[S")
+		expectScreen(t, term, "2\n3\n")
+		write(t, term, "\x1b[2T")
+		expectScreen(t, term, "\n\n2")
+	})
+}
+
+func TestAlternateScreen(t *testing.T) {
+	term := New(6, 2)
+	write(t, term, "shell$\r\n> ")
+
+	write(t, term, "\x1b[?1049h")
+	if !term.AltScreen() {
+		t.Fatal("Expected alternate screen")
+	}
+	expectScreen(t, term, "\n")
+	write(t, term, "\x1b[Hvim")
+	expectScreen(t, term, "vim\n")
+
+	write(t, term, "\x1b[?1049l")
+	if term.AltScreen() {
+		t.Fatal("Expected primary screen")
+	}
+	expectScreen(t, term, "shell$\n>")
+	expectCursor(t, term, 2, 1)
+}
+
+func TestSGR(t *testing.T) {
+	term := New(10, 1)
+	write(t, term, "\x1b[1;31mA\x1b[38;5;200;48;2;1;2;3mB\x1b[38:2::4:5:6;4mC\x1b[0;7;94mD\x1b[mE")
+
+	tests := []struct {
+		x    int
+		want Style
+	}{
+		{0, Style{FG: IndexedColor(1), Attrs: AttrBold}},
+		{1, Style{FG: IndexedColor(200), BG: RGBColor(1, 2, 3), Attrs: AttrBold}},
+		{2, Style{FG: RGBColor(4, 5, 6), BG: RGBColor(1, 2, 3), Attrs: AttrBold | AttrUnderline}},
+		{3, Style{FG: IndexedColor(12), Attrs: AttrReverse}},
+		{4, Style{}},
+	}
+	for _, tt := range tests {
+		if got := term.Cell(tt.x, 0).Style; got != tt.want {
+			t.Errorf("Cell %d style = %+v, want %+v", tt.x, got, tt.want)
+		}
+	}
+
+	t.Run("Erase uses the current background", func(t *testing.T) {
+		term := New(3, 1)
+		write(t, term, "\x1b[41;1m\x1b[2K")
+		if got := term.Cell(2, 0).Style; got != (Style{BG: IndexedColor(1)}) {
+			t.Errorf("Expected red background only, got %+v", got)
+		}
+	})
+
+	t.Run("Render emits SGR sequences", func(t *testing.T) {
+		term := New(3, 1)
+		write(t, term, "a\x1b[1;32mb\x1b[mc")
+		if got := term.Render(false); got != "a\x1b[0;1;32mb\x1b[0mc" {
+			t.Errorf("Unexpected render %q", got)
+		}
+		// The cursor stays on the last column until the next character wraps
+		if got := term.Render(true); !strings.Contains(got, "\x1b[0;7mc") {
+			t.Errorf("Expected cursor in reverse video, got %q", got)
+		}
+	})
+}
+
+func TestModesAndResponses(t *testing.T) {
+	term := New(10, 5)
+	var responses []string
+	term.OnResponse(func(b []byte) {
+		responses = append(responses, string(b))
+	})
+
+	write(t, term, "\x1b[?1h\x1b[?2004h\x1b[?25l")
+	if !term.AppCursorKeys() || !term.BracketedPaste() || term.CursorVisible() {
+		t.Error("Expected application cursor keys, bracketed paste and a hidden cursor")
+	}
+	write(t, term, "\x1b[?1l\x1b[?2004l\x1b[?25h")
+	if term.AppCursorKeys() || term.BracketedPaste() || !term.CursorVisible() {
+		t.Error("Expected modes to be reset")
+	}
+
+	write(t, term, "\x1b[3;4H\x1b[6n\x1b[5n\x1b[c")
+	want := []string{"\x1b[3;4R", "\x1b[0n", "\x1b[?1;2c"}
+	if strings.Join(responses, "|") != strings.Join(want, "|") {
+		t.Errorf("Unexpected responses %q", responses)
+	}
+
+	write(t, term, "\x1b]2;build: ok\x07\x1b]0;other\x1b\\")
+	if term.Title() != "other" {
+		t.Errorf("Expected title 'other', got %q", term.Title())
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	term := New(6, 2)
+
+	// A multi-byte character split across two writes
+	write(t, term, "é\xe4\xb8")
+	write(t, term, "\xad文")
+	expectScreen(t, term, "é中文\n")
+	if c := term.Cell(1, 0); c.Rune != '中' || c.Width != 2 {
+		t.Errorf("Expected wide character, got %+v", c)
+	}
+	expectCursor(t, term, 5, 0)
+
+	t.Run("Wide character wraps when it does not fit", func(t *testing.T) {
+		write(t, term, "字")
+		expectScreen(t, term, "é中文\n字")
+	})
+
+	t.Run("Overwriting half a wide character blanks the other half", func(t *testing.T) {
+		write(t, term, "\x1b[1;3Hx")
+		expectScreen(t, term, "é x文\n字")
+	})
+
+	t.Run("DEC line drawing", func(t *testing.T) {
+		term := New(5, 1)
+		write(t, term, "\x1b(0lqqk\x1b(Bx")
+		expectScreen(t, term, "┌──┐x")
+	})
+}
+
+func TestResize(t *testing.T) {
+	term := New(6, 4)
+	write(t, term, "one\r\ntwo\r\nthree\r\nfour")
+
+	term.Resize(4, 2)
+	if cols, rows := term.Size(); cols != 4 || rows != 2 {
+		t.Fatalf("Expected 4x2, got %dx%d", cols, rows)
+	}
+	// The cursor line stays visible
+	expectScreen(t, term, "thre\nfour")
+	expectCursor(t, term, 3, 1)
+
+	term.Resize(8, 3)
+	expectScreen(t, term, "thre\nfour\n")
+
+	// The scroll region covers the new size
+	write(t, term, "\x1b[3;1H\nend")
+	expectScreen(t, term, "four\n\nend")
+}
+
+func TestMalformedInput(t *testing.T) {
+	term := New(10, 2)
+	write(t, term, "\x1b[999999999999;99999999999999H")
+	write(t, term, "\x1b["+strings.Repeat("1;", 100)+"m")
+	write(t, term, "\x1b[H\x1bPignored payload\x1b\\ok")
+	write(t, term, "\x1b[31\x18x")
+	write(t, term, "\xff")
+	if got := term.String(); !strings.Contains(got, "okx�") {
+		t.Errorf("Unexpected screen %q", got)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	term := New(6, 3)
+	write(t, term, "top\r\n\x1b[1;31mred\x1b[m\r\nbottom\x1b[2;2H\x1b[?25l")
+
+	// Replaying the snapshot on a fresh terminal gives the same screen
+	replay := New(6, 3)
+	write(t, replay, string(term.Snapshot()))
+	expectScreen(t, replay, term.String())
+	expectCursor(t, replay, 1, 1)
+	if replay.CursorVisible() {
+		t.Error("Expected hidden cursor to stay hidden")
+	}
+	if got := replay.Cell(0, 1).Style; got != (Style{FG: IndexedColor(1), Attrs: AttrBold}) {
+		t.Errorf("Expected style to be kept, got %+v", got)
+	}
+
+	t.Run("Alternate screen", func(t *testing.T) {
+		write(t, term, "\x1b[?1049h\x1b[Hfull")
+		replay := New(6, 3)
+		write(t, replay, string(term.Snapshot()))
+		if !replay.AltScreen() {
+			t.Error("Expected snapshot to switch to the alternate screen")
+		}
+		expectScreen(t, replay, "full\n\n")
+	})
+}